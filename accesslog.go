@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// accessLog receives one line per request. It is nil when disabled.
+	accessLog      io.Writer
+	accessLogMutex = &sync.Mutex{}
+)
+
+// setupAccessLog opens the access log destination: "stdout", "stderr",
+// "off" or a file path.
+func setupAccessLog() error {
+	switch cfg.AccessLog {
+	case "off":
+		accessLog = nil
+	case "stdout":
+		accessLog = os.Stdout
+	case "stderr":
+		accessLog = os.Stderr
+	default:
+		f, err := os.OpenFile(cfg.AccessLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		accessLog = f
+	}
+	return nil
+}
+
+// accessLogger writes an access log entry for every request the server
+// receives, including ones that match no route.
+func accessLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accessLog == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		line := formatAccessLog(r, rec, start, time.Since(start))
+
+		accessLogMutex.Lock()
+		defer accessLogMutex.Unlock()
+		io.WriteString(accessLog, line)
+	})
+}
+
+func formatAccessLog(r *http.Request, rec *statusRecorder, start time.Time, latency time.Duration) string {
+	user := "-"
+	if p, ok := authenticate(r); ok {
+		user = p.Subject
+	}
+
+	switch cfg.AccessLogFormat {
+	case "json":
+		b, _ := json.Marshal(map[string]interface{}{
+			"time":       start.UTC().Format(time.RFC3339Nano),
+			"method":     r.Method,
+			"path":       r.URL.RequestURI(),
+			"proto":      r.Proto,
+			"status":     rec.status,
+			"bytes":      rec.bytes,
+			"latency_ms": float64(latency.Microseconds()) / 1000,
+			"client":     clientIP(r),
+			"user":       user,
+			"referer":    r.Referer(),
+			"user_agent": r.UserAgent(),
+		})
+		return string(b) + "\n"
+	case "common":
+		return fmt.Sprintf("%s - %s [%s] %q %d %d %d\n",
+			clientIP(r), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+r.URL.RequestURI()+" "+r.Proto, rec.status, rec.bytes, latency.Microseconds())
+	default:
+		return fmt.Sprintf("%s - %s [%s] %q %d %d %q %q %d\n",
+			clientIP(r), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+r.URL.RequestURI()+" "+r.Proto, rec.status, rec.bytes,
+			orDash(r.Referer()), orDash(r.UserAgent()), latency.Microseconds())
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
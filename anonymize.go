@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	fakeFirstNames = []string{"Alex", "Blake", "Casey", "Drew", "Emery", "Finley", "Harper", "Jordan", "Kai", "Logan", "Morgan", "Parker", "Quinn", "Riley", "Sage", "Taylor"}
+	fakeLastNames  = []string{"Adams", "Brooks", "Carter", "Diaz", "Ellis", "Foster", "Gray", "Hayes", "Irwin", "Jensen", "Kim", "Lopez", "Mills", "Nash", "Owens", "Patel"}
+)
+
+// pseudonymHash mixes in the configured salt so pseudonyms cannot be
+// reversed by hashing a list of known names.
+func pseudonymHash(value string) []byte {
+	sum := sha256.Sum256([]byte(cfg.AnonymizeSalt + "\x00" + value))
+	return sum[:]
+}
+
+// anonymizeStudent replaces the student's name and email with pseudonyms
+// derived from the originals, so the same input always maps to the same output.
+func anonymizeStudent(s Student) Student {
+	h := pseudonymHash(s.Name)
+	first := fakeFirstNames[binary.BigEndian.Uint16(h[0:2])%uint16(len(fakeFirstNames))]
+	last := fakeLastNames[binary.BigEndian.Uint16(h[2:4])%uint16(len(fakeLastNames))]
+	s.Name = first + " " + last
+
+	s.Email = fmt.Sprintf("student-%s@example.invalid", hex.EncodeToString(pseudonymHash(s.Email)[:6]))
+	return s
+}
+
+// anonymizeAll scrubs every student and the copies of their details kept
+// elsewhere: the change feed, webhook deliveries and dead letters, the
+// email log, cached summaries and the audit trail, in memory and in
+// audit_log_file. Photos, exports and backups, which cannot be scrubbed,
+// are deleted. The copies are scrubbed first, so the updates below
+// are not scrubbed twice. Each student is replaced as an update would
+// replace it, so caches follow; the update events carry pseudonyms on
+// both sides, not the real values they replace.
+func anonymizeAll(ctx context.Context) (int, error) {
+	list := allStudents(ctx)
+	names := anonymizeChanges(ctx)
+	for _, s := range list {
+		names[s.ID] = append(names[s.ID], s.Name)
+	}
+	anonymizeDeliveries(ctx)
+	anonymizeEmailLog(ctx, names)
+	forgetSummaries(ctx)
+	for _, s := range list {
+		removeStudentPhoto(ctx, s.ID)
+	}
+	for _, e := range exportStore.List(ctx) {
+		removeExport(ctx, e.ID)
+	}
+	for _, b := range backupStore.List(ctx) {
+		removeBackup(ctx, b.ID)
+	}
+
+	for _, s := range list {
+		scrubbed := anonymizeStudent(s)
+		scrubbed.UpdatedAt = clock.Now().UTC()
+		if _, ok := replaceStudent(ctx, s.ID, scrubbed); ok {
+			publishEvent(ctx, StudentUpdated{Before: anonymizeStudent(s), After: scrubbed})
+		}
+	}
+	return len(list), anonymizeAuditLog(ctx)
+}
+
+// anonymizeChanges scrubs the students recorded in the change feed and
+// returns the names each student had in it.
+func anonymizeChanges(ctx context.Context) map[int][]string {
+	feed := &stateOf(ctx).changes
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	names := make(map[int][]string)
+	for _, c := range changeStore.List(ctx) {
+		if c.Entity != "student" || c.Data == nil {
+			continue
+		}
+		// Data is a Student, or its JSON once a file store has read it back.
+		var s Student
+		if err := remarshal(c.Data, &s); err != nil {
+			slog.WarnContext(ctx, "dropping change data that could not be anonymized", "change_id", c.ID, "error", err)
+			c.Data = nil
+		} else {
+			names[c.EntityID] = append(names[c.EntityID], s.Name)
+			c.Data = anonymizeStudent(s)
+		}
+		repoReplace(ctx, changeStore, c.ID, c)
+	}
+	return names
+}
+
+// anonymizeDeliveries scrubs the events in webhook deliveries, pending,
+// delivered and dead-lettered alike. Summaries are dropped, as there is
+// no telling what of the student they repeat.
+func anonymizeDeliveries(ctx context.Context) {
+	for _, d := range deliveryStore.List(ctx) {
+		payload, err := anonymizeEventJSON(d.EventType, d.Payload)
+		if err != nil {
+			slog.WarnContext(ctx, "dropping webhook payload that could not be anonymized", "delivery_id", d.ID, "error", err)
+			payload = nil
+		}
+		d.Payload = payload
+		repoReplace(ctx, deliveryStore, d.ID, d)
+	}
+}
+
+// anonymizeEventJSON scrubs an event encoded as JSON.
+func anonymizeEventJSON(eventType string, payload json.RawMessage) (json.RawMessage, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+	var e struct {
+		Event
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	var data any
+	switch eventType {
+	case eventStudentCreated:
+		var p StudentCreated
+		err := json.Unmarshal(e.Data, &p)
+		p.Student = anonymizeStudent(p.Student)
+		data = p
+		if err != nil {
+			return nil, err
+		}
+	case eventStudentUpdated:
+		var p StudentUpdated
+		err := json.Unmarshal(e.Data, &p)
+		p.Before, p.After = anonymizeStudent(p.Before), anonymizeStudent(p.After)
+		data = p
+		if err != nil {
+			return nil, err
+		}
+	case eventStudentDeleted:
+		var p StudentDeleted
+		err := json.Unmarshal(e.Data, &p)
+		p.Student = anonymizeStudent(p.Student)
+		data = p
+		if err != nil {
+			return nil, err
+		}
+	case eventSummaryGenerated:
+		var p SummaryGenerated
+		err := json.Unmarshal(e.Data, &p)
+		p.Summary = ""
+		data = p
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return payload, nil
+	}
+	var err error
+	if e.Data, err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// anonymizeEmailLog scrubs the addresses emails went to, students' and
+// guardians' alike, and the student names in their subjects: names holds
+// every name a student is known to have had, as a subject keeps the name
+// the student had when the email was sent.
+func anonymizeEmailLog(ctx context.Context, names map[int][]string) {
+	for _, e := range emailLogStore.List(ctx) {
+		e.To = anonymizeStudent(Student{Email: e.To}).Email
+		// Longer names first, so no name is replaced inside another.
+		known := slices.SortedFunc(slices.Values(names[e.StudentID]), func(a, b string) int {
+			return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+		})
+		for _, name := range slices.Compact(known) {
+			if name != "" {
+				e.Subject = strings.ReplaceAll(e.Subject, name, anonymizeStudent(Student{Name: name}).Name)
+			}
+		}
+		repoReplace(ctx, emailLogStore, e.ID, e)
+	}
+}
+
+// forgetSummaries drops every cached summary, as summaries repeat the
+// details they were generated from.
+func forgetSummaries(ctx context.Context) {
+	cache := &stateOf(ctx).summaries
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	clear(cache.entries)
+}
+
+// remarshal converts v to out through JSON.
+func remarshal(v, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// anonymizeAuditLog scrubs the audit trail and rewrites its file, through a
+// temporary file so a failure leaves the old one whole.
+func anonymizeAuditLog(ctx context.Context) error {
+	trail := &stateOf(ctx).audit
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	for i := range trail.entries {
+		if b := trail.entries[i].Before; b != nil {
+			anon := anonymizeStudent(*b)
+			trail.entries[i].Before = &anon
+		}
+		if a := trail.entries[i].After; a != nil {
+			anon := anonymizeStudent(*a)
+			trail.entries[i].After = &anon
+		}
+	}
+	if cfg.AuditLogFile == "" {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cfg.AuditLogFile), filepath.Base(cfg.AuditLogFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, entry := range trail.entries {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := errors.Join(w.Flush(), tmp.Sync(), tmp.Close()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cfg.AuditLogFile)
+}
+
+// anonymizeStudents is the admin operation behind POST /admin/anonymize.
+// It is refused in production so real records are never overwritten.
+func anonymizeStudents(w http.ResponseWriter, r *http.Request) {
+	if cfg.isProduction() {
+		writeProblem(w, r, http.StatusForbidden, "Anonymization is disabled in production")
+		return
+	}
+
+	count, err := anonymizeAll(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to rewrite audit log file", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Students were anonymized but the audit log file could not be rewritten")
+		return
+	}
+	slog.InfoContext(r.Context(), "anonymized students", "count", count)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"anonymized": count})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	api := newTestAPI(t, func(c *Config) {
+		c.AuditLogFile = auditFile
+		// The sender is not started, so the welcome email is only logged.
+		c.SMTPHost, c.SMTPFrom = "smtp.example.com", "registrar@example.com"
+	})
+	var hook Webhook
+	api.decode(t, "POST", "/v1/webhooks", Webhook{URL: "https://hooks.example.com/studengo", Events: []string{eventStudentCreated, eventStudentUpdated}}, http.StatusCreated, &hook)
+	var ada Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &ada)
+	path := "/v1/students/" + strconv.Itoa(ada.ID)
+	api.decode(t, "PUT", path, Student{Name: "Ada King", Age: 21, Email: "ada@example.com"}, http.StatusOK, nil)
+	var before Student
+	api.decode(t, "GET", path, nil, http.StatusOK, &before)
+	// The delivery of the create runs out of attempts.
+	ctx := api.srv.state.context(context.Background())
+	d, _ := deliveryStore.Find(ctx, 1)
+	d.Status, d.DeadLetteredAt = deliveryFailed, clock.Now().UTC()
+	deliveryStore.Replace(ctx, d.ID, d)
+
+	var result struct{ Anonymized int }
+	api.decode(t, "POST", "/v1/admin/anonymize", nil, http.StatusOK, &result)
+	if result.Anonymized != 1 {
+		t.Errorf("anonymized %d students, want 1", result.Anonymized)
+	}
+
+	// The read goes through the same caches as the one before it.
+	var got Student
+	api.decode(t, "GET", path, nil, http.StatusOK, &got)
+	if got.Name == before.Name || !strings.HasSuffix(got.Email, "@example.invalid") {
+		t.Errorf("student after anonymizing: %+v", got)
+	}
+	_, trail := api.do(t, "GET", "/v1/admin/audit", nil)
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, changes := api.do(t, "GET", "/v1/changes", nil)
+	_, deliveries := api.do(t, "GET", "/v1/webhooks/"+strconv.Itoa(hook.ID)+"/deliveries", nil)
+	_, dead := api.do(t, "GET", "/v1/webhooks/dead-letters", nil)
+	_, emails := api.do(t, "GET", "/v1/admin/emails", nil)
+	if !strings.Contains(string(dead), `"event_id"`) || !strings.Contains(string(emails), "Welcome") {
+		t.Fatalf("dead letters %s, email log %s: want one entry each", dead, emails)
+	}
+	for name, text := range map[string]string{
+		"audit trail":          string(trail),
+		"audit log file":       string(data),
+		"change feed":          string(changes),
+		"webhook deliveries":   string(deliveries),
+		"webhook dead letters": string(dead),
+		"email log":            string(emails),
+	} {
+		if strings.Contains(text, "Lovelace") || strings.Contains(text, "King") || strings.Contains(text, "ada@example.com") {
+			t.Errorf("%s still names the student: %s", name, text)
+		}
+	}
+	if !strings.Contains(string(data), got.Email) {
+		t.Errorf("audit log file lost its entries: %s", data)
+	}
+}
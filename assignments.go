@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Assignment is a piece of coursework with a due date.
+type Assignment struct {
+	ID          int       `json:"id"`
+	CourseID    int       `json:"course_id"`
+	Title       string    `json:"title" validate:"required"`
+	Description string    `json:"description,omitempty"`
+	DueAt       time.Time `json:"due_at" validate:"required"`
+	MaxPoints   float64   `json:"max_points" validate:"positive"`
+}
+
+func (a Assignment) entityID() int { return a.ID }
+
+func (a Assignment) withID(id int) Assignment {
+	a.ID = id
+	return a
+}
+
+// Submission is a student's work for an assignment. Late is set when it
+// arrives after the due date; Score is null until it is graded.
+type Submission struct {
+	ID           int       `json:"id"`
+	AssignmentID int       `json:"assignment_id"`
+	StudentID    int       `json:"student_id"`
+	Content      string    `json:"content"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+	Late         bool      `json:"late"`
+	Score        *float64  `json:"score"`
+}
+
+func (s Submission) entityID() int { return s.ID }
+
+func (s Submission) withID(id int) Submission {
+	s.ID = id
+	return s
+}
+
+var (
+	assignmentStore Repository[Assignment] = newCollection[Assignment]("assignments")
+	submissionStore Repository[Submission] = newCollection[Submission]("submissions")
+)
+
+// submissionMutex serializes submitting so each student submits once.
+var submissionMutex = &sync.Mutex{}
+
+// validateAssignment lists every problem with an assignment submitted for create or update.
+func validateAssignment(a Assignment) []FieldError {
+	return checkFields(a)
+}
+
+func courseAssignments(ctx context.Context, courseID int) []Assignment {
+	list := []Assignment{}
+	for _, a := range repoList(ctx, assignmentStore) {
+		if a.CourseID == courseID {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+func submissionsWhere(ctx context.Context, keep func(Submission) bool) []Submission {
+	list := []Submission{}
+	for _, s := range repoList(ctx, submissionStore) {
+		if keep(s) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func createAssignment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	var assignment Assignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateAssignment(assignment); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment data", errs...)
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+
+	assignment.CourseID = courseID
+	assignment = repoInsert(r.Context(), assignmentStore, assignment)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func getCourseAssignments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+
+	writeJSONArray(w, courseAssignments(r.Context(), courseID))
+}
+
+func getAssignment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID")
+		return
+	}
+
+	assignment, exists := repoFind(r.Context(), assignmentStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Assignment not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, assignment)
+}
+
+func updateAssignment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID")
+		return
+	}
+
+	var updated Assignment
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateAssignment(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment data", errs...)
+		return
+	}
+
+	before, exists := repoFind(r.Context(), assignmentStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Assignment not found")
+		return
+	}
+	updated.ID, updated.CourseID = id, before.CourseID
+	repoReplace(r.Context(), assignmentStore, id, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteAssignment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID")
+		return
+	}
+
+	submissionMutex.Lock()
+	defer submissionMutex.Unlock()
+
+	if _, exists := repoRemove(r.Context(), assignmentStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Assignment not found")
+		return
+	}
+	for _, s := range submissionsWhere(r.Context(), func(s Submission) bool { return s.AssignmentID == id }) {
+		repoRemove(r.Context(), submissionStore, s.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSubmission accepts a student's work, flagging it late when the
+// due date has passed.
+func createSubmission(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	assignmentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID")
+		return
+	}
+
+	var submission Submission
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid submission data", decodeErrors(err)...)
+		return
+	}
+	if submission.StudentID <= 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid submission data", FieldError{Field: "student_id", Message: "is required"})
+		return
+	}
+
+	assignment, exists := repoFind(r.Context(), assignmentStore, assignmentID)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Assignment not found")
+		return
+	}
+	enrolled := false
+	for _, e := range studentEnrollments(r.Context(), submission.StudentID) {
+		enrolled = enrolled || e.CourseID == assignment.CourseID
+	}
+	if !enrolled {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Student is not enrolled in this course")
+		return
+	}
+
+	submissionMutex.Lock()
+	defer submissionMutex.Unlock()
+
+	if n := len(submissionsWhere(r.Context(), func(s Submission) bool {
+		return s.AssignmentID == assignmentID && s.StudentID == submission.StudentID
+	})); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Student has already submitted this assignment")
+		return
+	}
+
+	submission.AssignmentID = assignmentID
+	submission.SubmittedAt = clock.Now().UTC()
+	submission.Late = submission.SubmittedAt.After(assignment.DueAt)
+	submission.Score = nil
+	submission = repoInsert(r.Context(), submissionStore, submission)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(submission)
+}
+
+func getAssignmentSubmissions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	assignmentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), assignmentStore, assignmentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Assignment not found")
+		return
+	}
+
+	writeJSONArray(w, submissionsWhere(r.Context(), func(s Submission) bool { return s.AssignmentID == assignmentID }))
+}
+
+// scoreSubmission grades a submission with {"score": N}.
+func scoreSubmission(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid submission ID")
+		return
+	}
+
+	var req struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid score: "+err.Error())
+		return
+	}
+
+	submissionMutex.Lock()
+	defer submissionMutex.Unlock()
+
+	submission, exists := repoFind(r.Context(), submissionStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Submission not found")
+		return
+	}
+	assignment, _ := assignmentStore.Find(r.Context(), submission.AssignmentID)
+	if req.Score == nil || *req.Score < 0 || *req.Score > assignment.MaxPoints {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid score", FieldError{Field: "score", Message: "must be between 0 and " + strconv.FormatFloat(assignment.MaxPoints, 'f', -1, 64)})
+		return
+	}
+	submission.Score = req.Score
+	repoReplace(r.Context(), submissionStore, id, submission)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submission)
+}
+
+// CourseProgress summarizes a student's coursework in one course.
+// Missing counts past-due assignments without a submission; Average is
+// the mean percentage over scored submissions.
+type CourseProgress struct {
+	CourseID    int      `json:"course_id"`
+	Code        string   `json:"code"`
+	Assignments int      `json:"assignments"`
+	Submitted   int      `json:"submitted"`
+	Late        int      `json:"late"`
+	Missing     int      `json:"missing"`
+	Average     *float64 `json:"average_percent"`
+}
+
+// getStudentProgress reports coursework progress for each course the
+// student is enrolled in.
+func getStudentProgress(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	submitted := map[int]Submission{}
+	for _, s := range submissionsWhere(r.Context(), func(s Submission) bool { return s.StudentID == studentID }) {
+		submitted[s.AssignmentID] = s
+	}
+
+	now := clock.Now()
+	progress := []CourseProgress{}
+	seen := map[int]bool{}
+	for _, e := range studentEnrollments(r.Context(), studentID) {
+		if seen[e.CourseID] {
+			continue
+		}
+		seen[e.CourseID] = true
+		course, ok := courseStore.Find(r.Context(), e.CourseID)
+		if !ok {
+			continue
+		}
+
+		p := CourseProgress{CourseID: course.ID, Code: course.Code}
+		var percentSum float64
+		scored := 0
+		for _, a := range courseAssignments(r.Context(), course.ID) {
+			p.Assignments++
+			s, ok := submitted[a.ID]
+			switch {
+			case !ok && now.After(a.DueAt):
+				p.Missing++
+			case ok:
+				p.Submitted++
+				if s.Late {
+					p.Late++
+				}
+				if s.Score != nil {
+					percentSum += *s.Score / a.MaxPoints * 100
+					scored++
+				}
+			}
+		}
+		if scored > 0 {
+			avg := math.Round(percentSum/float64(scored)*100) / 100
+			p.Average = &avg
+		}
+		progress = append(progress, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
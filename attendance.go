@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AttendanceRecord marks a student's presence in one course on one day,
+// optionally for a single period of that day.
+type AttendanceRecord struct {
+	ID        int    `json:"id"`
+	StudentID int    `json:"student_id" validate:"required"`
+	CourseID  int    `json:"course_id" validate:"required"`
+	Date      string `json:"date" validate:"date"`
+	Period    int    `json:"period,omitempty" validate:"nonnegative"`
+	Status    string `json:"status" validate:"enum=attendance_status"`
+}
+
+func (a AttendanceRecord) entityID() int { return a.ID }
+
+func (a AttendanceRecord) withID(id int) AttendanceRecord {
+	a.ID = id
+	return a
+}
+
+var attendanceStore Repository[AttendanceRecord] = newCollection[AttendanceRecord]("attendance")
+
+// attendanceMutex serializes recording so duplicate checks cannot race.
+var attendanceMutex = &sync.Mutex{}
+
+const dateLayout = "2006-01-02"
+
+var attendanceStatuses = []string{"present", "absent", "late", "excused"}
+
+// validateAttendance lists every problem with a submitted attendance record.
+func validateAttendance(a AttendanceRecord) []FieldError {
+	return checkFields(a)
+}
+
+func createAttendance(w http.ResponseWriter, r *http.Request) {
+	var record AttendanceRecord
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid attendance data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateAttendance(record); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid attendance data", errs...)
+		return
+	}
+
+	enrolled := false
+	for _, e := range studentEnrollments(r.Context(), record.StudentID) {
+		enrolled = enrolled || e.CourseID == record.CourseID
+	}
+	if !enrolled {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Student is not enrolled in this course")
+		return
+	}
+
+	attendanceMutex.Lock()
+	defer attendanceMutex.Unlock()
+
+	for _, a := range attendanceStore.List(r.Context()) {
+		if a.StudentID == record.StudentID && a.CourseID == record.CourseID && a.Date == record.Date && a.Period == record.Period {
+			writeProblem(w, r, http.StatusConflict, "Attendance is already recorded for this student, course, date and period")
+			return
+		}
+	}
+	record = repoInsert(r.Context(), attendanceStore, record)
+	publishEvent(r.Context(), AttendanceRecorded{Record: record})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(record)
+}
+
+// attendanceFilter holds the ?student_id=, ?course_id=, ?from= and ?to=
+// query parameters shared by the attendance queries. Dates are inclusive.
+// ?term= sets the range to the dates of that term.
+type attendanceFilter struct {
+	studentID, courseID int
+	from, to            string
+}
+
+func parseAttendanceFilter(r *http.Request) (attendanceFilter, []FieldError) {
+	var f attendanceFilter
+	var errs []FieldError
+	query := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"student_id", &f.studentID}, {"course_id", &f.courseID}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				errs = append(errs, FieldError{Field: p.name, Message: "must be a positive integer"})
+			}
+			*p.dst = n
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"from", &f.from}, {"to", &f.to}} {
+		if v := query.Get(p.name); v != "" {
+			if _, err := time.Parse(dateLayout, v); err != nil {
+				errs = append(errs, FieldError{Field: p.name, Message: "must be a date such as 2026-09-01"})
+			}
+			*p.dst = v
+		}
+	}
+	if name := strings.ToUpper(query.Get("term")); name != "" {
+		if t, ok := findTerm(r.Context(), name); ok {
+			f.from, f.to = t.StartDate, t.EndDate
+		} else {
+			errs = append(errs, FieldError{Field: "term", Message: "does not exist"})
+		}
+	}
+	return f, errs
+}
+
+func (f attendanceFilter) matches(a AttendanceRecord) bool {
+	// Dates are ISO 8601, so string comparison orders them correctly.
+	return (f.studentID == 0 || a.StudentID == f.studentID) &&
+		(f.courseID == 0 || a.CourseID == f.courseID) &&
+		(f.from == "" || a.Date >= f.from) &&
+		(f.to == "" || a.Date <= f.to)
+}
+
+func filteredAttendance(w http.ResponseWriter, r *http.Request) ([]AttendanceRecord, bool) {
+	filter, errs := parseAttendanceFilter(r)
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid attendance query", errs...)
+		return nil, false
+	}
+	list := []AttendanceRecord{}
+	for _, a := range repoList(r.Context(), attendanceStore) {
+		if filter.matches(a) {
+			list = append(list, a)
+		}
+	}
+	return list, true
+}
+
+// getAttendance lists attendance records by student, course and date range.
+func getAttendance(w http.ResponseWriter, r *http.Request) {
+	list, ok := filteredAttendance(w, r)
+	if !ok {
+		return
+	}
+	writeJSONArray(w, list)
+}
+
+// AttendanceSummary aggregates attendance records. Excused absences do
+// not count towards the absence rate.
+type AttendanceSummary struct {
+	Records     int     `json:"records"`
+	Present     int     `json:"present"`
+	Absent      int     `json:"absent"`
+	Late        int     `json:"late"`
+	Excused     int     `json:"excused"`
+	AbsenceRate float64 `json:"absence_rate"`
+}
+
+// getAttendanceSummary reports counts and the absence rate for the same
+// filters as getAttendance.
+func getAttendanceSummary(w http.ResponseWriter, r *http.Request) {
+	list, ok := filteredAttendance(w, r)
+	if !ok {
+		return
+	}
+
+	var s AttendanceSummary
+	for _, a := range list {
+		s.Records++
+		switch a.Status {
+		case "present":
+			s.Present++
+		case "absent":
+			s.Absent++
+		case "late":
+			s.Late++
+		case "excused":
+			s.Excused++
+		}
+	}
+	if counted := s.Records - s.Excused; counted > 0 {
+		s.AbsenceRate = math.Round(float64(s.Absent)/float64(counted)*10000) / 10000
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
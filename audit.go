@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditEntry records who modified or deleted which student.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	StudentID int       `json:"student_id"`
+	Before    *Student  `json:"before,omitempty"`
+	After     *Student  `json:"after,omitempty"`
+}
+
+// auditTrail is a server's audit trail in memory.
+type auditTrail struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// loadAuditLog reads back a previously persisted trail so it survives restarts.
+// The trail is kept as append-only JSON lines.
+func loadAuditLog(ctx context.Context) error {
+	if cfg.AuditLogFile == "" {
+		return nil
+	}
+	f, err := os.Open(cfg.AuditLogFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	trail := &stateOf(ctx).audit
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return err
+		}
+		trail.entries = append(trail.entries, entry)
+	}
+	return scanner.Err()
+}
+
+// recordAudit appends an entry to the trail, persisting it when a file is
+// configured. Dry runs leave no entry.
+func recordAudit(r *http.Request, action string, id int, before, after *Student) {
+	recordAuditAs(r.Context(), actorFor(r), action, id, before, after)
+}
+
+// recordAuditAs records a change made by actor outside of a request, such
+// as a scheduled job.
+func recordAuditAs(ctx context.Context, actor, action string, id int, before, after *Student) {
+	if inDryRun(ctx) {
+		return
+	}
+	entry := AuditEntry{
+		Time:      clock.Now().UTC(),
+		Actor:     actor,
+		Action:    action,
+		StudentID: id,
+		Before:    before,
+		After:     after,
+	}
+
+	trail := &stateOf(ctx).audit
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	trail.entries = append(trail.entries, entry)
+	slog.InfoContext(ctx, "audit", "actor", entry.Actor, "action", entry.Action, "student_id", entry.StudentID)
+
+	if cfg.AuditLogFile == "" {
+		return
+	}
+	f, err := os.OpenFile(cfg.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to open audit log file", "error", err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		slog.ErrorContext(ctx, "failed to persist audit entry", "error", err)
+	}
+}
+
+// getAuditLog returns the trail, optionally filtered by actor, action and student_id.
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	actor := query.Get("actor")
+	action := query.Get("action")
+	studentID := 0
+	if v := query.Get("student_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+			return
+		}
+		studentID = id
+	}
+
+	trail := &stateOf(r.Context()).audit
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	list := []AuditEntry{}
+	for _, e := range trail.entries {
+		if actor != "" && e.Actor != actor {
+			continue
+		}
+		if action != "" && e.Action != action {
+			continue
+		}
+		if studentID != 0 && e.StudentID != studentID {
+			continue
+		}
+		list = append(list, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
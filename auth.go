@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Principal is the authenticated caller behind a request.
+type Principal struct {
+	Subject string
+	Role    string
+}
+
+const (
+	roleAdmin = "admin"
+	// roleStaff may see sensitive student contacts without full admin rights.
+	roleStaff = "staff"
+)
+
+// apiKeys maps an API key to the principal it authenticates. It is loaded
+// by NewServer from the api_keys setting.
+var apiKeys = map[string]Principal{}
+
+// loadAPIKeys parses "key:subject[:role]" entries.
+func loadAPIKeys(entries []string) map[string]Principal {
+	keys := make(map[string]Principal)
+	for _, entry := range entries {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		p := Principal{Subject: parts[1]}
+		if len(parts) > 2 {
+			p.Role = parts[2]
+		}
+		keys[parts[0]] = p
+	}
+	return keys
+}
+
+// apiKeyFromRequest returns the key sent in X-API-Key, as a bearer token,
+// or as the password of HTTP Basic credentials so browsers can log in.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// authenticate resolves the principal for a request, if it carries a known key.
+func authenticate(r *http.Request) (Principal, bool) {
+	key := apiKeyFromRequest(r)
+	if key == "" {
+		return Principal{}, false
+	}
+	p, ok := apiKeys[key]
+	return p, ok
+}
+
+// actorFor names the caller for audit purposes.
+func actorFor(r *http.Request) string {
+	if p, ok := authenticate(r); ok {
+		return p.Subject
+	}
+	return "anonymous"
+}
+
+// requireAdmin only lets through callers holding the admin role.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if wait, locked := lockedOut(r.Context(), ip); locked {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			writeProblem(w, r, http.StatusTooManyRequests, "Too many failed attempts")
+			return
+		}
+
+		p, ok := authenticate(r)
+		if !ok {
+			recordAuthFailure(r.Context(), ip)
+			w.Header().Set("WWW-Authenticate", `Basic realm="student-api admin"`)
+			writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		recordAuthSuccess(r.Context(), ip)
+
+		if p.Role != roleAdmin {
+			writeProblem(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
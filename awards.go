@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Award is a scholarship or other award granted to a student for a term.
+// Amounts are in cents like ledger transactions; honors without money
+// attached have an amount of zero.
+type Award struct {
+	ID          int    `json:"id"`
+	StudentID   int    `json:"student_id"`
+	Kind        string `json:"kind" validate:"enum=award_kind"`
+	Name        string `json:"name" validate:"required"`
+	AmountCents int64  `json:"amount_cents" validate:"nonnegative"`
+	Term        string `json:"term" validate:"term"`
+}
+
+func (a Award) entityID() int { return a.ID }
+
+func (a Award) withID(id int) Award {
+	a.ID = id
+	return a
+}
+
+var awardStore Repository[Award] = newCollection[Award]("awards")
+
+var awardKinds = []string{"scholarship", "grant", "prize", "honor"}
+
+// validateAward lists every problem with a submitted award.
+func validateAward(a Award) []FieldError {
+	return checkFields(a)
+}
+
+// decodeAward reads an award from the request, normalizing its term and
+// checking that the term exists. It writes the problem response itself.
+func decodeAward(w http.ResponseWriter, r *http.Request) (Award, bool) {
+	var award Award
+	if err := json.NewDecoder(r.Body).Decode(&award); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid award data", decodeErrors(err)...)
+		return award, false
+	}
+	award.Term = strings.ToUpper(strings.TrimSpace(award.Term))
+	if errs := validateAward(award); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid award data", errs...)
+		return award, false
+	}
+	if _, ok := findTerm(r.Context(), award.Term); !ok {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Term not found", FieldError{Field: "term", Message: "does not exist"})
+		return award, false
+	}
+	return award, true
+}
+
+func studentAwards(r *http.Request, studentID int) []Award {
+	list := []Award{}
+	for _, a := range repoList(r.Context(), awardStore) {
+		if a.StudentID == studentID {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+func createAward(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	award, ok := decodeAward(w, r)
+	if !ok {
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	award.StudentID = studentID
+	award = repoInsert(r.Context(), awardStore, award)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(award)
+}
+
+func getStudentAwards(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	list := []Award{}
+	for _, a := range studentAwards(r, studentID) {
+		if term == "" || a.Term == term {
+			list = append(list, a)
+		}
+	}
+	writeJSONArray(w, list)
+}
+
+func getAward(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid award ID")
+		return
+	}
+
+	award, exists := repoFind(r.Context(), awardStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Award not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, award)
+}
+
+func updateAward(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid award ID")
+		return
+	}
+
+	updated, ok := decodeAward(w, r)
+	if !ok {
+		return
+	}
+
+	before, exists := repoFind(r.Context(), awardStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Award not found")
+		return
+	}
+	updated.ID, updated.StudentID = id, before.StudentID
+	repoReplace(r.Context(), awardStore, id, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteAward(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid award ID")
+		return
+	}
+
+	if _, exists := repoRemove(r.Context(), awardStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Award not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBatchOps caps the operations of one batch request.
+	maxBatchOps = 50
+	// maxBatchBytes caps the body of a batch request.
+	maxBatchBytes = 1 << 20
+)
+
+// BatchOperation is one request of a batch.
+type BatchOperation struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResult is the response to one operation. Body holds JSON responses
+// as they are and anything else as a string.
+type BatchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+// batchResultHeaders are the response headers copied into a result.
+var batchResultHeaders = []string{"Content-Type", "ETag", "Location", "Link", "Retry-After", "Deprecation", "Allow", dryRunHeader}
+
+var batchMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// inBatch reports whether ctx belongs to an operation of a batch.
+func inBatch(ctx context.Context) bool {
+	on, _ := ctx.Value(batchKey).(bool)
+	return on
+}
+
+// runBatch executes the operations of a batch one after the other, as the
+// same caller, and answers with one result per operation. A failing
+// operation does not stop the ones after it. Each operation has its own
+// route timeout, so the batch is bounded by the server's write timeout.
+func runBatch(w http.ResponseWriter, r *http.Request) {
+	var ops []BatchOperation
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&ops); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid batch: "+err.Error())
+		return
+	}
+	if len(ops) == 0 || len(ops) > maxBatchOps {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid batch", FieldError{Field: "operations", Message: "must number between 1 and " + strconv.Itoa(maxBatchOps)})
+		return
+	}
+	var errs []FieldError
+	for i, op := range ops {
+		errs = append(errs, validateBatchOperation(i, op)...)
+	}
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid batch", errs...)
+		return
+	}
+
+	results := make([]BatchResult, 0, len(ops))
+	for _, op := range ops {
+		if r.Context().Err() != nil {
+			break
+		}
+		results = append(results, runBatchOperation(r, op))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+func validateBatchOperation(i int, op BatchOperation) []FieldError {
+	prefix := "[" + strconv.Itoa(i) + "]."
+	var errs []FieldError
+	if !batchMethods[strings.ToUpper(op.Method)] {
+		errs = append(errs, FieldError{Field: prefix + "method", Message: "must be GET, POST, PUT, PATCH or DELETE"})
+	}
+	u, err := url.Parse(op.Path)
+	switch {
+	case err != nil || !strings.HasPrefix(op.Path, "/") || u.Host != "" || u.Scheme != "":
+		errs = append(errs, FieldError{Field: prefix + "path", Message: "must be an absolute path such as /v1/students"})
+	case resourcePath(u.Path) == "/batch":
+		errs = append(errs, FieldError{Field: prefix + "path", Message: "must not be a batch"})
+	case streamingPaths[u.Path] || streamingPaths[apiV1+u.Path]:
+		errs = append(errs, FieldError{Field: prefix + "path", Message: "must not be a live update stream"})
+	}
+	return errs
+}
+
+func runBatchOperation(r *http.Request, op BatchOperation) BatchResult {
+	u, _ := url.Parse(op.Path)
+	req := r.Clone(context.WithValue(r.Context(), batchKey, true))
+	req.Method = strings.ToUpper(op.Method)
+	req.URL = u
+	req.RequestURI = u.RequestURI()
+	for _, h := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "Accept-Encoding", "Content-Type", "Content-Length"} {
+		req.Header.Del(h)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range op.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	if len(op.Body) > 0 && string(op.Body) != "null" {
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Body = io.NopCloser(bytes.NewReader(op.Body))
+		req.ContentLength = int64(len(op.Body))
+	}
+
+	res := &bufferedResponse{header: http.Header{}}
+	stateOf(req.Context()).batchHandler.ServeHTTP(res, req)
+	result := BatchResult{Status: res.status}
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+	for _, name := range batchResultHeaders {
+		if v := res.header.Get(name); v != "" {
+			if result.Headers == nil {
+				result.Headers = map[string]string{}
+			}
+			result.Headers[name] = v
+		}
+	}
+	if res.body.Len() > 0 {
+		mediaType, _, _ := mime.ParseMediaType(res.header.Get("Content-Type"))
+		if (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(res.body.Bytes()) {
+			result.Body = json.RawMessage(bytes.TrimSpace(res.body.Bytes()))
+		} else {
+			result.Body = res.body.String()
+		}
+	}
+	return result
+}
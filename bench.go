@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// benchTarget is one endpoint driven by the bench subcommand, written as
+// "METHOD /path".
+type benchTarget struct {
+	method string
+	path   string
+}
+
+// benchResult collects the latencies seen for one target.
+type benchResult struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+}
+
+func (b *benchResult) record(d time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latencies = append(b.latencies, d)
+	if failed {
+		b.errors++
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+func parseBenchTargets(raw string) ([]benchTarget, error) {
+	var targets []benchTarget
+	for _, entry := range strings.Split(raw, ",") {
+		method, path, ok := strings.Cut(strings.TrimSpace(entry), " ")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("endpoint %q: want \"METHOD /path\"", entry)
+		}
+		targets = append(targets, benchTarget{method: strings.ToUpper(method), path: path})
+	}
+	return targets, nil
+}
+
+// runBench drives a running server with concurrent requests and prints
+// p50/p95/p99 latencies per endpoint. It is invoked as "studengo bench".
+func runBench(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	target := fs.String("url", "http://localhost:8080", "base URL of the server under test")
+	concurrency := fs.Int("concurrency", 10, "number of concurrent workers")
+	duration := fs.Duration("duration", 10*time.Second, "how long to run")
+	requests := fs.Int("requests", 0, "stop after this many requests in total; 0 runs for -duration")
+	endpoints := fs.String("endpoints", "GET /v1/students,GET /v1/students/1,GET /healthz", "comma-separated \"METHOD /path\" list, requested round-robin")
+	seed := fs.Int("seed", 100, "students to create before the run so reads have data")
+	apiKey := fs.String("api-key", os.Getenv("BENCH_API_KEY"), "API key sent as X-API-Key (env BENCH_API_KEY)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency <= 0 {
+		return errors.New("concurrency: must be positive")
+	}
+	targets, err := parseBenchTargets(*endpoints)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimRight(*target, "/")
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+	}
+	do := func(ctx context.Context, method, path string, body []byte) (int, error) {
+		req, err := http.NewRequestWithContext(ctx, method, base+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if *apiKey != "" {
+			req.Header.Set("X-API-Key", *apiKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+
+	for i := range *seed {
+		body := fmt.Appendf(nil, `{"name":"Bench Student %d","age":%d,"email":"bench%d@example.com"}`, i, 18+i%10, i)
+		if status, err := do(context.Background(), http.MethodPost, apiV1+"/students", body); err != nil || status != http.StatusCreated {
+			return fmt.Errorf("seeding student %d failed: status %d, error %v", i, status, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	results := make([]benchResult, len(targets))
+	var issued atomic.Int64
+	var wg sync.WaitGroup
+	started := time.Now()
+	for range *concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				n := issued.Add(1)
+				if *requests > 0 && n > int64(*requests) {
+					return
+				}
+				i := int(n-1) % len(targets)
+				t := targets[i]
+				start := time.Now()
+				status, err := do(ctx, t.method, t.path, nil)
+				if ctx.Err() != nil {
+					return
+				}
+				results[i].record(time.Since(start), err != nil || status >= 400)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(started)
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "endpoint\trequests\terrors\treq/s\tp50\tp95\tp99\tmax\t")
+	for i, t := range targets {
+		r := &results[i]
+		sort.Slice(r.latencies, func(a, b int) bool { return r.latencies[a] < r.latencies[b] })
+		n := len(r.latencies)
+		fmt.Fprintf(tw, "%s %s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n",
+			t.method, t.path, n, r.errors, float64(n)/elapsed.Seconds(),
+			percentile(r.latencies, 50).Round(time.Microsecond),
+			percentile(r.latencies, 95).Round(time.Microsecond),
+			percentile(r.latencies, 99).Round(time.Microsecond),
+			percentile(r.latencies, 100).Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	studentv1 "studengo/proto/student/v1"
+)
+
+const (
+	msgpackMediaType  = "application/msgpack"
+	protobufMediaType = "application/x-protobuf"
+)
+
+// encodeMsgpack writes a decodeOrdered value as MessagePack, keeping the
+// member order of objects. Integers take the smallest encoding that holds
+// them; other numbers are float64.
+func encodeMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	if err := writeMsgpackValue(enc, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeMsgpackValue(enc *msgpack.Encoder, v any) error {
+	switch v := v.(type) {
+	case []jsonMember:
+		if err := enc.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, m := range v {
+			if err := enc.EncodeString(m.key); err != nil {
+				return err
+			}
+			if err := writeMsgpackValue(enc, m.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := enc.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeMsgpackValue(enc, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case string:
+		return enc.EncodeString(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return enc.EncodeInt(n)
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return enc.EncodeUint(n)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	case bool:
+		return enc.EncodeBool(v)
+	}
+	return enc.EncodeNil()
+}
+
+// protoResponse is a route whose successful responses have a message type
+// of their own in student.proto.
+type protoResponse struct {
+	message func() proto.Message
+	// wrap reshapes the JSON body into the JSON form of the message.
+	wrap func(body []byte, h http.Header) []byte
+}
+
+func newStudentMessage() proto.Message { return &studentv1.Student{} }
+
+// protoResponses maps "METHOD /path" to the message its responses are
+// encoded as. Other responses, problems included, are sent as a
+// google.protobuf.Value holding the JSON document.
+var protoResponses = map[string]protoResponse{
+	"POST /students":     {message: newStudentMessage},
+	"GET /students/{id}": {message: newStudentMessage},
+	"PUT /students/{id}": {message: newStudentMessage},
+	"GET /students": {
+		message: func() proto.Message { return &studentv1.ListStudentsResponse{} },
+		wrap:    wrapStudentList,
+	},
+}
+
+// wrapStudentList turns a page of students into a ListStudentsResponse,
+// taking next_after from the Link header.
+func wrapStudentList(body []byte, h http.Header) []byte {
+	nextAfter := "0"
+	if target, ok := strings.CutPrefix(h.Get("Link"), "<"); ok {
+		target, _, _ = strings.Cut(target, ">")
+		if u, err := url.Parse(target); err == nil && u.Query().Get("after") != "" {
+			nextAfter = u.Query().Get("after")
+		}
+	}
+	return []byte(`{"students":` + string(body) + `,"next_after":"` + nextAfter + `"}`)
+}
+
+// protoRoute names the matched route as protoResponses does.
+func protoRoute(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return r.Method + " " + resourcePath(path)
+}
+
+// encodeProtobuf converts a JSON response body to protobuf. The
+// Content-Type names the message in its messageType parameter. Fields
+// the message does not have, such as expanded records, are dropped.
+func encodeProtobuf(r *http.Request, status int, h http.Header, body []byte, problem bool) ([]byte, string, error) {
+	var msg proto.Message
+	if resp, ok := protoResponses[protoRoute(r)]; ok && !problem && status < http.StatusMultipleChoices {
+		in := body
+		if resp.wrap != nil {
+			in = resp.wrap(body, h)
+		}
+		typed := resp.message()
+		if (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(in, typed) == nil {
+			msg = typed
+		}
+	}
+	if msg == nil {
+		value := &structpb.Value{}
+		if err := protojson.Unmarshal(body, value); err != nil {
+			return nil, "", err
+		}
+		msg = value
+	}
+	out, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, protobufMediaType + `; messageType="` + string(proto.MessageName(msg)) + `"`, nil
+}
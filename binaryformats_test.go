@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	studentv1 "studengo/proto/student/v1"
+)
+
+func TestPreferredFormat(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                      formatJSON,
+		"*/*":                                   formatJSON,
+		"application/msgpack":                   formatMsgpack,
+		"application/x-msgpack":                 formatMsgpack,
+		"application/vnd.msgpack":               formatMsgpack,
+		"Application/MsgPack":                   formatMsgpack,
+		"application/x-protobuf":                formatProtobuf,
+		"application/protobuf":                  formatProtobuf,
+		"application/json, application/msgpack": formatJSON,
+		"application/json;q=0.5, application/msgpack":             formatMsgpack,
+		"application/msgpack;q=0.2, application/x-protobuf;q=0.8": formatProtobuf,
+		"application/x-protobuf;q=0, application/unknown":         formatJSON,
+		"text/html, application/msgpack":                          formatJSON,
+		"application/yaml, application/msgpack":                   formatYAML,
+	} {
+		if got := preferredFormat(accept); got != want {
+			t.Errorf("Accept %q: %s, want %s", accept, got, want)
+		}
+	}
+}
+
+func TestEncodeMsgpack(t *testing.T) {
+	v, err := decodeOrdered(json.NewDecoder(strings.NewReader(
+		`{"b":1,"a":[true,null,-1,300],"c":"x","d":1.5,"e":18446744073709551615}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := encodeMsgpack(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Members keep their JSON order, and integers their smallest form.
+	want := "85" +
+		" a1 62 01" +
+		" a1 61 94 c3 c0 ff cd 01 2c" +
+		" a1 63 a1 78" +
+		" a1 64 cb 3f f8 00 00 00 00 00 00" +
+		" a1 65 cf ff ff ff ff ff ff ff ff"
+	if hex.EncodeToString(got) != strings.ReplaceAll(want, " ", "") {
+		t.Errorf("encoded as % x\nwant %s", got, want)
+	}
+}
+
+func TestMsgpackResponses(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, nil)
+
+	resp, data := api.get(t, "/v1/students/1", http.Header{"Accept": {"application/msgpack"}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != msgpackMediaType {
+		t.Fatalf("status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var student struct {
+		ID    int    `msgpack:"id"`
+		Name  string `msgpack:"name"`
+		Age   int    `msgpack:"age"`
+		Email string `msgpack:"email"`
+	}
+	if err := msgpack.Unmarshal(data, &student); err != nil {
+		t.Fatal(err)
+	}
+	if student.ID != 1 || student.Name != "Ada Lovelace" || student.Age != 20 || student.Email != "ada@example.com" {
+		t.Errorf("student = %+v", student)
+	}
+
+	// The MessagePack representation has an entity tag of its own, which
+	// revalidates against the JSON one.
+	etag := resp.Header.Get("ETag")
+	if !strings.HasSuffix(etag, `-msgpack"`) {
+		t.Fatalf("ETag = %q, want a -msgpack tag", etag)
+	}
+	if !strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "Accept") {
+		t.Errorf("Vary = %q, want Accept", resp.Header.Values("Vary"))
+	}
+	resp, _ = api.get(t, "/v1/students/1", http.Header{"Accept": {"application/msgpack"}, "If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != etag {
+		t.Errorf("revalidation: status %d, ETag %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	resp, _ = api.get(t, "/v1/students/1", http.Header{"If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("JSON with the MessagePack tag: status %d, want 200", resp.StatusCode)
+	}
+
+	// Problems are converted too.
+	resp, data = api.get(t, "/v1/students/99", http.Header{"Accept": {"application/x-msgpack"}})
+	var problem map[string]any
+	if err := msgpack.Unmarshal(data, &problem); err != nil || resp.StatusCode != http.StatusNotFound || problem["detail"] != "Student not found" {
+		t.Errorf("problem: status %d, %v, error %v", resp.StatusCode, problem, err)
+	}
+}
+
+func TestProtobufResponses(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Alan Turing", Age: 41, Email: "alan@example.com"}, http.StatusCreated, nil)
+	accept := http.Header{"Accept": {"application/x-protobuf"}}
+
+	messageType := func(resp *http.Response) string {
+		_, name, _ := strings.Cut(resp.Header.Get("Content-Type"), `messageType="`)
+		return strings.TrimSuffix(name, `"`)
+	}
+
+	resp, data := api.get(t, "/v1/students/1", accept)
+	var student studentv1.Student
+	if err := proto.Unmarshal(data, &student); err != nil {
+		t.Fatal(err)
+	}
+	if messageType(resp) != "studengo.student.v1.Student" || student.GetId() != 1 || student.GetName() != "Ada Lovelace" || student.GetAge() != 20 {
+		t.Errorf("%s: %v", resp.Header.Get("Content-Type"), &student)
+	}
+
+	// A page carries the cursor of the next in next_after.
+	resp, data = api.get(t, "/v1/students?limit=1", accept)
+	var list studentv1.ListStudentsResponse
+	if err := proto.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	if messageType(resp) != "studengo.student.v1.ListStudentsResponse" || len(list.GetStudents()) != 1 || list.GetNextAfter() != 1 {
+		t.Errorf("%s: %v", resp.Header.Get("Content-Type"), &list)
+	}
+	resp, data = api.get(t, "/v1/students?after=1", accept)
+	list.Reset()
+	if err := proto.Unmarshal(data, &list); err != nil || len(list.GetStudents()) != 1 || list.GetStudents()[0].GetName() != "Alan Turing" || list.GetNextAfter() != 0 {
+		t.Errorf("last page: %v, error %v", &list, err)
+	}
+
+	// Responses without a message of their own, problems included, are a
+	// google.protobuf.Value holding the JSON document.
+	for path, status := range map[string]int{"/v1/students/99": http.StatusNotFound, "/v1/courses": http.StatusOK} {
+		resp, data = api.get(t, path, accept)
+		var value structpb.Value
+		if err := proto.Unmarshal(data, &value); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status || messageType(resp) != "google.protobuf.Value" {
+			t.Errorf("%s: status %d, %s", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		_, data = api.get(t, path, nil)
+		var want structpb.Value
+		if err := want.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		// Each request has an ID of its own.
+		for _, v := range []*structpb.Value{&value, &want} {
+			if s := v.GetStructValue(); s != nil {
+				delete(s.Fields, "request_id")
+			}
+		}
+		if !proto.Equal(&value, &want) {
+			t.Errorf("%s: %v, want %v", path, &value, &want)
+		}
+	}
+
+	// Other formats pass through unconverted.
+	resp, data = api.get(t, "/v1/students?format=csv", accept)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") || !bytes.HasPrefix(data, []byte("id,")) {
+		t.Errorf("CSV: %s: %q", resp.Header.Get("Content-Type"), data)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// errBlobNotFound is returned when a blob does not exist.
+var errBlobNotFound = errors.New("blob not found")
+
+// blobStore holds file contents, such as uploaded documents, by key. Keys
+// are slash-separated relative paths.
+type blobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob stores blobs can be kept in.
+const (
+	blobsDisk = "disk"
+	blobsS3   = "s3"
+)
+
+// blobs is the blob store shared by every feature keeping file contents:
+// each call goes to the blob store of the Server serving ctx, which
+// NewServer sets up from blob_store. Features keep their blobs apart by
+// key prefix, such as "documents/".
+var blobs blobStore = serverBlobs{}
+
+type serverBlobs struct{}
+
+func (serverBlobs) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	return stateOf(ctx).blobs.Put(ctx, key, r)
+}
+
+func (serverBlobs) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return stateOf(ctx).blobs.Open(ctx, key)
+}
+
+func (serverBlobs) Delete(ctx context.Context, key string) error {
+	return stateOf(ctx).blobs.Delete(ctx, key)
+}
+
+// storeBlob puts the file write produces under key as it is written, and
+// returns its size and SHA-256. In a dry run the file is written through,
+// so it is checked, but not kept.
+func storeBlob(ctx context.Context, key string, write func(io.Writer) error) (int64, string, error) {
+	hash := sha256.New()
+	if inDryRun(ctx) {
+		counter := &countingWriter{w: hash}
+		err := write(counter)
+		return counter.n, hex.EncodeToString(hash.Sum(nil)), err
+	}
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(write(pw)) }()
+	n, err := blobs.Put(ctx, key, io.TeeReader(pr, hash))
+	// A store that stops reading early must not leave write blocked.
+	pr.CloseWithError(cmp.Or(err, io.ErrClosedPipe))
+	return n, hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// serveBlob streams a blob as an attachment. what names it in errors,
+// such as "Document".
+func serveBlob(w http.ResponseWriter, r *http.Request, key, what, contentType, filename string, size int64, sum string) {
+	body, err := blobs.Open(r.Context(), key)
+	if errors.Is(err, errBlobNotFound) {
+		writeProblem(w, r, http.StatusNotFound, what+" contents not found")
+		return
+	}
+	what = strings.ToLower(what)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to open "+what, "key", key, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to read "+what)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+sum+`"`)
+	if _, err := io.Copy(w, body); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream "+what, "key", key, "error", err)
+	}
+}
+
+func newBlobStore(c Config) (blobStore, error) {
+	switch c.BlobStore {
+	case blobsDisk:
+		return diskBlobs{dir: c.DocumentsDir}, nil
+	case blobsS3:
+		return newS3Blobs(c)
+	}
+	return nil, fmt.Errorf("unknown blob store %q", c.BlobStore)
+}
+
+// checkBlobs reports whether the blob store is reachable, for stores that
+// can tell.
+func checkBlobs(ctx context.Context) error {
+	if p, ok := stateOf(ctx).blobs.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// diskBlobs keeps blobs as files below a directory.
+type diskBlobs struct {
+	dir string
+}
+
+func (d diskBlobs) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid blob key " + key)
+	}
+	return filepath.Join(d.dir, clean), nil
+}
+
+// Put writes to a temporary file and renames it into place, so readers
+// never see a partly written blob.
+func (d diskBlobs) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+func (d diskBlobs) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errBlobNotFound
+	}
+	return f, err
+}
+
+func (d diskBlobs) Delete(_ context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for its type to be sniffed.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestBlobBackedFeatures(t *testing.T) {
+	dir := t.TempDir()
+	api := newTestAPI(t, func(c *Config) { c.DocumentsDir = dir })
+	var ada Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &ada)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Alan Turing", Age: 41, Email: "alan@example.com"}, http.StatusCreated, nil)
+	photo := "/v1/students/" + strconv.Itoa(ada.ID) + "/photo"
+
+	if resp, data := api.do(t, "PUT", photo, strings.NewReader("GIF? no, plain text")); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("text photo: status %d: %s", resp.StatusCode, data)
+	}
+	api.decode(t, "PUT", photo, bytes.NewReader(pngHeader), http.StatusNoContent, nil)
+	resp, data := api.do(t, "GET", photo, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" || !bytes.Equal(data, pngHeader) {
+		t.Errorf("photo: status %d, type %s, %q", resp.StatusCode, resp.Header.Get("Content-Type"), data)
+	}
+
+	var export Export
+	api.decode(t, "POST", "/v1/exports", Export{Filter: "age lt 30", Fields: "name,email"}, http.StatusCreated, &export)
+	_, csv := api.do(t, "GET", "/v1/exports/"+strconv.Itoa(export.ID)+"/content", nil)
+	if want := "name,email\nAda Lovelace,ada@example.com\n"; string(csv) != want || export.Students != 1 || export.Size != int64(len(want)) {
+		t.Errorf("export %+v: %q, want %q", export, csv, want)
+	}
+	api.decode(t, "POST", "/v1/exports", Export{Filter: "age lt"}, http.StatusBadRequest, nil)
+
+	var backup Backup
+	api.decode(t, "POST", "/v1/admin/backups", nil, http.StatusCreated, &backup)
+	_, lines := api.do(t, "GET", "/v1/admin/backups/"+strconv.Itoa(backup.ID)+"/content", nil)
+	if backup.Students != 2 || strings.Count(string(lines), "\n") != 2 || !strings.Contains(string(lines), "alan@example.com") {
+		t.Errorf("backup %+v: %s", backup, lines)
+	}
+
+	// Each feature keeps its files in the one blob store, apart by prefix.
+	for _, key := range []string{photoKey(ada.ID), exportKey(export.ID), backupKey(backup.ID)} {
+		if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+			t.Errorf("blob %s: %v", key, err)
+		}
+	}
+
+	// Anonymizing deletes what cannot be scrubbed.
+	api.decode(t, "POST", "/v1/admin/anonymize", nil, http.StatusOK, nil)
+	api.decode(t, "GET", photo, nil, http.StatusNotFound, nil)
+	for _, path := range []string{"/v1/exports", "/v1/admin/backups"} {
+		if _, data := api.do(t, "GET", path, nil); strings.TrimSpace(string(data)) != "[]" {
+			t.Errorf("GET %s after anonymizing: %s", path, data)
+		}
+	}
+	for _, key := range []string{photoKey(ada.ID), exportKey(export.ID), backupKey(backup.ID)} {
+		if _, err := os.Stat(filepath.Join(dir, key)); !os.IsNotExist(err) {
+			t.Errorf("blob %s kept after anonymizing: %v", key, err)
+		}
+	}
+}
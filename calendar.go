@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// icsWriter writes iCalendar (RFC 5545) content lines, escaping text
+// values and folding lines longer than 75 octets.
+type icsWriter struct {
+	b strings.Builder
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// prop writes a property whose value is already in iCalendar syntax.
+func (w *icsWriter) prop(name, value string) {
+	line := name + ":" + value
+	// Continuation lines start with a space, which counts towards the limit.
+	for limit := 75; len(line) > limit; limit = 74 {
+		cut := limit
+		// Do not split a UTF-8 sequence.
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	w.b.WriteString(line + "\r\n")
+}
+
+// text writes a property with a TEXT value.
+func (w *icsWriter) text(name, value string) {
+	w.prop(name, icsEscaper.Replace(value))
+}
+
+// icsLocal formats a date and clock time as a floating local time, which
+// calendar apps show in the device's time zone.
+const icsLocal = "20060102T150405"
+
+// firstMeeting returns the first date on or after start that falls on
+// the meeting's weekday.
+func firstMeeting(start time.Time, day string) time.Time {
+	want := time.Weekday((slices.Index(weekdays, day) + 1) % 7)
+	return start.AddDate(0, 0, (int(want)-int(start.Weekday())+7)%7)
+}
+
+// writeSectionEvents adds one weekly recurring event per meeting of the
+// section, running from the start to the end of the term.
+func writeSectionEvents(w *icsWriter, e Enrollment, course Course, section Section, term Term, stamp string) {
+	start, err1 := time.Parse(dateLayout, term.StartDate)
+	end, err2 := time.Parse(dateLayout, term.EndDate)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	for i, m := range section.Meetings {
+		day := firstMeeting(start, m.Day)
+		if day.After(end) {
+			continue
+		}
+		from, err1 := time.Parse(clockLayout, m.Start)
+		to, err2 := time.Parse(clockLayout, m.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		at := func(clock time.Time) string {
+			return day.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute).Format(icsLocal)
+		}
+
+		w.prop("BEGIN", "VEVENT")
+		w.prop("UID", fmt.Sprintf("enrollment-%d-meeting-%d@%s", e.ID, i, serviceName))
+		w.prop("DTSTAMP", stamp)
+		w.prop("DTSTART", at(from))
+		w.prop("DTEND", at(to))
+		w.prop("RRULE", "FREQ=WEEKLY;UNTIL="+end.Format("20060102")+"T235959")
+		w.text("SUMMARY", course.Code+" "+course.Title)
+		if section.Room != "" {
+			w.text("LOCATION", section.Room)
+		}
+		w.text("DESCRIPTION", "Section "+section.Name+", "+term.Name)
+		w.prop("END", "VEVENT")
+	}
+}
+
+// getStudentSchedule serves GET /students/{id}/schedule.ics: the weekly
+// meetings of every section the student is enrolled in, as an iCalendar
+// feed that calendar apps can subscribe to. ?term= limits it to one term.
+// Enrollments without a section or a term have no times and are left out.
+func getStudentSchedule(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	student, exists := findStudent(r.Context(), id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+	termName, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	var cal icsWriter
+	cal.prop("BEGIN", "VCALENDAR")
+	cal.prop("VERSION", "2.0")
+	cal.prop("PRODID", "-//"+serviceName+"//class schedule//EN")
+	cal.prop("CALSCALE", "GREGORIAN")
+	cal.prop("METHOD", "PUBLISH")
+	cal.text("X-WR-CALNAME", student.Name+" classes")
+	stamp := clock.Now().UTC().Format(icsLocal + "Z")
+
+	for _, e := range studentEnrollments(r.Context(), id) {
+		if e.SectionID == 0 || e.Term == "" || (termName != "" && e.Term != termName) {
+			continue
+		}
+		section, ok1 := sectionStore.Find(r.Context(), e.SectionID)
+		course, ok2 := courseStore.Find(r.Context(), e.CourseID)
+		term, ok3 := findTerm(r.Context(), e.Term)
+		if ok1 && ok2 && ok3 {
+			writeSectionEvents(&cal, e, course, section, term, stamp)
+		}
+	}
+	cal.prop("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="schedule-`+strconv.Itoa(id)+`.ics"`)
+	w.Write([]byte(cal.b.String()))
+}
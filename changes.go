@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Change is one entry of the change feed. Its ID is the cursor: changes
+// are numbered in the order they happened. Data is the entity after the
+// change. It is left out for deletions, and removed from earlier changes
+// once their entity is deleted.
+type Change struct {
+	ID       int       `json:"id"`
+	Entity   string    `json:"entity"`
+	EntityID int       `json:"entity_id"`
+	Action   string    `json:"action"`
+	At       time.Time `json:"at"`
+	EventID  string    `json:"event_id"`
+	Data     any       `json:"data,omitempty"`
+}
+
+func (c Change) entityID() int { return c.ID }
+
+func (c Change) withID(id int) Change {
+	c.ID = id
+	return c
+}
+
+var changeStore Repository[Change] = newCollection[Change]("changes")
+
+const (
+	changeCreated = "created"
+	changeUpdated = "updated"
+	changeDeleted = "deleted"
+)
+
+// changeFeed is what a server keeps about its change feed besides the
+// changes themselves.
+type changeFeed struct {
+	// mu keeps cursor order equal to event order.
+	mu sync.Mutex
+	// purgedThrough is the highest cursor removed by the retention job.
+	// Clients further behind have missed changes.
+	purgedThrough int
+}
+
+func (f *changeFeed) purged() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.purgedThrough
+}
+
+// recordChange is the event bus subscriber that appends data changes to
+// the feed.
+func recordChange(ctx context.Context, e Event) {
+	c := Change{At: e.OccurredAt, EventID: e.ID}
+	switch d := e.Data.(type) {
+	case StudentCreated:
+		c.Entity, c.EntityID, c.Action, c.Data = "student", d.Student.ID, changeCreated, d.Student
+	case StudentUpdated:
+		c.Entity, c.EntityID, c.Action, c.Data = "student", d.After.ID, changeUpdated, d.After
+	case StudentDeleted:
+		c.Entity, c.EntityID, c.Action = "student", d.Student.ID, changeDeleted
+	case EnrollmentAdded:
+		c.Entity, c.EntityID, c.Action, c.Data = "enrollment", d.Enrollment.ID, changeCreated, d.Enrollment
+	case AttendanceRecorded:
+		c.Entity, c.EntityID, c.Action, c.Data = "attendance", d.Record.ID, changeCreated, d.Record
+	default:
+		return
+	}
+
+	st := stateOf(ctx)
+	st.changes.mu.Lock()
+	defer st.changes.mu.Unlock()
+	// A deleted student's details do not outlive it in the feed.
+	if c.Action == changeDeleted {
+		for _, old := range changeStore.List(ctx) {
+			if old.Entity == c.Entity && old.EntityID == c.EntityID && old.Data != nil {
+				old.Data = nil
+				repoReplace(ctx, changeStore, old.ID, old)
+			}
+		}
+	}
+	st.hub.broadcast(repoInsert(ctx, changeStore, c))
+}
+
+// purgeChanges removes changes older than cutoff and returns how many.
+func purgeChanges(ctx context.Context, cutoff time.Time) int {
+	feed := &stateOf(ctx).changes
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	n := 0
+	for _, c := range changeStore.List(ctx) {
+		if c.At.Before(cutoff) {
+			repoRemove(ctx, changeStore, c.ID)
+			feed.purgedThrough = max(feed.purgedThrough, c.ID)
+			n++
+		}
+	}
+	return n
+}
+
+// ChangePage is one response of the change feed. Pass NextCursor as
+// ?since= to continue; it stays the same while nothing has changed.
+type ChangePage struct {
+	Changes    []Change `json:"changes"`
+	NextCursor string   `json:"next_cursor"`
+	HasMore    bool     `json:"has_more"`
+}
+
+// getChanges serves GET /changes?since=<cursor>&limit=<n>: the changes
+// after the cursor, oldest first. Without a cursor the feed starts at the
+// beginning. A cursor whose changes were purged answers 410, and the
+// client must resynchronise from a full export.
+func getChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	since := 0
+	if v := query.Get("since"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "since", Message: "must be a cursor returned by this endpoint"})
+			return
+		}
+		since = n
+	}
+	limit := cfg.DefaultPageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > cfg.MaxPageSize {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(cfg.MaxPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	if since < stateOf(r.Context()).changes.purged() {
+		writeProblem(w, r, http.StatusGone, "Changes after cursor "+strconv.Itoa(since)+" are no longer available")
+		return
+	}
+
+	page := ChangePage{Changes: []Change{}, NextCursor: strconv.Itoa(since)}
+	for _, c := range repoList(r.Context(), changeStore) {
+		if c.ID <= since {
+			continue
+		}
+		if len(page.Changes) == limit {
+			page.HasMore = true
+			break
+		}
+		page.Changes = append(page.Changes, c)
+		page.NextCursor = strconv.Itoa(c.ID)
+	}
+	writeJSONWithETag(w, r, page)
+}
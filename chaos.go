@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"studengo/ollama"
+)
+
+// Faults chaos_faults can name.
+const (
+	chaosLatency = "latency"
+	chaosError   = "error"
+	chaosDrop    = "drop"
+)
+
+// chaosStatuses are the error statuses the error fault answers with,
+// the ones clients are expected to retry or give up on gracefully.
+var chaosStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// pickFault decides whether this call gets a fault and which one. It
+// returns "" for the calls left alone.
+func pickFault() string {
+	if cfg.ChaosPercent <= 0 || len(cfg.ChaosFaults) == 0 || random.IntN(100) >= cfg.ChaosPercent {
+		return ""
+	}
+	return cfg.ChaosFaults[random.IntN(len(cfg.ChaosFaults))]
+}
+
+// chaosDelay waits a random time up to chaos_latency, or less if ctx ends
+// first, and returns how long it waited.
+func chaosDelay(ctx context.Context) time.Duration {
+	d := time.Duration(random.Int64N(int64(cfg.ChaosLatency)) + 1)
+	start := time.Now()
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+	return time.Since(start)
+}
+
+// chaos injects faults into chaos_percent of requests, so client retries
+// and timeouts can be tried out against a local server: a delay, an error
+// status or a dropped connection. Admin, debug, health and metrics routes
+// are left alone so the server can still be operated and probed.
+// chaos_percent is only accepted in development.
+func chaos(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := resourcePath(r.URL.Path)
+		if strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/debug/") ||
+			path == "/healthz" || path == "/readyz" || path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch pickFault() {
+		case chaosLatency:
+			d := chaosDelay(r.Context())
+			slog.InfoContext(r.Context(), "injected fault", "fault", chaosLatency, "delay", d)
+			w.Header().Set("X-Chaos", chaosLatency)
+		case chaosError:
+			status := chaosStatuses[random.IntN(len(chaosStatuses))]
+			slog.InfoContext(r.Context(), "injected fault", "fault", chaosError, "status", status)
+			w.Header().Set("X-Chaos", chaosError)
+			if status == http.StatusServiceUnavailable {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeProblem(w, r, status, "Injected fault")
+			return
+		case chaosDrop:
+			slog.InfoContext(r.Context(), "injected fault", "fault", chaosDrop)
+			// The server closes the connection, or resets the stream on
+			// HTTP/2, without writing a response.
+			panic(http.ErrAbortHandler)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chaosLLM injects the same faults into calls to the model when
+// chaos_ollama is on, so summary endpoints can be seen handling a slow,
+// failing or unreachable Ollama.
+type chaosLLM struct {
+	LLM
+}
+
+// withChaos wraps model in chaosLLM if chaos_ollama is on.
+func withChaos(model LLM) LLM {
+	if cfg.ChaosOllama && cfg.ChaosPercent > 0 {
+		return chaosLLM{model}
+	}
+	return model
+}
+
+// errInjected is returned by chaosLLM for the error and drop faults.
+var errInjected = errors.New("injected fault")
+
+func (c chaosLLM) fault(ctx context.Context) error {
+	switch pickFault() {
+	case chaosLatency:
+		d := chaosDelay(ctx)
+		slog.InfoContext(ctx, "injected Ollama fault", "fault", chaosLatency, "delay", d)
+		return ctx.Err()
+	case chaosError:
+		slog.InfoContext(ctx, "injected Ollama fault", "fault", chaosError)
+		return fmt.Errorf("Ollama returned status 503: %w", errInjected)
+	case chaosDrop:
+		slog.InfoContext(ctx, "injected Ollama fault", "fault", chaosDrop)
+		return fmt.Errorf("Failed to call Ollama API: connection reset: %w", errInjected)
+	}
+	return nil
+}
+
+func (c chaosLLM) Generate(ctx context.Context, g ollama.GenerateRequest) (ollama.Generation, error) {
+	if err := c.fault(ctx); err != nil {
+		return ollama.Generation{}, err
+	}
+	return c.LLM.Generate(ctx, g)
+}
+
+func (c chaosLLM) Models(ctx context.Context) ([]string, error) {
+	if err := c.fault(ctx); err != nil {
+		return nil, err
+	}
+	return c.LLM.Models(ctx)
+}
@@ -0,0 +1,258 @@
+// Package client is the Go client of the student API.
+//
+// A Client is safe for concurrent use. Every call takes a context, which
+// is the way to bound it: the client sets no timeouts of its own, since
+// summaries can take as long as the language model does and event
+// streams stay open indefinitely.
+//
+//	c, err := client.New("https://students.internal", client.WithAPIKey(key))
+//	if err != nil {
+//		return err
+//	}
+//	s, err := c.CreateStudent(ctx, client.Student{Name: "Ada", Age: 21, Email: "ada@example.edu"})
+//
+// Failed calls return an *Error carrying the problem details the API
+// answered with.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetries   = 3
+	defaultRetryBase = 200 * time.Millisecond
+	// maxRetryWait caps the wait between attempts. A call whose
+	// Retry-After asks for longer fails instead.
+	maxRetryWait = 30 * time.Second
+)
+
+// Client calls the API's v1 endpoints.
+type Client struct {
+	base       *url.URL
+	apiKey     string
+	userAgent  string
+	httpClient *http.Client
+	retries    int
+	retryBase  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates every call with key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient sends the calls through hc instead of a default client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how often a failed call is retried and the wait before
+// the first retry, which doubles for each further one. Zero retries turns
+// retrying off.
+func WithRetries(retries int, base time.Duration) Option {
+	return func(c *Client) { c.retries, c.retryBase = retries, base }
+}
+
+// WithUserAgent names the calling service in the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client of the API served at baseURL, such as
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: %q is not an http or https URL", baseURL)
+	}
+	c := &Client{
+		base:       u,
+		userAgent:  "studengo-client",
+		httpClient: &http.Client{},
+		retries:    defaultRetries,
+		retryBase:  defaultRetryBase,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is a call the API refused or failed. The fields mirror the
+// problem details (RFC 7807) of the response.
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError names a field of a request the API rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	for _, fe := range e.Errors {
+		msg += "; " + fe.Field + " " + fe.Message
+	}
+	return "studengo: " + strconv.Itoa(e.StatusCode) + " " + msg
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// do sends a call and decodes a JSON response into out, when out is not
+// nil. in, when not nil, is sent as the JSON body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) (http.Header, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.send(ctx, method, path, query, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, fmt.Errorf("studengo: decoding %s %s: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// send makes a call, retrying it as retryable allows, and returns the
+// response of the first attempt that succeeded. The caller closes its
+// body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	u := *c.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+		var callErr error
+		wait := c.backoff(attempt)
+		if err != nil {
+			callErr = err
+		} else {
+			callErr = readError(resp)
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+		}
+		if ctx.Err() != nil || attempt >= c.retries || wait > maxRetryWait || !retryable(method, resp, err) {
+			return nil, callErr
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, callErr
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may be repeated. A 429, or a
+// 503 with Retry-After, means the server turned the request away before
+// acting on it, so it is retried whatever the method. Other 503s are
+// timeouts. They, network errors and gateway errors are retried for
+// every method but POST, since the first attempt may have been applied.
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		return method != http.MethodPost
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != "" || method != http.MethodPost
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != http.MethodPost
+	}
+	return false
+}
+
+// backoff is the wait before retry attempt+1: exponential, with jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retryBase << attempt
+	if d <= 0 || d > maxRetryWait {
+		d = maxRetryWait
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// readError turns an error response into an *Error and closes its body.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	e := &Error{}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, e) != nil {
+		e.Detail = strings.TrimSpace(string(data))
+	}
+	e.StatusCode = resp.StatusCode
+	return e
+}
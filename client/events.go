@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StudentChange is one entry of the student change feed.
+type StudentChange struct {
+	// Cursor orders the feed; pass the last one seen to WatchStudents to
+	// resume after it.
+	Cursor    int       `json:"id"`
+	StudentID int       `json:"entity_id"`
+	Action    string    `json:"action"`
+	At        time.Time `json:"at"`
+	EventID   string    `json:"event_id"`
+	// Student is the record as of the change.
+	Student *Student `json:"data,omitempty"`
+}
+
+// defaultReconnect is the wait before reconnecting a dropped stream until
+// the server says otherwise.
+const defaultReconnect = 3 * time.Second
+
+// WatchStudents streams student changes to fn, starting after cursor
+// since; zero starts with the next change. A dropped connection is
+// reopened where it left off. It returns when ctx is done, when fn
+// returns an error, or when the API refuses the stream.
+func (c *Client) WatchStudents(ctx context.Context, since int, fn func(StudentChange) error) error {
+	last := since
+	reconnect := defaultReconnect
+	for {
+		header := http.Header{"Accept": {"text/event-stream"}}
+		if last > 0 {
+			header.Set("Last-Event-ID", strconv.Itoa(last))
+		}
+		resp, err := c.send(ctx, http.MethodGet, "/v1/students/events", nil, nil, header)
+		if err != nil {
+			var apiErr *Error
+			if errors.As(err, &apiErr) || ctx.Err() != nil {
+				return err
+			}
+		} else {
+			err = readEvents(resp.Body, func(ev sseEvent) error {
+				if ev.retry > 0 {
+					reconnect = ev.retry
+				}
+				if ev.data == "" {
+					return nil
+				}
+				var change StudentChange
+				if err := json.Unmarshal([]byte(ev.data), &change); err != nil {
+					return err
+				}
+				if err := fn(change); err != nil {
+					return &callbackError{err}
+				}
+				last = change.Cursor
+				return nil
+			})
+			resp.Body.Close()
+			var cbErr *callbackError
+			if errors.As(err, &cbErr) {
+				return cbErr.err
+			}
+		}
+
+		timer := time.NewTimer(reconnect)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// callbackError carries an error returned by the caller's function out of
+// the stream reader.
+type callbackError struct{ err error }
+
+func (e *callbackError) Error() string { return e.err.Error() }
+
+// sseEvent is one server-sent event.
+type sseEvent struct {
+	id, event, data string
+	retry           time.Duration
+}
+
+// readEvents parses a text/event-stream body, calling fn for each event,
+// until the stream ends or fn fails.
+func readEvents(r io.Reader, fn func(sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			ev.data = strings.Join(data, "\n")
+			if err := fn(ev); err != nil {
+				return err
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				ev.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Student is a student record.
+type Student struct {
+	ID    int    `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Age   int    `json:"age"`
+	Email string `json:"email"`
+	// ProgramID is the program the student follows, if any.
+	ProgramID int `json:"program_id,omitempty"`
+	// UpdatedAt is set by the server; it is ignored on writes.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// ListOptions selects a page of a list. The zero value is the first page
+// of the server's default size.
+type ListOptions struct {
+	// After skips records with an ID up to and including After.
+	After int
+	Limit int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.After > 0 {
+		q.Set("after", strconv.Itoa(o.After))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// StudentPage is one page of students.
+type StudentPage struct {
+	Students []Student
+	// Next selects the following page; it is nil on the last page.
+	Next *ListOptions
+}
+
+func studentPath(id int) string {
+	return "/v1/students/" + strconv.Itoa(id)
+}
+
+// CreateStudent adds a student and returns it with its ID.
+func (c *Client) CreateStudent(ctx context.Context, s Student) (Student, error) {
+	var created Student
+	_, err := c.do(ctx, http.MethodPost, "/v1/students", nil, s, &created)
+	return created, err
+}
+
+// GetStudent returns one student. IsNotFound reports a missing one.
+func (c *Client) GetStudent(ctx context.Context, id int) (Student, error) {
+	var s Student
+	_, err := c.do(ctx, http.MethodGet, studentPath(id), nil, nil, &s)
+	return s, err
+}
+
+// ListStudents returns a page of students, ordered by ID.
+func (c *Client) ListStudents(ctx context.Context, opts ListOptions) (StudentPage, error) {
+	var page StudentPage
+	header, err := c.do(ctx, http.MethodGet, "/v1/students", opts.query(), nil, &page.Students)
+	if err != nil {
+		return page, err
+	}
+	if strings.Contains(header.Get("Link"), `rel="next"`) && len(page.Students) > 0 {
+		page.Next = &ListOptions{After: page.Students[len(page.Students)-1].ID, Limit: opts.Limit}
+	}
+	return page, nil
+}
+
+// AllStudents iterates over every student, fetching pages of pageSize as
+// it goes; zero uses the server's default. Iteration stops at the first
+// error, which is yielded.
+func (c *Client) AllStudents(ctx context.Context, pageSize int) iter.Seq2[Student, error] {
+	return func(yield func(Student, error) bool) {
+		opts := &ListOptions{Limit: pageSize}
+		for opts != nil {
+			page, err := c.ListStudents(ctx, *opts)
+			if err != nil {
+				yield(Student{}, err)
+				return
+			}
+			for _, s := range page.Students {
+				if !yield(s, nil) {
+					return
+				}
+			}
+			opts = page.Next
+		}
+	}
+}
+
+// UpdateStudent replaces a student and returns the stored record.
+func (c *Client) UpdateStudent(ctx context.Context, id int, s Student) (Student, error) {
+	var updated Student
+	_, err := c.do(ctx, http.MethodPut, studentPath(id), nil, s, &updated)
+	return updated, err
+}
+
+// DeleteStudent removes a student along with their enrollments and
+// documents.
+func (c *Client) DeleteStudent(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, studentPath(id), nil, nil, nil)
+	return err
+}
+
+// GetSummary returns the generated summary of a student. Summaries come
+// from a language model and can take a while when not cached.
+func (c *Client) GetSummary(ctx context.Context, id int) (string, error) {
+	var resp struct {
+		Summary string `json:"summary"`
+	}
+	_, err := c.do(ctx, http.MethodGet, studentPath(id)+"/summary", nil, nil, &resp)
+	return resp.Summary, err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies lists the networks of load balancers and reverse proxies
+// whose forwarding headers are believed.
+var trustedProxies []*net.IPNet
+
+// parseCIDRs parses a list of CIDRs or bare IPs.
+func parseCIDRs(entries []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			slog.Warn("ignoring invalid trusted proxy", "entry", entry)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that sent the request. When
+// the direct peer is a trusted proxy, the forwarded header is walked from
+// the right and the first address not belonging to a trusted proxy wins.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	// Over the Unix socket the peer is the reverse proxy on this host. It
+	// has no IP address and is always trusted.
+	peer := net.ParseIP(host)
+	overSocket := peer == nil && cfg.UnixSocket != ""
+	if !overSocket && (peer == nil || !isTrustedProxy(peer)) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values(cfg.ForwardedHeader), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !isTrustedProxy(ip) {
+			return ip.String()
+		}
+		host = ip.String()
+	}
+	return host
+}
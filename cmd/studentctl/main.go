@@ -0,0 +1,365 @@
+// Command studentctl administers the student API from a terminal.
+//
+//	studentctl [-url URL] [-api-key KEY] [-timeout D] <command> [arguments]
+//
+// Commands:
+//
+//	list [-limit N] [-json]      list students
+//	get ID                       print one student
+//	create -f FILE               create the student, or array of students, in a JSON file
+//	import csv FILE              create a student per row of a CSV file
+//	summary ID                   print a student's generated summary
+//	backup [-o FILE]             write every student to FILE as JSON lines
+//
+// FILE may be - for standard input or output.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"studengo/client"
+)
+
+const usage = `usage: studentctl [-url URL] [-api-key KEY] [-timeout D] <command> [arguments]
+
+commands:
+  list [-limit N] [-json]   list students
+  get ID                    print one student
+  create -f FILE            create the student, or array of students, in a JSON file
+  import csv FILE           create a student per row of a CSV file
+  summary ID                print a student's generated summary
+  backup [-o FILE]          write every student to FILE as JSON lines
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout)
+	stop()
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "studentctl:", err)
+		os.Exit(1)
+	}
+}
+
+// command is one studentctl subcommand.
+type command func(ctx context.Context, c *client.Client, args []string, stdin io.Reader, stdout io.Writer) error
+
+var commands = map[string]command{
+	"list":    listStudents,
+	"get":     getStudent,
+	"create":  createStudents,
+	"import":  importStudents,
+	"summary": printSummary,
+	"backup":  backupStudents,
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("studentctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	baseURL := fs.String("url", envOr("STUDENTCTL_URL", "http://localhost:8080"), "base URL of the API (env STUDENTCTL_URL)")
+	apiKey := fs.String("api-key", os.Getenv("STUDENTCTL_API_KEY"), "API key sent as X-API-Key (env STUDENTCTL_API_KEY)")
+	timeout := fs.Duration("timeout", 2*time.Minute, "time allowed for the whole command")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	c, err := client.New(*baseURL, client.WithAPIKey(*apiKey), client.WithUserAgent("studentctl"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return cmd(ctx, c, fs.Args()[1:], stdin, stdout)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// idArg reads the single student ID argument of a command.
+func idArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("want one student ID")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%q is not a student ID", args[0])
+	}
+	return id, nil
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func listStudents(ctx context.Context, c *client.Client, args []string, _ io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "stop after this many students; 0 lists all")
+	asJSON := fs.Bool("json", false, "print a JSON array instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var list []client.Student
+	for s, err := range c.AllStudents(ctx, 0) {
+		if err != nil {
+			return err
+		}
+		list = append(list, s)
+		if *limit > 0 && len(list) == *limit {
+			break
+		}
+	}
+	if *asJSON {
+		if list == nil {
+			list = []client.Student{}
+		}
+		return printJSON(out, list)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tAGE\tEMAIL\tPROGRAM")
+	for _, s := range list {
+		program := ""
+		if s.ProgramID != 0 {
+			program = strconv.Itoa(s.ProgramID)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", s.ID, s.Name, s.Age, s.Email, program)
+	}
+	return tw.Flush()
+}
+
+func getStudent(ctx context.Context, c *client.Client, args []string, _ io.Reader, out io.Writer) error {
+	id, err := idArg(args)
+	if err != nil {
+		return err
+	}
+	s, err := c.GetStudent(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printJSON(out, s)
+}
+
+// openInput opens a file argument, with - meaning stdin.
+func openInput(name string, stdin io.Reader) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(stdin), nil
+	}
+	return os.Open(name)
+}
+
+func createStudents(ctx context.Context, c *client.Client, args []string, stdin io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	file := fs.String("f", "", "JSON file holding a student or an array of students")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("create: -f is required")
+	}
+	in, err := openInput(*file, stdin)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+
+	var students []client.Student
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &students)
+	} else {
+		var s client.Student
+		err = json.Unmarshal(trimmed, &s)
+		students = append(students, s)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+
+	created := make([]client.Student, 0, len(students))
+	for i, s := range students {
+		s, err := c.CreateStudent(ctx, s)
+		if err != nil {
+			if len(students) > 1 {
+				err = fmt.Errorf("student %d: %w (%d created before it)", i, err, len(created))
+			}
+			return err
+		}
+		created = append(created, s)
+	}
+	if len(students) == 1 {
+		return printJSON(out, created[0])
+	}
+	return printJSON(out, created)
+}
+
+// importStudents creates a student per CSV row. The header names the
+// columns: name, age and email are required, program_id is optional, and
+// others, such as the id of an export, are ignored. Failed rows are
+// reported and skipped.
+func importStudents(ctx context.Context, c *client.Client, args []string, stdin io.Reader, out io.Writer) error {
+	if len(args) != 2 || args[0] != "csv" {
+		return errors.New("usage: import csv FILE")
+	}
+	in, err := openInput(args[1], stdin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "age", "email"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("header has no %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	created, failed := 0, 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := r.FieldPos(0)
+		s := client.Student{Name: field(record, "name"), Email: field(record, "email")}
+		for _, col := range []struct {
+			name string
+			dst  *int
+		}{{"age", &s.Age}, {"program_id", &s.ProgramID}} {
+			v := field(record, col.name)
+			if v == "" || err != nil {
+				continue
+			}
+			if *col.dst, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("%s %q is not an integer", col.name, v)
+			}
+		}
+		if err == nil {
+			_, err = c.CreateStudent(ctx, s)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(out, "line %d: %v\n", line, err)
+			failed++
+			continue
+		}
+		created++
+	}
+	fmt.Fprintf(out, "created %d students, %d rows failed\n", created, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d rows failed", failed)
+	}
+	return nil
+}
+
+func printSummary(ctx context.Context, c *client.Client, args []string, _ io.Reader, out io.Writer) error {
+	id, err := idArg(args)
+	if err != nil {
+		return err
+	}
+	summary, err := c.GetSummary(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, summary)
+	return err
+}
+
+// backupStudents writes every student as one JSON object per line. The
+// file is written next to its destination and renamed into place, so a
+// failed backup never replaces a good one.
+func backupStudents(ctx context.Context, c *client.Client, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	file := fs.String("o", "-", "file to write the backup to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	out, tmp := stdout, (*os.File)(nil)
+	if *file != "-" {
+		var err error
+		if tmp, err = os.CreateTemp(filepath.Dir(*file), ".studentctl-backup-*"); err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		out = tmp
+	}
+
+	enc := json.NewEncoder(out)
+	count := 0
+	for s, err := range c.AllStudents(ctx, 0) {
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+		count++
+	}
+	if tmp == nil {
+		return nil
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), *file); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backed up %d students to %s\n", count, *file)
+	return nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Cohort is a named group of students, such as a graduating class or an
+// advising group, that can be acted on together.
+type Cohort struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description,omitempty"`
+	MemberIDs   []int  `json:"member_ids"`
+}
+
+func (c Cohort) entityID() int { return c.ID }
+
+func (c Cohort) withID(id int) Cohort {
+	c.ID = id
+	return c
+}
+
+var cohortStore Repository[Cohort] = newCollection[Cohort]("cohorts")
+
+// cohortMutex serializes cohort writes so name checks and membership
+// changes cannot race.
+var cohortMutex = &sync.Mutex{}
+
+// validateCohort lists every problem with a submitted cohort.
+func validateCohort(c Cohort) []FieldError {
+	return checkFields(c)
+}
+
+func cohortNameTaken(r *http.Request, name string, exceptID int) bool {
+	for _, c := range cohortStore.List(r.Context()) {
+		if c.ID != exceptID && strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cohortMembers returns the cohort's students ordered by ID.
+func cohortMembers(r *http.Request, c Cohort) []Student {
+	members := []Student{}
+	for _, id := range c.MemberIDs {
+		if s, ok := findStudent(r.Context(), id); ok {
+			members = append(members, s)
+		}
+	}
+	return members
+}
+
+// findCohortVar loads the cohort named by the {id} route variable, writing
+// the problem response itself when it cannot.
+func findCohortVar(w http.ResponseWriter, r *http.Request) (Cohort, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort ID")
+		return Cohort{}, false
+	}
+	cohort, exists := repoFind(r.Context(), cohortStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Cohort not found")
+		return Cohort{}, false
+	}
+	return cohort, true
+}
+
+func createCohort(w http.ResponseWriter, r *http.Request) {
+	var cohort Cohort
+	if err := json.NewDecoder(r.Body).Decode(&cohort); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateCohort(cohort); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort data", errs...)
+		return
+	}
+
+	cohortMutex.Lock()
+	defer cohortMutex.Unlock()
+
+	if cohortNameTaken(r, cohort.Name, 0) {
+		writeProblem(w, r, http.StatusConflict, "A cohort named "+cohort.Name+" already exists")
+		return
+	}
+	// Members are managed through /cohorts/{id}/members.
+	cohort.MemberIDs = []int{}
+	cohort = repoInsert(r.Context(), cohortStore, cohort)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cohort)
+}
+
+func getCohorts(w http.ResponseWriter, r *http.Request) {
+	if err := writeJSONArray(w, repoList(r.Context(), cohortStore)); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream cohort list", "error", err)
+	}
+}
+
+func getCohort(w http.ResponseWriter, r *http.Request) {
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+	writeJSONWithETag(w, r, cohort)
+}
+
+func updateCohort(w http.ResponseWriter, r *http.Request) {
+	var updated Cohort
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateCohort(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort data", errs...)
+		return
+	}
+
+	cohortMutex.Lock()
+	defer cohortMutex.Unlock()
+
+	before, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+	if cohortNameTaken(r, updated.Name, before.ID) {
+		writeProblem(w, r, http.StatusConflict, "A cohort named "+updated.Name+" already exists")
+		return
+	}
+	updated.ID, updated.MemberIDs = before.ID, before.MemberIDs
+	repoReplace(r.Context(), cohortStore, before.ID, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteCohort(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid cohort ID")
+		return
+	}
+
+	if _, exists := repoRemove(r.Context(), cohortStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Cohort not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addCohortMembers adds {"student_ids": [...]} to a cohort. Students
+// already in it are left alone; unknown students fail the whole request.
+func addCohortMembers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		StudentIDs []int `json:"student_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid member data", decodeErrors(err)...)
+		return
+	}
+	if len(req.StudentIDs) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid member data", FieldError{Field: "student_ids", Message: "is required"})
+		return
+	}
+
+	cohortMutex.Lock()
+	defer cohortMutex.Unlock()
+
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+	var errs []FieldError
+	for i, id := range req.StudentIDs {
+		if _, exists := findStudent(r.Context(), id); !exists {
+			errs = append(errs, FieldError{Field: "student_ids[" + strconv.Itoa(i) + "]", Message: "student " + strconv.Itoa(id) + " does not exist"})
+		}
+	}
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Student not found", errs...)
+		return
+	}
+
+	for _, id := range req.StudentIDs {
+		if !slices.Contains(cohort.MemberIDs, id) {
+			cohort.MemberIDs = append(cohort.MemberIDs, id)
+		}
+	}
+	slices.Sort(cohort.MemberIDs)
+	repoReplace(r.Context(), cohortStore, cohort.ID, cohort)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cohort)
+}
+
+func getCohortMembers(w http.ResponseWriter, r *http.Request) {
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+	writeJSONArray(w, cohortMembers(r, cohort))
+}
+
+func removeCohortMember(w http.ResponseWriter, r *http.Request) {
+	studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	cohortMutex.Lock()
+	defer cohortMutex.Unlock()
+
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+	i := slices.Index(cohort.MemberIDs, studentID)
+	if i < 0 {
+		writeProblem(w, r, http.StatusNotFound, "Student is not a member of this cohort")
+		return
+	}
+	cohort.MemberIDs = slices.Delete(cohort.MemberIDs, i, i+1)
+	repoReplace(r.Context(), cohortStore, cohort.ID, cohort)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CohortSummary is one member's summary in a cohort summary response.
+// Error is set instead of Summary when generating it failed.
+type CohortSummary struct {
+	StudentID int    `json:"student_id"`
+	Name      string `json:"name"`
+	Summary   string `json:"summary,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// getCohortSummaries generates, or reuses cached, summaries for every
+// member, a few at a time.
+func getCohortSummaries(w http.ResponseWriter, r *http.Request) {
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+
+	members := cohortMembers(r, cohort)
+	summaries := make([]CohortSummary, len(members))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, cfg.CohortSummaryConcurrency)
+	for i, s := range members {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			summaries[i] = CohortSummary{StudentID: s.ID, Name: s.Name}
+			summary, err := cachedSummarizeStudent(r.Context(), s)
+			if err != nil {
+				slog.WarnContext(r.Context(), "cohort summary failed", "cohort_id", cohort.ID, "student_id", s.ID, "error", err)
+				summaries[i].Error = err.Error()
+				return
+			}
+			summaries[i].Summary = summary
+		}()
+	}
+	wg.Wait()
+
+	setContentLanguage(w, localeFromContext(r.Context()))
+	writeJSONArray(w, summaries)
+}
+
+// exportCohort writes the cohort's members as CSV.
+func exportCohort(w http.ResponseWriter, r *http.Request) {
+	cohort, ok := findCohortVar(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="cohort-`+strconv.Itoa(cohort.ID)+`.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "name", "age", "email", "program_id"})
+	for _, s := range cohortMembers(r, cohort) {
+		program := ""
+		if s.ProgramID != 0 {
+			program = strconv.Itoa(s.ProgramID)
+		}
+		out.Write([]string{strconv.Itoa(s.ID), s.Name, strconv.Itoa(s.Age), s.Email, program})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		slog.WarnContext(r.Context(), "failed to write cohort export", "cohort_id", cohort.ID, "error", err)
+	}
+}
+
+// removeCohortMemberships takes a deleted student out of every cohort.
+func removeCohortMemberships(ctx context.Context, studentID int) {
+	cohortMutex.Lock()
+	defer cohortMutex.Unlock()
+
+	for _, c := range cohortStore.List(ctx) {
+		if i := slices.Index(c.MemberIDs, studentID); i >= 0 {
+			c.MemberIDs = slices.Delete(c.MemberIDs, i, i+1)
+			repoReplace(ctx, cohortStore, c.ID, c)
+		}
+	}
+}
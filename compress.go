@@ -0,0 +1,189 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minCompressSize is the smallest body worth compressing. Anything smaller
+// usually grows once gzip framing is added.
+const minCompressSize = 1024
+
+var (
+	gzipWriters  = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	flateWriters = sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	}}
+)
+
+// negotiateEncoding picks gzip or deflate from Accept-Encoding, preferring
+// gzip, honoring q=0 exclusions. It returns "" when neither is acceptable.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			name = "gzip"
+		}
+		if (name != "gzip" && name != "deflate") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressResponses transparently compresses responses for clients that
+// accept it. Small bodies, already-encoded bodies, event streams and
+// protocol upgrades are passed through untouched.
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// compressing it is worthwhile.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	buf         []byte
+	decided     bool
+	compressing bool
+	enc         interface {
+		io.WriteCloser
+		Flush() error
+		Reset(io.Writer)
+	}
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < minCompressSize {
+			return len(b), nil
+		}
+		cw.decide(true)
+		return len(b), cw.flushBuffer()
+	}
+	if cw.compressing {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide chooses between compressing and passing through, then sends the
+// headers. large reports whether enough of the body has been seen.
+func (cw *compressWriter) decide(large bool) {
+	cw.decided = true
+	h := cw.Header()
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	cw.compressing = large &&
+		status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" &&
+		!strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+
+	if cw.compressing {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			cw.enc = gzipWriters.Get().(*gzip.Writer)
+		} else {
+			cw.enc = flateWriters.Get().(*flate.Writer)
+		}
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	if cw.status != 0 || cw.compressing {
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) flushBuffer() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.compressing {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// Flush sends what has been written so far. A handler flushing before the
+// size threshold is streaming, so the response is passed through.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+		cw.flushBuffer()
+	}
+	if cw.compressing {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close finishes the response and returns the encoder to its pool.
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		cw.decide(false)
+		cw.flushBuffer()
+	}
+	if !cw.compressing {
+		return
+	}
+	cw.enc.Close()
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	case *flate.Writer:
+		flateWriters.Put(enc)
+	}
+	cw.enc = nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
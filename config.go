@@ -0,0 +1,751 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every runtime setting of the service.
+//
+// Values are resolved in increasing order of precedence from built-in
+// defaults, an optional YAML file (-config or CONFIG_FILE), environment
+// variables and command-line flags.
+type Config struct {
+	Port                     string
+	Host                     string
+	UnixSocket               string
+	UnixSocketMode           string
+	GRPCPort                 string
+	LegacyPaths              bool
+	ResponseEnvelope         bool
+	Store                    string
+	StoreShards              int
+	StoreFile                string
+	StoreFlushInterval       time.Duration
+	StoreBatchSize           int
+	ListCacheEntries         int
+	DefaultPageSize          int
+	MaxPageSize              int
+	ListCacheMaxBytes        int
+	Env                      string
+	OllamaURL                string
+	OllamaModel              string
+	OllamaTimeout            time.Duration
+	OllamaMaxIdleConns       int
+	OllamaMaxLineBytes       int
+	OllamaReplay             string
+	OllamaReplayDir          string
+	SummaryCacheTTL          time.Duration
+	SummaryWarmWindow        string
+	SummaryWarmInterval      time.Duration
+	SummaryWarmConcurrency   int
+	SummaryWarmBatch         int
+	RequestTimeout           time.Duration
+	LLMRequestTimeout        time.Duration
+	ShutdownGracePeriod      time.Duration
+	ShutdownDrainDelay       time.Duration
+	MaxInFlight              int
+	MaxInFlightLLM           int
+	ReadHeaderTimeout        time.Duration
+	ReadTimeout              time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	KeepAlives               bool
+	MaxConnections           int
+	MaxHeaderBytes           int
+	TLSCertFile              string
+	TLSKeyFile               string
+	HTTP2                    bool
+	HTTP2MaxStreams          int
+	SlowRequestThreshold     time.Duration
+	SlowLLMThreshold         time.Duration
+	LogLevel                 string
+	LogFormat                string
+	AccessLog                string
+	AccessLogFormat          string
+	APIKeys                  []string
+	AuthMaxFailures          int
+	AuthLockout              time.Duration
+	TrustedProxies           []string
+	ForwardedHeader          string
+	AuditLogFile             string
+	ShareLinkSecret          string
+	PublicURL                string
+	ShareLinkTTL             time.Duration
+	AnonymizeSalt            string
+	Anonymize                bool
+	Seed                     string
+	ChaosPercent             int
+	ChaosFaults              []string
+	ChaosLatency             time.Duration
+	ChaosOllama              bool
+	SentryDSN                string
+	MaintenanceFile          string
+	FeatureFlagsFile         string
+	DocumentsDir             string
+	BlobStore                string
+	S3Endpoint               string
+	S3Bucket                 string
+	S3Region                 string
+	S3AccessKeyID            string
+	S3SecretAccessKey        string
+	S3Prefix                 string
+	DocumentMaxBytes         int64
+	PhotoMaxBytes            int64
+	CohortSummaryConcurrency int
+	WebhookWorkers           int
+	WebhookTimeout           time.Duration
+	WebhookMaxAttempts       int
+	WebhookRetryBase         time.Duration
+	EventBroker              string
+	EventBrokerURL           string
+	EventTopic               string
+	SMTPHost                 string
+	SMTPPort                 int
+	SMTPUsername             string
+	SMTPPassword             string
+	SMTPFrom                 string
+	NotifyWelcome            bool
+	NotifySummaryReady       bool
+	NotifyAbsence            bool
+	JobSchedules             string
+	LDAPURL                  string
+	LDAPBindDN               string
+	LDAPBindPassword         string
+	LDAPBaseDN               string
+	LDAPFilter               string
+	LDAPAttributes           string
+	LDAPPageSize             int
+	LDAPTimeout              time.Duration
+	GoogleSheetsID           string
+	GoogleSheetsSheet        string
+	GoogleCredentialsFile    string
+	GoogleSheetsFilter       string
+	GoogleSheetsFields       string
+	GoogleSheetsURL          string
+	Retention                time.Duration
+	MaxStreamClients         int
+}
+
+// cfg is the active configuration, set by main before serving.
+var cfg = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Port:                     "8080",
+		UnixSocketMode:           "0660",
+		LegacyPaths:              true,
+		Store:                    "memory",
+		StoreShards:              16,
+		StoreFile:                "students.jsonl",
+		StoreFlushInterval:       50 * time.Millisecond,
+		StoreBatchSize:           256,
+		DefaultPageSize:          100,
+		MaxPageSize:              1000,
+		ListCacheEntries:         64,
+		ListCacheMaxBytes:        4 << 20,
+		Env:                      "development",
+		OllamaURL:                "http://localhost:11434",
+		OllamaModel:              "llama3",
+		OllamaTimeout:            60 * time.Second,
+		OllamaMaxLineBytes:       1 << 20,
+		OllamaReplayDir:          "testdata/ollama",
+		OllamaMaxIdleConns:       32,
+		SummaryCacheTTL:          24 * time.Hour,
+		SummaryWarmInterval:      10 * time.Minute,
+		SummaryWarmConcurrency:   2,
+		SummaryWarmBatch:         50,
+		RequestTimeout:           10 * time.Second,
+		LLMRequestTimeout:        90 * time.Second,
+		ShutdownGracePeriod:      30 * time.Second,
+		ShutdownDrainDelay:       5 * time.Second,
+		MaxInFlight:              1024,
+		MaxInFlightLLM:           32,
+		ReadHeaderTimeout:        5 * time.Second,
+		ReadTimeout:              30 * time.Second,
+		WriteTimeout:             120 * time.Second,
+		KeepAlives:               true,
+		MaxHeaderBytes:           http.DefaultMaxHeaderBytes,
+		HTTP2:                    true,
+		HTTP2MaxStreams:          250,
+		IdleTimeout:              120 * time.Second,
+		SlowRequestThreshold:     2 * time.Second,
+		SlowLLMThreshold:         15 * time.Second,
+		LogLevel:                 "info",
+		LogFormat:                "json",
+		AccessLog:                "stdout",
+		AccessLogFormat:          "combined",
+		AuthMaxFailures:          5,
+		AuthLockout:              15 * time.Minute,
+		ForwardedHeader:          "X-Forwarded-For",
+		ShareLinkTTL:             24 * time.Hour,
+		ChaosFaults:              []string{chaosLatency, chaosError, chaosDrop},
+		ChaosLatency:             2 * time.Second,
+		DocumentsDir:             "data",
+		BlobStore:                blobsDisk,
+		S3Region:                 "us-east-1",
+		DocumentMaxBytes:         10 << 20,
+		PhotoMaxBytes:            2 << 20,
+		CohortSummaryConcurrency: 4,
+		WebhookWorkers:           4,
+		WebhookTimeout:           10 * time.Second,
+		WebhookMaxAttempts:       6,
+		WebhookRetryBase:         30 * time.Second,
+		EventTopic:               "studengo.events",
+		SMTPPort:                 587,
+		NotifyWelcome:            true,
+		NotifySummaryReady:       true,
+		NotifyAbsence:            true,
+		LDAPFilter:               "(objectClass=person)",
+		LDAPAttributes:           "name=displayName,email=mail",
+		LDAPPageSize:             500,
+		LDAPTimeout:              time.Minute,
+		GoogleSheetsSheet:        "Students",
+		GoogleSheetsURL:          "https://sheets.googleapis.com",
+		Retention:                90 * 24 * time.Hour,
+		MaxStreamClients:         100,
+	}
+}
+
+// setting binds one Config field to its YAML key, environment variable and
+// flag. Secrets have no flag so they never show up in process listings.
+type setting struct {
+	key   string
+	env   string
+	flag  bool
+	usage string
+	value interface{}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		// PORT is set by the platform on Render.com.
+		{"port", "PORT", true, "TCP port to listen on", &c.Port},
+		{"host", "HOST", true, "address to listen on, such as 127.0.0.1 (empty listens on every interface)", &c.Host},
+		{"unix_socket", "UNIX_SOCKET", true, "path of a Unix domain socket to listen on instead of host and port", &c.UnixSocket},
+		{"unix_socket_mode", "UNIX_SOCKET_MODE", true, "octal file mode of the Unix socket", &c.UnixSocketMode},
+		{"grpc_port", "GRPC_PORT", true, "TCP port of the plaintext gRPC API, for internal networks (empty disables it)", &c.GRPCPort},
+		{"legacy_paths", "LEGACY_PATHS", true, "serve the unversioned API paths as deprecated aliases of /v1", &c.LegacyPaths},
+		{"response_envelope", "RESPONSE_ENVELOPE", true, "wrap /v1 JSON responses in a data, error and meta envelope unless a request sets ?envelope=false", &c.ResponseEnvelope},
+		{"store", "STORE", true, "store backend (memory, sharded, file)", &c.Store},
+		{"store_shards", "STORE_SHARDS", true, "number of shards for the sharded store", &c.StoreShards},
+		{"store_file", "STORE_FILE", true, "log file of the file store", &c.StoreFile},
+		{"store_flush_interval", "STORE_FLUSH_INTERVAL", true, "how often the file store writes and syncs queued changes", &c.StoreFlushInterval},
+		{"store_batch_size", "STORE_BATCH_SIZE", true, "queued changes that trigger an early file store flush", &c.StoreBatchSize},
+		{"default_page_size", "DEFAULT_PAGE_SIZE", true, "items returned by list requests without a limit", &c.DefaultPageSize},
+		{"max_page_size", "MAX_PAGE_SIZE", true, "largest limit a list request may ask for", &c.MaxPageSize},
+		{"list_cache_entries", "LIST_CACHE_ENTRIES", true, "list responses kept in the cache; 0 disables it", &c.ListCacheEntries},
+		{"list_cache_max_bytes", "LIST_CACHE_MAX_BYTES", true, "largest list response that is cached", &c.ListCacheMaxBytes},
+		{"env", "APP_ENV", true, "deployment environment (development, staging, production)", &c.Env},
+		{"ollama_url", "OLLAMA_URL", true, "base URL of the Ollama server", &c.OllamaURL},
+		{"ollama_model", "OLLAMA_MODEL", true, "model used for summaries", &c.OllamaModel},
+		{"ollama_timeout", "OLLAMA_TIMEOUT", true, "timeout of a single Ollama call", &c.OllamaTimeout},
+		{"ollama_max_line_bytes", "OLLAMA_MAX_LINE_BYTES", true, "longest line accepted in a streamed Ollama response", &c.OllamaMaxLineBytes},
+		{"ollama_replay", "OLLAMA_REPLAY", true, "record Ollama calls to ollama_replay_dir, or replay them from it without a model server: record or replay", &c.OllamaReplay},
+		{"ollama_replay_dir", "OLLAMA_REPLAY_DIR", true, "directory of recorded Ollama calls", &c.OllamaReplayDir},
+		{"ollama_max_idle_conns", "OLLAMA_MAX_IDLE_CONNS", true, "idle keep-alive connections kept open to Ollama", &c.OllamaMaxIdleConns},
+		{"summary_cache_ttl", "SUMMARY_CACHE_TTL", true, "how long generated summaries are reused; 0 disables the cache", &c.SummaryCacheTTL},
+		{"summary_warm_window", "SUMMARY_WARM_WINDOW", true, "daily HH:MM-HH:MM window, in server local time, in which summaries are precomputed; empty disables warming", &c.SummaryWarmWindow},
+		{"summary_warm_interval", "SUMMARY_WARM_INTERVAL", true, "how often warming runs inside its window, unless job_schedules sets summary_warm", &c.SummaryWarmInterval},
+		{"summary_warm_concurrency", "SUMMARY_WARM_CONCURRENCY", true, "summaries generated in parallel while warming", &c.SummaryWarmConcurrency},
+		{"summary_warm_batch", "SUMMARY_WARM_BATCH", true, "summaries generated per warming run", &c.SummaryWarmBatch},
+		{"cohort_summary_concurrency", "COHORT_SUMMARY_CONCURRENCY", true, "summaries generated in parallel for one cohort summary request", &c.CohortSummaryConcurrency},
+		{"webhook_workers", "WEBHOOK_WORKERS", true, "webhook deliveries sent in parallel", &c.WebhookWorkers},
+		{"webhook_timeout", "WEBHOOK_TIMEOUT", true, "how long a webhook receiver has to answer one delivery", &c.WebhookTimeout},
+		{"webhook_max_attempts", "WEBHOOK_MAX_ATTEMPTS", true, "delivery attempts before a webhook delivery is dead-lettered", &c.WebhookMaxAttempts},
+		{"webhook_retry_base", "WEBHOOK_RETRY_BASE", true, "delay before the first webhook retry; each further retry waits twice as long", &c.WebhookRetryBase},
+		{"event_broker", "EVENT_BROKER", true, "broker domain events are forwarded to: nats, kafka (through a REST proxy) or empty for none", &c.EventBroker},
+		{"event_broker_url", "EVENT_BROKER_URL", false, "NATS server URL, or Kafka REST proxy base URL; may carry credentials", &c.EventBrokerURL},
+		{"event_topic", "EVENT_TOPIC", true, "Kafka topic, or NATS subject prefix, events are published to", &c.EventTopic},
+		{"smtp_host", "SMTP_HOST", true, "SMTP server notification emails are sent through; empty disables email", &c.SMTPHost},
+		{"smtp_port", "SMTP_PORT", true, "SMTP server port", &c.SMTPPort},
+		{"smtp_username", "SMTP_USERNAME", true, "SMTP user; empty sends without authenticating", &c.SMTPUsername},
+		{"smtp_password", "SMTP_PASSWORD", false, "SMTP password", &c.SMTPPassword},
+		{"smtp_from", "SMTP_FROM", true, "sender address of notification emails", &c.SMTPFrom},
+		{"notify_welcome", "NOTIFY_WELCOME", true, "email new students a welcome message", &c.NotifyWelcome},
+		{"notify_summary_ready", "NOTIFY_SUMMARY_READY", true, "email students when a summary they asked for is ready", &c.NotifySummaryReady},
+		{"notify_absence", "NOTIFY_ABSENCE", true, "email guardians when a student is marked absent", &c.NotifyAbsence},
+		{"job_schedules", "JOB_SCHEDULES", true, "semicolon-separated name=schedule overrides for scheduled jobs, e.g. \"stats_report=0 6 * * *; webhook_retry=off\"", &c.JobSchedules},
+		{"ldap_url", "LDAP_URL", true, "ldap:// or ldaps:// URL of the directory students are synced from; empty disables the ldap_sync job", &c.LDAPURL},
+		{"ldap_bind_dn", "LDAP_BIND_DN", true, "DN the sync binds as; empty binds anonymously", &c.LDAPBindDN},
+		{"ldap_bind_password", "LDAP_BIND_PASSWORD", false, "password of ldap_bind_dn", &c.LDAPBindPassword},
+		{"ldap_base_dn", "LDAP_BASE_DN", true, "subtree searched for student entries", &c.LDAPBaseDN},
+		{"ldap_filter", "LDAP_FILTER", true, "RFC 4515 filter selecting student entries", &c.LDAPFilter},
+		{"ldap_attributes", "LDAP_ATTRIBUTES", true, "comma-separated field=attribute mapping of name, email, age and program_id, e.g. \"name=displayName,email=mail,age=studentAge\"", &c.LDAPAttributes},
+		{"ldap_page_size", "LDAP_PAGE_SIZE", true, "entries requested per page of the directory search", &c.LDAPPageSize},
+		{"ldap_timeout", "LDAP_TIMEOUT", true, "time allowed for reading the directory in one sync", &c.LDAPTimeout},
+		{"google_sheets_id", "GOOGLE_SHEETS_ID", true, "ID of the spreadsheet the sheets_export job writes students to; empty disables the job", &c.GoogleSheetsID},
+		{"google_sheets_sheet", "GOOGLE_SHEETS_SHEET", true, "tab of the spreadsheet replaced by each export", &c.GoogleSheetsSheet},
+		{"google_credentials_file", "GOOGLE_APPLICATION_CREDENTIALS", true, "JSON key file of the service account the export authenticates as; share the spreadsheet with its client_email", &c.GoogleCredentialsFile},
+		{"google_sheets_filter", "GOOGLE_SHEETS_FILTER", true, "OData $filter choosing the students exported, e.g. \"program_id eq 3\"; empty exports all", &c.GoogleSheetsFilter},
+		{"google_sheets_fields", "GOOGLE_SHEETS_FIELDS", true, "comma-separated fields exported as columns, in order; empty exports all", &c.GoogleSheetsFields},
+		{"google_sheets_url", "GOOGLE_SHEETS_URL", true, "base URL of the Sheets API", &c.GoogleSheetsURL},
+		{"retention", "RETENTION", true, "age after which delivered webhook deliveries, email log entries and change feed entries are purged; 0 keeps them", &c.Retention},
+		{"max_stream_clients", "MAX_STREAM_CLIENTS", true, "clients connected to live update streams at once", &c.MaxStreamClients},
+		{"request_timeout", "REQUEST_TIMEOUT", true, "maximum duration of CRUD and admin requests", &c.RequestTimeout},
+		{"llm_request_timeout", "LLM_REQUEST_TIMEOUT", true, "maximum duration of requests that call Ollama", &c.LLMRequestTimeout},
+		{"shutdown_grace_period", "SHUTDOWN_GRACE_PERIOD", true, "time allowed for in-flight requests on shutdown", &c.ShutdownGracePeriod},
+		{"shutdown_drain_delay", "SHUTDOWN_DRAIN_DELAY", true, "time /readyz fails before the server stops accepting connections on shutdown; 0 stops at once", &c.ShutdownDrainDelay},
+		{"max_in_flight", "MAX_IN_FLIGHT", true, "requests served concurrently before answering 429; 0 disables the limit", &c.MaxInFlight},
+		{"max_in_flight_llm", "MAX_IN_FLIGHT_LLM", true, "summary requests served concurrently before answering 429; 0 disables the limit", &c.MaxInFlightLLM},
+		{"read_header_timeout", "READ_HEADER_TIMEOUT", true, "time allowed to read request headers", &c.ReadHeaderTimeout},
+		{"read_timeout", "READ_TIMEOUT", true, "time allowed to read a whole request", &c.ReadTimeout},
+		{"write_timeout", "WRITE_TIMEOUT", true, "time allowed to write a response; must exceed llm_request_timeout", &c.WriteTimeout},
+		{"idle_timeout", "IDLE_TIMEOUT", true, "how long idle keep-alive connections are kept open", &c.IdleTimeout},
+		{"keep_alives", "KEEP_ALIVES", true, "keep client connections open between requests", &c.KeepAlives},
+		{"max_connections", "MAX_CONNECTIONS", true, "client connections accepted at once; 0 is unlimited", &c.MaxConnections},
+		{"max_header_bytes", "MAX_HEADER_BYTES", true, "largest request header block accepted", &c.MaxHeaderBytes},
+		{"tls_cert_file", "TLS_CERT_FILE", true, "TLS certificate; serves HTTPS when set", &c.TLSCertFile},
+		{"tls_key_file", "TLS_KEY_FILE", true, "TLS private key", &c.TLSKeyFile},
+		{"http2", "HTTP2", true, "offer HTTP/2 to TLS clients", &c.HTTP2},
+		{"http2_max_streams", "HTTP2_MAX_STREAMS", true, "concurrent streams allowed per HTTP/2 connection", &c.HTTP2MaxStreams},
+		{"slow_request_threshold", "SLOW_REQUEST_THRESHOLD", true, "requests slower than this are logged with a timing breakdown", &c.SlowRequestThreshold},
+		{"slow_llm_threshold", "SLOW_LLM_THRESHOLD", true, "Ollama calls slower than this are logged with a timing breakdown", &c.SlowLLMThreshold},
+		{"log_level", "LOG_LEVEL", true, "minimum log level (debug, info, warn, error)", &c.LogLevel},
+		{"log_format", "LOG_FORMAT", true, "log output format (json, text)", &c.LogFormat},
+		{"access_log", "ACCESS_LOG", true, "access log destination (stdout, stderr, off or a file path)", &c.AccessLog},
+		{"access_log_format", "ACCESS_LOG_FORMAT", true, "access log format (combined, common, json); latency is appended in microseconds", &c.AccessLogFormat},
+		{"api_keys", "API_KEYS", false, "comma-separated key:subject[:role] entries", &c.APIKeys},
+		{"auth_max_failures", "AUTH_MAX_FAILURES", true, "failed authentications before a client is locked out", &c.AuthMaxFailures},
+		{"auth_lockout_duration", "AUTH_LOCKOUT_DURATION", true, "how long a locked out client must wait", &c.AuthLockout},
+		{"trusted_proxies", "TRUSTED_PROXIES", true, "comma-separated CIDRs of trusted reverse proxies", &c.TrustedProxies},
+		{"forwarded_header", "FORWARDED_HEADER", true, "header trusted proxies put the client address in", &c.ForwardedHeader},
+		{"audit_log_file", "AUDIT_LOG_FILE", true, "file the audit trail is persisted to", &c.AuditLogFile},
+		{"share_link_secret", "SHARE_LINK_SECRET", false, "secret used to sign summary share links", &c.ShareLinkSecret},
+		{"public_url", "PUBLIC_URL", true, "base URL clients reach the API at, such as https://api.example.com, which share links are built on", &c.PublicURL},
+		{"share_link_ttl", "SHARE_LINK_TTL", true, "default lifetime of summary share links", &c.ShareLinkTTL},
+		{"anonymize_salt", "ANONYMIZE_SALT", false, "salt mixed into anonymized pseudonyms", &c.AnonymizeSalt},
+		{"anonymize", "ANONYMIZE", true, "scramble student names and emails, and the audit trail, on startup after seeding; refused in production", &c.Anonymize},
+		{"seed", "SEED_FILE", true, "JSON file of students and courses loaded at startup into empty collections, and again by POST /admin/reset in development", &c.Seed},
+		{"chaos_percent", "CHAOS_PERCENT", true, "percentage of requests a fault is injected into, to exercise client retries and timeouts (development only)", &c.ChaosPercent},
+		{"chaos_faults", "CHAOS_FAULTS", true, "comma-separated faults to inject: latency, error, drop", &c.ChaosFaults},
+		{"chaos_latency", "CHAOS_LATENCY", true, "longest delay the latency fault adds", &c.ChaosLatency},
+		{"chaos_ollama", "CHAOS_OLLAMA", true, "inject faults into Ollama calls too", &c.ChaosOllama},
+		{"maintenance_file", "MAINTENANCE_FILE", true, "flag file whose presence turns on maintenance mode", &c.MaintenanceFile},
+		{"feature_flags_file", "FEATURE_FLAGS_FILE", true, "YAML file defining feature flags, reloaded when it changes", &c.FeatureFlagsFile},
+		{"sentry_dsn", "SENTRY_DSN", false, "Sentry DSN panics and 5xx responses are reported to", &c.SentryDSN},
+		{"documents_dir", "DOCUMENTS_DIR", true, "directory file contents, such as uploaded student documents, are stored in when blob_store is disk", &c.DocumentsDir},
+		{"blob_store", "BLOB_STORE", true, "where file contents are stored: disk (documents_dir) or s3 (an S3-compatible bucket such as MinIO)", &c.BlobStore},
+		{"s3_endpoint", "S3_ENDPOINT", true, "base URL of the S3 service, e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000", &c.S3Endpoint},
+		{"s3_bucket", "S3_BUCKET", true, "bucket file contents are stored in; it must exist", &c.S3Bucket},
+		{"s3_region", "S3_REGION", true, "region requests are signed for", &c.S3Region},
+		{"s3_access_key_id", "S3_ACCESS_KEY_ID", true, "access key ID requests are signed with", &c.S3AccessKeyID},
+		{"s3_secret_access_key", "S3_SECRET_ACCESS_KEY", false, "secret key of s3_access_key_id", &c.S3SecretAccessKey},
+		{"s3_prefix", "S3_PREFIX", true, "prefix of every object key, e.g. \"studengo/\", so a bucket can be shared", &c.S3Prefix},
+		{"document_max_bytes", "DOCUMENT_MAX_BYTES", true, "largest document upload accepted", &c.DocumentMaxBytes},
+		{"photo_max_bytes", "PHOTO_MAX_BYTES", true, "largest student photo accepted", &c.PhotoMaxBytes},
+	}
+}
+
+// flagName is the YAML key spelled the way command-line flags usually are.
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+func (s setting) set(raw string) error {
+	switch v := s.value.(type) {
+	case *string:
+		*v = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.key, raw)
+		}
+		*v = n
+	case *int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.key, raw)
+		}
+		*v = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", s.key, raw)
+		}
+		*v = b
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration", s.key, raw)
+		}
+		*v = d
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	switch v := s.value.(type) {
+	case *string:
+		return *v
+	case *int:
+		return strconv.Itoa(*v)
+	case *int64:
+		return strconv.FormatInt(*v, 10)
+	case *bool:
+		return strconv.FormatBool(*v)
+	case *time.Duration:
+		return v.String()
+	case *[]string:
+		return strings.Join(*v, ",")
+	}
+	return ""
+}
+
+// loadConfig resolves the configuration from defaults, the YAML file,
+// the environment and args, then validates it.
+func loadConfig(args []string) (Config, error) {
+	c := defaultConfig()
+	settings := c.settings()
+
+	fs := flag.NewFlagSet("studengo", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to an optional YAML config file")
+	for _, s := range settings {
+		if s.flag {
+			fs.String(s.flagName(), s.String(), s.usage+" (env "+s.env+")")
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	if *configPath != "" {
+		if err := c.loadFile(*configPath, settings); err != nil {
+			return c, err
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok && raw != "" {
+			if err := s.set(raw); err != nil {
+				return c, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag && s.flagName() == f.Name && flagErr == nil {
+				flagErr = s.set(f.Value.String())
+			}
+		}
+	})
+	if flagErr != nil {
+		return c, flagErr
+	}
+
+	return c, c.validate()
+}
+
+func (c *Config) loadFile(path string, settings []setting) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.key] = s
+	}
+	for key, v := range values {
+		s, ok := known[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		raw := fmt.Sprint(v)
+		if list, ok := v.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			raw = strings.Join(items, ",")
+		}
+		if err := s.set(raw); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// validate reports every invalid or missing value at once.
+func (c *Config) validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: %q is not a valid TCP port", c.Port))
+	}
+	if c.Host != "" && net.ParseIP(c.Host) == nil && !isHostname(c.Host) {
+		errs = append(errs, fmt.Errorf("host: %q is not an IP address or host name", c.Host))
+	}
+	if _, err := socketMode(c.UnixSocketMode); err != nil {
+		errs = append(errs, fmt.Errorf("unix_socket_mode: %q is not an octal file mode such as 0660", c.UnixSocketMode))
+	}
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("grpc_port: %q is not a valid TCP port", c.GRPCPort))
+		} else if c.GRPCPort == c.Port {
+			errs = append(errs, fmt.Errorf("grpc_port: must differ from port %s", c.Port))
+		}
+	}
+	if u, err := url.Parse(c.OllamaURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("ollama_url: %q is not an absolute URL", c.OllamaURL))
+	}
+	if c.OllamaModel == "" {
+		errs = append(errs, errors.New("ollama_model: required"))
+	}
+	for name, d := range map[string]time.Duration{
+		"ollama_timeout":         c.OllamaTimeout,
+		"request_timeout":        c.RequestTimeout,
+		"llm_request_timeout":    c.LLMRequestTimeout,
+		"shutdown_grace_period":  c.ShutdownGracePeriod,
+		"read_header_timeout":    c.ReadHeaderTimeout,
+		"read_timeout":           c.ReadTimeout,
+		"write_timeout":          c.WriteTimeout,
+		"idle_timeout":           c.IdleTimeout,
+		"slow_request_threshold": c.SlowRequestThreshold,
+		"slow_llm_threshold":     c.SlowLLMThreshold,
+		"auth_lockout_duration":  c.AuthLockout,
+		"share_link_ttl":         c.ShareLinkTTL,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive", name))
+		}
+	}
+	if c.WriteTimeout <= c.LLMRequestTimeout {
+		errs = append(errs, errors.New("write_timeout: must be longer than llm_request_timeout or summaries are cut off"))
+	}
+	switch c.Store {
+	case "memory", "sharded", "file":
+	default:
+		errs = append(errs, fmt.Errorf("store: %q is not one of memory, sharded, file", c.Store))
+	}
+	if c.Store == "file" && (c.StoreFlushInterval <= 0 || c.StoreBatchSize <= 0) {
+		errs = append(errs, errors.New("store_flush_interval, store_batch_size: must be positive"))
+	}
+	if c.StoreShards <= 0 {
+		errs = append(errs, errors.New("store_shards: must be positive"))
+	}
+	if c.DefaultPageSize <= 0 || c.MaxPageSize < c.DefaultPageSize {
+		errs = append(errs, errors.New("default_page_size: must be positive and at most max_page_size"))
+	}
+	if c.ListCacheEntries < 0 {
+		errs = append(errs, errors.New("list_cache_entries: must not be negative"))
+	}
+	if c.OllamaMaxLineBytes <= 0 {
+		errs = append(errs, errors.New("ollama_max_line_bytes: must be positive"))
+	}
+	switch c.OllamaReplay {
+	case "", "record", "replay":
+	default:
+		errs = append(errs, fmt.Errorf("ollama_replay: %q is not one of record, replay", c.OllamaReplay))
+	}
+	if c.OllamaReplay != "" && c.OllamaReplayDir == "" {
+		errs = append(errs, errors.New("ollama_replay_dir: required when ollama_replay is set"))
+	}
+	if c.CohortSummaryConcurrency <= 0 {
+		errs = append(errs, errors.New("cohort_summary_concurrency: must be positive"))
+	}
+	if c.WebhookWorkers <= 0 || c.WebhookMaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhook_workers, webhook_max_attempts: must be positive"))
+	}
+	if c.WebhookTimeout <= 0 || c.WebhookRetryBase <= 0 {
+		errs = append(errs, errors.New("webhook_timeout, webhook_retry_base: must be positive"))
+	}
+	if c.SMTPHost != "" {
+		if _, err := mail.ParseAddress(c.SMTPFrom); err != nil {
+			errs = append(errs, errors.New("smtp_from: must be an email address when smtp_host is set"))
+		}
+	}
+	if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+		errs = append(errs, errors.New("smtp_port: must be a port number"))
+	}
+	if _, err := parseJobSchedules(c.JobSchedules); err != nil {
+		errs = append(errs, fmt.Errorf("job_schedules: %v", err))
+	}
+	if c.LDAPURL != "" {
+		if u, err := url.Parse(c.LDAPURL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Hostname() == "" {
+			errs = append(errs, fmt.Errorf("ldap_url: %q is not an ldap:// or ldaps:// URL", c.LDAPURL))
+		}
+		if c.LDAPBaseDN == "" {
+			errs = append(errs, errors.New("ldap_base_dn: required when ldap_url is set"))
+		}
+		if _, err := compileLDAPFilter(c.LDAPFilter); err != nil {
+			errs = append(errs, fmt.Errorf("ldap_filter: %v", err))
+		}
+		if _, err := parseLDAPAttributes(c.LDAPAttributes); err != nil {
+			errs = append(errs, fmt.Errorf("ldap_attributes: %v", err))
+		}
+		if c.LDAPPageSize <= 0 || c.LDAPTimeout <= 0 {
+			errs = append(errs, errors.New("ldap_page_size, ldap_timeout: must be positive"))
+		}
+	}
+	if c.GoogleSheetsID != "" {
+		if c.GoogleCredentialsFile == "" {
+			errs = append(errs, errors.New("google_credentials_file: required when google_sheets_id is set"))
+		} else if _, err := loadServiceAccount(c.GoogleCredentialsFile); err != nil {
+			errs = append(errs, fmt.Errorf("google_credentials_file: %v", err))
+		}
+		if c.GoogleSheetsSheet == "" {
+			errs = append(errs, errors.New("google_sheets_sheet: required when google_sheets_id is set"))
+		}
+		if _, err := sheetsExportQuery(*c); err != nil {
+			errs = append(errs, fmt.Errorf("google_sheets_filter, google_sheets_fields: %v", err))
+		}
+		if u, err := url.Parse(c.GoogleSheetsURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("google_sheets_url: %q is not an http(s) URL", c.GoogleSheetsURL))
+		}
+	}
+	if c.MaxStreamClients <= 0 {
+		errs = append(errs, errors.New("max_stream_clients: must be positive"))
+	}
+	if c.Retention < 0 {
+		errs = append(errs, errors.New("retention: must not be negative"))
+	}
+	if c.ShutdownDrainDelay < 0 {
+		errs = append(errs, errors.New("shutdown_drain_delay: must not be negative"))
+	}
+	switch c.EventBroker {
+	case "":
+	case brokerNATS, brokerKafka:
+		if c.EventBrokerURL == "" || c.EventTopic == "" {
+			errs = append(errs, errors.New("event_broker_url, event_topic: required when event_broker is set"))
+		}
+	default:
+		errs = append(errs, errors.New("event_broker: must be nats, kafka or empty"))
+	}
+	if c.OllamaMaxIdleConns <= 0 {
+		errs = append(errs, errors.New("ollama_max_idle_conns: must be positive"))
+	}
+	if c.MaxInFlight < 0 || c.MaxInFlightLLM < 0 {
+		errs = append(errs, errors.New("max_in_flight, max_in_flight_llm: must not be negative"))
+	}
+	if c.SummaryWarmWindow != "" {
+		if _, err := parseWarmWindow(c.SummaryWarmWindow); err != nil {
+			errs = append(errs, fmt.Errorf("summary_warm_window: %w", err))
+		}
+		if c.SummaryWarmInterval <= 0 || c.SummaryWarmConcurrency <= 0 || c.SummaryWarmBatch <= 0 {
+			errs = append(errs, errors.New("summary_warm_interval, summary_warm_concurrency, summary_warm_batch: must be positive"))
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file, tls_key_file: must be set together"))
+	}
+	if c.MaxConnections < 0 || c.MaxHeaderBytes <= 0 || c.HTTP2MaxStreams <= 0 {
+		errs = append(errs, errors.New("max_connections must not be negative; max_header_bytes, http2_max_streams must be positive"))
+	}
+	if c.DocumentMaxBytes <= 0 || c.PhotoMaxBytes <= 0 {
+		errs = append(errs, errors.New("document_max_bytes, photo_max_bytes: must be positive"))
+	}
+	switch c.BlobStore {
+	case blobsDisk:
+		if c.DocumentsDir == "" {
+			errs = append(errs, errors.New("documents_dir: required when blob_store is disk"))
+		}
+	case blobsS3:
+		if c.S3Bucket == "" || c.S3Region == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			errs = append(errs, errors.New("s3_bucket, s3_region, s3_access_key_id, s3_secret_access_key: required when blob_store is s3"))
+		}
+		if _, err := newS3Blobs(*c); err != nil {
+			errs = append(errs, fmt.Errorf("s3_endpoint: %v", err))
+		}
+	default:
+		errs = append(errs, errors.New("blob_store: must be disk or s3"))
+	}
+	if c.AuthMaxFailures <= 0 {
+		errs = append(errs, errors.New("auth_max_failures: must be positive"))
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level: %q is not one of debug, info, warn, error", c.LogLevel))
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log_format: %q is not one of json, text", c.LogFormat))
+	}
+	switch c.AccessLogFormat {
+	case "combined", "common", "json":
+	default:
+		errs = append(errs, fmt.Errorf("access_log_format: %q is not one of combined, common, json", c.AccessLogFormat))
+	}
+	if c.Seed != "" {
+		if _, err := loadSeedFile(c.Seed); err != nil {
+			errs = append(errs, fmt.Errorf("seed: %v", err))
+		}
+	}
+	if c.ChaosPercent < 0 || c.ChaosPercent > 100 {
+		errs = append(errs, errors.New("chaos_percent: must be from 0 to 100"))
+	} else if c.ChaosPercent > 0 && !c.isDevelopment() {
+		errs = append(errs, errors.New("chaos_percent: only allowed in development"))
+	}
+	for _, f := range c.ChaosFaults {
+		if f != chaosLatency && f != chaosError && f != chaosDrop {
+			errs = append(errs, fmt.Errorf("chaos_faults: %q is not one of latency, error, drop", f))
+		}
+	}
+	if c.ChaosPercent > 0 && len(c.ChaosFaults) == 0 {
+		errs = append(errs, errors.New("chaos_faults: required when chaos_percent is set"))
+	}
+	if c.ChaosLatency <= 0 {
+		errs = append(errs, errors.New("chaos_latency: must be positive"))
+	}
+	if c.isProduction() && c.Anonymize {
+		errs = append(errs, errors.New("anonymize: refused in production"))
+	}
+	if c.PublicURL != "" {
+		if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			errs = append(errs, fmt.Errorf("public_url: %q is not an http(s) URL without a query", c.PublicURL))
+		}
+	}
+	if c.isProduction() && c.ShareLinkSecret == "" {
+		errs = append(errs, errors.New("share_link_secret: required in production"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) isProduction() bool {
+	return c.Env == "production"
+}
+
+func (c *Config) isDevelopment() bool {
+	return c.Env == "development"
+}
+
+// isHostname reports whether s could be a DNS name: dot-separated labels
+// of letters, digits and hyphens.
+func isHostname(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return len(s) <= 253
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+type Course struct {
+	ID       int    `json:"id"`
+	Code     string `json:"code" validate:"required"`
+	Title    string `json:"title" validate:"required"`
+	Credits  int    `json:"credits" validate:"positive"`
+	Capacity int    `json:"capacity" validate:"positive"`
+	// InstructorID is the teacher of the course, if one is assigned.
+	InstructorID int `json:"instructor_id,omitempty" validate:"omitempty,positive"`
+	// DepartmentID is the department offering the course, if any.
+	DepartmentID int `json:"department_id,omitempty" validate:"omitempty,positive"`
+	// Prerequisites must be completed before a student can enroll.
+	Prerequisites []Prerequisite `json:"prerequisites,omitempty"`
+}
+
+func (c Course) entityID() int { return c.ID }
+
+func (c Course) withID(id int) Course {
+	c.ID = id
+	return c
+}
+
+// courseStore is the active course repository, on the same backend as
+// the student store.
+var courseStore Repository[Course] = newCollection[Course]("courses")
+
+// courseCodeMutex serializes writes that check course code uniqueness or
+// the course's instructor.
+var courseCodeMutex = &sync.Mutex{}
+
+// validateCourse lists every problem with a course submitted for create or update.
+func validateCourse(c Course) []FieldError {
+	return append(checkFields(c), validatePrerequisites(c)...)
+}
+
+// instructorProblem reports a course naming an instructor who does not exist.
+func instructorProblem(ctx context.Context, c Course) []FieldError {
+	if c.InstructorID == 0 {
+		return nil
+	}
+	if _, ok := teacherStore.Find(ctx, c.InstructorID); !ok {
+		return []FieldError{{Field: "instructor_id", Message: "does not exist"}}
+	}
+	return nil
+}
+
+func maxValue(counts map[string]int) int {
+	n := 0
+	for _, c := range counts {
+		n = max(n, c)
+	}
+	return n
+}
+
+// courseCodeTaken reports whether another course already uses code.
+func courseCodeTaken(ctx context.Context, code string, exceptID int) bool {
+	for _, c := range courseStore.List(ctx) {
+		if c.ID != exceptID && strings.EqualFold(c.Code, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func createCourse(w http.ResponseWriter, r *http.Request) {
+	var course Course
+	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateCourse(course); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course data", errs...)
+		return
+	}
+
+	departmentMutex.Lock()
+	defer departmentMutex.Unlock()
+	if errs := departmentProblem(r.Context(), course); len(errs) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Department not found", errs...)
+		return
+	}
+
+	courseCodeMutex.Lock()
+	if errs := instructorProblem(r.Context(), course); len(errs) > 0 {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Instructor not found", errs...)
+		return
+	}
+	if errs := prerequisiteProblems(r.Context(), 0, course); len(errs) > 0 {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Invalid prerequisites", errs...)
+		return
+	}
+	if courseCodeTaken(r.Context(), course.Code, 0) {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusConflict, "A course with code "+course.Code+" already exists")
+		return
+	}
+	course = repoInsert(r.Context(), courseStore, course)
+	courseCodeMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(course)
+}
+
+func getCourses(w http.ResponseWriter, r *http.Request) {
+	page, errs := parsePage(r)
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid pagination parameters", errs...)
+		return
+	}
+
+	etag := listETag(courseStore.Version(r.Context()), r)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	list, link := paginate(r, repoList(r.Context(), courseStore), page)
+	if link != "" {
+		w.Header().Set("Link", link)
+	}
+	if err := writeJSONArray(w, list); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream course list", "error", err)
+	}
+}
+
+func getCourse(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	course, exists := repoFind(r.Context(), courseStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, course)
+}
+
+func updateCourse(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	var updated Course
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateCourse(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course data", errs...)
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+	perTerm := map[string]int{}
+	for _, e := range courseEnrollments(r.Context(), id) {
+		perTerm[e.Term]++
+	}
+	if n := maxValue(perTerm); updated.Capacity < n {
+		writeProblem(w, r, http.StatusConflict, "Capacity is below the "+strconv.Itoa(n)+" students already enrolled")
+		return
+	}
+
+	departmentMutex.Lock()
+	defer departmentMutex.Unlock()
+	if errs := departmentProblem(r.Context(), updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Department not found", errs...)
+		return
+	}
+
+	courseCodeMutex.Lock()
+	if errs := instructorProblem(r.Context(), updated); len(errs) > 0 {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Instructor not found", errs...)
+		return
+	}
+	if errs := prerequisiteProblems(r.Context(), id, updated); len(errs) > 0 {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Invalid prerequisites", errs...)
+		return
+	}
+	if courseCodeTaken(r.Context(), updated.Code, id) {
+		courseCodeMutex.Unlock()
+		writeProblem(w, r, http.StatusConflict, "A course with code "+updated.Code+" already exists")
+		return
+	}
+	_, exists := repoReplace(r.Context(), courseStore, id, updated)
+	courseCodeMutex.Unlock()
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+	// A larger capacity may free seats for waiting students.
+	promoteWaitlist(r.Context(), id)
+
+	updated.ID = id
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteCourse(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	if n := len(courseEnrollments(r.Context(), id)); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Course still has "+strconv.Itoa(n)+" enrolled students")
+		return
+	}
+	courseCodeMutex.Lock()
+	defer courseCodeMutex.Unlock()
+	if codes := requiredBy(r.Context(), id); len(codes) > 0 {
+		writeProblem(w, r, http.StatusConflict, "Course is a prerequisite of "+strings.Join(codes, ", "))
+		return
+	}
+	for _, s := range courseSections(r.Context(), id) {
+		repoRemove(r.Context(), sectionStore, s.ID)
+	}
+	for _, a := range courseAssignments(r.Context(), id) {
+		repoRemove(r.Context(), assignmentStore, a.ID)
+	}
+	for _, e := range waitlistWhere(r.Context(), func(e WaitlistEntry) bool { return e.CourseID == id }) {
+		repoRemove(r.Context(), waitlistStore, e.ID)
+	}
+	if _, exists := repoRemove(r.Context(), courseStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
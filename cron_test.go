@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	for field, want := range map[string]string{
+		"*":        "0-59",
+		"5":        "5",
+		"1,3,5":    "1,3,5",
+		"10-14":    "10,11,12,13,14",
+		"*/15":     "0,15,30,45",
+		"5/20":     "5,25,45",
+		"10-30/7":  "10,17,24",
+		"0-5/2,58": "0,2,4,58",
+		"59":       "59",
+	} {
+		bits, err := parseCronField(field, 0, 59)
+		if err != nil {
+			t.Errorf("%s: %v", field, err)
+			continue
+		}
+		if got := cronBits(bits, 0, 59); got != want {
+			t.Errorf("%s allows %s, want %s", field, got, want)
+		}
+	}
+}
+
+// cronBits lists the values a field's bitmask allows, folding a full run
+// of min to max into "min-max".
+func cronBits(bits uint64, min, max int) string {
+	var values []string
+	for v := min; v <= max; v++ {
+		if bits&(1<<v) != 0 {
+			values = append(values, strconv.Itoa(v))
+		}
+	}
+	if len(values) == max-min+1 {
+		return strconv.Itoa(min) + "-" + strconv.Itoa(max)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for spec, want := range map[string]string{
+		"* * * *":          `"* * * *": want five fields (minute hour day-of-month month day-of-week)`,
+		"* * * * * *":      `"* * * * * *": want five fields (minute hour day-of-month month day-of-week)`,
+		"@yearly":          `"@yearly": want five fields (minute hour day-of-month month day-of-week)`,
+		"60 * * * *":       `"60 * * * *": "60" is outside 0-59`,
+		"* 24 * * *":       `"* 24 * * *": "24" is outside 0-23`,
+		"* * 0 * *":        `"* * 0 * *": "0" is outside 1-31`,
+		"* * 32 * *":       `"* * 32 * *": "32" is outside 1-31`,
+		"* * * 13 *":       `"* * * 13 *": "13" is outside 1-12`,
+		"* * * * 8":        `"* * * * 8": "8" is outside 0-7`,
+		"5-1 * * * *":      `"5-1 * * * *": "5-1" is outside 0-59`,
+		"0-60 * * * *":     `"0-60 * * * *": "0-60" is outside 0-59`,
+		"0 22-2 * * *":     `"0 22-2 * * *": "22-2" is outside 0-23`,
+		"mon * * * *":      `"mon * * * *": "mon" is outside 0-59`,
+		"1,,2 * * * *":     `"1,,2 * * * *": "" is outside 0-59`,
+		"*/0 * * * *":      `"*/0 * * * *": bad step in "*/0"`,
+		"*/x * * * *":      `"*/x * * * *": bad step in "*/x"`,
+		"1-5/-1 * * * *":   `"1-5/-1 * * * *": bad step in "1-5/-1"`,
+		"@every 500ms":     `"@every 500ms": interval must be a duration of at least 1s`,
+		"@every fortnight": `"@every fortnight": interval must be a duration of at least 1s`,
+	} {
+		_, err := parseSchedule(spec)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: error %v, want %s", spec, err, want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2026-10-14 is a Wednesday. Each case is "spec | after" and the
+	// run that follows, or "never".
+	for tc, want := range map[string]string{
+		"* * * * * | 2026-10-14T10:07:30Z":      "2026-10-14T10:08:00Z",
+		"*/15 * * * * | 2026-10-14T10:07:00Z":   "2026-10-14T10:15:00Z",
+		"*/15 * * * * | 2026-10-14T10:15:00Z":   "2026-10-14T10:30:00Z",
+		"*/15 * * * * | 2026-10-14T10:14:59Z":   "2026-10-14T10:15:00Z",
+		"*/15 * * * * | 2026-10-14T10:45:00Z":   "2026-10-14T11:00:00Z",
+		"5-20/5 8 * * * | 2026-10-14T08:20:00Z": "2026-10-15T08:05:00Z",
+		"0 */6 * * * | 2026-10-14T13:00:00Z":    "2026-10-14T18:00:00Z",
+		"0 0 * * * | 2026-12-31T23:59:00Z":      "2027-01-01T00:00:00Z",
+		"30 2 1 1,7 * | 2026-10-14T00:00:00Z":   "2027-01-01T02:30:00Z",
+		"0 9 * * 1-5 | 2026-10-16T09:00:00Z":    "2026-10-19T09:00:00Z",
+		"0 0 * * 0 | 2026-10-14T00:00:00Z":      "2026-10-18T00:00:00Z",
+		"0 0 * * 7 | 2026-10-14T00:00:00Z":      "2026-10-18T00:00:00Z",
+		"0 0 13 * * | 2026-10-14T00:00:00Z":     "2026-11-13T00:00:00Z",
+		"0 0 31 * * | 2026-10-31T00:00:00Z":     "2026-12-31T00:00:00Z",
+		"0 0 29 2 * | 2026-10-14T00:00:00Z":     "2028-02-29T00:00:00Z",
+		"0 0 30 2 * | 2026-10-14T00:00:00Z":     "never",
+		"@hourly | 2026-10-14T10:59:30Z":        "2026-10-14T11:00:00Z",
+		"@daily | 2026-10-14T10:00:00Z":         "2026-10-15T00:00:00Z",
+		"@weekly | 2026-10-14T10:00:00Z":        "2026-10-18T00:00:00Z",
+		"@monthly | 2026-10-14T10:00:00Z":       "2026-11-01T00:00:00Z",
+		"@every 90s | 2026-10-14T10:00:10Z":     "2026-10-14T10:01:40Z",
+		// With both day fields restricted, either one matching will do:
+		// the 13th, or any Friday.
+		"0 0 13 * 5 | 2026-10-14T00:00:00Z":   "2026-10-16T00:00:00Z",
+		"0 0 13 * 5 | 2026-10-30T00:00:00Z":   "2026-11-06T00:00:00Z",
+		"0 0 13 * 5 | 2026-11-06T00:00:00Z":   "2026-11-13T00:00:00Z",
+		"0 0 1,15 * 1 | 2026-10-14T00:00:00Z": "2026-10-15T00:00:00Z",
+		"0 0 1,15 * 1 | 2026-10-15T00:00:00Z": "2026-10-19T00:00:00Z",
+		// With one restricted, the other does not widen it.
+		"0 0 1 * * | 2026-10-14T00:00:00Z":  "2026-11-01T00:00:00Z",
+		"0 0 * 11 3 | 2026-10-14T00:00:00Z": "2026-11-04T00:00:00Z",
+	} {
+		spec, after, _ := strings.Cut(tc, " | ")
+		s, err := parseSchedule(spec)
+		if err != nil {
+			t.Errorf("%s: %v", spec, err)
+			continue
+		}
+		from, err := time.Parse(time.RFC3339, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := "never"
+		if next := s.next(from); !next.IsZero() {
+			got = next.Format(time.RFC3339)
+		}
+		if got != want {
+			t.Errorf("%s after %s: %s, want %s", spec, after, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+//go:embed ui/dashboard.html
+var uiFiles embed.FS
+
+var dashboardTemplate = template.Must(template.ParseFS(uiFiles, "ui/dashboard.html"))
+
+// recentActivityLimit is how many audit entries the dashboard shows.
+const recentActivityLimit = 15
+
+type dashboardData struct {
+	Version        string
+	Env            string
+	Uptime         time.Duration
+	Maintenance    bool
+	StudentCount   int
+	AuditCount     int
+	LLMCalls       int
+	LLMErrors      int
+	LLMAvgLatency  time.Duration
+	Health         map[string]checkResult
+	RecentActivity []AuditEntry
+}
+
+func metricValue(m prometheus.Metric) *dto.Metric {
+	var out dto.Metric
+	m.Write(&out)
+	return &out
+}
+
+// adminDashboard renders a small HTML overview for schools without a
+// monitoring stack.
+func adminDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	data := dashboardData{
+		Version:      version,
+		Env:          cfg.Env,
+		Uptime:       time.Since(startTime).Round(time.Second),
+		StudentCount: len(allStudents(ctx)),
+		Health: map[string]checkResult{
+			"store":  toCheckResult(checkStore(ctx), false),
+			"ollama": toCheckResult(checkOllama(ctx), true),
+		},
+	}
+	data.Maintenance, _ = inMaintenance(r.Context())
+
+	successes := int(metricValue(ollamaRequests.WithLabelValues("success")).GetCounter().GetValue())
+	data.LLMErrors = int(metricValue(ollamaRequests.WithLabelValues("error")).GetCounter().GetValue())
+	data.LLMCalls = successes + data.LLMErrors
+	if h := metricValue(ollamaDuration).GetHistogram(); h.GetSampleCount() > 0 {
+		avg := h.GetSampleSum() / float64(h.GetSampleCount())
+		data.LLMAvgLatency = time.Duration(avg * float64(time.Second)).Round(time.Millisecond)
+	}
+
+	trail := &stateOf(r.Context()).audit
+	trail.mu.Lock()
+	data.AuditCount = len(trail.entries)
+	for i := len(trail.entries) - 1; i >= 0 && len(data.RecentActivity) < recentActivityLimit; i-- {
+		data.RecentActivity = append(data.RecentActivity, trail.entries[i])
+	}
+	trail.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		slog.ErrorContext(r.Context(), "failed to render dashboard", "error", err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+)
+
+// mountDebug exposes pprof and expvar under /debug for admins. These routes
+// are not wrapped in a request timeout because CPU profiles and execution
+// traces run for as long as the caller asks.
+func mountDebug(r *mux.Router) {
+	r.HandleFunc("/debug/pprof/cmdline", requireAdmin(pprof.Cmdline)).Methods("GET")
+	r.HandleFunc("/debug/pprof/profile", requireAdmin(pprof.Profile)).Methods("GET")
+	r.HandleFunc("/debug/pprof/symbol", requireAdmin(pprof.Symbol)).Methods("GET", "POST")
+	r.HandleFunc("/debug/pprof/trace", requireAdmin(pprof.Trace)).Methods("GET")
+	r.PathPrefix("/debug/pprof/").HandlerFunc(requireAdmin(pprof.Index)).Methods("GET")
+	r.HandleFunc("/debug/vars", requireAdmin(expvar.Handler().ServeHTTP)).Methods("GET")
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Department offers courses and owns academic programs.
+type Department struct {
+	ID   int    `json:"id"`
+	Code string `json:"code" validate:"required"`
+	Name string `json:"name" validate:"required"`
+}
+
+func (d Department) entityID() int { return d.ID }
+
+func (d Department) withID(id int) Department {
+	d.ID = id
+	return d
+}
+
+// Program is a course of study within a department that students follow.
+type Program struct {
+	ID           int    `json:"id"`
+	DepartmentID int    `json:"department_id"`
+	Code         string `json:"code" validate:"required"`
+	Name         string `json:"name" validate:"required"`
+}
+
+func (p Program) entityID() int { return p.ID }
+
+func (p Program) withID(id int) Program {
+	p.ID = id
+	return p
+}
+
+var (
+	departmentStore Repository[Department] = newCollection[Department]("departments")
+	programStore    Repository[Program]    = newCollection[Program]("programs")
+)
+
+// departmentMutex serializes writes that check department and program
+// references, so nothing can be assigned to one while it is deleted. It
+// also keeps a student's If-Unmodified-Since check and write together.
+var departmentMutex = &sync.Mutex{}
+
+// validateDepartment lists every problem with a submitted department.
+func validateDepartment(d Department) []FieldError {
+	return checkFields(d)
+}
+
+// validateProgram lists every problem with a submitted program.
+func validateProgram(p Program) []FieldError {
+	return checkFields(p)
+}
+
+// departmentProblem reports a course naming a department that does not exist.
+func departmentProblem(ctx context.Context, c Course) []FieldError {
+	if c.DepartmentID == 0 {
+		return nil
+	}
+	if _, ok := departmentStore.Find(ctx, c.DepartmentID); !ok {
+		return []FieldError{{Field: "department_id", Message: "does not exist"}}
+	}
+	return nil
+}
+
+// programProblem reports a student naming a program that does not exist.
+func programProblem(ctx context.Context, s Student) []FieldError {
+	if s.ProgramID == 0 {
+		return nil
+	}
+	if _, ok := programStore.Find(ctx, s.ProgramID); !ok {
+		return []FieldError{{Field: "program_id", Message: "does not exist"}}
+	}
+	return nil
+}
+
+func departmentPrograms(ctx context.Context, departmentID int) []Program {
+	list := []Program{}
+	for _, p := range repoList(ctx, programStore) {
+		if p.DepartmentID == departmentID {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+func programStudents(ctx context.Context, programID int) []Student {
+	list := []Student{}
+	for _, s := range allStudents(ctx) {
+		if s.ProgramID == programID {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func createDepartment(w http.ResponseWriter, r *http.Request) {
+	var department Department
+	if err := json.NewDecoder(r.Body).Decode(&department); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateDepartment(department); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department data", errs...)
+		return
+	}
+
+	department = repoInsert(r.Context(), departmentStore, department)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(department)
+}
+
+func getDepartments(w http.ResponseWriter, r *http.Request) {
+	if err := writeJSONArray(w, repoList(r.Context(), departmentStore)); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream department list", "error", err)
+	}
+}
+
+func getDepartment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	department, exists := repoFind(r.Context(), departmentStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Department not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, department)
+}
+
+func updateDepartment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	var updated Department
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateDepartment(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department data", errs...)
+		return
+	}
+
+	if _, exists := repoReplace(r.Context(), departmentStore, id, updated); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Department not found")
+		return
+	}
+
+	updated.ID = id
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteDepartment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	departmentMutex.Lock()
+	defer departmentMutex.Unlock()
+
+	if n := len(departmentPrograms(r.Context(), id)); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Department still has "+strconv.Itoa(n)+" programs")
+		return
+	}
+	for _, c := range courseStore.List(r.Context()) {
+		if c.DepartmentID == id {
+			writeProblem(w, r, http.StatusConflict, "Department still offers course "+c.Code)
+			return
+		}
+	}
+	if _, exists := repoRemove(r.Context(), departmentStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Department not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createProgram(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	departmentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	var program Program
+	if err := json.NewDecoder(r.Body).Decode(&program); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateProgram(program); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program data", errs...)
+		return
+	}
+
+	departmentMutex.Lock()
+	defer departmentMutex.Unlock()
+
+	if _, exists := repoFind(r.Context(), departmentStore, departmentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Department not found")
+		return
+	}
+	program.DepartmentID = departmentID
+	program = repoInsert(r.Context(), programStore, program)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(program)
+}
+
+func getDepartmentPrograms(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	departmentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), departmentStore, departmentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Department not found")
+		return
+	}
+
+	writeJSONArray(w, departmentPrograms(r.Context(), departmentID))
+}
+
+func getProgram(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program ID")
+		return
+	}
+
+	program, exists := repoFind(r.Context(), programStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Program not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, program)
+}
+
+func updateProgram(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program ID")
+		return
+	}
+
+	var updated Program
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateProgram(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program data", errs...)
+		return
+	}
+
+	before, exists := repoFind(r.Context(), programStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Program not found")
+		return
+	}
+	updated.ID, updated.DepartmentID = id, before.DepartmentID
+	repoReplace(r.Context(), programStore, id, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteProgram(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program ID")
+		return
+	}
+
+	departmentMutex.Lock()
+	defer departmentMutex.Unlock()
+
+	if n := len(programStudents(r.Context(), id)); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Program still has "+strconv.Itoa(n)+" students")
+		return
+	}
+	if _, exists := repoRemove(r.Context(), programStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Program not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getProgramStudents lists the students following a program.
+func getProgramStudents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid program ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), programStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Program not found")
+		return
+	}
+
+	writeJSONArray(w, programStudents(r.Context(), id))
+}
+
+// ProgramReport counts the students in one program and the awards they
+// hold. ?term= limits the awards to one term.
+type ProgramReport struct {
+	ProgramID    int    `json:"program_id"`
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	DepartmentID int    `json:"department_id"`
+	Students     int    `json:"students"`
+	Awards       int    `json:"awards"`
+	AwardedCents int64  `json:"awarded_cents"`
+}
+
+// DepartmentReport rolls up a department's programs. AverageGPA is the
+// mean GPA of its students who have any graded credits.
+type DepartmentReport struct {
+	DepartmentID int      `json:"department_id"`
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Programs     int      `json:"programs"`
+	Courses      int      `json:"courses"`
+	Students     int      `json:"students"`
+	AverageGPA   *float64 `json:"average_gpa"`
+}
+
+func (p ProgramReport) csvRecord() []string {
+	return []string{strconv.Itoa(p.ProgramID), p.Code, p.Name, strconv.Itoa(p.DepartmentID), strconv.Itoa(p.Students), strconv.Itoa(p.Awards), strconv.FormatInt(p.AwardedCents, 10)}
+}
+
+func (d DepartmentReport) csvRecord() []string {
+	gpa := ""
+	if d.AverageGPA != nil {
+		gpa = formatFloat(*d.AverageGPA)
+	}
+	return []string{strconv.Itoa(d.DepartmentID), d.Code, d.Name, strconv.Itoa(d.Programs), strconv.Itoa(d.Courses), strconv.Itoa(d.Students), gpa}
+}
+
+// getProgramReport serves GET /reports/programs.
+func getProgramReport(w http.ResponseWriter, r *http.Request) {
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	counts := map[int]int{}
+	programOf := map[int]int{}
+	for _, s := range allStudents(r.Context()) {
+		counts[s.ProgramID]++
+		programOf[s.ID] = s.ProgramID
+	}
+	awards := map[int]int{}
+	awarded := map[int]int64{}
+	for _, a := range awardStore.List(r.Context()) {
+		if p, ok := programOf[a.StudentID]; ok && (term == "" || a.Term == term) {
+			awards[p]++
+			awarded[p] += a.AmountCents
+		}
+	}
+
+	report := []ProgramReport{}
+	for _, p := range repoList(r.Context(), programStore) {
+		report = append(report, ProgramReport{
+			ProgramID:    p.ID,
+			Code:         p.Code,
+			Name:         p.Name,
+			DepartmentID: p.DepartmentID,
+			Students:     counts[p.ID],
+			Awards:       awards[p.ID],
+			AwardedCents: awarded[p.ID],
+		})
+	}
+	writeReport(w, r, "programs", []string{"program_id", "code", "name", "department_id", "students", "awards", "awarded_cents"}, report)
+}
+
+// getDepartmentReport serves GET /reports/departments.
+func getDepartmentReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	departmentOf := map[int]int{}
+	programs := map[int]int{}
+	for _, p := range programStore.List(ctx) {
+		departmentOf[p.ID] = p.DepartmentID
+		programs[p.DepartmentID]++
+	}
+	courses := map[int]int{}
+	for _, c := range courseStore.List(ctx) {
+		courses[c.DepartmentID]++
+	}
+
+	students := map[int]int{}
+	gpaSum := map[int]float64{}
+	graded := map[int]int{}
+	for _, s := range allStudents(ctx) {
+		d, ok := departmentOf[s.ProgramID]
+		if !ok {
+			continue
+		}
+		students[d]++
+		if gpa := computeGPA(ctx, s.ID).GPA; gpa != nil {
+			gpaSum[d] += *gpa
+			graded[d]++
+		}
+	}
+
+	report := []DepartmentReport{}
+	for _, d := range repoList(ctx, departmentStore) {
+		row := DepartmentReport{
+			DepartmentID: d.ID,
+			Code:         d.Code,
+			Name:         d.Name,
+			Programs:     programs[d.ID],
+			Courses:      courses[d.ID],
+			Students:     students[d.ID],
+		}
+		if graded[d.ID] > 0 {
+			avg := math.Round(gpaSum[d.ID]/float64(graded[d.ID])*100) / 100
+			row.AverageGPA = &avg
+		}
+		report = append(report, row)
+	}
+	writeReport(w, r, "departments", []string{"department_id", "code", "name", "programs", "courses", "students", "average_gpa"}, report)
+}
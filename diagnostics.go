@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"studengo/ollama"
+)
+
+// minFreeDisk is the free space below which a file store check fails.
+const minFreeDisk = 100 << 20
+
+// Diagnostic is the outcome of one self-check.
+type Diagnostic struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+const (
+	diagPass = "pass"
+	diagWarn = "warn"
+	diagFail = "fail"
+)
+
+func runDiagnostic(name string, check func() (string, string)) Diagnostic {
+	start := time.Now()
+	status, detail := check()
+	return Diagnostic{Name: name, Status: status, Detail: detail, DurationMs: time.Since(start).Milliseconds()}
+}
+
+func diagnoseStore(ctx context.Context) (string, string) {
+	list := allStudents(ctx)
+	if len(list) == 0 {
+		return diagPass, "store reachable, no students stored"
+	}
+	if _, ok := findStudent(ctx, list[0].ID); !ok {
+		return diagFail, fmt.Sprintf("student %d listed but not found", list[0].ID)
+	}
+	return diagPass, fmt.Sprintf("listed and read back %d students", len(list))
+}
+
+func diagnoseOllama(ctx context.Context) (string, string) {
+	llm := stateOf(ctx).llm
+	models, err := llm.Models(ctx)
+	if errors.Is(err, ollama.ErrBadModelList) {
+		return diagWarn, "reachable, but the model list could not be parsed"
+	}
+	if err != nil {
+		return diagFail, err.Error()
+	}
+	for _, name := range models {
+		if name == llm.Model() || name == llm.Model()+":latest" {
+			return diagPass, "model " + llm.Model() + " available"
+		}
+	}
+	return diagFail, "model " + llm.Model() + " is not pulled on the Ollama server"
+}
+
+// diagnoseDisk checks the free space on the file system of path, a file
+// or a directory.
+func diagnoseDisk(path string) (string, string) {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	free, err := diskFree(dir)
+	if err != nil {
+		return diagWarn, err.Error()
+	}
+	detail := fmt.Sprintf("%d MiB free for %s", free>>20, path)
+	if free < minFreeDisk {
+		return diagFail, detail
+	}
+	return diagPass, detail
+}
+
+// diagnoseConfig flags settings that are valid but likely mistakes.
+func diagnoseConfig() (string, string) {
+	var warnings []string
+	if len(apiKeys) == 0 {
+		warnings = append(warnings, "no API keys configured, admin endpoints are unreachable")
+	}
+	if cfg.ShareLinkSecret == "" {
+		warnings = append(warnings, "share links use a random secret and break on restart")
+	}
+	if cfg.PublicURL == "" {
+		warnings = append(warnings, "no public_url configured, share links cannot be created")
+	}
+	if cfg.isProduction() && cfg.SentryDSN == "" {
+		warnings = append(warnings, "no error reporting configured in production")
+	}
+	if cfg.LLMRequestTimeout < cfg.OllamaTimeout {
+		warnings = append(warnings, "llm_request_timeout is shorter than ollama_timeout")
+	}
+	if len(warnings) > 0 {
+		return diagWarn, strings.Join(warnings, "; ")
+	}
+	return diagPass, "no issues found"
+}
+
+// getDiagnostics runs live self-checks and returns a pass/fail report
+// suitable for attaching to support tickets.
+func getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	checks := []Diagnostic{
+		runDiagnostic("store", func() (string, string) { return diagnoseStore(ctx) }),
+		runDiagnostic("ollama", func() (string, string) { return diagnoseOllama(ctx) }),
+		runDiagnostic("config", diagnoseConfig),
+	}
+	files := []struct{ name, path string }{
+		{"disk:audit_log", cfg.AuditLogFile},
+		{"disk:access_log", cfg.AccessLog},
+	}
+	if cfg.Store == "file" {
+		files = append(files, struct{ name, path string }{"disk:store", cfg.StoreFile})
+	}
+	if cfg.BlobStore == blobsDisk {
+		files = append(files, struct{ name, path string }{"disk:documents", cfg.DocumentsDir})
+	}
+	for _, file := range files {
+		if file.path == "" || file.path == "stdout" || file.path == "stderr" || file.path == "off" {
+			continue
+		}
+		checks = append(checks, runDiagnostic(file.name, func() (string, string) { return diagnoseDisk(file.path) }))
+	}
+
+	status := diagPass
+	for _, c := range checks {
+		if c.Status == diagFail {
+			status = diagFail
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     status,
+		"version":    buildInfo(),
+		"checked_at": clock.Now().UTC(),
+		"checks":     checks,
+	})
+}
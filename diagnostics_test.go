@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsCheckDataDisks(t *testing.T) {
+	dir := t.TempDir()
+	api := newTestAPI(t, func(c *Config) {
+		c.Store = "file"
+		c.StoreFile = filepath.Join(dir, "students.jsonl")
+		c.DocumentsDir = dir
+	})
+	var report struct{ Checks []Diagnostic }
+	api.decode(t, "GET", "/v1/admin/diagnostics", nil, http.StatusOK, &report)
+	found := map[string]Diagnostic{}
+	for _, c := range report.Checks {
+		found[c.Name] = c
+	}
+	for name, path := range map[string]string{"disk:store": filepath.Join(dir, "students.jsonl"), "disk:documents": dir} {
+		if c := found[name]; c.Status == diagWarn || !strings.HasSuffix(c.Detail, " free for "+path) {
+			t.Errorf("%s check = %+v, want the free space for %s", name, c, path)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Document is an uploaded file attached to a student. The contents live
+// in the blob store; this is its metadata.
+type Document struct {
+	ID          int       `json:"id"`
+	StudentID   int       `json:"student_id"`
+	Type        string    `json:"type"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+func (d Document) entityID() int { return d.ID }
+
+func (d Document) withID(id int) Document {
+	d.ID = id
+	return d
+}
+
+var documentStore Repository[Document] = newCollection[Document]("documents")
+
+var documentTypes = []string{"transcript", "identification", "medical", "correspondence", "other"}
+
+func documentKey(id int) string {
+	return "documents/" + strconv.Itoa(id)
+}
+
+// uploadDocument accepts a multipart form with a "file" part and a "type"
+// field naming what the document is.
+func uploadDocument(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.DocumentMaxBytes)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid document upload: "+err.Error())
+		return
+	}
+
+	// The type field must come before the file so the file can be
+	// streamed straight to storage.
+	var docType string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid document upload", FieldError{Field: "file", Message: "is required"})
+			return
+		}
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid document upload: "+err.Error())
+			return
+		}
+
+		switch part.FormName() {
+		case "type":
+			b, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid document upload: "+err.Error())
+				return
+			}
+			docType = strings.TrimSpace(string(b))
+		case "file":
+			if !slices.Contains(documentTypes, docType) {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid document upload", FieldError{Field: "type", Message: "must precede the file and be one of " + strings.Join(documentTypes, ", ")})
+				return
+			}
+			storeDocument(w, r, studentID, docType, part.FileName(), part.Header.Get("Content-Type"), part)
+			return
+		}
+	}
+}
+
+func storeDocument(w http.ResponseWriter, r *http.Request, studentID int, docType, filename, contentType string, body io.Reader) {
+	filename = filepath.Base(filepath.Clean("/" + filename))
+	if filename == "/" || filename == "." {
+		filename = "document"
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	doc := repoInsert(r.Context(), documentStore, Document{
+		StudentID:   studentID,
+		Type:        docType,
+		Filename:    filename,
+		ContentType: contentType,
+		UploadedAt:  clock.Now().UTC(),
+	})
+
+	hash := sha256.New()
+	var size int64
+	var err error
+	if inDryRun(r.Context()) {
+		// Read the upload through, so size limits apply, but keep nothing.
+		size, err = io.Copy(hash, body)
+	} else {
+		size, err = blobs.Put(r.Context(), documentKey(doc.ID), io.TeeReader(body, hash))
+	}
+	if err != nil {
+		repoRemove(r.Context(), documentStore, doc.ID)
+		if !inDryRun(r.Context()) {
+			blobs.Delete(r.Context(), documentKey(doc.ID))
+		}
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, "Document exceeds "+strconv.FormatInt(tooBig.Limit, 10)+" bytes")
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to store document", "student_id", studentID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to store document")
+		return
+	}
+	doc.Size, doc.SHA256 = size, hex.EncodeToString(hash.Sum(nil))
+	repoReplace(r.Context(), documentStore, doc.ID, doc)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(doc)
+}
+
+func getStudentDocuments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+	docType := r.URL.Query().Get("type")
+
+	list := []Document{}
+	for _, d := range repoList(r.Context(), documentStore) {
+		if d.StudentID == studentID && (docType == "" || d.Type == docType) {
+			list = append(list, d)
+		}
+	}
+	writeJSONArray(w, list)
+}
+
+func getDocument(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	doc, exists := repoFind(r.Context(), documentStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Document not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, doc)
+}
+
+// downloadDocument streams a document's contents as an attachment.
+func downloadDocument(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	doc, exists := repoFind(r.Context(), documentStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Document not found")
+		return
+	}
+	serveBlob(w, r, documentKey(id), "Document", doc.ContentType, doc.Filename, doc.Size, doc.SHA256)
+}
+
+func deleteDocument(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	if _, exists := repoRemove(r.Context(), documentStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Document not found")
+		return
+	}
+	if inDryRun(r.Context()) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := blobs.Delete(r.Context(), documentKey(id)); err != nil {
+		slog.WarnContext(r.Context(), "failed to delete document contents", "document_id", id, "error", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeStudentDocuments deletes a deleted student's documents and their contents.
+func removeStudentDocuments(ctx context.Context, studentID int) {
+	for _, d := range documentStore.List(ctx) {
+		if d.StudentID != studentID {
+			continue
+		}
+		repoRemove(ctx, documentStore, d.ID)
+		if inDryRun(ctx) {
+			continue
+		}
+		if err := blobs.Delete(ctx, documentKey(d.ID)); err != nil {
+			slog.WarnContext(ctx, "failed to delete document contents", "document_id", d.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const dryRunHeader = "X-Dry-Run"
+
+// inDryRun reports whether ctx belongs to a request asking for a dry run.
+func inDryRun(ctx context.Context) bool {
+	on, _ := ctx.Value(dryRunKey).(bool)
+	return on
+}
+
+// dryRunSupported reports whether a dry run can be asked of the route at
+// path. Admin and debug operations change state the dry run cannot hold
+// back, such as the log level or running jobs, and refuse it.
+func dryRunSupported(path string) bool {
+	path = resourcePath(path)
+	return !strings.HasPrefix(path, "/admin/") && !strings.HasPrefix(path, "/debug/")
+}
+
+// dryRun lets POST, PUT, PATCH and DELETE requests carry ?dry_run=true or
+// X-Dry-Run: true. Such a request is validated and answered as usual,
+// status and body included, but the repository helpers write nothing, no
+// event is published, nothing is audited and no file contents are kept,
+// so an import can be previewed before it is run. Records that would be
+// created are returned with ID 0. Inside a batch, an operation does not
+// see what the operations before it would have written.
+func dryRun(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The operations of a batch run dry with the batch.
+		on := inDryRun(r.Context())
+		value := r.URL.Query().Get("dry_run")
+		if value == "" {
+			value = r.Header.Get(dryRunHeader)
+		}
+		if value != "" {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "dry_run", Message: "must be true or false"})
+				return
+			}
+			on = on || v
+		}
+		if !on {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !dryRunSupported(r.URL.Path) {
+			writeProblem(w, r, http.StatusBadRequest, "Dry run is not supported by this operation")
+			return
+		}
+		w.Header().Set(dryRunHeader, "true")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), dryRunKey, true)))
+	})
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailLogEntry records one notification email and whether it was sent.
+type EmailLogEntry struct {
+	ID        int       `json:"id"`
+	Template  string    `json:"template"`
+	StudentID int       `json:"student_id"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	QueuedAt  time.Time `json:"queued_at"`
+	SentAt    time.Time `json:"sent_at,omitzero"`
+}
+
+func (e EmailLogEntry) entityID() int { return e.ID }
+
+func (e EmailLogEntry) withID(id int) EmailLogEntry {
+	e.ID = id
+	return e
+}
+
+var emailLogStore Repository[EmailLogEntry] = newCollection[EmailLogEntry]("email_log")
+
+const (
+	emailQueued = "queued"
+	emailSent   = "sent"
+	emailFailed = "failed"
+)
+
+// emailTemplate is a notification email. The first line of text is the
+// subject; the rest, after a blank line, is the body.
+type emailTemplate struct {
+	name string
+	text *template.Template
+}
+
+func newEmailTemplate(name, text string) emailTemplate {
+	return emailTemplate{name: name, text: template.Must(template.New(name).Parse(text))}
+}
+
+var (
+	welcomeEmail = newEmailTemplate("welcome", `Welcome, {{.Student.Name}}
+
+Hello {{.Student.Name}},
+
+Your student record has been created. Your student ID is {{.Student.ID}}.
+`)
+
+	summaryReadyEmail = newEmailTemplate("summary_ready", `Your student summary is ready
+
+Hello {{.Student.Name}},
+
+A new summary of your record has been generated:
+
+{{.Summary}}
+`)
+
+	absenceEmail = newEmailTemplate("absence", `Absence recorded for {{.Student.Name}}
+
+Hello {{.Recipient}},
+
+{{.Student.Name}} was marked absent from {{.Course.Code}} {{.Course.Title}} on {{.Record.Date}}{{if .Record.Period}}, period {{.Record.Period}}{{end}}.
+
+Please contact the school if this is unexpected.
+`)
+)
+
+type outgoingEmail struct {
+	logID int
+	to    string
+	msg   []byte
+}
+
+// subscribeNotifications registers the notification emails that are
+// enabled. Nothing is sent while smtp_host is unset.
+func subscribeNotifications(events *eventBus) {
+	if cfg.SMTPHost == "" {
+		return
+	}
+	if cfg.NotifyWelcome {
+		events.subscribe("email-welcome", func(ctx context.Context, e Event) {
+			s := e.Data.(StudentCreated).Student
+			queueEmail(ctx, welcomeEmail, s.ID, s.Email, map[string]any{"Student": s})
+		}, eventStudentCreated)
+	}
+	if cfg.NotifySummaryReady {
+		events.subscribe("email-summary-ready", func(ctx context.Context, e Event) {
+			// Summaries precomputed by the warmer were not asked for.
+			if e.RequestID == "" {
+				return
+			}
+			g := e.Data.(SummaryGenerated)
+			s, ok := findStudent(ctx, g.StudentID)
+			if !ok {
+				return
+			}
+			queueEmail(ctx, summaryReadyEmail, s.ID, s.Email, map[string]any{"Student": s, "Summary": g.Summary})
+		}, eventSummaryGenerated)
+	}
+	if cfg.NotifyAbsence {
+		events.subscribe("email-absence", notifyAbsence, eventAttendanceRecorded)
+	}
+}
+
+// notifyAbsence emails the guardians of a student marked absent who can
+// be reached by email.
+func notifyAbsence(ctx context.Context, e Event) {
+	record := e.Data.(AttendanceRecorded).Record
+	if record.Status != "absent" {
+		return
+	}
+	s, ok := findStudent(ctx, record.StudentID)
+	if !ok {
+		return
+	}
+	course, _ := courseStore.Find(ctx, record.CourseID)
+	for _, g := range repoList(ctx, guardianStore) {
+		if g.StudentID != s.ID || g.Email == "" || (g.PreferredContact != "" && g.PreferredContact != "email") {
+			continue
+		}
+		queueEmail(ctx, absenceEmail, s.ID, g.Email, map[string]any{"Student": s, "Course": course, "Record": record, "Recipient": g.Name})
+	}
+}
+
+// queueEmail renders a template, logs the email and hands it to the sender.
+func queueEmail(ctx context.Context, t emailTemplate, studentID int, to string, data any) {
+	if to == "" {
+		return
+	}
+	var buf bytes.Buffer
+	if err := t.text.Execute(&buf, data); err != nil {
+		slog.ErrorContext(ctx, "failed to render email", "template", t.name, "error", err)
+		return
+	}
+	subject, body, _ := strings.Cut(buf.String(), "\n\n")
+
+	entry := repoInsert(ctx, emailLogStore, EmailLogEntry{
+		Template:  t.name,
+		StudentID: studentID,
+		To:        to,
+		Subject:   subject,
+		Status:    emailQueued,
+		QueuedAt:  clock.Now().UTC(),
+	})
+	select {
+	case stateOf(ctx).emailQueue <- outgoingEmail{logID: entry.ID, to: to, msg: composeEmail(to, subject, body, entry.ID)}:
+	default:
+		entry.Status, entry.Error = emailFailed, "send queue full"
+		emailLogStore.Replace(ctx, entry.ID, entry)
+	}
+}
+
+func composeEmail(to, subject, body string, id int) []byte {
+	var b bytes.Buffer
+	host := cfg.SMTPHost
+	if from, err := mail.ParseAddress(cfg.SMTPFrom); err == nil {
+		_, host, _ = strings.Cut(from.Address, "@")
+	}
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.SMTPFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", clock.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%d.%s@%s>\r\n", id, newRequestID(), host)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// startEmailSender sends queued emails one at a time until ctx is
+// cancelled, recording each outcome in the send log.
+func startEmailSender(ctx context.Context) {
+	if cfg.SMTPHost == "" {
+		return
+	}
+	queue := stateOf(ctx).emailQueue
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m := <-queue:
+				err := sendEmail(ctx, m.to, m.msg)
+				entry, ok := emailLogStore.Find(ctx, m.logID)
+				if !ok {
+					continue
+				}
+				if err != nil {
+					slog.WarnContext(ctx, "failed to send email", "email_id", m.logID, "error", err)
+					entry.Status, entry.Error = emailFailed, err.Error()
+				} else {
+					entry.Status, entry.SentAt = emailSent, clock.Now().UTC()
+				}
+				emailLogStore.Replace(ctx, m.logID, entry)
+			}
+		}
+	}()
+}
+
+// sendEmail delivers one message through the configured SMTP server,
+// upgrading to TLS whenever the server offers STARTTLS.
+func sendEmail(ctx context.Context, to string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	conn, err := (&net.Dialer{Timeout: 10 * time.Second}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	c, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.SMTPHost}); err != nil {
+			return err
+		}
+	}
+	if cfg.SMTPUsername != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return err
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// getEmailLog lists notification emails, newest first, optionally
+// filtered by ?status= and ?student_id=.
+func getEmailLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	studentID := 0
+	if v := query.Get("student_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "student_id", Message: "must be a positive integer"})
+			return
+		}
+		studentID = n
+	}
+
+	list := []EmailLogEntry{}
+	for _, e := range repoList(r.Context(), emailLogStore) {
+		if (status == "" || e.Status == status) && (studentID == 0 || e.StudentID == studentID) {
+			list = append(list, e)
+		}
+	}
+	slices.Reverse(list)
+	writeJSONArray(w, list)
+}
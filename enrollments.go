@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Enrollment places a student in a course.
+type Enrollment struct {
+	ID         int       `json:"id"`
+	StudentID  int       `json:"student_id"`
+	CourseID   int       `json:"course_id"`
+	SectionID  int       `json:"section_id,omitempty"`
+	Term       string    `json:"term,omitempty"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+}
+
+func (e Enrollment) entityID() int { return e.ID }
+
+func (e Enrollment) withID(id int) Enrollment {
+	e.ID = id
+	return e
+}
+
+var enrollmentStore Repository[Enrollment] = newCollection[Enrollment]("enrollments")
+
+// enrollmentMutex serializes enrolling so capacity and duplicate checks
+// cannot race with each other.
+var enrollmentMutex = &sync.Mutex{}
+
+// enrollmentsWhere returns the enrollments matching keep, ordered by ID.
+func enrollmentsWhere(ctx context.Context, keep func(Enrollment) bool) []Enrollment {
+	var list []Enrollment
+	for _, e := range repoList(ctx, enrollmentStore) {
+		if keep(e) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+func courseEnrollments(ctx context.Context, courseID int) []Enrollment {
+	return enrollmentsWhere(ctx, func(e Enrollment) bool { return e.CourseID == courseID })
+}
+
+func studentEnrollments(ctx context.Context, studentID int) []Enrollment {
+	return enrollmentsWhere(ctx, func(e Enrollment) bool { return e.StudentID == studentID })
+}
+
+// removeStudentEnrollments drops everything recorded against a deleted
+// student: enrollments, waitlist places, grades, attendance, submissions,
+// guardians, ledger transactions, awards and cohort memberships. Freed
+// seats go to the waitlist.
+func removeStudentEnrollments(ctx context.Context, studentID int) {
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	courses := map[int]bool{}
+	for _, e := range studentEnrollments(ctx, studentID) {
+		repoRemove(ctx, enrollmentStore, e.ID)
+		courses[e.CourseID] = true
+	}
+	for _, e := range waitlistStore.List(ctx) {
+		if e.StudentID == studentID {
+			repoRemove(ctx, waitlistStore, e.ID)
+		}
+	}
+	for courseID := range courses {
+		promoteWaitlist(ctx, courseID)
+	}
+	for _, g := range gradeStore.List(ctx) {
+		if g.StudentID == studentID {
+			repoRemove(ctx, gradeStore, g.ID)
+		}
+	}
+	for _, a := range attendanceStore.List(ctx) {
+		if a.StudentID == studentID {
+			repoRemove(ctx, attendanceStore, a.ID)
+		}
+	}
+	for _, s := range submissionStore.List(ctx) {
+		if s.StudentID == studentID {
+			repoRemove(ctx, submissionStore, s.ID)
+		}
+	}
+	for _, g := range guardianStore.List(ctx) {
+		if g.StudentID == studentID {
+			repoRemove(ctx, guardianStore, g.ID)
+		}
+	}
+	for _, t := range transactionStore.List(ctx) {
+		if t.StudentID == studentID {
+			repoRemove(ctx, transactionStore, t.ID)
+		}
+	}
+	for _, a := range awardStore.List(ctx) {
+		if a.StudentID == studentID {
+			repoRemove(ctx, awardStore, a.ID)
+		}
+	}
+	removeCohortMemberships(ctx, studentID)
+}
+
+func createEnrollment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var req struct {
+		CourseID  int    `json:"course_id"`
+		SectionID int    `json:"section_id"`
+		Term      string `json:"term"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid enrollment data", decodeErrors(err)...)
+		return
+	}
+	if req.CourseID <= 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid enrollment data", FieldError{Field: "course_id", Message: "is required"})
+		return
+	}
+
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	// Enrollments belong to the named term, or to the current one if the
+	// request does not say.
+	term := strings.ToUpper(strings.TrimSpace(req.Term))
+	if term != "" {
+		if _, ok := findTerm(r.Context(), term); !ok {
+			writeProblem(w, r, http.StatusUnprocessableEntity, "Term not found", FieldError{Field: "term", Message: "does not exist"})
+			return
+		}
+	} else if current, ok := currentTerm(r.Context()); ok {
+		term = current.Name
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	course, exists := repoFind(r.Context(), courseStore, req.CourseID)
+	if !exists {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Course not found", FieldError{Field: "course_id", Message: "does not exist"})
+		return
+	}
+	if unmet := unmetPrerequisites(r.Context(), studentID, course); len(unmet) > 0 {
+		writeUnmetPrerequisites(w, r, course, unmet)
+		return
+	}
+	for _, e := range courseEnrollments(r.Context(), course.ID) {
+		if e.StudentID == studentID && e.Term == term {
+			writeProblem(w, r, http.StatusConflict, "Student is already enrolled in "+course.Code)
+			return
+		}
+	}
+	if waiting := waitlistWhere(r.Context(), func(e WaitlistEntry) bool {
+		return e.StudentID == studentID && e.CourseID == course.ID && e.Term == term
+	}); len(waiting) > 0 {
+		writeProblem(w, r, http.StatusConflict, "Student is already on the waitlist for "+course.Code)
+		return
+	}
+	var section Section
+	if req.SectionID != 0 {
+		var ok bool
+		section, ok = repoFind(r.Context(), sectionStore, req.SectionID)
+		if !ok || section.CourseID != course.ID {
+			writeProblem(w, r, http.StatusUnprocessableEntity, "Section not found", FieldError{Field: "section_id", Message: "is not a section of this course"})
+			return
+		}
+		if conflicts := scheduleConflicts(r.Context(), studentID, term, section); len(conflicts) > 0 {
+			writeProblem(w, r, http.StatusConflict, "Schedule conflict: "+strings.Join(conflicts, "; "))
+			return
+		}
+	}
+
+	// A full course or section puts the student on its waitlist instead.
+	if !seatAvailable(r.Context(), course, section, term) {
+		entry := repoInsert(r.Context(), waitlistStore, WaitlistEntry{
+			StudentID: studentID,
+			CourseID:  course.ID,
+			SectionID: req.SectionID,
+			Term:      term,
+			AddedAt:   clock.Now().UTC(),
+		})
+		if inDryRun(r.Context()) {
+			// Nothing was stored: the entry would join the back of its queue.
+			entry.Position = len(waitlistWhere(r.Context(), func(e WaitlistEntry) bool { return sameQueue(e, entry) })) + 1
+		} else {
+			entry = waitlistWhere(r.Context(), func(e WaitlistEntry) bool { return e.ID == entry.ID })[0]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	enrollment := repoInsert(r.Context(), enrollmentStore, Enrollment{
+		StudentID:  studentID,
+		CourseID:   course.ID,
+		SectionID:  req.SectionID,
+		Term:       term,
+		EnrolledAt: clock.Now().UTC(),
+	})
+	publishEvent(r.Context(), EnrollmentAdded{Enrollment: enrollment})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func getStudentEnrollments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	list := []Enrollment{}
+	for _, e := range studentEnrollments(r.Context(), studentID) {
+		if term == "" || e.Term == term {
+			list = append(list, e)
+		}
+	}
+	writeJSONArray(w, list)
+}
+
+func deleteEnrollment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	enrollmentID, err := strconv.Atoi(params["enrollment_id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid enrollment ID")
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	e, exists := repoFind(r.Context(), enrollmentStore, enrollmentID)
+	if !exists || e.StudentID != studentID {
+		writeProblem(w, r, http.StatusNotFound, "Enrollment not found")
+		return
+	}
+	for _, g := range gradeStore.List(r.Context()) {
+		if g.EnrollmentID == enrollmentID {
+			writeProblem(w, r, http.StatusConflict, "Enrollment has recorded grades")
+			return
+		}
+	}
+	repoRemove(r.Context(), enrollmentStore, enrollmentID)
+	promoteWaitlist(r.Context(), e.CourseID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getCourseStudents lists the students enrolled in a course.
+func getCourseStudents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	students := []Student{}
+	for _, e := range courseEnrollments(r.Context(), courseID) {
+		if term != "" && e.Term != term {
+			continue
+		}
+		if s, ok := findStudent(r.Context(), e.StudentID); ok {
+			students = append(students, s)
+		}
+	}
+	writeJSONArray(w, students)
+}
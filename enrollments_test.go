@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestDryRunEnrollmentInFullCourse(t *testing.T) {
+	api := newTestAPI(t, nil)
+	var course Course
+	api.decode(t, "POST", "/v1/courses", Course{Code: "CS101", Title: "Programming", Credits: 3, Capacity: 1}, http.StatusCreated, &course)
+	students := make([]Student, 3)
+	for i := range students {
+		api.decode(t, "POST", "/v1/students", Student{Name: "Student " + strconv.Itoa(i), Age: 20, Email: "s" + strconv.Itoa(i) + "@example.com"}, http.StatusCreated, &students[i])
+	}
+	enroll := func(s Student) string { return "/v1/students/" + strconv.Itoa(s.ID) + "/enrollments" }
+	body := map[string]int{"course_id": course.ID}
+	api.decode(t, "POST", enroll(students[0]), body, http.StatusCreated, nil)
+	var waiting WaitlistEntry
+	api.decode(t, "POST", enroll(students[1]), body, http.StatusAccepted, &waiting)
+	if waiting.Position != 1 {
+		t.Errorf("first on the waitlist at position %d, want 1", waiting.Position)
+	}
+
+	var dry WaitlistEntry
+	api.decode(t, "POST", enroll(students[2])+"?dry_run=true", body, http.StatusAccepted, &dry)
+	if dry.ID != 0 || dry.Position != 2 || dry.StudentID != students[2].ID {
+		t.Errorf("dry run gave %+v, want ID 0 at position 2 for student %d", dry, students[2].ID)
+	}
+	var queue []WaitlistEntry
+	api.decode(t, "GET", "/v1/courses/"+strconv.Itoa(course.ID)+"/waitlist", nil, http.StatusOK, &queue)
+	if len(queue) != 1 {
+		t.Errorf("waitlist has %d entries after the dry run, want 1", len(queue))
+	}
+}
+
+func TestDryRunStudentDeleteKeepsDocuments(t *testing.T) {
+	api := newTestAPI(t, nil)
+	var student Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &student)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("type", "transcript")
+	file, _ := form.CreateFormFile("file", "transcript.txt")
+	io.WriteString(file, "All A grades")
+	form.Close()
+	req, _ := http.NewRequest("POST", api.URL+"/v1/students/"+strconv.Itoa(student.ID)+"/documents", &body)
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := api.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc Document
+	json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("upload: status %d", resp.StatusCode)
+	}
+
+	api.decode(t, "DELETE", "/v1/students/"+strconv.Itoa(student.ID)+"?dry_run=true", nil, http.StatusNoContent, nil)
+	resp, data := api.do(t, "GET", "/v1/documents/"+strconv.Itoa(doc.ID)+"/content", nil)
+	if resp.StatusCode != http.StatusOK || string(data) != "All A grades" {
+		t.Errorf("document content after a dry-run student delete: status %d, %q", resp.StatusCode, data)
+	}
+}
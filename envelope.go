@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Envelope is the shape of every /v1 JSON response when the envelope is
+// on: the resource or list in data, or the problem details in error, and
+// meta alongside either.
+type Envelope struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+	Meta  EnvelopeMeta    `json:"meta"`
+}
+
+// EnvelopeMeta describes the response rather than the resource. Count and
+// Next are set for lists; Next is the URL of the following page, as in the
+// Link header, and absent on the last one.
+type EnvelopeMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+	Count     *int   `json:"count,omitempty"`
+	Next      string `json:"next,omitempty"`
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]*)>\s*;\s*rel="next"`)
+
+// wantsEnvelope reports whether the response to r is enveloped:
+// ?envelope= when given, response_envelope otherwise. ok is false when
+// ?envelope= is not a boolean.
+func wantsEnvelope(r *http.Request) (on, ok bool) {
+	v := r.URL.Query().Get("envelope")
+	if v == "" {
+		return cfg.ResponseEnvelope, true
+	}
+	on, err := strconv.ParseBool(v)
+	return on, err == nil
+}
+
+// envelope wraps the JSON responses of /v1 routes in an Envelope, so
+// clients read resources, lists and errors from one shape instead of
+// telling a bare object, a bare array and a problem document apart.
+// Clients opt in with ?envelope=true, or out with ?envelope=false when
+// response_envelope puts every client in. HAL documents, files, event
+// streams and unversioned routes keep their own formats, and the
+// operations of a batch are enveloped with the batch, not one by one.
+// Status codes and headers are left as the handler set them.
+func envelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, apiV1+"/") || inBatch(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		on, ok := wantsEnvelope(r)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "envelope", Message: "must be true or false"})
+			return
+		}
+		if !on {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Handlers compare If-None-Match with the ETag of their own body,
+		// which envelopeWriter tags.
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			r.Header.Set("If-None-Match", strings.ReplaceAll(inm, `-envelope"`, `"`))
+		}
+		ew := &envelopeWriter{ResponseWriter: w, request: r}
+		defer ew.finish()
+		next.ServeHTTP(ew, r)
+	})
+}
+
+// envelopeWriter holds back JSON responses and wraps them in an Envelope
+// when the handler is done. It works like formatWriter, which converts the
+// envelope in turn when another format is asked for.
+type envelopeWriter struct {
+	http.ResponseWriter
+	request  *http.Request
+	status   int
+	decided  bool
+	wrapping bool
+	problem  bool
+	buf      bytes.Buffer
+}
+
+func (ew *envelopeWriter) WriteHeader(status int) {
+	if ew.decided {
+		return
+	}
+	ew.status = status
+	ew.decide()
+}
+
+func (ew *envelopeWriter) Write(b []byte) (int, error) {
+	if !ew.decided {
+		ew.decide()
+	}
+	if ew.wrapping {
+		return ew.buf.Write(b)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+func (ew *envelopeWriter) decide() {
+	ew.decided = true
+	mediaType, _, _ := mime.ParseMediaType(ew.Header().Get("Content-Type"))
+	ew.problem = mediaType == "application/problem+json"
+	ew.wrapping = (mediaType == "application/json" || ew.problem) &&
+		ew.status != http.StatusNoContent && ew.status != http.StatusNotModified
+	if ew.wrapping {
+		return
+	}
+	if ew.status == http.StatusNotModified {
+		ew.tagETag()
+	}
+	if ew.status != 0 {
+		ew.ResponseWriter.WriteHeader(ew.status)
+	}
+}
+
+func (ew *envelopeWriter) tagETag() {
+	if etag := ew.Header().Get("ETag"); strings.HasSuffix(etag, `"`) {
+		ew.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+`-envelope"`)
+	}
+}
+
+func (ew *envelopeWriter) Flush() {
+	if !ew.decided {
+		ew.decide()
+	}
+	if !ew.wrapping {
+		http.NewResponseController(ew.ResponseWriter).Flush()
+	}
+}
+
+func (ew *envelopeWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// finish wraps and sends a held-back response. A body that is not valid
+// JSON after all is sent unchanged.
+func (ew *envelopeWriter) finish() {
+	if !ew.decided {
+		ew.decide()
+	}
+	if !ew.wrapping {
+		return
+	}
+	h := ew.Header()
+	body := ew.buf.Bytes()
+	if trimmed := bytes.TrimSpace(body); json.Valid(trimmed) {
+		env := Envelope{Meta: EnvelopeMeta{RequestID: requestIDFromContext(ew.request.Context())}}
+		if ew.problem {
+			env.Error = trimmed
+		} else {
+			env.Data = trimmed
+			if trimmed[0] == '[' {
+				var items []json.RawMessage
+				if json.Unmarshal(trimmed, &items) == nil {
+					n := len(items)
+					env.Meta.Count = &n
+				}
+			}
+			if m := nextLinkPattern.FindStringSubmatch(strings.Join(h.Values("Link"), ", ")); m != nil {
+				env.Meta.Next = m[1]
+			}
+		}
+		if wrapped, err := json.Marshal(env); err == nil {
+			body = append(wrapped, '\n')
+			h.Set("Content-Type", "application/json")
+			ew.tagETag()
+		}
+	}
+	h.Del("Content-Length")
+	status := ew.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ew.ResponseWriter.WriteHeader(status)
+	ew.ResponseWriter.Write(body)
+}
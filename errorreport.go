@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrorEvent is a server-side failure worth reporting. It deliberately
+// carries the route template rather than the URL and no request bodies,
+// headers or client addresses, so reports stay free of student PII.
+type ErrorEvent struct {
+	Message   string
+	Panic     bool
+	Stack     string
+	Method    string
+	Route     string
+	Status    int
+	RequestID string
+}
+
+// ErrorReporter receives panics and 5xx responses.
+type ErrorReporter interface {
+	Report(ctx context.Context, event ErrorEvent)
+	Flush(timeout time.Duration)
+}
+
+// reporter is the active error reporter. It discards events unless a
+// Sentry DSN is configured.
+var reporter ErrorReporter = noopReporter{}
+
+type noopReporter struct{}
+
+func (noopReporter) Report(context.Context, ErrorEvent) {}
+func (noopReporter) Flush(time.Duration)                {}
+
+// sentryReporter forwards events to Sentry.
+type sentryReporter struct{}
+
+func newSentryReporter(dsn string) (ErrorReporter, error) {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:            dsn,
+		Environment:    cfg.Env,
+		Release:        version,
+		SendDefaultPII: false,
+		BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			event.Request = nil
+			event.User = sentry.User{}
+			event.ServerName = ""
+			return event
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sentryReporter{}, nil
+}
+
+func (sentryReporter) Report(_ context.Context, e ErrorEvent) {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("method", e.Method)
+		scope.SetTag("route", e.Route)
+		scope.SetTag("status", strconv.Itoa(e.Status))
+		scope.SetTag("request_id", e.RequestID)
+		scope.SetTag("panic", strconv.FormatBool(e.Panic))
+		if e.Stack != "" {
+			scope.SetExtra("stack", e.Stack)
+		}
+		scope.SetLevel(sentry.LevelError)
+	})
+	hub.CaptureException(errors.New(e.Message))
+}
+
+func (sentryReporter) Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
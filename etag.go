@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// weakETag derives a weak entity tag from a response body.
+func weakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatches applies the weak comparison of RFC 9110 to If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONWithETag encodes v with a weak ETag and answers 304 Not
+// Modified when the client already holds the same representation. A
+// Content-Type set beforehand, such as HAL's, is kept.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	body = append(body, '\n')
+
+	etag := weakETag(body)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(body)
+}
+
+// setLastModified sets Last-Modified, unless the time is unknown.
+func setLastModified(w http.ResponseWriter, modified time.Time) {
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// unmodifiedSince evaluates If-Unmodified-Since against the time a
+// resource was last written. It reports false when the write must be
+// refused with 412. As RFC 9110 requires, the header is ignored when it
+// is not a valid date or the modification time is unknown.
+func unmodifiedSince(r *http.Request, modified time.Time) bool {
+	v := r.Header.Get("If-Unmodified-Since")
+	if v == "" || modified.IsZero() {
+		return true
+	}
+	since, err := http.ParseTime(v)
+	if err != nil {
+		return true
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// listETag derives a weak ETag for a list response from the store version
+// and the query, so it can be checked before the list is read or encoded.
+func listETag(version uint64, r *http.Request) string {
+	return weakETag([]byte(fmt.Sprintf("%d?%s", version, r.URL.RawQuery)))
+}
+
+// writeJSONArray streams items as a JSON array one element at a time, so
+// large lists are never held in memory as a single encoded buffer.
+func writeJSONArray[T any](w http.ResponseWriter, items []T) error {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
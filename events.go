@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Event is a domain event as delivered to subscribers. Data holds one of
+// the payload types below.
+type Event struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Data       eventPayload `json:"data"`
+}
+
+// eventPayload is implemented by every event type.
+type eventPayload interface {
+	eventType() string
+}
+
+const (
+	eventStudentCreated     = "student.created"
+	eventStudentUpdated     = "student.updated"
+	eventStudentDeleted     = "student.deleted"
+	eventSummaryGenerated   = "summary.generated"
+	eventEnrollmentAdded    = "enrollment.added"
+	eventAttendanceRecorded = "attendance.recorded"
+)
+
+// eventTypes lists every event type, for validating subscriptions.
+var eventTypes = []string{
+	eventStudentCreated,
+	eventStudentUpdated,
+	eventStudentDeleted,
+	eventSummaryGenerated,
+	eventEnrollmentAdded,
+	eventAttendanceRecorded,
+}
+
+type StudentCreated struct {
+	Student Student `json:"student"`
+}
+
+type StudentUpdated struct {
+	Before Student `json:"before"`
+	After  Student `json:"after"`
+}
+
+type StudentDeleted struct {
+	Student Student `json:"student"`
+}
+
+type SummaryGenerated struct {
+	StudentID int    `json:"student_id"`
+	Model     string `json:"model"`
+	Summary   string `json:"summary"`
+}
+
+// EnrollmentAdded is published for direct enrollments and for students
+// promoted off a waitlist.
+type EnrollmentAdded struct {
+	Enrollment Enrollment `json:"enrollment"`
+	Promoted   bool       `json:"promoted,omitempty"`
+}
+
+type AttendanceRecorded struct {
+	Record AttendanceRecord `json:"record"`
+}
+
+func (StudentCreated) eventType() string     { return eventStudentCreated }
+func (StudentUpdated) eventType() string     { return eventStudentUpdated }
+func (StudentDeleted) eventType() string     { return eventStudentDeleted }
+func (SummaryGenerated) eventType() string   { return eventSummaryGenerated }
+func (EnrollmentAdded) eventType() string    { return eventEnrollmentAdded }
+func (AttendanceRecorded) eventType() string { return eventAttendanceRecorded }
+
+// eventHandler reacts to an event. Handlers run synchronously on the
+// publishing goroutine, so anything slow must be handed off to a queue.
+type eventHandler func(ctx context.Context, e Event)
+
+type subscription struct {
+	name    string
+	types   map[string]bool
+	handler eventHandler
+}
+
+// eventBus fans published events out to subscribers.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs []subscription
+}
+
+var eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "events_published_total",
+	Help: "Domain events published, by type.",
+}, []string{"type"})
+
+// subscribe registers handler for the given event types, or for every
+// type when none are given. name identifies the subscriber in logs.
+func (b *eventBus) subscribe(name string, handler eventHandler, types ...string) {
+	s := subscription{name: name, handler: handler}
+	if len(types) > 0 {
+		s.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, s)
+}
+
+// publish delivers an event to every interested subscriber. A panicking
+// subscriber is logged and does not affect the others or the caller.
+// Nothing is published during a dry run.
+func (b *eventBus) publish(ctx context.Context, payload eventPayload) {
+	if inDryRun(ctx) {
+		return
+	}
+	e := Event{
+		ID:         newRequestID(),
+		Type:       payload.eventType(),
+		OccurredAt: clock.Now().UTC(),
+		RequestID:  requestIDFromContext(ctx),
+		Data:       payload,
+	}
+	eventsPublished.WithLabelValues(e.Type).Inc()
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, s := range subs {
+		if s.types == nil || s.types[e.Type] {
+			b.deliver(ctx, s, e)
+		}
+	}
+}
+
+func (b *eventBus) deliver(ctx context.Context, s subscription, e Event) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(ctx, "event subscriber panicked",
+				"subscriber", s.name,
+				"event", e.Type,
+				"panic", fmt.Sprint(rec),
+				"stack", string(debug.Stack()),
+			)
+		}
+	}()
+	s.handler(ctx, e)
+}
+
+// publishEvent publishes on the event bus of the server serving ctx.
+func publishEvent(ctx context.Context, payload eventPayload) {
+	stateOf(ctx).events.publish(ctx, payload)
+}
+
+// subscribeCoreHandlers wires the subscribers built into the service.
+func subscribeCoreHandlers(events *eventBus) {
+	events.subscribe("event-log", logEvent)
+	events.subscribe("webhooks", recordDeliveries)
+	events.subscribe("change-feed", recordChange)
+	if cfg.EventBroker != "" {
+		events.subscribe("broker", queueBrokerEvent)
+	}
+	events.subscribe("summary-cache", func(ctx context.Context, e Event) {
+		forgetSummary(ctx, e.Data.(StudentDeleted).Student.ID)
+	}, eventStudentDeleted)
+	subscribeNotifications(events)
+}
+
+// logEvent records every event at debug level, and promotions off a
+// waitlist at info so they show up in normal logs.
+func logEvent(ctx context.Context, e Event) {
+	level := slog.LevelDebug
+	attrs := []any{"event", e.Type, "event_id", e.ID}
+	if added, ok := e.Data.(EnrollmentAdded); ok {
+		attrs = append(attrs, "student_id", added.Enrollment.StudentID, "course_id", added.Enrollment.CourseID, "enrollment_id", added.Enrollment.ID)
+		if added.Promoted {
+			level = slog.LevelInfo
+			attrs = append(attrs, "promoted", true)
+		}
+	}
+	slog.Log(ctx, level, "domain event", attrs...)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Export is a CSV file of the students matching a filter. It is kept in
+// the blob store once built, so a large export can be downloaded, more
+// than once, without being rebuilt.
+type Export struct {
+	ID        int       `json:"id"`
+	Filter    string    `json:"filter,omitempty"`
+	Fields    string    `json:"fields,omitempty"`
+	Students  int       `json:"students"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e Export) entityID() int { return e.ID }
+
+func (e Export) withID(id int) Export {
+	e.ID = id
+	return e
+}
+
+// Backup is a copy of every student as JSON lines, the format studentctl
+// backup writes, kept in the blob store.
+type Backup struct {
+	ID        int       `json:"id"`
+	Students  int       `json:"students"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (b Backup) entityID() int { return b.ID }
+
+func (b Backup) withID(id int) Backup {
+	b.ID = id
+	return b
+}
+
+var (
+	exportStore Repository[Export] = newCollection[Export]("exports")
+	backupStore Repository[Backup] = newCollection[Backup]("backups")
+)
+
+func exportKey(id int) string {
+	return "exports/" + strconv.Itoa(id) + ".csv"
+}
+
+func backupKey(id int) string {
+	return "backups/" + strconv.Itoa(id) + ".jsonl"
+}
+
+// createExport builds an export from an OData $filter and a
+// comma-separated $select list, both optional.
+func createExport(w http.ResponseWriter, r *http.Request) {
+	var export Export
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid export", decodeErrors(err)...)
+		return
+	}
+	q, errs := parseODataOptions(url.Values{"$filter": {export.Filter}, "$select": {export.Fields}}, reflect.TypeFor[Student]())
+	if len(errs) > 0 {
+		for i := range errs {
+			errs[i].Field = map[string]string{"$filter": "filter", "$select": "fields"}[errs[i].Field]
+		}
+		writeProblem(w, r, http.StatusBadRequest, "Invalid export", errs...)
+		return
+	}
+	fields := q.fields
+	if !q.selects() {
+		fields = jsonFieldNames(reflect.TypeFor[Student]())
+	}
+
+	students := applyODataFilter(q, allStudents(r.Context()))
+	export = repoInsert(r.Context(), exportStore, Export{Filter: export.Filter, Fields: export.Fields, Students: len(students), CreatedAt: clock.Now().UTC()})
+	size, sum, err := storeBlob(r.Context(), exportKey(export.ID), func(out io.Writer) error {
+		return encodeCSVFields(out, fields, students)
+	})
+	if err != nil {
+		repoRemove(r.Context(), exportStore, export.ID)
+		slog.ErrorContext(r.Context(), "failed to store export", "export_id", export.ID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to store export")
+		return
+	}
+	export.Size, export.SHA256 = size, sum
+	repoReplace(r.Context(), exportStore, export.ID, export)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(export)
+}
+
+func getExports(w http.ResponseWriter, r *http.Request) {
+	writeJSONArray(w, repoList(r.Context(), exportStore))
+}
+
+func findExportVar(w http.ResponseWriter, r *http.Request) (Export, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid export ID")
+		return Export{}, false
+	}
+	export, exists := repoFind(r.Context(), exportStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Export not found")
+		return Export{}, false
+	}
+	return export, true
+}
+
+func getExport(w http.ResponseWriter, r *http.Request) {
+	if export, ok := findExportVar(w, r); ok {
+		writeJSONWithETag(w, r, export)
+	}
+}
+
+func downloadExport(w http.ResponseWriter, r *http.Request) {
+	if export, ok := findExportVar(w, r); ok {
+		serveBlob(w, r, exportKey(export.ID), "Export", "text/csv; charset=utf-8", "students-"+strconv.Itoa(export.ID)+".csv", export.Size, export.SHA256)
+	}
+}
+
+func deleteExport(w http.ResponseWriter, r *http.Request) {
+	export, ok := findExportVar(w, r)
+	if !ok {
+		return
+	}
+	removeExport(r.Context(), export.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeExport deletes an export and its file.
+func removeExport(ctx context.Context, id int) {
+	repoRemove(ctx, exportStore, id)
+	if inDryRun(ctx) {
+		return
+	}
+	if err := blobs.Delete(ctx, exportKey(id)); err != nil {
+		slog.WarnContext(ctx, "failed to delete export", "export_id", id, "error", err)
+	}
+}
+
+// createBackup backs up every student.
+func createBackup(w http.ResponseWriter, r *http.Request) {
+	students := allStudents(r.Context())
+	backup := repoInsert(r.Context(), backupStore, Backup{Students: len(students), CreatedAt: clock.Now().UTC()})
+	size, sum, err := storeBlob(r.Context(), backupKey(backup.ID), func(out io.Writer) error {
+		enc := json.NewEncoder(out)
+		for _, s := range students {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		repoRemove(r.Context(), backupStore, backup.ID)
+		slog.ErrorContext(r.Context(), "failed to store backup", "backup_id", backup.ID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to store backup")
+		return
+	}
+	backup.Size, backup.SHA256 = size, sum
+	repoReplace(r.Context(), backupStore, backup.ID, backup)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backup)
+}
+
+func getBackups(w http.ResponseWriter, r *http.Request) {
+	writeJSONArray(w, repoList(r.Context(), backupStore))
+}
+
+func downloadBackup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid backup ID")
+		return
+	}
+	backup, exists := repoFind(r.Context(), backupStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Backup not found")
+		return
+	}
+	serveBlob(w, r, backupKey(id), "Backup", "application/jsonl", "students-"+backup.CreatedAt.Format("20060102T150405Z")+".jsonl", backup.Size, backup.SHA256)
+}
+
+func deleteBackup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid backup ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), backupStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Backup not found")
+		return
+	}
+	removeBackup(r.Context(), id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeBackup deletes a backup and its file.
+func removeBackup(ctx context.Context, id int) {
+	repoRemove(ctx, backupStore, id)
+	if inDryRun(ctx) {
+		return
+	}
+	if err := blobs.Delete(ctx, backupKey(id)); err != nil {
+		slog.WarnContext(ctx, "failed to delete backup", "backup_id", id, "error", err)
+	}
+}
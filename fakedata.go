@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+// maxGenerate caps one POST /admin/generate request.
+const maxGenerate = 10000
+
+// fakeStudent makes up a plausible student. The email is built from the
+// name, with n to keep it unique across a batch.
+func fakeStudent(f *gofakeit.Faker, n int, programs []Program) Student {
+	first, last := f.FirstName(), f.LastName()
+	local := strings.ToLower(first + "." + last)
+	local = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r == '.' {
+			return r
+		}
+		return -1
+	}, local)
+	s := Student{
+		Name:  first + " " + last,
+		Age:   f.Number(17, 30),
+		Email: fmt.Sprintf("%s%d@%s", local, n, f.DomainName()),
+	}
+	// Most students follow a program, when there are any to follow.
+	if len(programs) > 0 && f.Number(1, 10) <= 8 {
+		s.ProgramID = programs[f.Number(0, len(programs)-1)].ID
+	}
+	return s
+}
+
+// generateStudents is the admin operation behind POST /admin/generate. It
+// creates ?count= fake students, 100 by default, for load tests and UI
+// work. ?seed= makes the batch reproducible; without it every batch
+// differs. Like fixtures, generated students publish no events. It only
+// exists in development.
+func generateStudents(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isDevelopment() {
+		writeProblem(w, r, http.StatusForbidden, "Generating data is only available in development")
+		return
+	}
+
+	count := 100
+	if v := r.URL.Query().Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxGenerate {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "count", Message: fmt.Sprintf("must be an integer from 1 to %d", maxGenerate)})
+			return
+		}
+		count = n
+	}
+	seed := random.Int64N(math.MaxInt64) + 1
+	if v := r.URL.Query().Get("seed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "seed", Message: "must be an integer"})
+			return
+		}
+		seed = n
+	}
+
+	ctx := r.Context()
+	f := gofakeit.New(seed)
+	programs := repoList(ctx, programStore)
+	created, firstID, lastID := 0, 0, 0
+	for i := range count {
+		if ctx.Err() != nil {
+			break
+		}
+		s := insertStudent(ctx, fakeStudent(f, i+1, programs))
+		if firstID == 0 {
+			firstID = s.ID
+		}
+		lastID = s.ID
+		created++
+	}
+	slog.InfoContext(ctx, "generated fake students", "count", created)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"created": created, "first_id": firstID, "last_id": lastID})
+}
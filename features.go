@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FeatureFlag gates an experimental capability. An enabled flag may be
+// narrowed to some environments and some tenants; an empty list means all.
+// Until the API has tenants, a tenant is the subject of the caller's API key.
+type FeatureFlag struct {
+	Enabled      bool     `yaml:"enabled" json:"enabled"`
+	Environments []string `yaml:"environments" json:"environments,omitempty"`
+	Tenants      []string `yaml:"tenants" json:"tenants,omitempty"`
+}
+
+// featureFlagRecheck is how often the flags file is checked for changes.
+const featureFlagRecheck = 2 * time.Second
+
+// featureFlagFile is a server's flags as last read from feature_flags_file.
+type featureFlagFile struct {
+	mu        sync.Mutex
+	flags     map[string]FeatureFlag
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// loadFeatureFlags reads the flags file if it changed since the last read,
+// so flags can be flipped without a redeploy or restart.
+func loadFeatureFlags(ctx context.Context) error {
+	if cfg.FeatureFlagsFile == "" {
+		return nil
+	}
+
+	f := &stateOf(ctx).features
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checkedAt) < featureFlagRecheck {
+		return nil
+	}
+	f.checkedAt = time.Now()
+
+	info, err := os.Stat(cfg.FeatureFlagsFile)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(cfg.FeatureFlagsFile)
+	if err != nil {
+		return err
+	}
+	flags := map[string]FeatureFlag{}
+	if err := yaml.Unmarshal(data, &flags); err != nil {
+		return fmt.Errorf("feature flags %s: %w", cfg.FeatureFlagsFile, err)
+	}
+	f.flags = flags
+	f.modTime = info.ModTime()
+	slog.InfoContext(ctx, "feature flags loaded", "count", len(flags))
+	return nil
+}
+
+func currentFeatureFlags(ctx context.Context) map[string]FeatureFlag {
+	if err := loadFeatureFlags(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to reload feature flags", "error", err)
+	}
+	f := &stateOf(ctx).features
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flags
+}
+
+func (f FeatureFlag) enabledFor(env, tenant string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Environments) > 0 && !slices.Contains(f.Environments, env) {
+		return false
+	}
+	if len(f.Tenants) > 0 && !slices.Contains(f.Tenants, tenant) {
+		return false
+	}
+	return true
+}
+
+func tenantFor(r *http.Request) string {
+	if p, ok := authenticate(r); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+// featureEnabled evaluates a flag for the request. Unknown flags are off.
+func featureEnabled(r *http.Request, name string) bool {
+	flag, ok := currentFeatureFlags(r.Context())[name]
+	return ok && flag.enabledFor(cfg.Env, tenantFor(r))
+}
+
+// requireFeature hides an experimental route behind a flag, answering 404
+// as if the route did not exist while the flag is off for the caller.
+func requireFeature(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !featureEnabled(r, name) {
+			notFoundHandler(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getFeatures lists every flag as evaluated for the caller, so clients can
+// adapt their UI to what is available to them.
+func getFeatures(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantFor(r)
+	result := map[string]bool{}
+	for name, flag := range currentFeatureFlags(r.Context()) {
+		result[name] = flag.enabledFor(cfg.Env, tenant)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// getFeatureDefinitions shows admins the raw flag definitions.
+func getFeatureDefinitions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentFeatureFlags(r.Context()))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"studengo/ollama/ollamatest"
+)
+
+func newFlaggedAPI(t *testing.T, flags string) *testAPI {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "features.yaml")
+	if err := os.WriteFile(path, []byte(flags), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return newTestAPI(t, func(c *Config) { c.FeatureFlagsFile = path })
+}
+
+func TestStudentQueryBehindFlag(t *testing.T) {
+	question := StudentQuery{Question: "students over 20"}
+
+	off := newFlaggedAPI(t, "nl_query:\n  enabled: false\n")
+	if resp, data := off.do(t, "POST", "/v1/students/query", question); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("flag off: status %d, want 404: %s", resp.StatusCode, data)
+	}
+	if n := len(off.ollama.Requests()); n != 0 {
+		t.Errorf("flag off: Ollama called %d times", n)
+	}
+
+	on := newFlaggedAPI(t, "nl_query:\n  enabled: true\n  tenants: [tester]\n")
+	on.ollama.Set(ollamatest.WithResponse("age gt 20"))
+	var young, old Student
+	on.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 19, Email: "ada@example.com"}, http.StatusCreated, &young)
+	on.decode(t, "POST", "/v1/students", Student{Name: "Alan", Age: 23, Email: "alan@example.com"}, http.StatusCreated, &old)
+
+	var result StudentQueryResult
+	on.decode(t, "POST", "/v1/students/query", question, http.StatusOK, &result)
+	if result.Filter != "age gt 20" || len(result.Students) != 1 || result.Students[0].ID != old.ID {
+		t.Errorf("query gave %+v, want the filter and only student %d", result, old.ID)
+	}
+
+	// Callers outside the flag's tenants do not see the route.
+	resp, err := http.Post(on.URL+"/v1/students/query", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("other tenant: status %d, want 404", resp.StatusCode)
+	}
+
+	on.ollama.Set(ollamatest.WithResponse("age is big"))
+	if resp, data := on.do(t, "POST", "/v1/students/query", question); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("invalid filter: status %d, want 422: %s", resp.StatusCode, data)
+	}
+}
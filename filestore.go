@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileRecord is one line of the file store's append-only log.
+type fileRecord[T any] struct {
+	Op   string `json:"op"`
+	ID   int    `json:"id"`
+	Item *T     `json:"item,omitempty"`
+}
+
+// fileStore serves reads from memory and persists every write to an
+// append-only JSON lines log. Writes are batched: they are queued and a
+// background flusher writes and fsyncs them together every flush
+// interval, or sooner once a batch fills, so bulk imports do not pay one
+// fsync per row. A crash loses at most the writes of one interval.
+type fileStore[T entity[T]] struct {
+	*memoryStore[T]
+
+	mu        sync.Mutex // orders memory updates with their log records
+	file      *os.File
+	pending   []fileRecord[T]
+	batchSize int
+	full      chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	// closeErr is the error of the last flush, which Close reports.
+	closeErr error
+}
+
+// newFileStore replays the log at path and starts the flusher.
+func newFileStore[T entity[T]](path string, interval time.Duration, batchSize int) (*fileStore[T], error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileStore[T]{
+		memoryStore: newMemoryStore[T](),
+		file:        f,
+		batchSize:   batchSize,
+		full:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	go s.flushLoop(interval)
+	return s, nil
+}
+
+// replay loads the log into memory. Records can be of any length. A last
+// line without its newline is a record torn by a crash in the middle of a
+// write; it is cut from the file, so the next write starts a line of its
+// own.
+func (s *fileStore[T]) replay() error {
+	r := bufio.NewReader(s.file)
+	var offset int64
+	maxID, records := 0, 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				slog.Warn("dropping partial record at end of store file", "file", s.file.Name(), "offset", offset, "bytes", len(line))
+				if err := s.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		records++
+		var rec fileRecord[T]
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		switch rec.Op {
+		case "put":
+			if rec.Item == nil {
+				return errors.New("file store: put record without item")
+			}
+			s.items[rec.ID] = *rec.Item
+		case "delete":
+			delete(s.items, rec.ID)
+		}
+		maxID = max(maxID, rec.ID)
+	}
+	s.nextID.Store(int64(maxID))
+	// Every write appends one record, so the record count keeps Version
+	// increasing across restarts.
+	s.version.Store(uint64(records))
+	return nil
+}
+
+func (s *fileStore[T]) enqueue(rec fileRecord[T]) {
+	s.pending = append(s.pending, rec)
+	if len(s.pending) >= s.batchSize {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *fileStore[T]) Insert(ctx context.Context, v T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v = s.memoryStore.Insert(ctx, v)
+	s.enqueue(fileRecord[T]{Op: "put", ID: v.entityID(), Item: &v})
+	return v
+}
+
+func (s *fileStore[T]) Replace(ctx context.Context, id int, v T) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.memoryStore.Replace(ctx, id, v)
+	if ok {
+		v = v.withID(id)
+		s.enqueue(fileRecord[T]{Op: "put", ID: id, Item: &v})
+	}
+	return before, ok
+}
+
+func (s *fileStore[T]) Remove(ctx context.Context, id int) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.memoryStore.Remove(ctx, id)
+	if ok {
+		s.enqueue(fileRecord[T]{Op: "delete", ID: id})
+	}
+	return before, ok
+}
+
+func (s *fileStore[T]) flushLoop(interval time.Duration) {
+	defer close(s.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.full:
+		case <-s.done:
+			s.closeErr = s.flush()
+			return
+		}
+		s.flush()
+	}
+}
+
+// flush writes the queued records in one go and syncs the file once. A
+// batch that cannot be written is put back at the head of the queue, so
+// the next flush retries it before the writes made since.
+func (s *fileStore[T]) flush() error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	err := s.write(batch)
+	if err == nil {
+		return nil
+	}
+	slog.Error("failed to write store batch", "records", len(batch), "error", err)
+	s.mu.Lock()
+	s.pending = append(batch, s.pending...)
+	s.mu.Unlock()
+	return err
+}
+
+// write appends batch to the log and syncs it. On failure, whatever part
+// of the batch reached the file is cut off again, so a retry does not
+// follow a torn record.
+func (s *fileStore[T]) write(batch []fileRecord[T]) error {
+	start, err := s.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(s.file)
+	enc := json.NewEncoder(w)
+	for _, rec := range batch {
+		if err := enc.Encode(rec); err != nil {
+			slog.Error("failed to encode store record", "error", err)
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if terr := s.file.Truncate(start); terr != nil {
+			err = errors.Join(err, terr)
+		}
+	}
+	return err
+}
+
+// Close flushes outstanding writes and closes the log. It reports writes
+// the last flush could not make, which are then lost.
+func (s *fileStore[T]) Close() error {
+	close(s.done)
+	<-s.stopped
+	return errors.Join(s.closeErr, s.file.Close())
+}
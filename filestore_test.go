@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestFileStore(t *testing.T, path string) *fileStore[Student] {
+	t.Helper()
+	s, err := newFileStore[Student](path, time.Hour, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileStoreDropsTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.jsonl")
+	long := strings.Repeat("x", 100<<10)
+	log := `{"op":"put","id":1,"item":{"id":1,"name":"` + long + `","age":20,"email":"a@example.com"}}` + "\n" +
+		`{"op":"put","id":2,"item":{"id":2,"na`
+	if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := openTestFileStore(t, path)
+	ctx := context.Background()
+	if got, ok := s.Find(ctx, 1); !ok || got.Name != long {
+		t.Fatalf("record longer than 64 KB not replayed: found %v", ok)
+	}
+	if _, ok := s.Find(ctx, 2); ok {
+		t.Fatal("torn record replayed")
+	}
+	added := s.Insert(ctx, Student{Name: "Ada", Age: 20, Email: "ada@example.com"})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestFileStore(t, path)
+	defer s.Close()
+	if got, ok := s.Find(ctx, added.ID); !ok || got.Name != "Ada" {
+		t.Errorf("record written after the torn one not replayed: %+v, %v", got, ok)
+	}
+}
+
+func TestFileStoreKeepsBatchThatFailsToWrite(t *testing.T) {
+	s := openTestFileStore(t, filepath.Join(t.TempDir(), "students.jsonl"))
+	s.Insert(context.Background(), Student{Name: "Ada", Age: 20, Email: "ada@example.com"})
+	s.file.Close()
+
+	if err := s.flush(); err == nil {
+		t.Fatal("flush to a closed file succeeded")
+	}
+	s.Insert(context.Background(), Student{Name: "Alan", Age: 21, Email: "alan@example.com"})
+	if len(s.pending) != 2 || s.pending[0].Item.Name != "Ada" {
+		t.Errorf("pending after a failed flush = %+v, want the failed batch first", s.pending)
+	}
+	if err := s.Close(); err == nil {
+		t.Error("Close did not report the writes it could not make")
+	}
+}
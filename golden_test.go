@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenTranscript runs a fixed series of requests against a server with
+// fixed sources and returns each response's status, request ID and body.
+func goldenTranscript(t *testing.T) []byte {
+	clock := NewFakeClock(time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC))
+	api := newTestAPI(t, nil, WithClock(clock), WithIDs(&SequentialIDs{}), WithRandom(NewSeededRandom(1)))
+
+	var out bytes.Buffer
+	step := func(method, path string, body any) {
+		resp, data := api.do(t, method, path, body)
+		fmt.Fprintf(&out, "%s %s\n%d %s\n%s\n", method, path, resp.StatusCode, resp.Header.Get("X-Request-ID"), data)
+	}
+	step("POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"})
+	clock.Advance(time.Hour)
+	step("PUT", "/v1/students/1", Student{Name: "Ada Lovelace", Age: 21, Email: "ada@example.com"})
+	step("GET", "/v1/students?envelope=true", nil)
+	step("POST", "/v1/students", Student{Age: -1})
+	step("POST", "/v1/webhooks", Webhook{URL: "https://hooks.example.com/studengo", Events: []string{eventStudentDeleted}})
+	return out.Bytes()
+}
+
+func TestGoldenTranscript(t *testing.T) {
+	got := goldenTranscript(t)
+	if again := goldenTranscript(t); !bytes.Equal(got, again) {
+		t.Fatalf("two runs differ:\n%s\n---\n%s", got, again)
+	}
+
+	path := filepath.Join("testdata", "transcript.golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to write it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("transcript differs from %s (run go test -update after a deliberate change):\n%s", path, got)
+	}
+}
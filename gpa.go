@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TermGPA is the grade point average of one term.
+type TermGPA struct {
+	Term    string  `json:"term"`
+	GPA     float64 `json:"gpa"`
+	Credits int     `json:"credits"`
+}
+
+// GPAReport is a student's credit-weighted grade point average overall
+// and per term. GPA is null until a graded course counts towards it.
+type GPAReport struct {
+	GPA     *float64  `json:"gpa"`
+	Credits int       `json:"credits"`
+	Terms   []TermGPA `json:"terms"`
+}
+
+// gradePoints converts a grade to points on the 4.0 scale. Pass/fail
+// grades do not count towards the GPA.
+func gradePoints(g Grade) (float64, bool) {
+	switch g.Scale {
+	case scaleLetter:
+		p, ok := letterPoints[g.Value]
+		return p, ok
+	case scalePercent:
+		n, err := strconv.ParseFloat(g.Value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return letterPoints[percentToLetter(n)], true
+	}
+	return 0, false
+}
+
+// percentToLetter applies the common 10-point scale with +/- bands.
+func percentToLetter(n float64) string {
+	switch {
+	case n >= 93:
+		return "A"
+	case n >= 90:
+		return "A-"
+	case n >= 87:
+		return "B+"
+	case n >= 83:
+		return "B"
+	case n >= 80:
+		return "B-"
+	case n >= 77:
+		return "C+"
+	case n >= 73:
+		return "C"
+	case n >= 70:
+		return "C-"
+	case n >= 67:
+		return "D+"
+	case n >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+func roundGPA(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// computeGPA weights each counted grade by the credits of its course.
+func computeGPA(ctx context.Context, studentID int) GPAReport {
+	type sums struct {
+		points  float64
+		credits int
+	}
+	overall := sums{}
+	terms := map[string]*sums{}
+
+	for _, g := range studentGrades(ctx, studentID, "") {
+		points, ok := gradePoints(g)
+		if !ok {
+			continue
+		}
+		course, ok := courseStore.Find(ctx, g.CourseID)
+		if !ok {
+			continue
+		}
+		t, ok := terms[g.Term]
+		if !ok {
+			t = &sums{}
+			terms[g.Term] = t
+		}
+		t.points += points * float64(course.Credits)
+		t.credits += course.Credits
+		overall.points += points * float64(course.Credits)
+		overall.credits += course.Credits
+	}
+
+	report := GPAReport{Credits: overall.credits, Terms: []TermGPA{}}
+	if overall.credits > 0 {
+		gpa := roundGPA(overall.points / float64(overall.credits))
+		report.GPA = &gpa
+	}
+	for term, t := range terms {
+		report.Terms = append(report.Terms, TermGPA{Term: term, GPA: roundGPA(t.points / float64(t.credits)), Credits: t.credits})
+	}
+	slices.SortFunc(report.Terms, func(a, b TermGPA) int { return termOrder(a.Term) - termOrder(b.Term) })
+	return report
+}
+
+// termOrder sorts terms such as 2026-FALL chronologically.
+func termOrder(term string) int {
+	year, season, _ := strings.Cut(term, "-")
+	y, _ := strconv.Atoi(year)
+	return y*10 + slices.Index([]string{"WINTER", "SPRING", "SUMMER", "FALL"}, season)
+}
+
+// expands reports whether ?expand= asks for the named relation, as in
+// ?expand=gpa or ?expand=gpa,enrollments.
+func expands(r *http.Request, name string) bool {
+	for _, v := range r.URL.Query()["expand"] {
+		if slices.Contains(strings.Split(v, ","), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func getStudentGPA(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, computeGPA(r.Context(), id))
+}
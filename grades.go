@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Grade is a result recorded for one enrollment in one term.
+type Grade struct {
+	ID           int       `json:"id"`
+	EnrollmentID int       `json:"enrollment_id"`
+	StudentID    int       `json:"student_id"`
+	CourseID     int       `json:"course_id"`
+	Term         string    `json:"term" validate:"term"`
+	Scale        string    `json:"scale" validate:"enum=grade_scale"`
+	Value        string    `json:"value"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+func (g Grade) entityID() int { return g.ID }
+
+func (g Grade) withID(id int) Grade {
+	g.ID = id
+	return g
+}
+
+var gradeStore Repository[Grade] = newCollection[Grade]("grades")
+
+// Grading scales a grade may be recorded on.
+const (
+	scaleLetter   = "letter"
+	scalePercent  = "percent"
+	scalePassFail = "pass_fail"
+)
+
+// letterPoints maps letter grades to grade points on a 4.0 scale.
+var letterPoints = map[string]float64{
+	"A": 4.0, "A-": 3.7,
+	"B+": 3.3, "B": 3.0, "B-": 2.7,
+	"C+": 2.3, "C": 2.0, "C-": 1.7,
+	"D+": 1.3, "D": 1.0,
+	"F": 0,
+}
+
+// termPattern matches terms such as 2026-FALL.
+var termPattern = regexp.MustCompile(`^\d{4}-(SPRING|SUMMER|FALL|WINTER)$`)
+
+// validateGrade lists every problem with a grade submitted for recording.
+func validateGrade(g Grade) []FieldError {
+	errs := checkFields(g)
+	switch g.Scale {
+	case scaleLetter:
+		if _, ok := letterPoints[g.Value]; !ok {
+			errs = append(errs, FieldError{Field: "value", Message: "must be a letter grade from A to F"})
+		}
+	case scalePercent:
+		if n, err := strconv.ParseFloat(g.Value, 64); err != nil || n < 0 || n > 100 {
+			errs = append(errs, FieldError{Field: "value", Message: "must be a number between 0 and 100"})
+		}
+	case scalePassFail:
+		if g.Value != "P" && g.Value != "F" {
+			errs = append(errs, FieldError{Field: "value", Message: "must be P or F"})
+		}
+	}
+	return errs
+}
+
+// recordGrade stores a grade for one of the student's enrollments.
+func recordGrade(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	enrollmentID, err := strconv.Atoi(params["enrollment_id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid enrollment ID")
+		return
+	}
+
+	var grade Grade
+	if err := json.NewDecoder(r.Body).Decode(&grade); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid grade data", decodeErrors(err)...)
+		return
+	}
+	grade.Term = strings.ToUpper(strings.TrimSpace(grade.Term))
+	grade.Value = strings.ToUpper(strings.TrimSpace(grade.Value))
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	enrollment, exists := repoFind(r.Context(), enrollmentStore, enrollmentID)
+	if !exists || enrollment.StudentID != studentID {
+		writeProblem(w, r, http.StatusNotFound, "Enrollment not found")
+		return
+	}
+	// A grade belongs to the term of its enrollment.
+	if grade.Term == "" {
+		grade.Term = enrollment.Term
+	}
+	if errs := validateGrade(grade); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid grade data", errs...)
+		return
+	}
+	if enrollment.Term != "" && grade.Term != enrollment.Term {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Grade term does not match the enrollment", FieldError{Field: "term", Message: "must be " + enrollment.Term})
+		return
+	}
+	if _, ok := findTerm(r.Context(), grade.Term); !ok {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Term not found", FieldError{Field: "term", Message: "does not exist"})
+		return
+	}
+	for _, g := range gradeStore.List(r.Context()) {
+		if g.EnrollmentID == enrollmentID && g.Term == grade.Term {
+			writeProblem(w, r, http.StatusConflict, "A grade is already recorded for this enrollment in "+grade.Term)
+			return
+		}
+	}
+
+	grade.EnrollmentID = enrollment.ID
+	grade.StudentID = enrollment.StudentID
+	grade.CourseID = enrollment.CourseID
+	grade.RecordedAt = clock.Now().UTC()
+	grade = repoInsert(r.Context(), gradeStore, grade)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(grade)
+}
+
+// studentGrades returns a student's grades ordered by ID, optionally
+// limited to one term.
+func studentGrades(ctx context.Context, studentID int, term string) []Grade {
+	list := []Grade{}
+	for _, g := range repoList(ctx, gradeStore) {
+		if g.StudentID == studentID && (term == "" || g.Term == term) {
+			list = append(list, g)
+		}
+	}
+	return list
+}
+
+// getStudentGrades returns the grade history of a student, optionally filtered by term.
+func getStudentGrades(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+	writeJSONArray(w, studentGrades(r.Context(), studentID, term))
+}
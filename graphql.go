@@ -0,0 +1,1399 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file holds a small GraphQL implementation: a parser for executable
+// documents and an executor over the object types in graphqlschema.go.
+// It supports queries and mutations, variables, aliases, fragments,
+// @skip/@include, __typename and introspection, and validates documents
+// before running them. The schema is also published as SDL at
+// /graphql/schema.
+
+// gqlMaxDepth bounds how deeply selections may nest, so a single request
+// cannot walk student → enrollments → student → … without end.
+const gqlMaxDepth = 12
+
+// gqlMaxQueryBytes bounds the size of a request body.
+const gqlMaxQueryBytes = 64 << 10
+
+type gqlError struct {
+	Message    string         `json:"message"`
+	Locations  []gqlLocation  `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+type gqlLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *gqlError) Error() string { return e.Message }
+
+// gqlErrorList is the errors of a document that fails validation.
+type gqlErrorList []*gqlError
+
+func (l gqlErrorList) Error() string { return l[0].Message }
+
+// Lexer
+
+type gqlTokenKind int
+
+const (
+	tokEOF gqlTokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type gqlToken struct {
+	kind  gqlTokenKind
+	value string
+	pos   int
+}
+
+func lexGraphQL(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	i := 0
+	if strings.HasPrefix(src, "\uFEFF") {
+		i = len("\uFEFF")
+	}
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+			tokens = append(tokens, gqlToken{tokPunct, string(c), i})
+			i++
+		case c == '.':
+			if !strings.HasPrefix(src[i:], "...") {
+				return nil, syntaxError(src, i, "unexpected .")
+			}
+			tokens = append(tokens, gqlToken{tokPunct, "...", i})
+			i += 3
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{tokName, src[start:i], start})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := tokInt
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				kind = tokFloat
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = tokFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if src[start:i] == "-" {
+				return nil, syntaxError(src, start, "expected a number")
+			}
+			tokens = append(tokens, gqlToken{kind, src[start:i], start})
+		case c == '"':
+			value, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, gqlToken{tokString, value, i})
+			i = end
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, syntaxError(src, i, fmt.Sprintf("unexpected character %q", r))
+		}
+	}
+	return append(tokens, gqlToken{tokEOF, "", len(src)}), nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// lexString reads a quoted or block string starting at src[start] and
+// returns its value and the offset just past it.
+func lexString(src string, start int) (string, int, error) {
+	if strings.HasPrefix(src[start:], `"""`) {
+		end := strings.Index(src[start+3:], `"""`)
+		if end < 0 {
+			return "", 0, syntaxError(src, start, "unterminated string")
+		}
+		raw := src[start+3 : start+3+end]
+		return strings.TrimSpace(strings.ReplaceAll(raw, `\"""`, `"""`)), start + 6 + end, nil
+	}
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n', '\r':
+			return "", 0, syntaxError(src, i, "unterminated string")
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, syntaxError(src, start, "unterminated string")
+			}
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, syntaxError(src, i, "bad unicode escape")
+				}
+				n, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, syntaxError(src, i, "bad unicode escape")
+				}
+				b.WriteRune(rune(n))
+				i += 4
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, syntaxError(src, start, "unterminated string")
+}
+
+func locate(src string, pos int) gqlLocation {
+	line, col := 1, 1
+	for _, c := range src[:min(pos, len(src))] {
+		if c == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return gqlLocation{Line: line, Column: col}
+}
+
+func syntaxError(src string, pos int, msg string) *gqlError {
+	return &gqlError{Message: "Syntax error: " + msg, Locations: []gqlLocation{locate(src, pos)}}
+}
+
+// Syntax tree
+
+type gqlDocument struct {
+	src        string
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+type gqlOperation struct {
+	pos       int
+	kind      string // query or mutation
+	name      string
+	variables []gqlVariable
+	selection []gqlSelection
+}
+
+type gqlVariable struct {
+	pos      int
+	name     string
+	typ      string
+	fallback any
+	hasValue bool
+}
+
+type gqlFragment struct {
+	pos           int
+	typeCondition string
+	selection     []gqlSelection
+}
+
+// gqlSelection is a field, a fragment spread (spread set) or an inline
+// fragment (inline set).
+type gqlSelection struct {
+	pos        int
+	alias      string
+	name       string
+	args       map[string]any
+	directives []gqlDirective
+	selection  []gqlSelection
+
+	spread        string
+	inline        bool
+	typeCondition string
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]any
+}
+
+// gqlVarRef is a $variable in an argument value, resolved at execution.
+type gqlVarRef string
+
+// gqlEnum is an unquoted enum value.
+type gqlEnum string
+
+// Parser
+
+type gqlParser struct {
+	src    string
+	tokens []gqlToken
+	i      int
+}
+
+func parseGraphQL(src string) (*gqlDocument, error) {
+	tokens, err := lexGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{src: src, tokens: tokens}
+	return p.document()
+}
+
+func (p *gqlParser) peek() gqlToken { return p.tokens[p.i] }
+
+func (p *gqlParser) next() gqlToken {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *gqlParser) is(punct string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.value == punct
+}
+
+func (p *gqlParser) skip(punct string) bool {
+	if p.is(punct) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *gqlParser) expect(punct string) error {
+	if !p.skip(punct) {
+		return p.unexpected("expected " + punct)
+	}
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return "", p.unexpected("expected a name")
+	}
+	p.i++
+	return t.value, nil
+}
+
+func (p *gqlParser) unexpected(msg string) *gqlError {
+	t := p.peek()
+	found := t.value
+	if t.kind == tokEOF {
+		found = "end of document"
+	}
+	return syntaxError(p.src, t.pos, msg+", found "+found)
+}
+
+func (p *gqlParser) document() (*gqlDocument, error) {
+	doc := &gqlDocument{src: p.src, fragments: map[string]*gqlFragment{}}
+	for p.peek().kind != tokEOF {
+		t := p.peek()
+		switch {
+		case t.kind == tokPunct && t.value == "{":
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{pos: t.pos, kind: "query", selection: sel})
+		case t.kind == tokName && (t.value == "query" || t.value == "mutation" || t.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == tokName && t.value == "fragment":
+			p.next()
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if on, err := p.name(); err != nil || on != "on" {
+				return nil, p.unexpected("expected on")
+			}
+			typ, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[name]; dup {
+				return nil, &gqlError{Message: "There can be only one fragment named " + name}
+			}
+			doc.fragments[name] = &gqlFragment{pos: t.pos, typeCondition: typ, selection: sel}
+		default:
+			return nil, p.unexpected("expected an operation or fragment")
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &gqlError{Message: "The document contains no operation"}
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	t := p.next()
+	op := &gqlOperation{pos: t.pos, kind: t.value}
+	if p.peek().kind == tokName {
+		op.name = p.next().value
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			pos := p.peek().pos
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			typ, err := p.typeRef()
+			if err != nil {
+				return nil, err
+			}
+			v := gqlVariable{pos: pos, name: name, typ: typ}
+			if p.skip("=") {
+				if v.fallback, err = p.value(true); err != nil {
+					return nil, err
+				}
+				v.hasValue = true
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, v)
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selection = sel
+	return op, nil
+}
+
+func (p *gqlParser) typeRef() (string, error) {
+	var typ string
+	if p.skip("[") {
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.skip("!") {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sel []gqlSelection
+	for !p.skip("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, s)
+	}
+	if len(sel) == 0 {
+		return nil, p.unexpected("expected a selection")
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) selection() (gqlSelection, error) {
+	s := gqlSelection{pos: p.peek().pos}
+	var err error
+	if p.skip("...") {
+		if t := p.peek(); t.kind == tokName && t.value != "on" {
+			s.spread = p.next().value
+			s.directives, err = p.directives()
+			return s, err
+		}
+		s.inline = true
+		if t := p.peek(); t.kind == tokName && t.value == "on" {
+			p.next()
+			if s.typeCondition, err = p.name(); err != nil {
+				return s, err
+			}
+		}
+		if s.directives, err = p.directives(); err != nil {
+			return s, err
+		}
+		s.selection, err = p.selectionSet()
+		return s, err
+	}
+
+	if s.name, err = p.name(); err != nil {
+		return s, err
+	}
+	if p.skip(":") {
+		s.alias = s.name
+		if s.name, err = p.name(); err != nil {
+			return s, err
+		}
+	}
+	if s.args, err = p.arguments(); err != nil {
+		return s, err
+	}
+	if s.directives, err = p.directives(); err != nil {
+		return s, err
+	}
+	if p.is("{") {
+		s.selection, err = p.selectionSet()
+	}
+	return s, err
+}
+
+func (p *gqlParser) arguments() (map[string]any, error) {
+	args := map[string]any{}
+	if !p.skip("(") {
+		return args, nil
+	}
+	for !p.skip(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func (p *gqlParser) directives() ([]gqlDirective, error) {
+	var dirs []gqlDirective
+	for p.skip("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, gqlDirective{name: name, args: args})
+	}
+	return dirs, nil
+}
+
+// value parses an input value. Variables are not allowed in constant
+// positions such as defaults.
+func (p *gqlParser) value(constant bool) (any, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokInt:
+		p.next()
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, syntaxError(p.src, t.pos, "integer out of range")
+		}
+		return n, nil
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, syntaxError(p.src, t.pos, "bad number")
+		}
+		return f, nil
+	case tokString:
+		p.next()
+		return t.value, nil
+	case tokName:
+		p.next()
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return gqlEnum(t.value), nil
+	}
+	switch {
+	case p.skip("$"):
+		if constant {
+			return nil, syntaxError(p.src, t.pos, "variables are not allowed here")
+		}
+		name, err := p.name()
+		return gqlVarRef(name), err
+	case p.skip("["):
+		list := []any{}
+		for !p.skip("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case p.skip("{"):
+		obj := map[string]any{}
+		for !p.skip("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return nil, p.unexpected("expected a value")
+}
+
+// Validation
+
+// gqlArgDef is one argument, or input object field, of a schema type.
+type gqlArgDef struct {
+	name       string
+	typ        string
+	fallback   string // the default as written in the SDL
+	hasDefault bool
+}
+
+// gqlArgDefs parses argument definitions such as "id: ID!, limit: Int = 10".
+func gqlArgDefs(args string) []gqlArgDef {
+	var defs []gqlArgDef
+	for arg := range strings.SplitSeq(args, ", ") {
+		if arg == "" {
+			continue
+		}
+		name, typ, _ := strings.Cut(arg, ": ")
+		typ, fallback, hasDefault := strings.Cut(typ, " = ")
+		defs = append(defs, gqlArgDef{name: name, typ: typ, fallback: fallback, hasDefault: hasDefault})
+	}
+	return defs
+}
+
+// gqlNamedType strips the list and non-null wrappers from a type.
+func gqlNamedType(typ string) string {
+	return strings.Trim(typ, "[]!")
+}
+
+// gqlScalars are the scalar types of the schema.
+var gqlScalars = []string{"Boolean", "DateTime", "Float", "ID", "Int", "String"}
+
+func isGQLInputType(name string) bool {
+	_, isInput := gqlInputTypes[name]
+	return isInput || slices.Contains(gqlScalars, name)
+}
+
+// gqlDirectiveArgs are the arguments of the directives the executor applies.
+var gqlDirectiveArgs = map[string]string{"skip": "if: Boolean!", "include": "if: Boolean!"}
+
+// gqlVarUsage is a variable passed where a value of typ is expected.
+type gqlVarUsage struct {
+	pos        int
+	name       string
+	typ        string
+	hasDefault bool
+}
+
+// gqlScope is what a selection set refers to: the variables it uses and
+// the fragments it spreads, each directly.
+type gqlScope struct {
+	usages  []gqlVarUsage
+	spreads []string
+}
+
+type gqlValidator struct {
+	doc    *gqlDocument
+	errors []*gqlError
+	seen   map[gqlLocation]map[string]bool
+	scopes map[string]*gqlScope // of fragments, by name
+}
+
+// validateGraphQL checks a document against the schema before it runs,
+// so mistakes such as a missing argument or a misspelt variable are
+// reported instead of quietly resolving to null.
+func validateGraphQL(doc *gqlDocument) []*gqlError {
+	v := &gqlValidator{doc: doc, seen: map[gqlLocation]map[string]bool{}, scopes: map[string]*gqlScope{}}
+
+	names := map[string]bool{}
+	for _, op := range doc.operations {
+		switch {
+		case op.name == "" && len(doc.operations) > 1:
+			v.errorf(op.pos, "This anonymous operation must be the only defined operation")
+		case op.name != "" && names[op.name]:
+			v.errorf(op.pos, "There can be only one operation named %q", op.name)
+		}
+		names[op.name] = true
+	}
+
+	for _, name := range sortedKeys(doc.fragments) {
+		frag := doc.fragments[name]
+		scope := &gqlScope{}
+		v.scopes[name] = scope
+		if _, ok := gqlObjectType(frag.typeCondition); !ok {
+			v.errorf(frag.pos, "Unknown type %q", frag.typeCondition)
+			continue
+		}
+		v.selection(frag.typeCondition, frag.selection, scope)
+	}
+
+	used := map[string]bool{}
+	for _, op := range doc.operations {
+		root, ok := gqlRoots[op.kind]
+		if !ok {
+			continue
+		}
+		scope := &gqlScope{}
+		v.selection(root, op.selection, scope)
+		v.merge(root, op.selection, map[string]bool{})
+		v.variables(op, v.usages(scope, used, map[string]bool{}))
+	}
+	for _, name := range sortedKeys(doc.fragments) {
+		if !used[name] {
+			v.errorf(doc.fragments[name].pos, "Fragment %q is never used", name)
+		}
+	}
+	return v.errors
+}
+
+// errorf records an error once, however many operations reach it.
+func (v *gqlValidator) errorf(pos int, format string, args ...any) {
+	loc := locate(v.doc.src, pos)
+	msg := fmt.Sprintf(format, args...)
+	if v.seen[loc] == nil {
+		v.seen[loc] = map[string]bool{}
+	}
+	if v.seen[loc][msg] {
+		return
+	}
+	v.seen[loc][msg] = true
+	v.errors = append(v.errors, &gqlError{Message: msg, Locations: []gqlLocation{loc}})
+}
+
+// selection checks the fields, arguments and fragments of a selection
+// set on an object of type typeName.
+func (v *gqlValidator) selection(typeName string, sel []gqlSelection, scope *gqlScope) {
+	for _, s := range sel {
+		for _, d := range s.directives {
+			args, ok := gqlDirectiveArgs[d.name]
+			if !ok {
+				v.errorf(s.pos, "Unknown directive \"@%s\"", d.name)
+				continue
+			}
+			v.arguments("@"+d.name, gqlArgDefs(args), d.args, s.pos, scope)
+		}
+		switch {
+		case s.spread != "":
+			frag, ok := v.doc.fragments[s.spread]
+			if !ok {
+				v.errorf(s.pos, "Unknown fragment %q", s.spread)
+				continue
+			}
+			scope.spreads = append(scope.spreads, s.spread)
+			if _, known := gqlObjectType(frag.typeCondition); known && frag.typeCondition != typeName {
+				v.errorf(s.pos, "Fragment %q cannot be spread here as objects of type %q can never be of type %q", s.spread, typeName, frag.typeCondition)
+			}
+		case s.inline:
+			if s.typeCondition != "" && s.typeCondition != typeName {
+				if _, known := gqlObjectType(s.typeCondition); !known {
+					v.errorf(s.pos, "Unknown type %q", s.typeCondition)
+				} else {
+					v.errorf(s.pos, "Fragment cannot be spread here as objects of type %q can never be of type %q", typeName, s.typeCondition)
+				}
+				continue
+			}
+			v.selection(typeName, s.selection, scope)
+		default:
+			def, ok := gqlFieldDef(typeName, s.name)
+			if !ok {
+				v.errorf(s.pos, "Cannot query field %q on type %q", s.name, typeName)
+				continue
+			}
+			v.arguments(typeName+"."+s.name, gqlArgDefs(def.args), s.args, s.pos, scope)
+			named := gqlNamedType(def.typ)
+			if _, isObject := gqlObjectType(named); !isObject {
+				if s.selection != nil {
+					v.errorf(s.pos, "Field %q must not have a selection since type %q has no subfields", s.name, def.typ)
+				}
+			} else if s.selection == nil {
+				v.errorf(s.pos, "Field %q of type %q must have a selection of subfields", s.name, def.typ)
+			} else {
+				v.selection(named, s.selection, scope)
+			}
+		}
+	}
+}
+
+// arguments checks the arguments given to a field or directive.
+func (v *gqlValidator) arguments(owner string, defs []gqlArgDef, args map[string]any, pos int, scope *gqlScope) {
+	for _, name := range sortedKeys(args) {
+		i := slices.IndexFunc(defs, func(d gqlArgDef) bool { return d.name == name })
+		if i < 0 {
+			v.errorf(pos, "Unknown argument %q on %q", name, owner)
+			continue
+		}
+		v.value(defs[i].typ, args[name], defs[i].hasDefault, pos, scope)
+	}
+	for _, d := range defs {
+		if value, given := args[d.name]; strings.HasSuffix(d.typ, "!") && !d.hasDefault && (!given || value == nil) {
+			v.errorf(pos, "Argument %q of %q, of type %q, is required", d.name, owner, d.typ)
+		}
+	}
+}
+
+// value checks a literal against the type it is passed as, and records
+// the variables in it.
+func (v *gqlValidator) value(typ string, value any, hasDefault bool, pos int, scope *gqlScope) {
+	if ref, ok := value.(gqlVarRef); ok {
+		scope.usages = append(scope.usages, gqlVarUsage{pos: pos, name: string(ref), typ: typ, hasDefault: hasDefault})
+		return
+	}
+	base := strings.TrimSuffix(typ, "!")
+	if value == nil {
+		if base != typ {
+			v.errorf(pos, "Expected value of type %q, found null", typ)
+		}
+		return
+	}
+	if strings.HasPrefix(base, "[") {
+		elem := base[1 : len(base)-1]
+		if list, ok := value.([]any); ok {
+			for _, item := range list {
+				v.value(elem, item, false, pos, scope)
+			}
+		} else {
+			v.value(elem, value, false, pos, scope)
+		}
+		return
+	}
+	if fields, isInput := gqlInputTypes[base]; isInput {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.errorf(pos, "Expected value of type %q, found %s", typ, gqlLiteral(value))
+			return
+		}
+		v.arguments(base, gqlArgDefs(strings.Join(fields, ", ")), obj, pos, scope)
+		return
+	}
+	var ok bool
+	switch value.(type) {
+	case int64:
+		ok = base == "Int" || base == "Float" || base == "ID"
+	case float64:
+		ok = base == "Float"
+	case string:
+		ok = base == "String" || base == "ID" || base == "DateTime"
+	case bool:
+		ok = base == "Boolean"
+	}
+	if !ok {
+		v.errorf(pos, "Expected value of type %q, found %s", typ, gqlLiteral(value))
+	}
+}
+
+// gqlLiteral formats a value as it would be written in a document.
+func gqlLiteral(value any) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case gqlEnum:
+		return string(value)
+	case gqlVarRef:
+		return "$" + string(value)
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = gqlLiteral(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		fields := make([]string, 0, len(value))
+		for _, k := range sortedKeys(value) {
+			fields = append(fields, k+": "+gqlLiteral(value[k]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return fmt.Sprint(value)
+}
+
+// usages gathers the variables a scope uses, directly or through the
+// fragments it spreads, and marks those fragments used.
+func (v *gqlValidator) usages(scope *gqlScope, used, visiting map[string]bool) []gqlVarUsage {
+	usages := scope.usages
+	for _, name := range scope.spreads {
+		if visiting[name] {
+			v.errorf(v.doc.fragments[name].pos, "Cannot spread fragment %q within itself", name)
+			continue
+		}
+		used[name] = true
+		visiting[name] = true
+		usages = append(usages, v.usages(v.scopes[name], used, visiting)...)
+		delete(visiting, name)
+	}
+	return usages
+}
+
+// variables checks the variables an operation defines against those it uses.
+func (v *gqlValidator) variables(op *gqlOperation, usages []gqlVarUsage) {
+	opName := op.name
+	if opName == "" {
+		opName = "the operation"
+	} else {
+		opName = strconv.Quote(opName)
+	}
+	defined := map[string]gqlVariable{}
+	for _, d := range op.variables {
+		if _, dup := defined[d.name]; dup {
+			v.errorf(d.pos, "There can be only one variable named \"$%s\"", d.name)
+			continue
+		}
+		defined[d.name] = d
+		if !isGQLInputType(gqlNamedType(d.typ)) {
+			v.errorf(d.pos, "Variable \"$%s\" cannot be of non-input type %q", d.name, d.typ)
+		} else if d.hasValue {
+			v.value(d.typ, d.fallback, false, d.pos, &gqlScope{})
+		}
+	}
+
+	used := map[string]bool{}
+	for _, u := range usages {
+		used[u.name] = true
+		d, ok := defined[u.name]
+		if !ok {
+			v.errorf(u.pos, "Variable \"$%s\" is not defined by %s", u.name, opName)
+			continue
+		}
+		typ := d.typ
+		// A nullable variable with a default fills a non-null position.
+		if (d.hasValue && d.fallback != nil || u.hasDefault) && !strings.HasSuffix(typ, "!") {
+			typ += "!"
+		}
+		if !gqlTypeFits(typ, u.typ) {
+			v.errorf(u.pos, "Variable \"$%s\" of type %q used in position expecting type %q", u.name, d.typ, u.typ)
+		}
+	}
+	for _, d := range op.variables {
+		if !used[d.name] {
+			v.errorf(d.pos, "Variable \"$%s\" is never used in %s", d.name, opName)
+		}
+	}
+}
+
+// gqlTypeFits reports whether a value of type typ may be passed where
+// want is expected.
+func gqlTypeFits(typ, want string) bool {
+	if strings.HasSuffix(want, "!") {
+		return strings.HasSuffix(typ, "!") && gqlTypeFits(typ[:len(typ)-1], want[:len(want)-1])
+	}
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(want, "[") {
+		return strings.HasPrefix(typ, "[") && gqlTypeFits(typ[1:len(typ)-1], want[1:len(want)-1])
+	}
+	return typ == want
+}
+
+// merge checks that the fields sharing a response key, however they are
+// spread in, are the same field with the same arguments, so none of them
+// is dropped, and that their selections merge in turn.
+func (v *gqlValidator) merge(typeName string, sel []gqlSelection, visiting map[string]bool) {
+	var keys []string
+	byKey := map[string][]gqlSelection{}
+	v.responseFields(typeName, sel, visiting, &keys, byKey)
+	for _, key := range keys {
+		fields := byKey[key]
+		first := fields[0]
+		var merged []gqlSelection
+		for _, f := range fields {
+			switch {
+			case f.name != first.name:
+				v.errorf(f.pos, "Fields %q conflict because %q and %q are different fields; use different aliases to fetch both", key, first.name, f.name)
+			case !reflect.DeepEqual(f.args, first.args):
+				v.errorf(f.pos, "Fields %q conflict because they have differing arguments; use different aliases to fetch both", key)
+			}
+			merged = append(merged, f.selection...)
+		}
+		if def, ok := gqlFieldDef(typeName, first.name); ok && merged != nil {
+			v.merge(gqlNamedType(def.typ), merged, visiting)
+		}
+	}
+}
+
+// responseFields flattens fragments into the fields of a selection set,
+// grouped by response key in document order.
+func (v *gqlValidator) responseFields(typeName string, sel []gqlSelection, visiting map[string]bool, keys *[]string, byKey map[string][]gqlSelection) {
+	for _, s := range sel {
+		switch {
+		case s.spread != "":
+			frag, ok := v.doc.fragments[s.spread]
+			if !ok || visiting[s.spread] || frag.typeCondition != typeName {
+				continue
+			}
+			visiting[s.spread] = true
+			v.responseFields(typeName, frag.selection, visiting, keys, byKey)
+			delete(visiting, s.spread)
+		case s.inline:
+			if s.typeCondition == "" || s.typeCondition == typeName {
+				v.responseFields(typeName, s.selection, visiting, keys, byKey)
+			}
+		default:
+			key := cmp.Or(s.alias, s.name)
+			if _, seen := byKey[key]; !seen {
+				*keys = append(*keys, key)
+			}
+			byKey[key] = append(byKey[key], s)
+		}
+	}
+}
+
+// Execution
+
+// gqlArgs are the arguments of one field with variables substituted.
+type gqlArgs map[string]any
+
+// int reads an Int or ID argument. IDs may be sent as strings.
+func (a gqlArgs) int(name string) (int, bool, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		return int(v), true, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true, nil
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("argument %s must be an integer", name)
+}
+
+func (a gqlArgs) string(name string) (string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case gqlEnum:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("argument %s must be a string", name)
+}
+
+type gqlExecutor struct {
+	r         *http.Request
+	doc       *gqlDocument
+	variables map[string]any
+	errors    []*gqlError
+}
+
+// errNonNull marks a null that has to propagate to the parent because the
+// field that produced it is non-null.
+var errNonNull = errors.New("non-null field resolved to null")
+
+func executeGraphQL(r *http.Request, query, operationName string, variables map[string]any) (any, []*gqlError, error) {
+	doc, err := parseGraphQL(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if errs := validateGraphQL(doc); len(errs) > 0 {
+		return nil, nil, gqlErrorList(errs)
+	}
+	var op *gqlOperation
+	for _, o := range doc.operations {
+		if o.name == operationName || operationName == "" && len(doc.operations) == 1 {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		if operationName == "" {
+			return nil, nil, &gqlError{Message: "operationName is required when the document has several operations"}
+		}
+		return nil, nil, &gqlError{Message: "Unknown operation " + operationName}
+	}
+
+	root, ok := gqlRoots[op.kind]
+	if !ok {
+		return nil, nil, &gqlError{Message: "Operation type " + op.kind + " is not supported; use /ws for live updates"}
+	}
+
+	ex := &gqlExecutor{r: r, doc: doc, variables: map[string]any{}}
+	for _, v := range op.variables {
+		value, given := variables[v.name]
+		if !given && v.hasValue {
+			value, given = v.fallback, true
+		}
+		if (!given || value == nil) && strings.HasSuffix(v.typ, "!") {
+			return nil, nil, &gqlError{Message: "Variable $" + v.name + " of type " + v.typ + " is required"}
+		}
+		if given {
+			ex.variables[v.name] = normalizeJSONNumber(value)
+		}
+	}
+	if err := ex.checkDepth(op.selection, 1, map[string]bool{}); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := ex.selectObject(root, nil, op.selection, nil)
+	if err != nil {
+		return nil, ex.errors, nil
+	}
+	return data, ex.errors, nil
+}
+
+// normalizeJSONNumber turns the numbers of decoded variables into the
+// int64 and float64 values literals parse to.
+func normalizeJSONNumber(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = normalizeJSONNumber(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = normalizeJSONNumber(v[k])
+		}
+	}
+	return v
+}
+
+func (ex *gqlExecutor) checkDepth(sel []gqlSelection, depth int, visiting map[string]bool) error {
+	if depth > gqlMaxDepth {
+		return &gqlError{Message: "Query is nested more than " + strconv.Itoa(gqlMaxDepth) + " levels deep"}
+	}
+	for _, s := range sel {
+		switch {
+		case s.spread != "":
+			frag, ok := ex.doc.fragments[s.spread]
+			if !ok {
+				return &gqlError{Message: "Unknown fragment " + s.spread}
+			}
+			if visiting[s.spread] {
+				return &gqlError{Message: "Fragment " + s.spread + " spreads itself"}
+			}
+			visiting[s.spread] = true
+			if err := ex.checkDepth(frag.selection, depth, visiting); err != nil {
+				return err
+			}
+			delete(visiting, s.spread)
+		case s.inline:
+			if err := ex.checkDepth(s.selection, depth, visiting); err != nil {
+				return err
+			}
+		case s.selection != nil:
+			if err := ex.checkDepth(s.selection, depth+1, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveValue substitutes variables in an argument value.
+func (ex *gqlExecutor) resolveValue(v any) any {
+	switch v := v.(type) {
+	case gqlVarRef:
+		return ex.variables[string(v)]
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = ex.resolveValue(v[i])
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k := range v {
+			out[k] = ex.resolveValue(v[k])
+		}
+		return out
+	}
+	return v
+}
+
+// included applies @skip and @include.
+func (ex *gqlExecutor) included(dirs []gqlDirective) bool {
+	for _, d := range dirs {
+		cond, _ := ex.resolveValue(d.args["if"]).(bool)
+		if d.name == "skip" && cond || d.name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// collectFields flattens fragments into the fields to resolve on an
+// object of type typeName, in document order, with the selections of
+// fields sharing a response key merged into the first of them.
+func (ex *gqlExecutor) collectFields(typeName string, sel []gqlSelection) []gqlSelection {
+	var fields []gqlSelection
+	ex.flattenFields(typeName, sel, &fields)
+	var merged []gqlSelection
+	index := map[string]int{}
+	for _, f := range fields {
+		key := cmp.Or(f.alias, f.name)
+		if i, seen := index[key]; seen {
+			merged[i].selection = append(slices.Clip(merged[i].selection), f.selection...)
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, f)
+	}
+	return merged
+}
+
+func (ex *gqlExecutor) flattenFields(typeName string, sel []gqlSelection, out *[]gqlSelection) {
+	for _, s := range sel {
+		if !ex.included(s.directives) {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			frag := ex.doc.fragments[s.spread]
+			if frag.typeCondition == typeName {
+				ex.flattenFields(typeName, frag.selection, out)
+			}
+		case s.inline:
+			if s.typeCondition == "" || s.typeCondition == typeName {
+				ex.flattenFields(typeName, s.selection, out)
+			}
+		default:
+			*out = append(*out, s)
+		}
+	}
+}
+
+// gqlObjectResult keeps the keys of a selection in document order, as
+// clients expect.
+type gqlObjectResult struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *gqlObjectResult) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+func (ex *gqlExecutor) selectObject(typeName string, src any, sel []gqlSelection, path []any) (any, error) {
+	result := &gqlObjectResult{values: map[string]any{}}
+	for _, f := range ex.collectFields(typeName, sel) {
+		key := cmp.Or(f.alias, f.name)
+		fieldPath := append(append([]any{}, path...), key)
+
+		if f.name == "__typename" {
+			result.keys = append(result.keys, key)
+			result.values[key] = typeName
+			continue
+		}
+		def, ok := gqlFieldDef(typeName, f.name)
+		if !ok {
+			ex.fail(f, fieldPath, errors.New("Cannot query field "+f.name+" on type "+typeName))
+			return nil, errNonNull
+		}
+		args := gqlArgs{}
+		for name, v := range f.args {
+			args[name] = ex.resolveValue(v)
+		}
+		value, err := def.resolve(ex.r, src, args)
+		if err == nil {
+			value, err = ex.complete(def.typ, value, f, fieldPath)
+		} else {
+			ex.fail(f, fieldPath, err)
+		}
+		if err != nil {
+			if strings.HasSuffix(def.typ, "!") {
+				return nil, errNonNull
+			}
+			value = nil
+		}
+		result.keys = append(result.keys, key)
+		result.values[key] = value
+	}
+	return result, nil
+}
+
+// complete shapes a resolved value according to its GraphQL type.
+func (ex *gqlExecutor) complete(typ string, value any, f gqlSelection, path []any) (any, error) {
+	nonNull := strings.HasSuffix(typ, "!")
+	typ = strings.TrimSuffix(typ, "!")
+	if isNil(value) {
+		if nonNull {
+			ex.fail(f, path, errors.New("Cannot return null for non-nullable field"))
+			return nil, errNonNull
+		}
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer {
+		value = rv.Elem().Interface()
+	}
+
+	if strings.HasPrefix(typ, "[") {
+		elemType := typ[1 : len(typ)-1]
+		items, ok := value.([]any)
+		if !ok {
+			items = toAnySlice(value)
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			v, err := ex.complete(elemType, item, f, append(append([]any{}, path...), i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+
+	if _, isObject := gqlObjectType(typ); isObject {
+		if f.selection == nil {
+			ex.fail(f, path, errors.New("Field "+f.name+" of type "+typ+" must have a selection of subfields"))
+			return nil, errNonNull
+		}
+		return ex.selectObject(typ, value, f.selection, path)
+	}
+	if f.selection != nil {
+		ex.fail(f, path, errors.New("Field "+f.name+" of type "+typ+" cannot have a selection"))
+		return nil, errNonNull
+	}
+	return value, nil
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	// A nil slice is an empty list, as repoList returns for no records.
+	return false
+}
+
+// toAnySlice converts a typed slice such as []Student for completion.
+func toAnySlice(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []any{v}
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+// fail records a field error. A rejection by a REST handler carries its
+// status and field errors in the extensions.
+func (ex *gqlExecutor) fail(f gqlSelection, path []any, err error) {
+	e := &gqlError{Message: err.Error()}
+	var he *handlerError
+	if errors.As(err, &he) {
+		e.Extensions = map[string]any{"status": he.status}
+		if len(he.problem.Errors) > 0 {
+			e.Extensions["errors"] = he.problem.Errors
+		}
+	}
+	e.Path = path
+	e.Locations = []gqlLocation{locate(ex.doc.src, f.pos)}
+	ex.errors = append(ex.errors, e)
+}
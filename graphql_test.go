@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+// graphQL posts a query and returns the status and the decoded response.
+func (api *testAPI) graphQL(t *testing.T, query string, variables map[string]any) (int, graphQLResponse) {
+	t.Helper()
+	resp, data := api.do(t, "POST", "/graphql", graphQLRequest{Query: query, Variables: variables})
+	var out graphQLResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestGraphQLValidation(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, nil)
+
+	// Each document is rejected before it runs, with an error naming the mistake.
+	for query, want := range map[string]string{
+		`{ student { id } }`:                                                     `Argument "id" of "Query.student", of type "ID!", is required`,
+		`{ student(id: null) { id } }`:                                           `Expected value of type "ID!", found null`,
+		`{ students(first: -1) { id } }`:                                         `Unknown argument "first" on "Query.students"`,
+		`{ students(limit: "ten") { id } }`:                                      `Expected value of type "Int", found "ten"`,
+		`{ students { id } } { courses { id } }`:                                 `This anonymous operation must be the only defined operation`,
+		`query A { courses { id } } query A { terms { id } }`:                    `There can be only one operation named "A"`,
+		`{ s: student(id: 1) { id } s: course(id: 1) { id } }`:                   `Fields "s" conflict because "student" and "course" are different fields`,
+		`{ student(id: 1) { id } student(id: 2) { name } }`:                      `Fields "student" conflict because they have differing arguments`,
+		`{ student(id: 1) { ...N } } fragment N on Student { n: name n: email }`: `Fields "n" conflict`,
+		`{ student(id: $x) { id } }`:                                             `Variable "$x" is not defined by the operation`,
+		`query Q($id: ID) { student(id: $id) { id } }`:                           `Variable "$id" of type "ID" used in position expecting type "ID!"`,
+		`query Q($id: ID!, $unused: Int) { student(id: $id) { id } }`:            `Variable "$unused" is never used in "Q"`,
+		`query Q($s: Student) { students { id } }`:                               `Variable "$s" cannot be of non-input type "Student"`,
+		`{ students { id nickname } }`:                                           `Cannot query field "nickname" on type "Student"`,
+		`{ students }`:                                                           `Field "students" of type "[Student!]!" must have a selection of subfields`,
+		`{ students { name { first } } }`:                                        `Field "name" must not have a selection since type "String!" has no subfields`,
+		`{ students { ...C } } fragment C on Course { id }`:                      `Fragment "C" cannot be spread here as objects of type "Student" can never be of type "Course"`,
+		`{ students { id } } fragment F on Student { id }`:                       `Fragment "F" is never used`,
+		`{ students { ...F } } fragment F on Student { ...F }`:                   `Cannot spread fragment "F" within itself`,
+		`{ students { id @skip } }`:                                              `Argument "if" of "@skip", of type "Boolean!", is required`,
+		`mutation { createStudent(input: {name: "Alan", age: 20}) { id } }`:      `Argument "email" of "StudentInput", of type "String!", is required`,
+	} {
+		status, resp := api.graphQL(t, query, nil)
+		if status != http.StatusBadRequest || resp.Data != nil {
+			t.Errorf("%s: status %d, data %s, want 400 and no data", query, status, resp.Data)
+		}
+		if !hasGraphQLError(resp.Errors, want) {
+			t.Errorf("%s: errors %+v, want one containing %q", query, resp.Errors, want)
+		}
+	}
+}
+
+func hasGraphQLError(errs []gqlError, want string) bool {
+	for _, e := range errs {
+		if strings.Contains(e.Message, want) && len(e.Locations) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGraphQLValidDocuments(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, nil)
+
+	for query, want := range map[string]string{
+		// Fields sharing a response key merge their selections.
+		`{ student(id: 1) { name } student(id: 1) { age ...E } } fragment E on Student { email }`: `{"student":{"name":"Ada Lovelace","age":20,"email":"ada@example.com"}}`,
+		`query Q($id: ID!) { student(id: $id) { name } }`:                                         `{"student":{"name":"Ada Lovelace"}}`,
+		`query Q($id: ID = 1) { student(id: $id) { name } }`:                                      `{"student":{"name":"Ada Lovelace"}}`,
+		`query Q($skip: Boolean!) { students { id name @skip(if: $skip) } }`:                      `{"students":[{"id":1}]}`,
+		`{ students(limit: 5) { ... on Student { id } } }`:                                        `{"students":[{"id":1}]}`,
+	} {
+		status, resp := api.graphQL(t, query, map[string]any{"id": 1, "skip": true})
+		if status != http.StatusOK || len(resp.Errors) > 0 || string(resp.Data) != want {
+			t.Errorf("%s: status %d, data %s, errors %+v, want %s", query, status, resp.Data, resp.Errors, want)
+		}
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	status, resp := api.graphQL(t, `{
+		__schema { queryType { name } mutationType { name } subscriptionType { name } directives { name args { name } } }
+		__type(name: "StudentInput") { kind inputFields { name type { kind name ofType { name } } } }
+		missing: __type(name: "Nothing") { name }
+	}`, nil)
+	if status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("status %d, errors %+v", status, resp.Errors)
+	}
+	want := `{"__schema":{"queryType":{"name":"Query"},"mutationType":{"name":"Mutation"},"subscriptionType":null,` +
+		`"directives":[{"name":"include","args":[{"name":"if"}]},{"name":"skip","args":[{"name":"if"}]}]},` +
+		`"__type":{"kind":"INPUT_OBJECT","inputFields":[` +
+		`{"name":"name","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"String"}}},` +
+		`{"name":"age","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"Int"}}},` +
+		`{"name":"email","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"String"}}},` +
+		`{"name":"programId","type":{"kind":"SCALAR","name":"Int","ofType":null}}]},` +
+		`"missing":null}`
+	if string(resp.Data) != want {
+		t.Errorf("data = %s\nwant   %s", resp.Data, want)
+	}
+
+	// The query GraphiQL and most code generators send.
+	status, resp = api.graphQL(t, `query IntrospectionQuery {
+		__schema {
+			queryType { name } mutationType { name } subscriptionType { name }
+			types { ...FullType }
+			directives { name description locations args { ...InputValue } }
+		}
+	}
+	fragment FullType on __Type {
+		kind name description
+		fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+		inputFields { ...InputValue }
+		interfaces { ...TypeRef }
+		enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+		possibleTypes { ...TypeRef }
+	}
+	fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+	fragment TypeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }`, nil)
+	if status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("introspection query: status %d, errors %+v", status, resp.Errors)
+	}
+	var schema struct {
+		Schema struct {
+			Types []struct {
+				Kind   string
+				Name   string
+				Fields []struct {
+					Name string
+					Args []struct{ Name, DefaultValue string }
+					Type struct {
+						Kind   string
+						OfType struct{ Kind, Name string }
+					}
+				}
+			}
+		} `json:"__schema"`
+	}
+	if err := json.Unmarshal(resp.Data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, typ := range schema.Schema.Types {
+		if typ.Name == "Query" {
+			for _, f := range typ.Fields {
+				if strings.HasPrefix(f.Name, "__") {
+					t.Errorf("Query lists the meta field %s", f.Name)
+				}
+				if f.Name == "student" {
+					found = len(f.Args) == 1 && f.Args[0].Name == "id" && f.Type.OfType.Name == "" && f.Type.Kind == "OBJECT"
+				}
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Query.student(id: ID!): Student not described in %s", resp.Data)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Introspection describes the schema in gqlTypes and gqlInputTypes with
+// the __Schema and __Type objects of the GraphQL specification, so tools
+// such as GraphiQL and code generators can load it from /graphql.
+
+// gqlTypeInfo is a __Type: a named type, or a list or non-null wrapper
+// around ofType.
+type gqlTypeInfo struct {
+	kind        string
+	name        string
+	fields      []gqlFieldInfo
+	inputFields []gqlInputValueInfo
+	ofType      *gqlTypeInfo
+}
+
+// gqlFieldInfo is a __Field.
+type gqlFieldInfo struct {
+	name string
+	args []gqlInputValueInfo
+	typ  *gqlTypeInfo
+}
+
+// gqlInputValueInfo is an __InputValue: an argument or input field.
+type gqlInputValueInfo struct {
+	name     string
+	typ      *gqlTypeInfo
+	fallback any
+}
+
+// gqlDirectiveInfo is a __Directive.
+type gqlDirectiveInfo struct {
+	name      string
+	locations []string
+	args      []gqlInputValueInfo
+}
+
+// gqlSchemaInfo is the __Schema.
+type gqlSchemaInfo struct {
+	types      []*gqlTypeInfo
+	byName     map[string]*gqlTypeInfo
+	directives []gqlDirectiveInfo
+}
+
+// gqlMetaFields are the introspection fields of the query type. Like
+// __typename, they are not listed among its fields.
+var gqlMetaFields = map[string]gqlField{
+	"__schema": root("__Schema!", "", func(*http.Request, gqlArgs) (any, error) {
+		return gqlIntrospection(), nil
+	}),
+	"__type": root("__Type", "name: String!", func(_ *http.Request, a gqlArgs) (any, error) {
+		name, err := a.string("name")
+		t, ok := gqlIntrospection().byName[name]
+		return findOrNil(t, ok), err
+	}),
+}
+
+// includeDeprecated is accepted for the clients that send it; nothing in
+// the schema is deprecated.
+const gqlIncludeDeprecated = "includeDeprecated: Boolean = false"
+
+// gqlMetaTypes are the object types introspection results are made of.
+var gqlMetaTypes = map[string]map[string]gqlField{
+	"__Schema": {
+		"description":      prop("String", func(gqlSchemaInfo) any { return nil }),
+		"types":            prop("[__Type!]!", func(s gqlSchemaInfo) any { return s.types }),
+		"queryType":        prop("__Type!", func(s gqlSchemaInfo) any { return s.byName[gqlRoots["query"]] }),
+		"mutationType":     prop("__Type", func(s gqlSchemaInfo) any { return s.byName[gqlRoots["mutation"]] }),
+		"subscriptionType": prop("__Type", func(gqlSchemaInfo) any { return nil }),
+		"directives":       prop("[__Directive!]!", func(s gqlSchemaInfo) any { return s.directives }),
+	},
+
+	"__Type": {
+		"kind":        prop("String!", func(t gqlTypeInfo) any { return t.kind }),
+		"name":        prop("String", func(t gqlTypeInfo) any { return zeroAsNil(t.name) }),
+		"description": prop("String", func(gqlTypeInfo) any { return nil }),
+		"fields": edge("[__Field!]", gqlIncludeDeprecated, func(_ *http.Request, t gqlTypeInfo, _ gqlArgs) (any, error) {
+			if t.kind != "OBJECT" {
+				return nil, nil
+			}
+			return t.fields, nil
+		}),
+		"interfaces": prop("[__Type!]", func(t gqlTypeInfo) any {
+			if t.kind != "OBJECT" {
+				return nil
+			}
+			return []*gqlTypeInfo{}
+		}),
+		"possibleTypes": prop("[__Type!]", func(gqlTypeInfo) any { return nil }),
+		"enumValues": edge("[__EnumValue!]", gqlIncludeDeprecated, func(*http.Request, gqlTypeInfo, gqlArgs) (any, error) {
+			return nil, nil
+		}),
+		"inputFields": edge("[__InputValue!]", gqlIncludeDeprecated, func(_ *http.Request, t gqlTypeInfo, _ gqlArgs) (any, error) {
+			if t.kind != "INPUT_OBJECT" {
+				return nil, nil
+			}
+			return t.inputFields, nil
+		}),
+		"ofType":         prop("__Type", func(t gqlTypeInfo) any { return t.ofType }),
+		"specifiedByURL": prop("String", func(gqlTypeInfo) any { return nil }),
+	},
+
+	"__Field": {
+		"name":        prop("String!", func(f gqlFieldInfo) any { return f.name }),
+		"description": prop("String", func(gqlFieldInfo) any { return nil }),
+		"args": edge("[__InputValue!]!", gqlIncludeDeprecated, func(_ *http.Request, f gqlFieldInfo, _ gqlArgs) (any, error) {
+			return f.args, nil
+		}),
+		"type":              prop("__Type!", func(f gqlFieldInfo) any { return f.typ }),
+		"isDeprecated":      prop("Boolean!", func(gqlFieldInfo) any { return false }),
+		"deprecationReason": prop("String", func(gqlFieldInfo) any { return nil }),
+	},
+
+	"__InputValue": {
+		"name":              prop("String!", func(v gqlInputValueInfo) any { return v.name }),
+		"description":       prop("String", func(gqlInputValueInfo) any { return nil }),
+		"type":              prop("__Type!", func(v gqlInputValueInfo) any { return v.typ }),
+		"defaultValue":      prop("String", func(v gqlInputValueInfo) any { return v.fallback }),
+		"isDeprecated":      prop("Boolean!", func(gqlInputValueInfo) any { return false }),
+		"deprecationReason": prop("String", func(gqlInputValueInfo) any { return nil }),
+	},
+
+	// The schema has no enums; the type is here for the queries that ask.
+	"__EnumValue": {
+		"name":              prop("String!", func(string) any { return nil }),
+		"description":       prop("String", func(string) any { return nil }),
+		"isDeprecated":      prop("Boolean!", func(string) any { return false }),
+		"deprecationReason": prop("String", func(string) any { return nil }),
+	},
+
+	"__Directive": {
+		"name":        prop("String!", func(d gqlDirectiveInfo) any { return d.name }),
+		"description": prop("String", func(gqlDirectiveInfo) any { return nil }),
+		"locations":   prop("[String!]!", func(d gqlDirectiveInfo) any { return d.locations }),
+		"args": edge("[__InputValue!]!", gqlIncludeDeprecated, func(_ *http.Request, d gqlDirectiveInfo, _ gqlArgs) (any, error) {
+			return d.args, nil
+		}),
+		"isRepeatable": prop("Boolean!", func(gqlDirectiveInfo) any { return false }),
+	},
+}
+
+// gqlObjectType looks up an object type, the introspection ones included.
+func gqlObjectType(name string) (map[string]gqlField, bool) {
+	if t, ok := gqlTypes[name]; ok {
+		return t, true
+	}
+	t, ok := gqlMetaTypes[name]
+	return t, ok
+}
+
+// gqlFieldDef looks up a field of an object type, including __typename
+// and, on the query type, __schema and __type.
+func gqlFieldDef(typeName, name string) (gqlField, bool) {
+	if name == "__typename" {
+		return gqlField{typ: "String!"}, true
+	}
+	if f, ok := gqlMetaFields[name]; ok && typeName == gqlRoots["query"] {
+		return f, true
+	}
+	t, _ := gqlObjectType(typeName)
+	f, ok := t[name]
+	return f, ok
+}
+
+// gqlIntrospection builds the __Schema once; the schema does not change
+// while the server runs.
+var gqlIntrospection = sync.OnceValue(func() gqlSchemaInfo {
+	s := gqlSchemaInfo{byName: map[string]*gqlTypeInfo{}}
+	add := func(kind, name string) {
+		t := &gqlTypeInfo{kind: kind, name: name}
+		s.types = append(s.types, t)
+		s.byName[name] = t
+	}
+	for _, name := range gqlScalars {
+		add("SCALAR", name)
+	}
+	for _, name := range sortedKeys(gqlInputTypes) {
+		add("INPUT_OBJECT", name)
+	}
+	for _, name := range sortedKeys(gqlTypes) {
+		add("OBJECT", name)
+	}
+	for _, name := range sortedKeys(gqlMetaTypes) {
+		add("OBJECT", name)
+	}
+
+	// The types are all named before the fields refer to them.
+	values := func(defs []gqlArgDef) []gqlInputValueInfo {
+		list := []gqlInputValueInfo{}
+		for _, d := range defs {
+			v := gqlInputValueInfo{name: d.name, typ: s.typeRef(d.typ)}
+			if d.hasDefault {
+				v.fallback = d.fallback
+			}
+			list = append(list, v)
+		}
+		return list
+	}
+	for _, t := range s.types {
+		if fields, ok := gqlInputTypes[t.name]; ok {
+			t.inputFields = values(gqlArgDefs(strings.Join(fields, ", ")))
+		}
+		if fields, ok := gqlObjectType(t.name); ok {
+			t.fields = []gqlFieldInfo{}
+			for _, name := range sortedKeys(fields) {
+				t.fields = append(t.fields, gqlFieldInfo{name: name, args: values(gqlArgDefs(fields[name].args)), typ: s.typeRef(fields[name].typ)})
+			}
+		}
+	}
+	for _, name := range sortedKeys(gqlDirectiveArgs) {
+		s.directives = append(s.directives, gqlDirectiveInfo{
+			name:      name,
+			locations: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+			args:      values(gqlArgDefs(gqlDirectiveArgs[name])),
+		})
+	}
+	return s
+})
+
+// typeRef describes a type such as [Enrollment!]!.
+func (s gqlSchemaInfo) typeRef(typ string) *gqlTypeInfo {
+	if inner, ok := strings.CutSuffix(typ, "!"); ok {
+		return &gqlTypeInfo{kind: "NON_NULL", ofType: s.typeRef(inner)}
+	}
+	if strings.HasPrefix(typ, "[") {
+		return &gqlTypeInfo{kind: "LIST", ofType: s.typeRef(typ[1 : len(typ)-1])}
+	}
+	return s.byName[typ]
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gqlField is one field of an object type. typ is its GraphQL type such
+// as [Enrollment!]! and args its argument definitions for the SDL.
+type gqlField struct {
+	typ     string
+	args    string
+	resolve func(r *http.Request, src any, args gqlArgs) (any, error)
+}
+
+// prop exposes a plain value of T.
+func prop[T any](typ string, get func(T) any) gqlField {
+	return gqlField{typ: typ, resolve: func(_ *http.Request, src any, _ gqlArgs) (any, error) {
+		return get(src.(T)), nil
+	}}
+}
+
+// edge exposes a value of T that takes arguments or has to be looked up.
+func edge[T any](typ, args string, get func(*http.Request, T, gqlArgs) (any, error)) gqlField {
+	return gqlField{typ: typ, args: args, resolve: func(r *http.Request, src any, a gqlArgs) (any, error) {
+		return get(r, src.(T), a)
+	}}
+}
+
+// root is a top-level query or mutation field.
+func root(typ, args string, get func(*http.Request, gqlArgs) (any, error)) gqlField {
+	return gqlField{typ: typ, args: args, resolve: func(r *http.Request, _ any, a gqlArgs) (any, error) {
+		return get(r, a)
+	}}
+}
+
+var gqlRoots = map[string]string{"query": "Query", "mutation": "Mutation"}
+
+// gqlEnrollResult is what enroll returns: an enrollment, or a waitlist
+// place when the course is full.
+type gqlEnrollResult struct {
+	enrollment *Enrollment
+	waitlist   *WaitlistEntry
+}
+
+var gqlInputTypes = map[string][]string{
+	"StudentInput": {"name: String!", "age: Int!", "email: String!", "programId: Int"},
+}
+
+// gqlTypes are the object types of the schema. Mutations return nullable
+// types so one failing mutation does not discard the results of the
+// others in the same request.
+var gqlTypes = map[string]map[string]gqlField{
+	"Query": {
+		"student": root("Student", "id: ID!", func(r *http.Request, a gqlArgs) (any, error) {
+			id, _, err := a.int("id")
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := findStudent(r.Context(), id); ok {
+				return s, nil
+			}
+			return nil, nil
+		}),
+		"students": root("[Student!]!", "after: Int, limit: Int", func(r *http.Request, a gqlArgs) (any, error) {
+			after, _, err := a.int("after")
+			if err != nil {
+				return nil, err
+			}
+			limit, given, err := a.int("limit")
+			if err != nil {
+				return nil, err
+			}
+			if !given {
+				limit = cfg.DefaultPageSize
+			}
+			if limit < 1 || limit > cfg.MaxPageSize {
+				return nil, fmt.Errorf("argument limit must be between 1 and %d", cfg.MaxPageSize)
+			}
+			list := []Student{}
+			for _, s := range allStudents(r.Context()) {
+				if s.ID > after && len(list) < limit {
+					list = append(list, s)
+				}
+			}
+			return list, nil
+		}),
+		"course": root("Course", "id: ID!", func(r *http.Request, a gqlArgs) (any, error) {
+			id, _, err := a.int("id")
+			if err != nil {
+				return nil, err
+			}
+			return findOrNil(repoFind(r.Context(), courseStore, id)), nil
+		}),
+		"courses": root("[Course!]!", "", func(r *http.Request, _ gqlArgs) (any, error) {
+			return repoList(r.Context(), courseStore), nil
+		}),
+		"teacher": root("Teacher", "id: ID!", func(r *http.Request, a gqlArgs) (any, error) {
+			id, _, err := a.int("id")
+			if err != nil {
+				return nil, err
+			}
+			return findOrNil(repoFind(r.Context(), teacherStore, id)), nil
+		}),
+		"terms": root("[Term!]!", "", func(r *http.Request, _ gqlArgs) (any, error) {
+			return repoList(r.Context(), termStore), nil
+		}),
+		"currentTerm": root("Term", "", func(r *http.Request, _ gqlArgs) (any, error) {
+			return findOrNil(currentTerm(r.Context())), nil
+		}),
+	},
+
+	"Mutation": {
+		"createStudent": root("Student", "input: StudentInput!", func(r *http.Request, a gqlArgs) (any, error) {
+			var s Student
+			err := callHandler(r, createStudent, "POST", "/students", nil, gqlInput(a["input"]), &s)
+			return s, err
+		}),
+		"updateStudent": root("Student", "id: ID!, input: StudentInput!", func(r *http.Request, a gqlArgs) (any, error) {
+			id, _, err := a.int("id")
+			if err != nil {
+				return nil, err
+			}
+			var s Student
+			err = callHandler(r, updateStudent, "PUT", "/students/{id}", map[string]string{"id": strconv.Itoa(id)}, gqlInput(a["input"]), &s)
+			return s, err
+		}),
+		"deleteStudent": root("Boolean", "id: ID!", func(r *http.Request, a gqlArgs) (any, error) {
+			id, _, err := a.int("id")
+			if err != nil {
+				return nil, err
+			}
+			err = callHandler(r, deleteStudent, "DELETE", "/students/{id}", map[string]string{"id": strconv.Itoa(id)}, nil, nil)
+			return err == nil, err
+		}),
+		"enroll": root("EnrollmentResult", "studentId: ID!, courseId: ID!, sectionId: ID, term: String", func(r *http.Request, a gqlArgs) (any, error) {
+			studentID, _, err := a.int("studentId")
+			if err != nil {
+				return nil, err
+			}
+			courseID, _, err := a.int("courseId")
+			if err != nil {
+				return nil, err
+			}
+			sectionID, _, err := a.int("sectionId")
+			if err != nil {
+				return nil, err
+			}
+			term, err := a.string("term")
+			if err != nil {
+				return nil, err
+			}
+			body := map[string]any{"course_id": courseID, "section_id": sectionID, "term": term}
+			var raw json.RawMessage
+			status, err := invokeHandler(r, createEnrollment, "POST", "/students/{id}/enrollments", map[string]string{"id": strconv.Itoa(studentID)}, body, &raw)
+			if err != nil {
+				return nil, err
+			}
+			if status == http.StatusAccepted {
+				var entry WaitlistEntry
+				return gqlEnrollResult{waitlist: &entry}, json.Unmarshal(raw, &entry)
+			}
+			var e Enrollment
+			return gqlEnrollResult{enrollment: &e}, json.Unmarshal(raw, &e)
+		}),
+		"dropEnrollment": root("Boolean", "studentId: ID!, enrollmentId: ID!", func(r *http.Request, a gqlArgs) (any, error) {
+			studentID, _, err := a.int("studentId")
+			if err != nil {
+				return nil, err
+			}
+			enrollmentID, _, err := a.int("enrollmentId")
+			if err != nil {
+				return nil, err
+			}
+			vars := map[string]string{"id": strconv.Itoa(studentID), "enrollment_id": strconv.Itoa(enrollmentID)}
+			err = callHandler(r, deleteEnrollment, "DELETE", "/students/{id}/enrollments/{enrollment_id}", vars, nil, nil)
+			return err == nil, err
+		}),
+	},
+
+	"Student": {
+		"id":        prop("ID!", func(s Student) any { return s.ID }),
+		"name":      prop("String!", func(s Student) any { return s.Name }),
+		"age":       prop("Int!", func(s Student) any { return s.Age }),
+		"email":     prop("String!", func(s Student) any { return s.Email }),
+		"programId": prop("Int", func(s Student) any { return zeroAsNil(s.ProgramID) }),
+		"updatedAt": prop("String", func(s Student) any {
+			if s.UpdatedAt.IsZero() {
+				return nil
+			}
+			return s.UpdatedAt.Format(time.RFC3339Nano)
+		}),
+		"enrollments": edge("[Enrollment!]!", "term: String", func(r *http.Request, s Student, a gqlArgs) (any, error) {
+			term, err := a.string("term")
+			if err != nil {
+				return nil, err
+			}
+			term = strings.ToUpper(term)
+			list := []Enrollment{}
+			for _, e := range studentEnrollments(r.Context(), s.ID) {
+				if term == "" || e.Term == term {
+					list = append(list, e)
+				}
+			}
+			return list, nil
+		}),
+		"grades": edge("[Grade!]!", "term: String", func(r *http.Request, s Student, a gqlArgs) (any, error) {
+			term, err := a.string("term")
+			return studentGrades(r.Context(), s.ID, strings.ToUpper(term)), err
+		}),
+		"gpa": edge("GPA!", "", func(r *http.Request, s Student, _ gqlArgs) (any, error) {
+			return computeGPA(r.Context(), s.ID), nil
+		}),
+		"guardians": edge("[Guardian!]!", "", func(r *http.Request, s Student, _ gqlArgs) (any, error) {
+			return studentGuardians(r, s.ID), nil
+		}),
+		"awards": edge("[Award!]!", "", func(r *http.Request, s Student, _ gqlArgs) (any, error) {
+			return studentAwards(r, s.ID), nil
+		}),
+	},
+
+	"Course": {
+		"id":       prop("ID!", func(c Course) any { return c.ID }),
+		"code":     prop("String!", func(c Course) any { return c.Code }),
+		"title":    prop("String!", func(c Course) any { return c.Title }),
+		"credits":  prop("Int!", func(c Course) any { return c.Credits }),
+		"capacity": prop("Int!", func(c Course) any { return c.Capacity }),
+		"instructor": edge("Teacher", "", func(r *http.Request, c Course, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), teacherStore, c.InstructorID)), nil
+		}),
+		"sections": edge("[Section!]!", "", func(r *http.Request, c Course, _ gqlArgs) (any, error) {
+			return courseSections(r.Context(), c.ID), nil
+		}),
+		"students": edge("[Student!]!", "term: String", func(r *http.Request, c Course, a gqlArgs) (any, error) {
+			term, err := a.string("term")
+			if err != nil {
+				return nil, err
+			}
+			term = strings.ToUpper(term)
+			list := []Student{}
+			for _, e := range courseEnrollments(r.Context(), c.ID) {
+				if term != "" && e.Term != term {
+					continue
+				}
+				if s, ok := findStudent(r.Context(), e.StudentID); ok {
+					list = append(list, s)
+				}
+			}
+			return list, nil
+		}),
+	},
+
+	"Teacher": {
+		"id":    prop("ID!", func(t Teacher) any { return t.ID }),
+		"name":  prop("String!", func(t Teacher) any { return t.Name }),
+		"email": prop("String!", func(t Teacher) any { return t.Email }),
+		"courses": edge("[Course!]!", "", func(r *http.Request, t Teacher, _ gqlArgs) (any, error) {
+			return coursesTaughtBy(r.Context(), t.ID), nil
+		}),
+	},
+
+	"Section": {
+		"id":       prop("ID!", func(s Section) any { return s.ID }),
+		"name":     prop("String!", func(s Section) any { return s.Name }),
+		"room":     prop("String!", func(s Section) any { return s.Room }),
+		"capacity": prop("Int", func(s Section) any { return zeroAsNil(s.Capacity) }),
+		"meetings": prop("[Meeting!]!", func(s Section) any { return s.Meetings }),
+		"course": edge("Course", "", func(r *http.Request, s Section, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), courseStore, s.CourseID)), nil
+		}),
+	},
+
+	"Meeting": {
+		"day":   prop("String!", func(m Meeting) any { return m.Day }),
+		"start": prop("String!", func(m Meeting) any { return m.Start }),
+		"end":   prop("String!", func(m Meeting) any { return m.End }),
+	},
+
+	"Enrollment": {
+		"id":         prop("ID!", func(e Enrollment) any { return e.ID }),
+		"term":       prop("String", func(e Enrollment) any { return zeroAsNil(e.Term) }),
+		"enrolledAt": prop("DateTime!", func(e Enrollment) any { return e.EnrolledAt }),
+		"student": edge("Student", "", func(r *http.Request, e Enrollment, _ gqlArgs) (any, error) {
+			return findOrNil(findStudent(r.Context(), e.StudentID)), nil
+		}),
+		"course": edge("Course", "", func(r *http.Request, e Enrollment, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), courseStore, e.CourseID)), nil
+		}),
+		"section": edge("Section", "", func(r *http.Request, e Enrollment, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), sectionStore, e.SectionID)), nil
+		}),
+		"grades": edge("[Grade!]!", "", func(r *http.Request, e Enrollment, _ gqlArgs) (any, error) {
+			list := []Grade{}
+			for _, g := range studentGrades(r.Context(), e.StudentID, "") {
+				if g.EnrollmentID == e.ID {
+					list = append(list, g)
+				}
+			}
+			return list, nil
+		}),
+	},
+
+	"WaitlistEntry": {
+		"id":       prop("ID!", func(e WaitlistEntry) any { return e.ID }),
+		"term":     prop("String", func(e WaitlistEntry) any { return zeroAsNil(e.Term) }),
+		"position": prop("Int!", func(e WaitlistEntry) any { return e.Position }),
+		"addedAt":  prop("DateTime!", func(e WaitlistEntry) any { return e.AddedAt }),
+		"course": edge("Course", "", func(r *http.Request, e WaitlistEntry, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), courseStore, e.CourseID)), nil
+		}),
+	},
+
+	"EnrollmentResult": {
+		"waitlisted":    prop("Boolean!", func(res gqlEnrollResult) any { return res.waitlist != nil }),
+		"enrollment":    prop("Enrollment", func(res gqlEnrollResult) any { return res.enrollment }),
+		"waitlistEntry": prop("WaitlistEntry", func(res gqlEnrollResult) any { return res.waitlist }),
+	},
+
+	"Grade": {
+		"id":         prop("ID!", func(g Grade) any { return g.ID }),
+		"term":       prop("String!", func(g Grade) any { return g.Term }),
+		"scale":      prop("String!", func(g Grade) any { return g.Scale }),
+		"value":      prop("String!", func(g Grade) any { return g.Value }),
+		"recordedAt": prop("DateTime!", func(g Grade) any { return g.RecordedAt }),
+		"course": edge("Course", "", func(r *http.Request, g Grade, _ gqlArgs) (any, error) {
+			return findOrNil(repoFind(r.Context(), courseStore, g.CourseID)), nil
+		}),
+	},
+
+	"GPA": {
+		"gpa":     prop("Float", func(g GPAReport) any { return g.GPA }),
+		"credits": prop("Int!", func(g GPAReport) any { return g.Credits }),
+		"terms":   prop("[TermGPA!]!", func(g GPAReport) any { return g.Terms }),
+	},
+
+	"TermGPA": {
+		"term":    prop("String!", func(t TermGPA) any { return t.Term }),
+		"gpa":     prop("Float!", func(t TermGPA) any { return t.GPA }),
+		"credits": prop("Int!", func(t TermGPA) any { return t.Credits }),
+	},
+
+	"Guardian": {
+		"id":               prop("ID!", func(g Guardian) any { return g.ID }),
+		"name":             prop("String!", func(g Guardian) any { return g.Name }),
+		"relationship":     prop("String!", func(g Guardian) any { return g.Relationship }),
+		"email":            prop("String", func(g Guardian) any { return zeroAsNil(g.Email) }),
+		"phone":            prop("String", func(g Guardian) any { return zeroAsNil(g.Phone) }),
+		"preferredContact": prop("String", func(g Guardian) any { return zeroAsNil(g.PreferredContact) }),
+	},
+
+	"Award": {
+		"id":          prop("ID!", func(a Award) any { return a.ID }),
+		"kind":        prop("String!", func(a Award) any { return a.Kind }),
+		"name":        prop("String!", func(a Award) any { return a.Name }),
+		"amountCents": prop("Int!", func(a Award) any { return a.AmountCents }),
+		"term":        prop("String!", func(a Award) any { return a.Term }),
+	},
+
+	"Term": {
+		"id":        prop("ID!", func(t Term) any { return t.ID }),
+		"name":      prop("String!", func(t Term) any { return t.Name }),
+		"startDate": prop("String!", func(t Term) any { return t.StartDate }),
+		"endDate":   prop("String!", func(t Term) any { return t.EndDate }),
+		"current":   prop("Boolean!", func(t Term) any { return t.Current }),
+	},
+}
+
+// findOrNil turns a (value, found) lookup into the value or nil.
+func findOrNil[T any](v T, ok bool) any {
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// zeroAsNil maps the zero values that stand for "not set" to null.
+func zeroAsNil[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+// gqlInput converts an input object to the JSON body of the REST
+// endpoint, renaming camelCase fields to snake_case.
+func gqlInput(v any) map[string]any {
+	in, _ := v.(map[string]any)
+	out := make(map[string]any, len(in))
+	for k, v := range in {
+		var b strings.Builder
+		for _, c := range k {
+			if c >= 'A' && c <= 'Z' {
+				b.WriteByte('_')
+				c += 'a' - 'A'
+			}
+			b.WriteRune(c)
+		}
+		out[b.String()] = v
+	}
+	return out
+}
+
+// graphQLSchema renders the schema as SDL, sorted by type and field name.
+func graphQLSchema() string {
+	var b strings.Builder
+	b.WriteString("scalar DateTime\n")
+	for _, name := range sortedKeys(gqlInputTypes) {
+		fmt.Fprintf(&b, "\ninput %s {\n", name)
+		for _, f := range gqlInputTypes[name] {
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+		b.WriteString("}\n")
+	}
+	for _, name := range sortedKeys(gqlTypes) {
+		fmt.Fprintf(&b, "\ntype %s {\n", name)
+		fields := gqlTypes[name]
+		for _, f := range sortedKeys(fields) {
+			if args := fields[f].args; args != "" {
+				fmt.Fprintf(&b, "  %s(%s): %s\n", f, args, fields[f].typ)
+			} else {
+				fmt.Fprintf(&b, "  %s: %s\n", f, fields[f].typ)
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// serveGraphQL accepts POST with a JSON body, or GET with ?query= for
+// queries. Execution errors are reported in the errors array with a 200,
+// as GraphQL clients expect; only requests that cannot run get a 400.
+func serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			dec := json.NewDecoder(strings.NewReader(v))
+			dec.UseNumber()
+			if err := dec.Decode(&req.Variables); err != nil {
+				writeGraphQLErrors(w, http.StatusBadRequest, &gqlError{Message: "variables must be a JSON object"})
+				return
+			}
+		}
+	} else {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, gqlMaxQueryBytes))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeGraphQLErrors(w, http.StatusBadRequest, &gqlError{Message: "Invalid GraphQL request: " + err.Error()})
+			return
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQLErrors(w, http.StatusBadRequest, &gqlError{Message: "query is required"})
+		return
+	}
+
+	doc, err := parseGraphQL(req.Query)
+	if err == nil && r.Method == http.MethodGet && slices.ContainsFunc(doc.operations, func(op *gqlOperation) bool { return op.kind == "mutation" }) {
+		w.Header().Set("Allow", "POST")
+		writeGraphQLErrors(w, http.StatusMethodNotAllowed, &gqlError{Message: "Mutations must be sent with POST"})
+		return
+	}
+
+	data, errs, err := executeGraphQL(r, req.Query, req.OperationName, req.Variables)
+	if list, ok := err.(gqlErrorList); ok {
+		writeGraphQLErrors(w, http.StatusBadRequest, list...)
+		return
+	} else if err != nil {
+		writeGraphQLErrors(w, http.StatusBadRequest, err.(*gqlError))
+		return
+	}
+	resp := map[string]any{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeGraphQLErrors(w http.ResponseWriter, status int, errs ...*gqlError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"errors": errs})
+}
+
+func getGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, graphQLSchema())
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	studentv1 "studengo/proto/student/v1"
+)
+
+// studentService implements the gRPC StudentService. Writes go through the
+// REST handlers in-process, so both APIs validate, audit and publish
+// events the same way.
+type studentService struct {
+	studentv1.UnimplementedStudentServiceServer
+}
+
+// grpcMetadataHeaders are the metadata keys passed on to the REST handlers
+// as request headers.
+var grpcMetadataHeaders = []string{"authorization", "x-api-key", "x-request-id", "x-tenant-id", "user-agent"}
+
+// grpcRequest builds the HTTP request a REST handler sees for a call.
+func grpcRequest(ctx context.Context, method string) *http.Request {
+	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", http.NoBody)
+	r.Header.Set("X-Grpc-Method", method)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range grpcMetadataHeaders {
+			for _, v := range md.Get(key) {
+				r.Header.Add(key, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// grpcStatus maps a REST status to the matching gRPC code.
+func grpcStatus(err error) error {
+	var he *handlerError
+	if !errors.As(err, &he) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	code := codes.Unknown
+	switch he.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusGone:
+		code = codes.OutOfRange
+	case http.StatusPreconditionFailed, http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		if he.status >= 500 {
+			code = codes.Internal
+		}
+	}
+	msg := he.Error()
+	for _, fe := range he.problem.Errors {
+		msg += "; " + fe.Field + " " + fe.Message
+	}
+	return status.Error(code, msg)
+}
+
+func toStudentPB(s Student) *studentv1.Student {
+	return &studentv1.Student{
+		Id:        int64(s.ID),
+		Name:      s.Name,
+		Age:       int32(s.Age),
+		Email:     s.Email,
+		ProgramId: int64(s.ProgramID),
+	}
+}
+
+func fromStudentPB(s *studentv1.Student) Student {
+	if s == nil {
+		return Student{}
+	}
+	return Student{Name: s.Name, Age: int(s.Age), Email: s.Email, ProgramID: int(s.ProgramId)}
+}
+
+func studentPath(id int64) map[string]string {
+	return map[string]string{"id": strconv.FormatInt(id, 10)}
+}
+
+func (studentService) GetStudent(ctx context.Context, req *studentv1.GetStudentRequest) (*studentv1.Student, error) {
+	var s Student
+	if err := callHandler(grpcRequest(ctx, "GetStudent"), getStudent, "GET", "/students/{id}", studentPath(req.Id), nil, &s); err != nil {
+		return nil, grpcStatus(err)
+	}
+	return toStudentPB(s), nil
+}
+
+func (studentService) ListStudents(ctx context.Context, req *studentv1.ListStudentsRequest) (*studentv1.ListStudentsResponse, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = cfg.DefaultPageSize
+	}
+	if limit < 0 || limit > cfg.MaxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", cfg.MaxPageSize)
+	}
+	if req.After < 0 {
+		return nil, status.Error(codes.InvalidArgument, "after must not be negative")
+	}
+
+	resp := &studentv1.ListStudentsResponse{}
+	for _, s := range allStudents(ctx) {
+		if int64(s.ID) <= req.After {
+			continue
+		}
+		if len(resp.Students) == limit {
+			resp.NextAfter = resp.Students[limit-1].Id
+			break
+		}
+		resp.Students = append(resp.Students, toStudentPB(s))
+	}
+	return resp, nil
+}
+
+func (studentService) CreateStudent(ctx context.Context, req *studentv1.CreateStudentRequest) (*studentv1.Student, error) {
+	var s Student
+	if err := callHandler(grpcRequest(ctx, "CreateStudent"), createStudent, "POST", "/students", nil, fromStudentPB(req.Student), &s); err != nil {
+		return nil, grpcStatus(err)
+	}
+	return toStudentPB(s), nil
+}
+
+func (studentService) UpdateStudent(ctx context.Context, req *studentv1.UpdateStudentRequest) (*studentv1.Student, error) {
+	var s Student
+	if err := callHandler(grpcRequest(ctx, "UpdateStudent"), updateStudent, "PUT", "/students/{id}", studentPath(req.Id), fromStudentPB(req.Student), &s); err != nil {
+		return nil, grpcStatus(err)
+	}
+	return toStudentPB(s), nil
+}
+
+func (studentService) DeleteStudent(ctx context.Context, req *studentv1.DeleteStudentRequest) (*emptypb.Empty, error) {
+	if err := callHandler(grpcRequest(ctx, "DeleteStudent"), deleteStudent, "DELETE", "/students/{id}", studentPath(req.Id), nil, nil); err != nil {
+		return nil, grpcStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toStudentChangePB(c Change) *studentv1.StudentChange {
+	pb := &studentv1.StudentChange{
+		Cursor:    int64(c.ID),
+		Action:    c.Action,
+		StudentId: int64(c.EntityID),
+		At:        timestamppb.New(c.At),
+	}
+	if s, ok := c.Data.(Student); ok && c.Action != changeDeleted {
+		pb.Student = toStudentPB(s)
+	}
+	return pb
+}
+
+// WatchStudents works like /students/events: it replays the feed after
+// since and then forwards live changes until the client goes away or the
+// server shuts down.
+func (studentService) WatchStudents(req *studentv1.WatchStudentsRequest, stream studentv1.StudentService_WatchStudentsServer) error {
+	ctx := stream.Context()
+	isStudent := func(c Change) bool { return c.Entity == "student" }
+
+	if req.Since < 0 || req.Since < int64(stateOf(ctx).changes.purged()) {
+		return status.Errorf(codes.OutOfRange, "changes after cursor %d are no longer available", req.Since)
+	}
+
+	hub := stateOf(ctx).hub
+	updates, err := hub.join()
+	if err != nil {
+		return status.Error(codes.Unavailable, "live updates are unavailable: "+err.Error())
+	}
+	defer hub.leave(updates)
+
+	last := int(req.Since)
+	if last > 0 {
+		for _, c := range changesAfter(ctx, last, isStudent) {
+			if err := stream.Send(toStudentChangePB(c)); err != nil {
+				return err
+			}
+			last = c.ID
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case c, ok := <-updates:
+			if !ok {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if c.ID <= last || !isStudent(c) {
+				continue
+			}
+			if err := stream.Send(toStudentChangePB(c)); err != nil {
+				return err
+			}
+			last = c.ID
+		}
+	}
+}
+
+// grpcUnaryState and grpcStreamState put st in the context of every call,
+// as Server.ServeHTTP does for HTTP requests.
+func grpcUnaryState(st *state) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(st.context(ctx), req)
+	}
+}
+
+func grpcStreamState(st *state) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, stateStream{ServerStream: ss, ctx: st.context(ss.Context())})
+	}
+}
+
+// stateStream is a stream whose context carries a server's state.
+type stateStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s stateStream) Context() context.Context { return s.ctx }
+
+// grpcUnaryLogger logs each call and turns panics into INTERNAL errors,
+// like requestLogger and recoverer do for HTTP.
+func grpcUnaryLogger(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ctx, "panic serving gRPC call", "panic", p, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		logGRPCCall(ctx, info.FullMethod, err, time.Since(start))
+	}()
+	return handler(ctx, req)
+}
+
+func grpcStreamLogger(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ss.Context(), "panic serving gRPC stream", "panic", p, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		logGRPCCall(ss.Context(), info.FullMethod, err, time.Since(start))
+	}()
+	return handler(srv, ss)
+}
+
+func logGRPCCall(ctx context.Context, method string, err error, latency time.Duration) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled, codes.NotFound, codes.InvalidArgument, codes.AlreadyExists, codes.FailedPrecondition, codes.OutOfRange:
+	default:
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "grpc call", "method", method, "code", code.String(), "latency_ms", latency.Milliseconds())
+}
+
+// startGRPC serves the gRPC API on cfg.GRPCPort, with the state of the
+// server the calls go to, until the returned stop function is called. It
+// returns nil when gRPC is disabled.
+func startGRPC(st *state) (func(context.Context), error) {
+	if cfg.GRPCPort == "" {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		return nil, err
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcUnaryState(st), grpcUnaryLogger),
+		grpc.ChainStreamInterceptor(grpcStreamState(st), grpcStreamLogger),
+	)
+	studentv1.RegisterStudentServiceServer(srv, studentService{})
+	go func() {
+		slog.Info("grpc server running", "port", cfg.GRPCPort)
+		if err := srv.Serve(ln); err != nil {
+			slog.Error("grpc server stopped", "error", err)
+		}
+	}()
+
+	return func(ctx context.Context) {
+		done := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			srv.Stop()
+		}
+	}, nil
+}
+
+// grpcGateway translates JSON/HTTP calls to the gRPC server, following the
+// google.api.http bindings in student.proto. It is mounted under
+// /gateway, next to the hand-written REST handlers it mirrors.
+func grpcGateway(ctx context.Context) (http.Handler, error) {
+	// Keep the REST API's snake_case field names.
+	gw := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}))
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if err := studentv1.RegisterStudentServiceHandlerFromEndpoint(ctx, gw, "localhost:"+cfg.GRPCPort, opts); err != nil {
+		return nil, err
+	}
+	return http.StripPrefix("/gateway", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// WatchStudents streams for as long as the client listens.
+		if strings.HasSuffix(r.URL.Path, "/watch") {
+			http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		}
+		gw.ServeHTTP(w, r)
+	})), nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Guardian is a parent or other contact responsible for a student.
+type Guardian struct {
+	ID               int    `json:"id"`
+	StudentID        int    `json:"student_id"`
+	Name             string `json:"name" validate:"required"`
+	Relationship     string `json:"relationship" validate:"enum=relationship"`
+	Email            string `json:"email,omitempty"`
+	Phone            string `json:"phone,omitempty"`
+	PreferredContact string `json:"preferred_contact,omitempty" validate:"omitempty,enum=contact_method"`
+}
+
+func (g Guardian) entityID() int { return g.ID }
+
+func (g Guardian) withID(id int) Guardian {
+	g.ID = id
+	return g
+}
+
+var guardianStore Repository[Guardian] = newCollection[Guardian]("guardians")
+
+var (
+	guardianRelationships = []string{"parent", "guardian", "grandparent", "sibling", "other"}
+	contactMethods        = []string{"email", "phone", "sms"}
+)
+
+// validateGuardian lists every problem with a submitted guardian.
+func validateGuardian(g Guardian) []FieldError {
+	errs := checkFields(g)
+	if g.Email == "" && g.Phone == "" {
+		errs = append(errs, FieldError{Field: "email", Message: "email or phone is required"})
+	}
+	switch g.PreferredContact {
+	case "email":
+		if g.Email == "" {
+			errs = append(errs, FieldError{Field: "preferred_contact", Message: "is email but no email is set"})
+		}
+	case "phone", "sms":
+		if g.Phone == "" {
+			errs = append(errs, FieldError{Field: "preferred_contact", Message: "is " + g.PreferredContact + " but no phone is set"})
+		}
+	}
+	return errs
+}
+
+// canSeeContacts reports whether the caller may see guardians' email and
+// phone. Only admin and staff keys can; everyone else sees names and
+// relationships only.
+func canSeeContacts(r *http.Request) bool {
+	p, ok := authenticate(r)
+	return ok && (p.Role == roleAdmin || p.Role == roleStaff)
+}
+
+// guardianView hides contact details from callers not entitled to them.
+func guardianView(r *http.Request, g Guardian) Guardian {
+	if !canSeeContacts(r) {
+		g.Email, g.Phone, g.PreferredContact = "", "", ""
+	}
+	return g
+}
+
+func studentGuardians(r *http.Request, studentID int) []Guardian {
+	list := []Guardian{}
+	for _, g := range repoList(r.Context(), guardianStore) {
+		if g.StudentID == studentID {
+			list = append(list, guardianView(r, g))
+		}
+	}
+	return list
+}
+
+func createGuardian(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var guardian Guardian
+	if err := json.NewDecoder(r.Body).Decode(&guardian); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateGuardian(guardian); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian data", errs...)
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	guardian.StudentID = studentID
+	guardian = repoInsert(r.Context(), guardianStore, guardian)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(guardianView(r, guardian))
+}
+
+func getStudentGuardians(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	writeJSONArray(w, studentGuardians(r, studentID))
+}
+
+func getGuardian(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID")
+		return
+	}
+
+	guardian, exists := repoFind(r.Context(), guardianStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Guardian not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, guardianView(r, guardian))
+}
+
+func updateGuardian(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID")
+		return
+	}
+
+	var updated Guardian
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateGuardian(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian data", errs...)
+		return
+	}
+
+	before, exists := repoFind(r.Context(), guardianStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Guardian not found")
+		return
+	}
+	updated.ID, updated.StudentID = id, before.StudentID
+	repoReplace(r.Context(), guardianStore, id, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guardianView(r, updated))
+}
+
+func deleteGuardian(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID")
+		return
+	}
+
+	if _, exists := repoRemove(r.Context(), guardianStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Guardian not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// halMediaType asks for hypermedia links like ?hateoas=true does, and is
+// then used as the response Content-Type.
+const halMediaType = "application/hal+json"
+
+type halLink struct {
+	Href string `json:"href"`
+}
+
+// studentLinks are the HAL links of a student. update and delete point at
+// the student itself; the relation names the method to use.
+type studentLinks struct {
+	Self        halLink `json:"self"`
+	Update      halLink `json:"update"`
+	Delete      halLink `json:"delete"`
+	Summary     halLink `json:"summary"`
+	Enrollments halLink `json:"enrollments"`
+}
+
+// studentCollection is a HAL page of students.
+type studentCollection struct {
+	Links struct {
+		Self halLink  `json:"self"`
+		Next *halLink `json:"next,omitempty"`
+	} `json:"_links"`
+	Embedded struct {
+		Students []studentDetail `json:"students"`
+	} `json:"_embedded"`
+}
+
+// wantsHAL reports whether the Accept header asks for HAL.
+func wantsHAL(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), halMediaType)
+}
+
+// wantsLinks reports whether student responses should carry _links.
+func wantsLinks(r *http.Request) bool {
+	on, _ := strconv.ParseBool(r.URL.Query().Get("hateoas"))
+	return on || wantsHAL(r)
+}
+
+func linkStudent(id int) *studentLinks {
+	self := halLink{Href: apiV1 + "/students/" + strconv.Itoa(id)}
+	return &studentLinks{
+		Self:        self,
+		Update:      self,
+		Delete:      self,
+		Summary:     halLink{Href: self.Href + "/summary"},
+		Enrollments: halLink{Href: self.Href + "/enrollments"},
+	}
+}
+
+// setLinkedContentType labels a response carrying _links, leaving
+// application/json unless the client asked for HAL.
+func setLinkedContentType(w http.ResponseWriter, r *http.Request) {
+	contentType := "application/json"
+	if wantsHAL(r) {
+		contentType = halMediaType
+	}
+	w.Header().Set("Content-Type", contentType)
+}
+
+// studentRepresentation is what a student response contains: the student,
+// plus the records named in ?expand= and the links when asked for.
+func studentRepresentation(r *http.Request, student Student) any {
+	if r.URL.Query().Get("expand") == "" && !wantsLinks(r) {
+		return student
+	}
+	detail := expandStudent(r, student)
+	if wantsLinks(r) {
+		detail.Links = linkStudent(student.ID)
+	}
+	return detail
+}
+
+// linkedStudentPage wraps a page of students as a HAL collection. next is
+// the Link header from paginate.
+func linkedStudentPage(r *http.Request, list []Student, next string) studentCollection {
+	var page studentCollection
+	page.Links.Self = halLink{Href: r.URL.RequestURI()}
+	if target, ok := strings.CutPrefix(next, "<"); ok {
+		target, _, _ = strings.Cut(target, ">")
+		page.Links.Next = &halLink{Href: target}
+	}
+	page.Embedded.Students = make([]studentDetail, 0, len(list))
+	for _, s := range list {
+		page.Embedded.Students = append(page.Embedded.Students, studentDetail{Student: s, Links: linkStudent(s.ID)})
+	}
+	return page
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"studengo/ollama"
+)
+
+// checkResult is the outcome of one readiness check.
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+func checkStore(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		findStudent(ctx, 0)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkOllama only needs the server to answer; whether the model is
+// pulled is left to the diagnostics.
+func checkOllama(ctx context.Context) error {
+	if _, err := stateOf(ctx).llm.Models(ctx); err != nil && !errors.Is(err, ollama.ErrBadModelList) {
+		return err
+	}
+	return nil
+}
+
+func toCheckResult(err error, optional bool) checkResult {
+	if err != nil {
+		return checkResult{Status: "unavailable", Error: err.Error(), Optional: optional}
+	}
+	return checkResult{Status: "ok", Optional: optional}
+}
+
+// healthHandler reports that the process is alive.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// readyHandler reports whether the service can take traffic. The store
+// must be reachable; Ollama and the blob store are reported but only
+// degrade summaries and documents, so they do not fail readiness.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if stateOf(r.Context()).shuttingDown.Load() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "shutting down"})
+		return
+	}
+
+	storeErr := checkStore(ctx)
+	checks := map[string]checkResult{
+		"store":  toCheckResult(storeErr, false),
+		"ollama": toCheckResult(checkOllama(ctx), true),
+		"blobs":  toCheckResult(checkBlobs(ctx), true),
+	}
+
+	status, code := "ok", http.StatusOK
+	if storeErr != nil {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Message catalogs translate problem details and field errors, and name
+// the language summaries are written in. English is the source language
+// and has no catalog. Messages missing from a catalog stay in English.
+//
+// A catalog key may hold %s for a part of the message that varies, such
+// as "Invalid student data: %s"; the translation takes the parts in the
+// same order, or picks them with %[2]s.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// locale is a language the API answers in.
+type locale struct {
+	tag language.Tag
+	// Language is the English name of the language, used to ask the model
+	// for summaries in it.
+	Language string            `json:"language"`
+	Messages map[string]string `json:"messages"`
+	patterns []messagePattern
+}
+
+// messagePattern is a catalog key with %s in it.
+type messagePattern struct {
+	re          *regexp.Regexp
+	translation string
+}
+
+var english = &locale{tag: language.English, Language: "English"}
+
+var (
+	locales       = loadLocales()
+	localeMatcher = language.NewMatcher(localeTags())
+)
+
+func loadLocales() []*locale {
+	found := []*locale{english}
+	files, _ := fs.Glob(localeFiles, "locales/*.json")
+	for _, name := range files {
+		data, err := localeFiles.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		l := &locale{tag: language.MustParse(strings.TrimSuffix(path.Base(name), ".json"))}
+		if err := json.Unmarshal(data, l); err != nil {
+			panic(fmt.Sprintf("%s: %v", name, err))
+		}
+		for key, translation := range l.Messages {
+			if !strings.Contains(key, "%s") {
+				continue
+			}
+			parts := strings.Split(key, "%s")
+			for i, p := range parts {
+				parts[i] = regexp.QuoteMeta(p)
+			}
+			re := regexp.MustCompile("^" + strings.Join(parts, "(.*)") + "$")
+			l.patterns = append(l.patterns, messagePattern{re: re, translation: translation})
+		}
+		// Longer keys are more specific, so they are tried first.
+		slices.SortFunc(l.patterns, func(a, b messagePattern) int {
+			return cmp.Or(len(b.re.String())-len(a.re.String()), strings.Compare(a.re.String(), b.re.String()))
+		})
+		found = append(found, l)
+	}
+	return found
+}
+
+func localeTags() []language.Tag {
+	tags := make([]language.Tag, len(locales))
+	for i, l := range locales {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// requestLocale picks the locale best matching Accept-Language, English
+// when none does.
+func requestLocale(r *http.Request) *locale {
+	accept := r.Header.Get("Accept-Language")
+	if accept == "" {
+		return english
+	}
+	tags, _, err := language.ParseAcceptLanguage(accept)
+	if err != nil {
+		return english
+	}
+	_, i, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return english
+	}
+	return locales[i]
+}
+
+type localeKey struct{}
+
+// localize puts the locale of the request in its context, for code such
+// as summary generation that only has the context.
+func localize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, requestLocale(r))))
+	})
+}
+
+// localeFromContext returns the locale localize stored, or English.
+func localeFromContext(ctx context.Context) *locale {
+	if l, ok := ctx.Value(localeKey{}).(*locale); ok {
+		return l
+	}
+	return english
+}
+
+// translate returns msg in the locale, or msg itself when the catalog
+// does not have it.
+func (l *locale) translate(msg string) string {
+	if l == english || msg == "" {
+		return msg
+	}
+	if t, ok := l.Messages[msg]; ok {
+		return t
+	}
+	for _, p := range l.patterns {
+		if m := p.re.FindStringSubmatch(msg); m != nil {
+			args := make([]any, len(m)-1)
+			for i, s := range m[1:] {
+				args[i] = s
+			}
+			return fmt.Sprintf(p.translation, args...)
+		}
+	}
+	return msg
+}
+
+// summaryInstruction is appended to the summary prompt to have the model
+// write in the locale's language.
+func (l *locale) summaryInstruction() string {
+	if l == english {
+		return ""
+	}
+	return " Write the summary in " + l.Language + "."
+}
+
+// setContentLanguage marks a response as varying by Accept-Language and
+// names the language it is in.
+func setContentLanguage(w http.ResponseWriter, l *locale) {
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", l.tag.String())
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestLocale(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                "en",
+		"fr":              "fr",
+		"FR":              "fr",
+		"fr-CA":           "fr",
+		"es-419":          "es",
+		"es-MX,es;q=0.9":  "es",
+		"fr;q=0.5, es":    "es",
+		"de, es;q=0.5":    "es",
+		"en-GB, fr;q=0.9": "en",
+		"de":              "en",
+		"pt-BR":           "en",
+		"fr;q=0":          "en",
+		"*":               "en",
+		"garbage;;":       "en",
+	} {
+		r := httptest.NewRequest("GET", "/v1/students", nil)
+		r.Header.Set("Accept-Language", accept)
+		if got := requestLocale(r).tag.String(); got != want {
+			t.Errorf("Accept-Language %q: %s, want %s", accept, got, want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/students", nil)
+	r.Header.Set("Accept-Language", "fr")
+	fr := requestLocale(r)
+	for msg, want := range map[string]string{
+		"Not Found":                      "Introuvable",
+		"Student not found":              "L'étudiant est introuvable",
+		"must be between 1 and 10":       "doit être compris entre 1 et 10",
+		"Invalid student data: EOF":      "Données d'étudiant non valides : EOF",
+		"Invalid student data":           "Données d'étudiant non valides",
+		"A message not in the catalog":   "A message not in the catalog",
+		"Student not found, for a while": "Student not found, for a while",
+		"":                               "",
+	} {
+		if got := fr.translate(msg); got != want {
+			t.Errorf("%q in French: %q, want %q", msg, got, want)
+		}
+	}
+	if got := english.translate("Student not found"); got != "Student not found" {
+		t.Errorf("English: %q", got)
+	}
+}
+
+// Every catalog translates the same messages, keeping the parts that vary.
+func TestLocaleCatalogs(t *testing.T) {
+	var keys []string
+	for _, l := range locales[1:] {
+		if l.Language == "" {
+			t.Errorf("%s: no language name", l.tag)
+		}
+		got := make([]string, 0, len(l.Messages))
+		for key, translation := range l.Messages {
+			got = append(got, key)
+			if n := strings.Count(key, "%s"); n != strings.Count(translation, "%s")+strings.Count(translation, "%[") {
+				t.Errorf("%s: %q has %d parts, translated as %q", l.tag, key, n, translation)
+			}
+		}
+		slices.Sort(got)
+		if keys == nil {
+			keys = got
+		} else if !slices.Equal(got, keys) {
+			t.Errorf("%s translates other messages than %s", l.tag, locales[1].tag)
+		}
+	}
+}
+
+func TestLocalizedResponses(t *testing.T) {
+	api := newTestAPI(t, nil)
+	french := http.Header{"Accept-Language": {"fr-FR, en;q=0.5"}}
+
+	resp, data := api.get(t, "/v1/students/99", french)
+	var problem Problem
+	if err := json.Unmarshal(data, &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Title != "Introuvable" || problem.Detail != "L'étudiant est introuvable" ||
+		resp.Header.Get("Content-Language") != "fr" || !slices.Contains(resp.Header.Values("Vary"), "Accept-Language") {
+		t.Errorf("problem %+v, headers %v", problem, resp.Header)
+	}
+	resp, data = api.get(t, "/v1/students/99", http.Header{"Accept-Language": {"de"}})
+	if !strings.Contains(string(data), `"detail":"Student not found"`) || resp.Header.Get("Content-Language") != "en" {
+		t.Errorf("German falls back to English: %s, Content-Language %q", data, resp.Header.Get("Content-Language"))
+	}
+
+	// Field errors are translated; the field names are not.
+	req, err := http.NewRequest("POST", api.URL+"/v1/students", strings.NewReader(`{"name":"","age":-1,"email":"ada@example.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "es")
+	resp, data = api.send(t, req)
+	problem = Problem{}
+	if err := json.Unmarshal(data, &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{{Field: "name", Message: "es obligatorio"}, {Field: "age", Message: "debe ser un entero positivo"}}
+	if resp.StatusCode != http.StatusBadRequest || problem.Title != "Solicitud incorrecta" || problem.Detail != "Datos de estudiante no válidos" || !slices.Equal(problem.Errors, want) {
+		t.Errorf("status %d, problem %+v", resp.StatusCode, problem)
+	}
+
+	// Summaries are asked for, and cached, in the language of the request.
+	var ada Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &ada)
+	summaryPath := "/v1/students/" + strconv.Itoa(ada.ID) + "/summary"
+	resp, _ = api.get(t, summaryPath, french)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Language") != "fr" {
+		t.Errorf("French summary: status %d, Content-Language %q", resp.StatusCode, resp.Header.Get("Content-Language"))
+	}
+	api.get(t, summaryPath, french)
+	resp, _ = api.get(t, summaryPath, nil)
+	if resp.Header.Get("Content-Language") != "en" {
+		t.Errorf("English summary: Content-Language %q", resp.Header.Get("Content-Language"))
+	}
+	requests := api.ollama.Requests()
+	if len(requests) != 2 || !strings.HasSuffix(requests[0].Prompt, " Write the summary in French.") || strings.Contains(requests[1].Prompt, "Write the summary in") {
+		t.Errorf("Ollama got %+v, want a French prompt then an English one", requests)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// JobStatus is what /admin/jobs reports about one scheduled job.
+type JobStatus struct {
+	Name           string    `json:"name"`
+	Schedule       string    `json:"schedule"`
+	Running        bool      `json:"running"`
+	Runs           int       `json:"runs"`
+	Failures       int       `json:"failures"`
+	NextRunAt      time.Time `json:"next_run_at,omitzero"`
+	LastStartedAt  time.Time `json:"last_started_at,omitzero"`
+	LastDurationMS int64     `json:"last_duration_ms"`
+	LastResult     string    `json:"last_result,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// jobFunc does one run of a job and returns a one-line result for the
+// status page.
+type jobFunc func(ctx context.Context) (string, error)
+
+type job struct {
+	name     string
+	schedule schedule
+	run      jobFunc
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// jobDefinition is a job the service knows how to run and the schedule
+// it gets unless job_schedules says otherwise.
+type jobDefinition struct {
+	name     string
+	schedule func() string
+	run      jobFunc
+	// enabled reports whether the job applies in this configuration.
+	enabled func() bool
+}
+
+var jobDefinitions = []jobDefinition{
+	{name: "stats_report", schedule: func() string { return "0 2 * * *" }, run: statsReportJob},
+	{name: "summary_warm", schedule: func() string { return "@every " + cfg.SummaryWarmInterval.String() }, run: summaryWarmJob,
+		enabled: func() bool { return cfg.SummaryWarmWindow != "" && cfg.SummaryCacheTTL > 0 }},
+	{name: "retention_purge", schedule: func() string { return "30 3 * * *" }, run: retentionPurgeJob,
+		enabled: func() bool { return cfg.Retention > 0 }},
+	{name: "webhook_retry", schedule: func() string { return "@every 5s" }, run: webhookRetryJob},
+	{name: "ldap_sync", schedule: func() string { return "0 * * * *" }, run: ldapSyncJob,
+		enabled: func() bool { return cfg.LDAPURL != "" }},
+	{name: "sheets_export", schedule: func() string { return "30 * * * *" }, run: sheetsExportJob,
+		enabled: func() bool { return cfg.GoogleSheetsID != "" }},
+}
+
+// jobsOff disables a job in job_schedules.
+const jobsOff = "off"
+
+// parseJobSchedules reads job_schedules: name=schedule pairs separated
+// by semicolons, such as "stats_report=0 6 * * 1-5; webhook_retry=@every 30s".
+// A schedule of "off" disables the job.
+func parseJobSchedules(raw string) (map[string]string, error) {
+	specs := map[string]string{}
+	for _, entry := range strings.Split(raw, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		name, spec = strings.TrimSpace(name), strings.TrimSpace(spec)
+		if !ok || spec == "" {
+			return nil, fmt.Errorf("%q is not of the form name=schedule", entry)
+		}
+		if !slices.ContainsFunc(jobDefinitions, func(d jobDefinition) bool { return d.name == name }) {
+			return nil, fmt.Errorf("unknown job %q", name)
+		}
+		if spec != jobsOff {
+			if _, err := parseSchedule(spec); err != nil {
+				return nil, err
+			}
+		}
+		specs[name] = spec
+	}
+	return specs, nil
+}
+
+// scheduler holds the jobs a server runs.
+type scheduler struct {
+	mu   sync.RWMutex
+	jobs []*job
+	// ctx is the scheduler's context, used for runs started by hand.
+	ctx context.Context
+}
+
+var jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "job_runs_total",
+	Help: "Scheduled job runs, by job and result.",
+}, []string{"job", "result"})
+
+// startScheduler starts every enabled job on its schedule. Jobs stop when
+// ctx is cancelled; a run in progress sees ctx cancelled too.
+func startScheduler(ctx context.Context) {
+	specs, err := parseJobSchedules(cfg.JobSchedules)
+	if err != nil {
+		slog.Error("scheduler disabled", "error", err)
+		return
+	}
+
+	s := &stateOf(ctx).jobs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	for _, d := range jobDefinitions {
+		if d.enabled != nil && !d.enabled() {
+			continue
+		}
+		spec, ok := specs[d.name]
+		if !ok {
+			spec = d.schedule()
+		}
+		if spec == jobsOff {
+			continue
+		}
+		sched, err := parseSchedule(spec)
+		if err != nil {
+			slog.Error("job disabled", "job", d.name, "error", err)
+			continue
+		}
+		j := &job{name: d.name, schedule: sched, run: d.run, status: JobStatus{Name: d.name, Schedule: spec}}
+		s.jobs = append(s.jobs, j)
+		go j.loop(ctx)
+	}
+	slog.Info("scheduler started", "jobs", len(s.jobs))
+}
+
+func (j *job) loop(ctx context.Context) {
+	for {
+		next := j.schedule.next(time.Now())
+		if next.IsZero() {
+			slog.Warn("job schedule never fires", "job", j.name)
+			return
+		}
+		j.mu.Lock()
+		j.status.NextRunAt = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			j.execute(ctx)
+		}
+	}
+}
+
+// start marks the job running, unless it already is.
+func (j *job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return false
+	}
+	j.status.Running = true
+	j.status.LastStartedAt = clock.Now().UTC()
+	return true
+}
+
+// execute runs the job once, unless it is already running.
+func (j *job) execute(ctx context.Context) {
+	if j.start() {
+		j.runAndRecord(ctx)
+	}
+}
+
+// runAndRecord runs a started job and records the outcome. Panics are
+// recovered and recorded as failures.
+func (j *job) runAndRecord(ctx context.Context) {
+	start := time.Now()
+	result, err := func() (result string, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				slog.ErrorContext(ctx, "job panicked", "job", j.name, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+				err = fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		return j.run(ctx)
+	}()
+	elapsed := time.Since(start)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastDurationMS = elapsed.Milliseconds()
+	j.status.LastResult, j.status.LastError = result, ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		jobRuns.WithLabelValues(j.name, "error").Inc()
+		slog.WarnContext(ctx, "job failed", "job", j.name, "duration_ms", elapsed.Milliseconds(), "error", err)
+		return
+	}
+	jobRuns.WithLabelValues(j.name, "success").Inc()
+}
+
+func findJob(ctx context.Context, name string) (*job, bool) {
+	s := &stateOf(ctx).jobs
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// getJobs reports the status of every scheduled job.
+func getJobs(w http.ResponseWriter, r *http.Request) {
+	s := &stateOf(r.Context()).jobs
+	s.mu.RLock()
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		list = append(list, j.status)
+		j.mu.Unlock()
+	}
+	s.mu.RUnlock()
+	writeJSONArray(w, list)
+}
+
+// runJob starts a job now, outside its schedule, and answers 202 without
+// waiting for it to finish.
+func runJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := findJob(r.Context(), mux.Vars(r)["name"])
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+	if !j.start() {
+		writeProblem(w, r, http.StatusConflict, "Job "+j.name+" is already running")
+		return
+	}
+
+	s := &stateOf(r.Context()).jobs
+	s.mu.RLock()
+	ctx := s.ctx
+	s.mu.RUnlock()
+	go j.runAndRecord(ctx)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// statsReportJob logs a snapshot of the service's records.
+func statsReportJob(ctx context.Context) (string, error) {
+	today := clock.Now().Format(dateLayout)
+	absent := 0
+	for _, a := range attendanceStore.List(ctx) {
+		if a.Date == today && a.Status == "absent" {
+			absent++
+		}
+	}
+	failedDeliveries := 0
+	for _, d := range deliveryStore.List(ctx) {
+		if d.Status == deliveryFailed {
+			failedDeliveries++
+		}
+	}
+
+	counts := []any{
+		"students", len(allStudents(ctx)),
+		"courses", courseStore.Count(ctx),
+		"enrollments", enrollmentStore.Count(ctx),
+		"waitlisted", waitlistStore.Count(ctx),
+		"grades", gradeStore.Count(ctx),
+		"absent_today", absent,
+		"failed_webhook_deliveries", failedDeliveries,
+	}
+	slog.InfoContext(ctx, "stats report", counts...)
+
+	var b strings.Builder
+	for i := 0; i < len(counts); i += 2 {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s=%v", counts[i], counts[i+1])
+	}
+	return b.String(), nil
+}
+
+// summaryWarmJob precomputes summaries while inside summary_warm_window.
+func summaryWarmJob(ctx context.Context) (string, error) {
+	window, err := parseWarmWindow(cfg.SummaryWarmWindow)
+	if err != nil {
+		return "", err
+	}
+	if !window.contains(clock.Now()) {
+		return "outside warm window", nil
+	}
+	warmSummaries(ctx)
+	return "warmed", nil
+}
+
+// retentionPurgeJob deletes delivered webhook deliveries, email log
+// entries and change feed entries older than the retention period. Dead
+// letters are kept until they are replayed or discarded.
+func retentionPurgeJob(ctx context.Context) (string, error) {
+	cutoff := clock.Now().Add(-cfg.Retention)
+	deliveries, emails := 0, 0
+	for _, d := range deliveryStore.List(ctx) {
+		if d.Status == deliverySucceeded && d.CreatedAt.Before(cutoff) {
+			repoRemove(ctx, deliveryStore, d.ID)
+			deliveries++
+		}
+	}
+	for _, e := range emailLogStore.List(ctx) {
+		if e.Status != emailQueued && e.QueuedAt.Before(cutoff) {
+			repoRemove(ctx, emailLogStore, e.ID)
+			emails++
+		}
+	}
+	changes := purgeChanges(ctx, cutoff)
+	return fmt.Sprintf("removed %d webhook deliveries, %d email log entries, %d changes", deliveries, emails, changes), nil
+}
+
+// webhookRetryJob requeues webhook deliveries whose retry is due.
+func webhookRetryJob(ctx context.Context) (string, error) {
+	return fmt.Sprintf("%d deliveries requeued", sweepDeliveries(ctx)), nil
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// A minimal LDAPv3 client (RFC 4511): simple bind, paged subtree search
+// and unbind, which is all the directory sync needs. It speaks ldap:// and
+// ldaps://.
+
+// LDAP protocol operations, as APPLICATION tags.
+const (
+	ldapBindRequest     ber.Tag = 0
+	ldapBindResponse    ber.Tag = 1
+	ldapUnbindRequest   ber.Tag = 2
+	ldapSearchRequest   ber.Tag = 3
+	ldapSearchEntry     ber.Tag = 4
+	ldapSearchDone      ber.Tag = 5
+	ldapSearchReference ber.Tag = 19
+)
+
+const (
+	ldapScopeWholeSubtree = 2
+	ldapNeverDerefAliases = 0
+)
+
+// ldapPagedResultsOID is the simple paged results control (RFC 2696).
+// Active Directory caps unpaged searches at 1000 entries.
+const ldapPagedResultsOID = "1.2.840.113556.1.4.319"
+
+// ldapResultError is a non-success LDAPResult.
+type ldapResultError struct {
+	code    int64
+	message string
+}
+
+func (e *ldapResultError) Error() string {
+	if e.message == "" {
+		return "ldap result code " + strconv.FormatInt(e.code, 10)
+	}
+	return "ldap result code " + strconv.FormatInt(e.code, 10) + ": " + e.message
+}
+
+// ldapEntry is a search result. Attribute names are lowercased, as LDAP
+// compares them case-insensitively.
+type ldapEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// get returns the first value of an attribute.
+func (e ldapEntry) get(name string) string {
+	if v := e.Attributes[strings.ToLower(name)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+type ldapConn struct {
+	conn   net.Conn
+	nextID int64
+}
+
+// dialLDAP connects to an ldap:// or ldaps:// URL. The connection's
+// deadline follows ctx.
+func dialLDAP(ctx context.Context, rawURL string) (*ldapConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		d := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+		conn, err = d.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported ldap scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return &ldapConn{conn: conn}, nil
+}
+
+// Close unbinds and closes the connection.
+func (c *ldapConn) Close() error {
+	c.send(ber.Encode(ber.ClassApplication, ber.TypePrimitive, ldapUnbindRequest, nil, "unbind"), nil)
+	return c.conn.Close()
+}
+
+// send writes one LDAPMessage and returns its message ID.
+func (c *ldapConn) send(op *ber.Packet, controls *ber.Packet) (int64, error) {
+	c.nextID++
+	msg := ber.NewSequence("LDAPMessage")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, c.nextID, "messageID"))
+	msg.AppendChild(op)
+	if controls != nil {
+		msg.AppendChild(controls)
+	}
+	_, err := c.conn.Write(msg.Bytes())
+	return c.nextID, err
+}
+
+// receive reads the next LDAPMessage answering id and returns its
+// protocol operation and controls, if any.
+func (c *ldapConn) receive(id int64) (op, controls *ber.Packet, err error) {
+	msg, err := ber.ReadPacket(c.conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(msg.Children) < 2 {
+		return nil, nil, errors.New("malformed ldap message")
+	}
+	if got, _ := msg.Children[0].Value.(int64); got != id {
+		return nil, nil, fmt.Errorf("ldap response to message %d, expected %d", got, id)
+	}
+	if len(msg.Children) > 2 {
+		controls = msg.Children[2]
+	}
+	return msg.Children[1], controls, nil
+}
+
+// ldapResult checks the resultCode of a response operation.
+func ldapResult(op *ber.Packet) error {
+	if len(op.Children) < 3 {
+		return errors.New("malformed ldap result")
+	}
+	code, _ := op.Children[0].Value.(int64)
+	if code == 0 {
+		return nil
+	}
+	return &ldapResultError{code: code, message: string(op.Children[2].ByteValue)}
+}
+
+// bind authenticates with a DN and password. An empty DN binds
+// anonymously.
+func (c *ldapConn) bind(dn, password string) error {
+	req := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapBindRequest, nil, "bind")
+	req.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 3, "version"))
+	req.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "name"))
+	req.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, password, "simple"))
+	id, err := c.send(req, nil)
+	if err != nil {
+		return err
+	}
+	op, _, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.Tag != ldapBindResponse {
+		return fmt.Errorf("unexpected ldap operation %d in response to bind", op.Tag)
+	}
+	return ldapResult(op)
+}
+
+// search runs a subtree search under base, asking for pages of pageSize
+// entries, and calls fn for each entry found. Referrals are not followed.
+func (c *ldapConn) search(base, filter string, attributes []string, pageSize int, fn func(ldapEntry)) error {
+	compiled, err := compileLDAPFilter(filter)
+	if err != nil {
+		return fmt.Errorf("ldap filter: %w", err)
+	}
+	var cookie []byte
+	for {
+		req := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchRequest, nil, "search")
+		req.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, base, "baseObject"))
+		req.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, ldapScopeWholeSubtree, "scope"))
+		req.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, ldapNeverDerefAliases, "derefAliases"))
+		req.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 0, "sizeLimit"))
+		req.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 0, "timeLimit"))
+		req.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "typesOnly"))
+		req.AppendChild(compiled)
+		attrs := ber.NewSequence("attributes")
+		for _, a := range attributes {
+			attrs.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a, "attribute"))
+		}
+		req.AppendChild(attrs)
+
+		id, err := c.send(req, pagedResultsControl(pageSize, cookie))
+		if err != nil {
+			return err
+		}
+		cookie = nil
+		for done := false; !done; {
+			op, controls, err := c.receive(id)
+			if err != nil {
+				return err
+			}
+			switch op.Tag {
+			case ldapSearchEntry:
+				fn(parseLDAPEntry(op))
+			case ldapSearchReference:
+			case ldapSearchDone:
+				if err := ldapResult(op); err != nil {
+					return err
+				}
+				cookie = pagedResultsCookie(controls)
+				done = true
+			default:
+				return fmt.Errorf("unexpected ldap operation %d in response to search", op.Tag)
+			}
+		}
+		if len(cookie) == 0 {
+			return nil
+		}
+	}
+}
+
+// pagedResultsControl asks for the page after cookie. The control is not
+// critical, so servers without paging return everything at once.
+func pagedResultsControl(size int, cookie []byte) *ber.Packet {
+	value := ber.NewSequence("pagedResults")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, size, "size"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, string(cookie), "cookie"))
+
+	control := ber.NewSequence("control")
+	control.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, ldapPagedResultsOID, "controlType"))
+	control.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, string(value.Bytes()), "controlValue"))
+
+	controls := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "controls")
+	controls.AppendChild(control)
+	return controls
+}
+
+// pagedResultsCookie returns the cookie of the next page, or nil on the
+// last one.
+func pagedResultsCookie(controls *ber.Packet) []byte {
+	if controls == nil {
+		return nil
+	}
+	for _, control := range controls.Children {
+		if len(control.Children) < 2 || string(control.Children[0].ByteValue) != ldapPagedResultsOID {
+			continue
+		}
+		value, err := ber.DecodePacketErr(control.Children[len(control.Children)-1].ByteValue)
+		if err != nil || len(value.Children) < 2 {
+			return nil
+		}
+		return value.Children[1].ByteValue
+	}
+	return nil
+}
+
+func parseLDAPEntry(op *ber.Packet) ldapEntry {
+	entry := ldapEntry{Attributes: map[string][]string{}}
+	if len(op.Children) < 2 {
+		return entry
+	}
+	entry.DN = string(op.Children[0].ByteValue)
+	for _, attr := range op.Children[1].Children {
+		if len(attr.Children) < 2 {
+			continue
+		}
+		name := strings.ToLower(string(attr.Children[0].ByteValue))
+		for _, v := range attr.Children[1].Children {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.ByteValue))
+		}
+	}
+	return entry
+}
+
+// LDAP filter choices (RFC 4511 section 4.5.1), as context tags.
+const (
+	ldapFilterAnd            ber.Tag = 0
+	ldapFilterOr             ber.Tag = 1
+	ldapFilterNot            ber.Tag = 2
+	ldapFilterEquality       ber.Tag = 3
+	ldapFilterSubstrings     ber.Tag = 4
+	ldapFilterGreaterOrEqual ber.Tag = 5
+	ldapFilterLessOrEqual    ber.Tag = 6
+	ldapFilterPresent        ber.Tag = 7
+	ldapFilterApprox         ber.Tag = 8
+)
+
+// compileLDAPFilter encodes a string filter (RFC 4515) such as
+// "(&(objectClass=person)(mail=*@example.edu))". Extensible matches are
+// not supported.
+func compileLDAPFilter(filter string) (*ber.Packet, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	p, rest, err := parseLDAPFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q after filter", rest)
+	}
+	return p, nil
+}
+
+// parseLDAPFilter parses one parenthesized filter and returns what follows it.
+func parseLDAPFilter(s string) (*ber.Packet, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, errors.New("filter must start with (")
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, s, errors.New("unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|', '!':
+		tag := map[byte]ber.Tag{'&': ldapFilterAnd, '|': ldapFilterOr, '!': ldapFilterNot}[s[0]]
+		p := ber.Encode(ber.ClassContext, ber.TypeConstructed, tag, nil, "filter")
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseLDAPFilter(s)
+			if err != nil {
+				return nil, rest, err
+			}
+			p.AppendChild(child)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, s, errors.New("unterminated filter")
+		}
+		if len(p.Children) == 0 || (tag == ldapFilterNot && len(p.Children) != 1) {
+			return nil, s, errors.New("filter has the wrong number of operands")
+		}
+		return p, s[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, s, errors.New("unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, rest, fmt.Errorf("%q is not an attribute assertion", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+	tag := ldapFilterEquality
+	switch {
+	case strings.HasSuffix(attr, ">"):
+		tag, attr = ldapFilterGreaterOrEqual, strings.TrimSuffix(attr, ">")
+	case strings.HasSuffix(attr, "<"):
+		tag, attr = ldapFilterLessOrEqual, strings.TrimSuffix(attr, "<")
+	case strings.HasSuffix(attr, "~"):
+		tag, attr = ldapFilterApprox, strings.TrimSuffix(attr, "~")
+	case strings.Contains(attr, ":"):
+		return nil, rest, errors.New("extensible match filters are not supported")
+	}
+
+	if tag == ldapFilterEquality && value == "*" {
+		return ber.NewString(ber.ClassContext, ber.TypePrimitive, ldapFilterPresent, attr, "present"), rest, nil
+	}
+	if tag == ldapFilterEquality && strings.Contains(value, "*") {
+		p := ber.Encode(ber.ClassContext, ber.TypeConstructed, ldapFilterSubstrings, nil, "substrings")
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr, "type"))
+		parts := ber.NewSequence("substrings")
+		pieces := strings.Split(value, "*")
+		for i, piece := range pieces {
+			if piece == "" {
+				continue
+			}
+			unescaped, err := unescapeLDAPFilterValue(piece)
+			if err != nil {
+				return nil, rest, err
+			}
+			var which ber.Tag = 1 // any
+			switch i {
+			case 0:
+				which = 0 // initial
+			case len(pieces) - 1:
+				which = 2 // final
+			}
+			parts.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, which, unescaped, "substring"))
+		}
+		p.AppendChild(parts)
+		return p, rest, nil
+	}
+
+	unescaped, err := unescapeLDAPFilterValue(value)
+	if err != nil {
+		return nil, rest, err
+	}
+	p := ber.Encode(ber.ClassContext, ber.TypeConstructed, tag, nil, "assertion")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr, "attributeDesc"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, unescaped, "assertionValue"))
+	return p, rest, nil
+}
+
+// unescapeLDAPFilterValue decodes the \XX escapes of a filter value.
+func unescapeLDAPFilterValue(v string) (string, error) {
+	if !strings.Contains(v, `\`) {
+		return v, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' {
+			b.WriteByte(v[i])
+			continue
+		}
+		if i+2 >= len(v) {
+			return "", fmt.Errorf("truncated escape in %q", v)
+		}
+		n, err := strconv.ParseUint(v[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", v)
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+	return b.String(), nil
+}
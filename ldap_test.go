@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// berHex decodes hex written with spaces between the bytes.
+func berHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompileLDAPFilter(t *testing.T) {
+	for filter, want := range map[string]string{
+		"(cn=Ada)":              "a3 09 04 02 63 6e 04 03 41 64 61",
+		"cn=Ada":                "a3 09 04 02 63 6e 04 03 41 64 61",
+		" (cn=Ada) ":            "a3 09 04 02 63 6e 04 03 41 64 61",
+		`(cn=a\2ab)`:            "a3 09 04 02 63 6e 04 03 61 2a 62",
+		"(mail=*)":              "87 04 6d 61 69 6c",
+		"(cn~=ada)":             "a8 09 04 02 63 6e 04 03 61 64 61",
+		"(|(age>=18)(age<=30))": "a1 16 a5 09 04 03 61 67 65 04 02 31 38 a6 09 04 03 61 67 65 04 02 33 30",
+		"(&(a=1)(!(b=2)))":      "a0 12 a3 06 04 01 61 04 01 31 a2 08 a3 06 04 01 62 04 01 32",
+		// initial, any and final substrings.
+		"(mail=a*b*c)": "a4 11 04 04 6d 61 69 6c 30 09 80 01 61 81 01 62 82 01 63",
+		"(mail=*@x)":   "a4 0c 04 04 6d 61 69 6c 30 04 82 02 40 78",
+	} {
+		p, err := compileLDAPFilter(filter)
+		if err != nil {
+			t.Errorf("%s: %v", filter, err)
+			continue
+		}
+		if got := hex.EncodeToString(p.Bytes()); got != strings.ReplaceAll(want, " ", "") {
+			t.Errorf("%s encodes as %s, want %s", filter, got, want)
+		}
+	}
+}
+
+func TestCompileLDAPFilterErrors(t *testing.T) {
+	for filter, want := range map[string]string{
+		"(cn=Ada":       "unterminated filter",
+		"(&(cn=Ada)":    "unterminated filter",
+		"(":             "unterminated filter",
+		"(&)":           "filter has the wrong number of operands",
+		"(!(a=1)(b=2))": "filter has the wrong number of operands",
+		"(cn)":          `"cn" is not an attribute assertion`,
+		"(=Ada)":        `"=Ada" is not an attribute assertion`,
+		"(cn:dn:=Ada)":  "extensible match filters are not supported",
+		`(cn=\zz)`:      `invalid escape in "\\zz"`,
+		`(cn=a\2)`:      `truncated escape in "a\\2"`,
+		"(a=1)(b=2)":    `unexpected "(b=2)" after filter`,
+	} {
+		_, err := compileLDAPFilter(filter)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: error %v, want %q", filter, err, want)
+		}
+	}
+}
+
+func TestParseLDAPEntry(t *testing.T) {
+	// SearchResultEntry for uid=ada with mail and a two-valued CN.
+	op := ber.DecodePacket(berHex(t, "64 2e"+
+		" 04 07 75 69 64 3d 61 64 61"+
+		" 30 23"+
+		" 30 0f 04 04 6d 61 69 6c 31 07 04 05 61 40 62 2e 63"+
+		" 30 10 04 02 43 4e 31 0a 04 03 41 64 61 04 03 41 2e 4c"))
+	if op.Tag != ldapSearchEntry {
+		t.Fatalf("tag = %d, want %d", op.Tag, ldapSearchEntry)
+	}
+	entry := parseLDAPEntry(op)
+	want := ldapEntry{DN: "uid=ada", Attributes: map[string][]string{"mail": {"a@b.c"}, "cn": {"Ada", "A.L"}}}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("entry = %+v, want %+v", entry, want)
+	}
+	if got := entry.get("Cn"); got != "Ada" {
+		t.Errorf(`get("Cn") = %q, want "Ada"`, got)
+	}
+	if got := entry.get("sn"); got != "" {
+		t.Errorf(`get("sn") = %q, want ""`, got)
+	}
+}
+
+func TestLDAPResult(t *testing.T) {
+	for response, want := range map[string]string{
+		// BindResponse: success, then invalidCredentials with and
+		// without a diagnostic message.
+		"61 07 0a 01 00 04 00 04 00":          "",
+		"61 0a 0a 01 31 04 00 04 03 62 61 64": "ldap result code 49: bad",
+		"61 07 0a 01 31 04 00 04 00":          "ldap result code 49",
+		"61 03 0a 01 00":                      "malformed ldap result",
+	} {
+		err := ldapResult(ber.DecodePacket(berHex(t, response)))
+		if got := errString(err); got != want {
+			t.Errorf("%s: error %q, want %q", response, got, want)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestPagedResultsControl(t *testing.T) {
+	control := pagedResultsControl(500, []byte("ab"))
+	want := "a0 26 30 24" +
+		" 04 16 31 2e 32 2e 38 34 30 2e 31 31 33 35 35 36 2e 31 2e 34 2e 33 31 39" +
+		" 04 0a 30 08 02 02 01 f4 04 02 61 62"
+	if got := hex.EncodeToString(control.Bytes()); got != strings.ReplaceAll(want, " ", "") {
+		t.Errorf("control = %s, want %s", got, want)
+	}
+
+	// The server's answer carries the cookie of the next page, and an
+	// empty one on the last.
+	for controls, want := range map[string]string{
+		"a0 25 30 23 04 16 31 2e 32 2e 38 34 30 2e 31 31 33 35 35 36 2e 31 2e 34 2e 33 31 39 04 09 30 07 02 01 00 04 02 61 62": "ab",
+		"a0 23 30 21 04 16 31 2e 32 2e 38 34 30 2e 31 31 33 35 35 36 2e 31 2e 34 2e 33 31 39 04 07 30 05 02 01 00 04 00":       "",
+		"a0 0c 30 0a 04 03 31 2e 32 04 03 30 01 00": "",
+	} {
+		if got := string(pagedResultsCookie(ber.DecodePacket(berHex(t, controls)))); got != want {
+			t.Errorf("%s: cookie %q, want %q", controls, got, want)
+		}
+	}
+	if got := pagedResultsCookie(nil); got != nil {
+		t.Errorf("cookie without controls = %q", got)
+	}
+}
+
+func TestLDAPBind(t *testing.T) {
+	for response, want := range map[string]string{
+		"30 0c 02 01 01 61 07 0a 01 00 04 00 04 00":          "",
+		"30 0f 02 01 01 61 0a 0a 01 31 04 00 04 03 62 61 64": "ldap result code 49: bad",
+		"30 0c 02 01 02 61 07 0a 01 00 04 00 04 00":          "ldap response to message 2, expected 1",
+		"30 0c 02 01 01 65 07 0a 01 00 04 00 04 00":          "unexpected ldap operation 5 in response to bind",
+		"30 03 02 01 01": "malformed ldap message",
+	} {
+		client, server := net.Pipe()
+		conn := &ldapConn{conn: client}
+		done := make(chan error, 1)
+		go func() { done <- conn.bind("cn=admin", "secret") }()
+
+		// LDAPMessage 1: BindRequest, version 3, simple authentication.
+		request := make([]byte, 28)
+		if _, err := io.ReadFull(server, request); err != nil {
+			t.Fatal(err)
+		}
+		wantRequest := "30 1a 02 01 01 60 15 02 01 03 04 08 63 6e 3d 61 64 6d 69 6e 80 06 73 65 63 72 65 74"
+		if got := hex.EncodeToString(request); got != strings.ReplaceAll(wantRequest, " ", "") {
+			t.Errorf("bind request = %s, want %s", got, wantRequest)
+		}
+		server.Write(berHex(t, response))
+		if got := errString(<-done); got != want {
+			t.Errorf("%s: error %q, want %q", response, got, want)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+// fakeDirectory is an LDAP server holding entries, which it returns a
+// page at a time for the paged results control.
+type fakeDirectory struct {
+	dn, password string
+	entries      []ldapEntry
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeDirectory(t *testing.T, dn, password string, entries ...ldapEntry) (*fakeDirectory, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	d := &fakeDirectory{dn: dn, password: password, entries: entries}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d, "ldap://" + ln.Addr().String()
+}
+
+// log records a request the directory received.
+func (d *fakeDirectory) log(request string) {
+	d.mu.Lock()
+	d.requests = append(d.requests, request)
+	d.mu.Unlock()
+}
+
+func (d *fakeDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msg, err := ber.ReadPacket(conn)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		id, op := msg.Children[0].Value.(int64), msg.Children[1]
+		reply := func(op *ber.Packet, controls *ber.Packet) {
+			out := ber.NewSequence("LDAPMessage")
+			out.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "messageID"))
+			out.AppendChild(op)
+			if controls != nil {
+				out.AppendChild(controls)
+			}
+			conn.Write(out.Bytes())
+		}
+		switch op.Tag {
+		case ldapBindRequest:
+			dn, password := string(op.Children[1].ByteValue), op.Children[2].Data.String()
+			d.log("bind " + dn)
+			code := 0
+			if dn != d.dn || password != d.password {
+				code = 49
+			}
+			reply(fakeLDAPResult(ldapBindResponse, code), nil)
+		case ldapSearchRequest:
+			size, cookie := 0, ""
+			if len(msg.Children) > 2 {
+				value := ber.DecodePacket(msg.Children[2].Children[0].Children[1].ByteValue)
+				size, cookie = int(value.Children[0].Value.(int64)), string(value.Children[1].ByteValue)
+			}
+			var attrs []string
+			for _, a := range op.Children[7].Children {
+				attrs = append(attrs, string(a.ByteValue))
+			}
+			d.log("search " + string(op.Children[0].ByteValue) + " " + hex.EncodeToString(op.Children[6].Bytes()) + " " + strings.Join(attrs, ",") + " page " + strconv.Itoa(size) + " after " + strconv.Quote(cookie))
+
+			start, _ := strconv.Atoi(cookie)
+			end := min(start+size, len(d.entries))
+			for _, e := range d.entries[start:end] {
+				reply(fakeLDAPEntry(e), nil)
+			}
+			next := ""
+			if end < len(d.entries) {
+				next = strconv.Itoa(end)
+			}
+			// One referral, which the client skips.
+			ref := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchReference, nil, "reference")
+			ref.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "ldap://other.example.edu/", "uri"))
+			reply(ref, nil)
+			reply(fakeLDAPResult(ldapSearchDone, 0), pagedResultsControl(0, []byte(next)))
+		case ldapUnbindRequest:
+			d.log("unbind")
+			return
+		}
+	}
+}
+
+func fakeLDAPResult(tag ber.Tag, code int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return p
+}
+
+func fakeLDAPEntry(e ldapEntry) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchEntry, nil, "entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "objectName"))
+	attrs := ber.NewSequence("attributes")
+	for _, name := range sortedKeys(e.Attributes) {
+		attr := ber.NewSequence("attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range e.Attributes[name] {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(values)
+		attrs.AppendChild(attr)
+	}
+	p.AppendChild(attrs)
+	return p
+}
+
+func ldapPerson(uid, name, mail string) ldapEntry {
+	return ldapEntry{DN: "uid=" + uid + ",ou=students,dc=example,dc=edu", Attributes: map[string][]string{"displayName": {name}, "mail": {mail}}}
+}
+
+func TestLDAPSearchPages(t *testing.T) {
+	dir, url := newFakeDirectory(t, "cn=sync,dc=example,dc=edu", "secret",
+		ldapPerson("ada", "Ada Lovelace", "ada@example.edu"),
+		ldapPerson("alan", "Alan Turing", "alan@example.edu"),
+		ldapPerson("grace", "Grace Hopper", "grace@example.edu"))
+
+	conn, err := dialLDAP(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resultErr *ldapResultError
+	if err := conn.bind("cn=sync,dc=example,dc=edu", "wrong"); !errors.As(err, &resultErr) || resultErr.code != 49 {
+		t.Errorf("bind with a wrong password: %v, want result code 49", err)
+	}
+	if err := conn.bind("cn=sync,dc=example,dc=edu", "secret"); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	var names []string
+	err = conn.search("ou=students,dc=example,dc=edu", "(mail=*)", []string{"displayName", "mail"}, 2, func(e ldapEntry) {
+		names = append(names, e.get("displayName")+" <"+e.get("mail")+">")
+	})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if got, want := strings.Join(names, ", "), "Ada Lovelace <ada@example.edu>, Alan Turing <alan@example.edu>, Grace Hopper <grace@example.edu>"; got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+	if err := conn.search("ou=students", "(mail=*", nil, 2, func(ldapEntry) {}); err == nil || err.Error() != "ldap filter: unterminated filter" {
+		t.Errorf("search with a bad filter: %v", err)
+	}
+	conn.Close()
+
+	// The search asks for the second page with the cookie of the first,
+	// and the connection is unbound when closed.
+	want := []string{
+		"bind cn=sync,dc=example,dc=edu",
+		"bind cn=sync,dc=example,dc=edu",
+		"search ou=students,dc=example,dc=edu 87046d61696c displayName,mail page 2 after \"\"",
+		"search ou=students,dc=example,dc=edu 87046d61696c displayName,mail page 2 after \"2\"",
+		"unbind",
+	}
+	for range 100 {
+		dir.mu.Lock()
+		n := len(dir.requests)
+		dir.mu.Unlock()
+		if n == len(want) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	dir.mu.Lock()
+	defer dir.mu.Unlock()
+	if !reflect.DeepEqual(dir.requests, want) {
+		t.Errorf("requests = %q\nwant %q", dir.requests, want)
+	}
+}
+
+func TestLDAPSyncJob(t *testing.T) {
+	alan := ldapPerson("alan", "Alan Turing", "alan@example.edu")
+	alan.Attributes["studentAge"] = []string{"41"}
+	_, url := newFakeDirectory(t, "cn=sync,dc=example,dc=edu", "secret",
+		ldapPerson("ada", "Ada King", "ADA@example.edu"),
+		alan,
+		ldapPerson("grace", "Grace Hopper", "grace@example.edu"),
+		ldapPerson("grace2", "Grace B. Hopper", "grace@example.edu"),
+		ldapEntry{DN: "uid=nomail,ou=students,dc=example,dc=edu", Attributes: map[string][]string{"displayName": {"No Mail"}}})
+	api := newTestAPI(t, func(c *Config) {
+		c.LDAPURL = url
+		c.LDAPBindDN = "cn=sync,dc=example,dc=edu"
+		c.LDAPBindPassword = "secret"
+		c.LDAPBaseDN = "ou=students,dc=example,dc=edu"
+		c.LDAPAttributes = "name=displayName,email=mail,age=studentAge"
+		c.LDAPPageSize = 2
+	})
+	var ada Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.edu"}, http.StatusCreated, &ada)
+
+	result, err := ldapSyncJob(api.srv.state.context(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5 entries: added 1, updated 1, unchanged 0, conflicts 3"; result != want {
+		t.Errorf("result = %q, want %q", result, want)
+	}
+	var report LDAPSyncReport
+	api.decode(t, "GET", "/v1/admin/ldap-sync", nil, http.StatusOK, &report)
+	if len(report.Updated) != 1 || report.Updated[0] != ada.ID || len(report.Added) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	var reasons []string
+	for _, c := range report.Conflicts {
+		reasons = append(reasons, c.DN+": "+c.Reason)
+	}
+	if got, want := strings.Join(reasons, "; "), "uid=nomail,ou=students,dc=example,dc=edu: entry has no mail; "+
+		"uid=grace,ou=students,dc=example,dc=edu: 2 directory entries share this email; "+
+		"uid=grace2,ou=students,dc=example,dc=edu: 2 directory entries share this email"; got != want {
+		t.Errorf("conflicts = %s\nwant %s", got, want)
+	}
+
+	var updated Student
+	api.decode(t, "GET", "/v1/students/"+strconv.Itoa(ada.ID), nil, http.StatusOK, &updated)
+	if updated.Name != "Ada King" || updated.Email != "ADA@example.edu" || updated.Age != 20 {
+		t.Errorf("Ada after the sync = %+v", updated)
+	}
+}
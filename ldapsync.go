@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ldapFields are the student fields ldap_attributes can map. email is
+// required: it is how directory entries are matched to students.
+var ldapFields = []string{"name", "email", "age", "program_id"}
+
+// ldapSyncActor is the audit actor of changes made by the sync.
+const ldapSyncActor = "ldap_sync"
+
+// LDAPConflict is a directory entry the sync left alone, and why.
+type LDAPConflict struct {
+	DN        string `json:"dn"`
+	Email     string `json:"email,omitempty"`
+	StudentID int    `json:"student_id,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// LDAPSyncReport is the outcome of one ldap_sync run. Added and Updated
+// hold student IDs.
+type LDAPSyncReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Entries    int            `json:"entries"`
+	Added      []int          `json:"added"`
+	Updated    []int          `json:"updated"`
+	Unchanged  int            `json:"unchanged"`
+	Conflicts  []LDAPConflict `json:"conflicts"`
+}
+
+func (r LDAPSyncReport) String() string {
+	return fmt.Sprintf("%d entries: added %d, updated %d, unchanged %d, conflicts %d",
+		r.Entries, len(r.Added), len(r.Updated), r.Unchanged, len(r.Conflicts))
+}
+
+// ldapSyncStatus holds the report of a server's last ldap_sync run.
+type ldapSyncStatus struct {
+	mu   sync.Mutex
+	last *LDAPSyncReport
+}
+
+// parseLDAPAttributes reads ldap_attributes, such as
+// "name=displayName,email=mail", into a field to attribute map.
+func parseLDAPAttributes(raw string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, attr, ok := strings.Cut(pair, "=")
+		field, attr = strings.TrimSpace(field), strings.TrimSpace(attr)
+		if !ok || attr == "" {
+			return nil, fmt.Errorf("%q is not of the form field=attribute", pair)
+		}
+		if !slices.Contains(ldapFields, field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(ldapFields, ", "))
+		}
+		mapping[field] = attr
+	}
+	if mapping["name"] == "" || mapping["email"] == "" {
+		return nil, errors.New("name and email must be mapped")
+	}
+	return mapping, nil
+}
+
+// ldapSyncJob pulls student entries from the directory and adds or
+// updates the matching students. Students missing from the directory are
+// kept: the sync never deletes.
+func ldapSyncJob(ctx context.Context) (string, error) {
+	mapping, err := parseLDAPAttributes(cfg.LDAPAttributes)
+	if err != nil {
+		return "", err
+	}
+	report := LDAPSyncReport{StartedAt: clock.Now().UTC(), Added: []int{}, Updated: []int{}, Conflicts: []LDAPConflict{}}
+	entries, err := fetchLDAPEntries(ctx, mapping)
+	if err != nil {
+		return "", fmt.Errorf("reading the directory: %w", err)
+	}
+	report.Entries = len(entries)
+	err = syncLDAPEntries(ctx, entries, mapping, &report)
+	report.FinishedAt = clock.Now().UTC()
+
+	for _, c := range report.Conflicts {
+		slog.WarnContext(ctx, "ldap sync conflict", "dn", c.DN, "email", c.Email, "student_id", c.StudentID, "reason", c.Reason)
+	}
+	status := &stateOf(ctx).ldapSync
+	status.mu.Lock()
+	status.last = &report
+	status.mu.Unlock()
+	return report.String(), err
+}
+
+// fetchLDAPEntries reads every entry matching ldap_filter under
+// ldap_base_dn, within ldap_timeout.
+func fetchLDAPEntries(ctx context.Context, mapping map[string]string) ([]ldapEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.LDAPTimeout)
+	defer cancel()
+	conn, err := dialLDAP(ctx, cfg.LDAPURL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.bind(cfg.LDAPBindDN, cfg.LDAPBindPassword); err != nil {
+		return nil, fmt.Errorf("bind: %w", err)
+	}
+
+	attributes := make([]string, 0, len(mapping))
+	for _, field := range ldapFields {
+		if attr := mapping[field]; attr != "" {
+			attributes = append(attributes, attr)
+		}
+	}
+	var entries []ldapEntry
+	err = conn.search(cfg.LDAPBaseDN, cfg.LDAPFilter, attributes, cfg.LDAPPageSize, func(e ldapEntry) {
+		entries = append(entries, e)
+	})
+	return entries, err
+}
+
+// syncLDAPEntries applies the entries to the store, matching them to
+// students by email, case-insensitively. Entries sharing an email, or
+// matching several students, are conflicts rather than guesses.
+func syncLDAPEntries(ctx context.Context, entries []ldapEntry, mapping map[string]string, report *LDAPSyncReport) error {
+	conflict := func(e ldapEntry, id int, reason string) {
+		report.Conflicts = append(report.Conflicts, LDAPConflict{DN: e.DN, Email: e.get(mapping["email"]), StudentID: id, Reason: reason})
+	}
+
+	byEmail := map[string][]ldapEntry{}
+	var order []string
+	for _, e := range entries {
+		email := strings.ToLower(strings.TrimSpace(e.get(mapping["email"])))
+		if email == "" {
+			conflict(e, 0, "entry has no "+mapping["email"])
+			continue
+		}
+		if byEmail[email] == nil {
+			order = append(order, email)
+		}
+		byEmail[email] = append(byEmail[email], e)
+	}
+	students := map[string][]int{}
+	for _, s := range allStudents(ctx) {
+		email := strings.ToLower(s.Email)
+		students[email] = append(students[email], s.ID)
+	}
+
+	for _, email := range order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		group := byEmail[email]
+		if len(group) > 1 {
+			for _, e := range group {
+				conflict(e, 0, fmt.Sprintf("%d directory entries share this email", len(group)))
+			}
+			continue
+		}
+		e := group[0]
+		ids := students[email]
+		if len(ids) > 1 {
+			conflict(e, 0, "email matches students "+joinInts(ids))
+			continue
+		}
+		id := 0
+		if len(ids) == 1 {
+			id = ids[0]
+		}
+		if reason := applyLDAPEntry(ctx, e, mapping, id, report); reason != "" {
+			conflict(e, id, reason)
+		}
+	}
+	return nil
+}
+
+// applyLDAPEntry adds the student of an entry, or updates student id with
+// it, and returns why it could not.
+func applyLDAPEntry(ctx context.Context, e ldapEntry, mapping map[string]string, id int, report *LDAPSyncReport) string {
+	departmentMutex.Lock()
+	current, exists := Student{}, false
+	if id != 0 {
+		current, exists = findStudent(ctx, id)
+	}
+	s, reason := studentFromLDAPEntry(e, mapping, current)
+	if reason == "" {
+		reason = describeFieldErrors(validateStudent(s))
+	}
+	if reason == "" {
+		reason = describeFieldErrors(programProblem(ctx, s))
+	}
+	if reason != "" {
+		departmentMutex.Unlock()
+		return reason
+	}
+
+	if !exists {
+		s.ID = 0
+		s = insertStudent(ctx, s)
+		departmentMutex.Unlock()
+		report.Added = append(report.Added, s.ID)
+		publishEvent(ctx, StudentCreated{Student: s})
+		return ""
+	}
+	if s == current {
+		departmentMutex.Unlock()
+		report.Unchanged++
+		return ""
+	}
+	s.UpdatedAt = clock.Now().UTC()
+	replaceStudent(ctx, id, s)
+	departmentMutex.Unlock()
+	report.Updated = append(report.Updated, id)
+	recordAuditAs(ctx, ldapSyncActor, "update", id, &current, &s)
+	publishEvent(ctx, StudentUpdated{Before: current, After: s})
+	return ""
+}
+
+// studentFromLDAPEntry maps the attributes of an entry onto base. age and
+// program_id keep their value when the entry lacks the attribute.
+func studentFromLDAPEntry(e ldapEntry, mapping map[string]string, base Student) (Student, string) {
+	s := base
+	for _, field := range ldapFields {
+		attr := mapping[field]
+		if attr == "" {
+			continue
+		}
+		value := strings.TrimSpace(e.get(attr))
+		switch field {
+		case "name", "email":
+			if value == "" {
+				return s, "entry has no " + attr
+			}
+			if field == "name" {
+				s.Name = value
+			} else {
+				s.Email = value
+			}
+		case "age", "program_id":
+			if value == "" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return s, fmt.Sprintf("%s %q is not an integer", attr, value)
+			}
+			if field == "age" {
+				s.Age = n
+			} else {
+				s.ProgramID = n
+			}
+		}
+	}
+	return s, ""
+}
+
+func describeFieldErrors(errs []FieldError) string {
+	parts := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		parts = append(parts, fe.Field+" "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func joinInts(ids []int) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// getLDAPSync reports the outcome of the last ldap_sync run.
+func getLDAPSync(w http.ResponseWriter, r *http.Request) {
+	status := &stateOf(r.Context()).ldapSync
+	status.mu.Lock()
+	report := status.last
+	status.mu.Unlock()
+	if report == nil {
+		writeProblem(w, r, http.StatusNotFound, "No LDAP sync has run yet")
+		return
+	}
+	writeJSONWithETag(w, r, report)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Transaction is one entry in a student's fee ledger. Amounts are in
+// cents so balances add up exactly. Charges may carry a due date.
+type Transaction struct {
+	ID          int       `json:"id"`
+	StudentID   int       `json:"student_id"`
+	Kind        string    `json:"kind" validate:"enum=transaction_kind"`
+	AmountCents int64     `json:"amount_cents" validate:"positive"`
+	Description string    `json:"description" validate:"required"`
+	DueDate     string    `json:"due_date,omitempty"`
+	PostedAt    time.Time `json:"posted_at"`
+}
+
+func (t Transaction) entityID() int { return t.ID }
+
+func (t Transaction) withID(id int) Transaction {
+	t.ID = id
+	return t
+}
+
+var transactionStore Repository[Transaction] = newCollection[Transaction]("transactions")
+
+const (
+	kindCharge  = "charge"
+	kindPayment = "payment"
+)
+
+// validateTransaction lists every problem with a submitted transaction.
+func validateTransaction(t Transaction) []FieldError {
+	errs := checkFields(t)
+	if t.DueDate != "" {
+		if t.Kind != kindCharge {
+			errs = append(errs, FieldError{Field: "due_date", Message: "only applies to charges"})
+		} else if _, err := time.Parse(dateLayout, t.DueDate); err != nil {
+			errs = append(errs, FieldError{Field: "due_date", Message: "must be a date such as 2026-09-01"})
+		}
+	}
+	return errs
+}
+
+// Statement is a student's ledger with its totals. OverdueCents is the
+// part of the balance owed on charges past their due date, with payments
+// applied to the oldest charges first.
+type Statement struct {
+	StudentID    int           `json:"student_id"`
+	Transactions []Transaction `json:"transactions"`
+	ChargedCents int64         `json:"charged_cents"`
+	PaidCents    int64         `json:"paid_cents"`
+	BalanceCents int64         `json:"balance_cents"`
+	OverdueCents int64         `json:"overdue_cents"`
+}
+
+// buildStatements computes the statement of every student with ledger
+// entries, keyed by student ID.
+func buildStatements(ctx context.Context, today string) map[int]*Statement {
+	statements := map[int]*Statement{}
+	for _, t := range repoList(ctx, transactionStore) {
+		s, ok := statements[t.StudentID]
+		if !ok {
+			s = &Statement{StudentID: t.StudentID, Transactions: []Transaction{}}
+			statements[t.StudentID] = s
+		}
+		s.Transactions = append(s.Transactions, t)
+		if t.Kind == kindCharge {
+			s.ChargedCents += t.AmountCents
+		} else {
+			s.PaidCents += t.AmountCents
+		}
+	}
+
+	for _, s := range statements {
+		s.BalanceCents = s.ChargedCents - s.PaidCents
+		charges := slices.DeleteFunc(slices.Clone(s.Transactions), func(t Transaction) bool { return t.Kind != kindCharge })
+		slices.SortStableFunc(charges, func(a, b Transaction) int { return strings.Compare(dueOrder(a), dueOrder(b)) })
+		credit := s.PaidCents
+		for _, c := range charges {
+			owed := c.AmountCents - min(credit, c.AmountCents)
+			credit -= c.AmountCents - owed
+			if c.DueDate != "" && c.DueDate < today {
+				s.OverdueCents += owed
+			}
+		}
+	}
+	return statements
+}
+
+// dueOrder sorts charges without a due date after those with one.
+func dueOrder(t Transaction) string {
+	if t.DueDate == "" {
+		return "9999-12-31"
+	}
+	return t.DueDate
+}
+
+func today() string {
+	return clock.Now().Format(dateLayout)
+}
+
+func postTransaction(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var transaction Transaction
+	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid transaction data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateTransaction(transaction); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid transaction data", errs...)
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	transaction.StudentID = studentID
+	transaction.PostedAt = clock.Now().UTC()
+	transaction = repoInsert(r.Context(), transactionStore, transaction)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+func getStatement(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	statement, ok := buildStatements(r.Context(), today())[studentID]
+	if !ok {
+		statement = &Statement{StudentID: studentID, Transactions: []Transaction{}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statement)
+}
+
+// Balance is the summary line of one student's statement.
+type Balance struct {
+	StudentID    int    `json:"student_id"`
+	Name         string `json:"name"`
+	BalanceCents int64  `json:"balance_cents"`
+	OverdueCents int64  `json:"overdue_cents"`
+}
+
+// getBalances lists students with a ledger and their balances. With
+// ?overdue=true only students owing on past-due charges are listed.
+func getBalances(w http.ResponseWriter, r *http.Request) {
+	overdueOnly := false
+	if v := r.URL.Query().Get("overdue"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid overdue filter", FieldError{Field: "overdue", Message: "must be true or false"})
+			return
+		}
+		overdueOnly = b
+	}
+
+	statements := buildStatements(r.Context(), today())
+	balances := []Balance{}
+	for _, s := range allStudents(r.Context()) {
+		st, ok := statements[s.ID]
+		if !ok || (overdueOnly && st.OverdueCents == 0) {
+			continue
+		}
+		balances = append(balances, Balance{
+			StudentID:    s.ID,
+			Name:         s.Name,
+			BalanceCents: st.BalanceCents,
+			OverdueCents: st.OverdueCents,
+		})
+	}
+	writeJSONArray(w, balances)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// newSlots makes a buffered channel to use as a semaphore of n slots. A
+// nil channel, for n of 0, disables its limit.
+func newSlots(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// acquireSlot takes a slot without waiting, answering 429 when none is
+// free so saturation sheds load instead of piling up goroutines.
+func acquireSlot(w http.ResponseWriter, r *http.Request, slots chan struct{}, pool string) bool {
+	if slots == nil {
+		return true
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		slog.DebugContext(r.Context(), "request rejected, server saturated", "pool", pool, "limit", cap(slots))
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusTooManyRequests, "Server is busy, retry shortly")
+		return false
+	}
+}
+
+// limitInFlight bounds the number of requests served concurrently. Health
+// and metrics endpoints are exempt so probes keep working under load, and
+// so are live update streams, which max_stream_clients limits instead, and
+// the operations of a batch.
+func limitInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz", "/readyz", "/metrics":
+			next.ServeHTTP(w, r)
+			return
+		}
+		if streamingPaths[r.URL.Path] || inBatch(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		slots := stateOf(r.Context()).requestSlots
+		if !acquireSlot(w, r, slots, "all") {
+			return
+		}
+		defer func() { <-slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitLLM bounds the requests waiting on Ollama separately, so slow
+// summaries cannot use up the slots CRUD traffic needs.
+func limitLLM(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slots := stateOf(r.Context()).llmSlots
+		if !acquireSlot(w, r, slots, "llm") {
+			return
+		}
+		defer func() { <-slots }()
+		next(w, r)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// cachedList is a serialized list response and the Link header sent with it.
+type cachedList struct {
+	body []byte
+	link string
+}
+
+// listCache keeps serialized list responses keyed by their normalized
+// query. Entries are tied to the store version they were built from, so
+// any mutation invalidates the whole cache.
+type listCache struct {
+	mu       sync.Mutex
+	version  uint64
+	entries  map[string]cachedList
+	order    []string
+	maxItems int
+	maxBytes int
+}
+
+func newListCache(maxItems, maxBytes int) *listCache {
+	return &listCache{entries: map[string]cachedList{}, maxItems: maxItems, maxBytes: maxBytes}
+}
+
+// cacheKey normalizes a query so parameter order does not split entries.
+func cacheKey(r *http.Request) string {
+	return url.Values(r.URL.Query()).Encode()
+}
+
+func (c *listCache) resetIfStale(version uint64) {
+	if version != c.version {
+		c.version = version
+		c.entries = map[string]cachedList{}
+		c.order = nil
+	}
+}
+
+func (c *listCache) get(version uint64, key string) (cachedList, bool) {
+	if c.maxItems == 0 {
+		return cachedList{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetIfStale(version)
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *listCache) put(version uint64, key string, entry cachedList) {
+	if c.maxItems == 0 || len(entry.body) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetIfStale(version)
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) >= c.maxItems {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = entry
+}
+
+// captureWriter copies what is written to the response until the limit is
+// exceeded, after which it gives up so huge responses stay streamed.
+type captureWriter struct {
+	http.ResponseWriter
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if !cw.overflow {
+		if cw.buf.Len()+len(b) > cw.limit {
+			cw.overflow = true
+			cw.buf = bytes.Buffer{}
+		} else {
+			cw.buf.Write(b)
+		}
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// liveHub fans change feed entries out to connected streaming clients.
+// Each client has a small buffer; one that falls behind is disconnected
+// rather than slowing everybody else down, and can resume from its last
+// change ID.
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[chan Change]bool
+	closed  bool
+}
+
+func newLiveHub() *liveHub {
+	return &liveHub{clients: map[chan Change]bool{}}
+}
+
+var liveClients = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "live_clients",
+	Help: "Clients connected to a live update stream.",
+})
+
+// errTooManyClients is returned when max_stream_clients are connected.
+var errTooManyClients = errors.New("too many live update clients")
+
+// join registers a client. Its channel is closed when the client is
+// dropped or the hub shuts down.
+func (h *liveHub) join() (chan Change, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, http.ErrServerClosed
+	}
+	if len(h.clients) >= cfg.MaxStreamClients {
+		return nil, errTooManyClients
+	}
+	c := make(chan Change, 64)
+	h.clients[c] = true
+	liveClients.Inc()
+	return c, nil
+}
+
+func (h *liveHub) leave(c chan Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c)
+		liveClients.Dec()
+	}
+}
+
+// broadcast hands a change to every client without blocking.
+func (h *liveHub) broadcast(change Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c <- change:
+		default:
+			delete(h.clients, c)
+			close(c)
+			liveClients.Dec()
+		}
+	}
+}
+
+// close disconnects every client, so streams do not hold up a graceful
+// shutdown.
+func (h *liveHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c)
+		liveClients.Dec()
+	}
+}
+
+// changesAfter returns the recorded changes after id that keep selects,
+// for clients resuming a stream.
+func changesAfter(ctx context.Context, id int, keep func(Change) bool) []Change {
+	var list []Change
+	for _, c := range repoList(ctx, changeStore) {
+		if c.ID > id && keep(c) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// streamingPaths are the long-lived live update connections, which are
+// neither limited as in-flight requests nor reported as slow.
+var streamingPaths = map[string]bool{"/v1/students/events": true, "/v1/ws": true, "/gateway/students/watch": true}
+
+// sseKeepAlive is how often an idle stream sends a comment, so proxies
+// do not time the connection out.
+const sseKeepAlive = 15 * time.Second
+
+// streamStudentEvents serves GET /students/events: student changes as
+// Server-Sent Events, named like student.updated and carrying the change
+// feed entry as data. A client reconnecting with Last-Event-ID first
+// receives the changes it missed.
+func streamStudentEvents(w http.ResponseWriter, r *http.Request) {
+	isStudent := func(c Change) bool { return c.Entity == "student" }
+	rc := http.NewResponseController(w)
+
+	last := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		last = n
+	}
+
+	hub := stateOf(r.Context()).hub
+	updates, err := hub.join()
+	if err != nil {
+		w.Header().Set("Retry-After", "5")
+		writeProblem(w, r, http.StatusServiceUnavailable, "Live updates are unavailable: "+err.Error())
+		return
+	}
+	defer hub.leave(updates)
+
+	// The stream outlives write_timeout.
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+
+	send := func(c Change) bool {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s.%s\ndata: %s\n\n", c.ID, c.Entity, c.Action, data); err != nil {
+			return false
+		}
+		last = c.ID
+		return true
+	}
+	if last > 0 {
+		for _, c := range changesAfter(r.Context(), last, isStudent) {
+			if !send(c) {
+				return
+			}
+		}
+	}
+	rc.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-updates:
+			if !ok {
+				return
+			}
+			// Skip what the catch-up above already sent.
+			if c.ID <= last || !isStudent(c) {
+				continue
+			}
+			if !send(c) {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			slog.DebugContext(r.Context(), "live update stream closed", "error", err)
+			return
+		}
+	}
+}
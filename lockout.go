@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// failedAuth tracks consecutive authentication failures from one client.
+// It is forgotten once the client has neither failed nor been locked out
+// for the lockout duration.
+type failedAuth struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func (f *failedAuth) expired(now time.Time) bool {
+	return now.After(f.lockedUntil) && now.Sub(f.lastFailure) >= cfg.AuthLockout
+}
+
+// maxAuthFailureClients bounds the clients tracked at once, so failures
+// from many addresses cannot grow the map without limit.
+const maxAuthFailureClients = 10_000
+
+// lockouts are a server's failed authentications, by client.
+type lockouts struct {
+	mu      sync.Mutex
+	clients map[string]*failedAuth
+}
+
+// lockedOut reports how long the client must wait before authenticating again.
+func lockedOut(ctx context.Context, ip string) (time.Duration, bool) {
+	l := &stateOf(ctx).authFailures
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.clients[ip]
+	if !ok {
+		return 0, false
+	}
+	now := clock.Now()
+	if f.expired(now) {
+		delete(l.clients, ip)
+		return 0, false
+	}
+	remaining := f.lockedUntil.Sub(now)
+	return remaining, remaining > 0
+}
+
+// recordAuthFailure counts a failed attempt and locks the client out once
+// it reaches the limit, raising an alert so credential stuffing is visible.
+func recordAuthFailure(ctx context.Context, ip string) {
+	l := &stateOf(ctx).authFailures
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := clock.Now()
+	f, ok := l.clients[ip]
+	if ok && f.expired(now) {
+		f.count = 0
+	}
+	if !ok {
+		if l.clients == nil {
+			l.clients = make(map[string]*failedAuth)
+		}
+		if len(l.clients) >= maxAuthFailureClients {
+			l.makeRoom(now)
+		}
+		f = &failedAuth{}
+		l.clients[ip] = f
+	}
+	f.count++
+	f.lastFailure = now
+	if f.count >= cfg.AuthMaxFailures {
+		f.lockedUntil = now.Add(cfg.AuthLockout)
+		f.count = 0
+		slog.WarnContext(ctx, "client locked out after repeated failed authentication", "alert", true, "client", ip, "failures", cfg.AuthMaxFailures, "lockout", cfg.AuthLockout.String())
+	}
+}
+
+// makeRoom drops the expired entries, or when none have expired the one
+// idle longest, preferring clients that are not locked out. The caller
+// holds l.mu.
+func (l *lockouts) makeRoom(now time.Time) {
+	var oldest string
+	var oldestFailure *failedAuth
+	for ip, f := range l.clients {
+		if f.expired(now) {
+			delete(l.clients, ip)
+			continue
+		}
+		if oldestFailure == nil || evictBefore(f, oldestFailure, now) {
+			oldest, oldestFailure = ip, f
+		}
+	}
+	if len(l.clients) >= maxAuthFailureClients {
+		delete(l.clients, oldest)
+	}
+}
+
+// evictBefore reports whether a should make room before b.
+func evictBefore(a, b *failedAuth, now time.Time) bool {
+	aLocked, bLocked := a.lockedUntil.After(now), b.lockedUntil.After(now)
+	if aLocked != bLocked {
+		return bLocked
+	}
+	return a.lastFailure.Before(b.lastFailure)
+}
+
+// recordAuthSuccess clears the failure history of a client.
+func recordAuthSuccess(ctx context.Context, ip string) {
+	l := &stateOf(ctx).authFailures
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, ip)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// useLockoutClock sets up lockouts after 3 failures for a minute, timed by
+// the returned clock, for a server whose state the returned context carries.
+func useLockoutClock(t *testing.T) (*FakeClock, context.Context) {
+	oldCfg, oldClock := cfg, clock
+	t.Cleanup(func() { cfg, clock = oldCfg, oldClock })
+	c := NewFakeClock(time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC))
+	cfg.AuthMaxFailures, cfg.AuthLockout, clock = 3, time.Minute, c
+	return c, new(state).context(context.Background())
+}
+
+func TestLockoutExpires(t *testing.T) {
+	c, ctx := useLockoutClock(t)
+	for range 3 {
+		recordAuthFailure(ctx, "192.0.2.1")
+	}
+	if _, locked := lockedOut(ctx, "192.0.2.1"); !locked {
+		t.Fatal("not locked out after 3 failures")
+	}
+	recordAuthFailure(ctx, "192.0.2.2")
+
+	c.Advance(time.Minute + time.Second)
+	if _, locked := lockedOut(ctx, "192.0.2.1"); locked {
+		t.Fatal("still locked out after the lockout")
+	}
+	if _, ok := stateOf(ctx).authFailures.clients["192.0.2.1"]; ok {
+		t.Error("entry kept after its lockout expired")
+	}
+
+	// An old failure does not count towards a new lockout.
+	recordAuthFailure(ctx, "192.0.2.2")
+	recordAuthFailure(ctx, "192.0.2.2")
+	if _, locked := lockedOut(ctx, "192.0.2.2"); locked {
+		t.Error("locked out by failures more than a lockout apart")
+	}
+}
+
+func TestLockoutMapIsBounded(t *testing.T) {
+	c, ctx := useLockoutClock(t)
+	for range 3 {
+		recordAuthFailure(ctx, "192.0.2.1")
+	}
+	for i := range maxAuthFailureClients + 100 {
+		c.Advance(time.Millisecond)
+		recordAuthFailure(ctx, "10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256))
+	}
+	if n := len(stateOf(ctx).authFailures.clients); n > maxAuthFailureClients {
+		t.Errorf("tracking %d clients, want at most %d", n, maxAuthFailureClients)
+	}
+	if _, locked := lockedOut(ctx, "192.0.2.1"); !locked {
+		t.Error("locked out client evicted to make room for clients that are not")
+	}
+}
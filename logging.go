@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// logLevel is the minimum level emitted by the application logger.
+var logLevel = new(slog.LevelVar)
+
+// setupLogging installs the default logger using the configured level and
+// format ("json" or "text").
+func setupLogging() {
+	if err := logLevel.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		logLevel.Set(slog.LevelInfo)
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	if strings.EqualFold(cfg.LogFormat, "text") {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// statusRecorder captures the status code and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// flushing and hijacking keep working through the middleware chain.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// routeTemplate returns the matched route pattern, e.g. /students/{id}.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// requestLogger logs one line per handled request with its outcome and latency.
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r = r.WithContext(withTimings(r.Context()))
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		latency := time.Since(start)
+		if latency > cfg.SlowRequestThreshold && !streamingPaths[r.URL.Path] {
+			attrs := append([]any{
+				"method", r.Method,
+				"route", routeTemplate(r),
+				"status", rec.status,
+				"total_ms", latency.Milliseconds(),
+				"threshold_ms", cfg.SlowRequestThreshold.Milliseconds(),
+			}, timingAttrs(r.Context())...)
+			slog.WarnContext(r.Context(), "slow request", attrs...)
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.Log(r.Context(), level, "request",
+			"method", r.Method,
+			"route", routeTemplate(r),
+			"status", rec.status,
+			"latency_ms", latency.Milliseconds(),
+		)
+	})
+}
+
+// getLogLevel reports the current minimum log level.
+func getLogLevel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"level": strings.ToLower(logLevel.Level().String())})
+}
+
+// setLogLevel changes the minimum log level without a restart.
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	var level slog.Level
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || level.UnmarshalText([]byte(body.Level)) != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid log level")
+		return
+	}
+
+	previous := logLevel.Level()
+	logLevel.Set(level)
+	slog.WarnContext(r.Context(), "log level changed", "from", previous.String(), "to", level.String(), "actor", actorFor(r))
+
+	getLogLevel(w, r)
+}
@@ -1,243 +1,146 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"os"
-	"strconv"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
 	"time"
-
-	"github.com/gorilla/mux"
-)
-
-type Student struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Age   int    `json:"age"`
-	Email string `json:"email"`
-}
-
-var (
-	students = make(map[int]Student)
-	mutex    = &sync.Mutex{}
 )
 
-func createStudent(w http.ResponseWriter, r *http.Request) {
-	var student Student
-	err := json.NewDecoder(r.Body).Decode(&student)
-	if err != nil || student.Name == "" || student.Email == "" || student.Age <= 0 {
-		http.Error(w, "Invalid student data", http.StatusBadRequest)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		if err := runBench(os.Args[2:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "bench:", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	student.ID = len(students) + 1
-	students[student.ID] = student
-
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(student)
-}
-
-func getStudents(w http.ResponseWriter, r *http.Request) {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	var list []Student
-	for _, s := range students {
-		list = append(list, s)
-	}
-
-	json.NewEncoder(w).Encode(list)
-}
-
-func getStudent(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
+	loaded, err := loadConfig(os.Args[1:])
 	if err != nil {
-		http.Error(w, "Invalid student ID", http.StatusBadRequest)
-		return
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(2)
 	}
+	cfg = loaded
 
-	mutex.Lock()
-	student, exists := students[id]
-	mutex.Unlock()
-
-	if !exists {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		return
+	setupLogging()
+	if err := setupAccessLog(); err != nil {
+		slog.Error("failed to open access log", "error", err)
+		os.Exit(1)
 	}
 
-	json.NewEncoder(w).Encode(student)
-}
-
-func updateStudent(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
-	if err != nil {
-		http.Error(w, "Invalid student ID", http.StatusBadRequest)
-		return
+	if cfg.SentryDSN != "" {
+		sentryReporter, err := newSentryReporter(cfg.SentryDSN)
+		if err != nil {
+			slog.Error("failed to set up error reporting", "error", err)
+			os.Exit(1)
+		}
+		reporter = sentryReporter
 	}
+	defer reporter.Flush(5 * time.Second)
 
-	var updated Student
-	err = json.NewDecoder(r.Body).Decode(&updated)
-	if err != nil || updated.Name == "" || updated.Email == "" || updated.Age <= 0 {
-		http.Error(w, "Invalid student data", http.StatusBadRequest)
-		return
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	_, exists := students[id]
-	if !exists {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		return
+	store, err := newStore(cfg)
+	if err != nil {
+		slog.Error("failed to set up store", "error", err)
+		os.Exit(1)
 	}
-
-	updated.ID = id
-	students[id] = updated
-
-	json.NewEncoder(w).Encode(updated)
-}
-
-func deleteStudent(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
+	api, err := NewServer(cfg, store, withChaos(newOllamaClient(cfg)))
 	if err != nil {
-		http.Error(w, "Invalid student ID", http.StatusBadRequest)
-		return
+		slog.Error("failed to set up server", "error", err)
+		os.Exit(1)
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	_, exists := students[id]
-	if !exists {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		return
+	// gRPC gateway
+	if cfg.GRPCPort != "" {
+		gw, err := grpcGateway(context.Background())
+		if err != nil {
+			slog.Error("failed to set up grpc gateway", "error", err)
+			os.Exit(1)
+		}
+		api.Mount("/gateway/", gw)
 	}
 
-	delete(students, id)
-	w.WriteHeader(http.StatusNoContent)
-}
-
-func getStudentSummary(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
-	if err != nil {
-		http.Error(w, "Invalid student ID", http.StatusBadRequest)
-		return
+	if students, courses, err := seedFromConfig(api.state.context(context.Background())); err != nil {
+		slog.Error("failed to load seed file", "error", err)
+		os.Exit(1)
+	} else if students > 0 || courses > 0 {
+		slog.Info("loaded seed file", "file", cfg.Seed, "students", students, "courses", courses)
 	}
 
-	mutex.Lock()
-	student, exists := students[id]
-	mutex.Unlock()
-
-	if !exists {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		return
+	if cfg.Anonymize {
+		count, err := anonymizeAll(api.state.context(context.Background()))
+		if err != nil {
+			slog.Error("failed to rewrite audit log file", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("anonymized students", "count", count)
 	}
 
-	prompt := fmt.Sprintf("Summarize this student profile: Name: %s, Age: %d, Email: %s", student.Name, student.Age, student.Email)
+	srv := newHTTPServer(api)
+	srv.RegisterOnShutdown(api.state.hub.close)
 
-	requestBody := map[string]interface{}{
-		"model":       "llama3",
-		"prompt":      prompt,
-		"temperature": 0.3,
-		"top_p":       0.9,
-		"max_tokens":  50,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		http.Error(w, "Failed to encode request", http.StatusInternalServerError)
-		return
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	client := &http.Client{Timeout: 60 * time.Second}
+	api.Start(ctx)
 
-	req, err := http.NewRequest("POST", "http://localhost:11434/api/generate", bytes.NewBuffer(jsonData))
+	stopGRPC, err := startGRPC(api.state)
 	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
+		slog.Error("failed to start grpc server", "error", err)
+		os.Exit(1)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		http.Error(w, "Failed to call Ollama API: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server running", "addr", listenAddress(srv), "version", version, "tls", cfg.TLSCertFile != "", "http2", srv.Protocols.HTTP2())
+		serverErr <- listenAndServe(srv)
+	}()
 
-	if resp.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("Ollama returned status %d", resp.StatusCode)
-		http.Error(w, msg, http.StatusInternalServerError)
-		return
+	select {
+	case err := <-serverErr:
+		slog.Error("server stopped", "error", err)
+		shutdownTracing(context.Background())
+		reporter.Flush(5 * time.Second)
+		os.Exit(1)
+	case <-ctx.Done():
 	}
 
-	scanner := bufio.NewScanner(resp.Body)
-	var fullResponse strings.Builder
-
-	for scanner.Scan() {
-		var chunk struct {
-			Response string `json:"response"`
-			Done     bool   `json:"done"`
-		}
-
-		line := scanner.Text()
-		err := json.Unmarshal([]byte(line), &chunk)
-		if err != nil {
-			http.Error(w, "Failed to parse Ollama response chunk", http.StatusInternalServerError)
-			return
-		}
-
-		fullResponse.WriteString(chunk.Response)
+	// Keep serving while /readyz fails, so load balancers take the
+	// instance out of rotation before it refuses connections. A second
+	// signal ends the wait, and the process, at once.
+	stop()
+	api.state.shuttingDown.Store(true)
+	slog.Info("shutting down", "drain_delay", cfg.ShutdownDrainDelay.String(), "grace_period", cfg.ShutdownGracePeriod.String())
+	time.Sleep(cfg.ShutdownDrainDelay)
 
-		if chunk.Done {
-			break
-		}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown incomplete", "error", err)
+		srv.Close()
 	}
-
-	if err := scanner.Err(); err != nil {
-		http.Error(w, "Error reading Ollama response stream", http.StatusInternalServerError)
-		return
+	if stopGRPC != nil {
+		stopGRPC(shutdownCtx)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"summary": fullResponse.String()})
-}
-
-func homeHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintln(w, "✅ Student API is working! Visit /students or /students/{id}")
-}
-
-func main() {
-	r := mux.NewRouter()
-
-	// Root route
-	r.HandleFunc("/", homeHandler).Methods("GET")
-
-	// Student CRUD
-	r.HandleFunc("/students", createStudent).Methods("POST")
-	r.HandleFunc("/students", getStudents).Methods("GET")
-	r.HandleFunc("/students/{id}", getStudent).Methods("GET")
-	r.HandleFunc("/students/{id}", updateStudent).Methods("PUT")
-	r.HandleFunc("/students/{id}", deleteStudent).Methods("DELETE")
-	r.HandleFunc("/students/{id}/summary", getStudentSummary).Methods("GET")
-
-	// Read port from environment (required for Render.com)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default for local dev
-	}
-	fmt.Println("Server running on port", port)
-	http.ListenAndServe(":"+port, r)
+	if err := api.Close(); err != nil {
+		slog.Error("failed to close store", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
 }
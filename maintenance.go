@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const defaultMaintenanceMessage = "The service is undergoing maintenance. Changes are temporarily disabled; reads are still available."
+
+// maintenanceMode is the admin switch of a server.
+type maintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+}
+
+// inMaintenance reports whether writes are currently disabled, either by
+// the admin switch or by the presence of the configured flag file.
+func inMaintenance(ctx context.Context) (bool, string) {
+	m := &stateOf(ctx).maintenance
+	m.mu.RLock()
+	enabled, message := m.enabled, m.message
+	m.mu.RUnlock()
+
+	if enabled {
+		return true, message
+	}
+	if cfg.MaintenanceFile != "" {
+		if _, err := os.Stat(cfg.MaintenanceFile); err == nil {
+			return true, defaultMaintenanceMessage
+		}
+	}
+	return false, ""
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// maintenanceGuard rejects mutating requests with 503 while maintenance
+// mode is on. Admin and debug routes stay writable so the switch can be
+// turned off again, and dry runs are let through since they write nothing.
+func maintenanceGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isMutating(r.Method) && !inDryRun(r.Context()) && !strings.HasPrefix(resourcePath(r.URL.Path), "/admin/") && !strings.HasPrefix(r.URL.Path, "/debug/") {
+			if on, message := inMaintenance(r.Context()); on {
+				w.Header().Set("Retry-After", "300")
+				writeProblem(w, r, http.StatusServiceUnavailable, message)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeMaintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	on, message := inMaintenance(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": on,
+		"message": message,
+	})
+}
+
+// getMaintenance reports whether maintenance mode is on.
+func getMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeMaintenanceStatus(w, r)
+}
+
+// setMaintenance turns maintenance mode on or off, optionally with a
+// custom message for clients.
+func setMaintenance(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool  `json:"enabled"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid maintenance settings", FieldError{Field: "enabled", Message: "is required"})
+		return
+	}
+
+	m := &stateOf(r.Context()).maintenance
+	m.mu.Lock()
+	m.enabled = *body.Enabled
+	m.message = defaultMaintenanceMessage
+	if body.Message != "" {
+		m.message = body.Message
+	}
+	m.mu.Unlock()
+
+	slog.WarnContext(r.Context(), "maintenance mode changed", "enabled", *body.Enabled, "actor", actorFor(r))
+	writeMaintenanceStatus(w, r)
+}
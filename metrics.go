@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsState is the state of the last Server built, which the gauges of
+// stored records report on. A process serves one Server, so it is that one.
+var metricsState atomic.Pointer[state]
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	ollamaRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ollama_requests_total",
+		Help: "Calls made to Ollama, by outcome.",
+	}, []string{"outcome"})
+
+	ollamaDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ollama_request_duration_seconds",
+		Help:    "Latency of Ollama calls, including streaming the full response.",
+		Buckets: []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 90},
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "students_stored",
+		Help: "Number of students in the store.",
+	}, func() float64 {
+		st := metricsState.Load()
+		if st == nil {
+			return 0
+		}
+		return float64(studentStore.Count(st.context(context.Background())))
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "audit_entries_stored",
+		Help: "Number of entries in the audit trail.",
+	}, func() float64 {
+		st := metricsState.Load()
+		if st == nil {
+			return 0
+		}
+		st.audit.mu.Lock()
+		defer st.audit.mu.Unlock()
+		return float64(len(st.audit.entries))
+	})
+)
+
+// metricsMiddleware records request counts, latencies and in-flight requests.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		route := routeTemplate(r)
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeOllamaCall records the outcome and latency of one Ollama call.
+func observeOllamaCall(start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	ollamaRequests.WithLabelValues(outcome).Inc()
+	ollamaDuration.Observe(time.Since(start).Seconds())
+}
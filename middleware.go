@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// withTimeout cancels the handler's context after d and answers 503 with a
+// problem document if it has not responded by then.
+func withTimeout(d time.Duration, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(newProblem(r, http.StatusServiceUnavailable, "Request timed out"))
+		http.TimeoutHandler(h, d, string(body)).ServeHTTP(&timeoutProblemWriter{ResponseWriter: w}, r)
+	})
+}
+
+// timeoutProblemWriter labels the body http.TimeoutHandler writes on
+// expiry, which is the only 503 that arrives without a Content-Type.
+type timeoutProblemWriter struct {
+	http.ResponseWriter
+}
+
+func (tw *timeoutProblemWriter) WriteHeader(status int) {
+	if status == http.StatusServiceUnavailable && tw.Header().Get("Content-Type") == "" {
+		tw.Header().Set("Content-Type", problemContentType)
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+// recoverer turns a handler panic into a logged stack trace and a 500
+// response instead of a dropped connection. Panics and any other 5xx
+// response are passed to the error reporter.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		event := ErrorEvent{
+			Method:    r.Method,
+			Route:     routeTemplate(r),
+			RequestID: requestIDFromContext(r.Context()),
+		}
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				if rec.status >= 500 {
+					event.Status = rec.status
+					event.Message = fmt.Sprintf("%s %s returned %d", event.Method, event.Route, rec.status)
+					reporter.Report(r.Context(), event)
+				}
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			stack := string(debug.Stack())
+			slog.ErrorContext(r.Context(), "panic serving request",
+				"panic", fmt.Sprint(p),
+				"stack", stack,
+			)
+
+			event.Panic = true
+			event.Stack = stack
+			event.Status = http.StatusInternalServerError
+			event.Message = fmt.Sprintf("panic: %v", p)
+			reporter.Report(r.Context(), event)
+
+			writeProblem(rec, r, http.StatusInternalServerError, "Internal server error")
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// Handlers only read and write JSON. XML and YAML are offered by
+// converting request bodies and responses at the edge, in negotiateFormat.
+// MessagePack and protobuf are offered for responses only.
+const (
+	formatJSON     = "json"
+	formatXML      = "xml"
+	formatYAML     = "yaml"
+	formatMsgpack  = "msgpack"
+	formatProtobuf = "protobuf"
+)
+
+// maxTranscodedBody caps XML and YAML request bodies, which are read whole
+// before being converted.
+const maxTranscodedBody = 1 << 20
+
+// problemNamespace is the XML namespace of RFC 7807 problem details.
+const problemNamespace = "urn:ietf:rfc:7807"
+
+var mediaFormats = map[string]string{
+	"application/json":         formatJSON,
+	"application/problem+json": formatJSON,
+	"application/xml":          formatXML,
+	"application/problem+xml":  formatXML,
+	"text/xml":                 formatXML,
+	"application/yaml":         formatYAML,
+	"application/x-yaml":       formatYAML,
+	"text/yaml":                formatYAML,
+	msgpackMediaType:           formatMsgpack,
+	"application/x-msgpack":    formatMsgpack,
+	"application/vnd.msgpack":  formatMsgpack,
+	protobufMediaType:          formatProtobuf,
+	"application/protobuf":     formatProtobuf,
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// preferredFormat picks the response format from Accept. The entry with
+// the highest q wins, and the earlier one on a tie. Wildcards and unknown
+// types leave the default, JSON. Browsers list application/xml in their
+// default Accept header, so a request that also accepts text/html is
+// answered in JSON as before.
+func preferredFormat(accept string) string {
+	best, bestQ := formatJSON, 0.0
+	for _, ar := range acceptRanges(accept) {
+		if ar.mediaType == "text/html" {
+			return formatJSON
+		}
+		if format, ok := mediaFormats[ar.mediaType]; ok && ar.q > bestQ {
+			best, bestQ = format, ar.q
+		}
+	}
+	return best
+}
+
+// acceptRange is one entry of an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// acceptRanges splits an Accept header into its entries, in order, with
+// lowercased media types. q defaults to 1.
+func acceptRanges(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: name, q: q})
+	}
+	return ranges
+}
+
+// bodyFormat returns the format named by a request's Content-Type, or ""
+// when it is not one negotiateFormat handles.
+func bodyFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaFormats[mediaType]
+}
+
+// negotiateFormat lets clients send and receive XML or YAML instead of
+// JSON. XML and YAML request bodies are converted to JSON before the
+// handler reads them, guided by the request type documented for the
+// route. JSON responses are converted to the format preferred by Accept,
+// which may also be MessagePack or protobuf. Other responses, such as CSV, PDF and event streams, pass through.
+func negotiateFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		format := preferredFormat(r.Header.Get("Accept"))
+		if format != formatJSON {
+			// Handlers compare If-None-Match with the ETag of their JSON
+			// body, which formatWriter tags with the format.
+			if inm := r.Header.Get("If-None-Match"); inm != "" {
+				r.Header.Set("If-None-Match", strings.ReplaceAll(inm, "-"+format+`"`, `"`))
+			}
+			fw := &formatWriter{ResponseWriter: w, format: format, request: r}
+			defer fw.finish()
+			w = fw
+		}
+
+		if in := bodyFormat(r.Header.Get("Content-Type")); in == formatXML || in == formatYAML {
+			if err := transcodeRequest(r, in); errors.Is(err, errBodyTooLarge) {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, "Request body exceeds "+strconv.Itoa(maxTranscodedBody)+" bytes")
+				return
+			} else if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid "+strings.ToUpper(in)+" body: "+err.Error())
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// transcodeRequest replaces an XML or YAML request body with its JSON
+// equivalent.
+func transcodeRequest(r *http.Request, format string) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTranscodedBody+1))
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	if len(body) > maxTranscodedBody {
+		return errBodyTooLarge
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		r.Body = http.NoBody
+		return nil
+	}
+
+	var doc *docNode
+	if format == formatXML {
+		doc, err = parseXMLDocument(body)
+	} else {
+		doc, err = parseYAMLDocument(body)
+	}
+	if err != nil {
+		return err
+	}
+	converted, err := json.Marshal(doc.toJSON(requestType(r)))
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(converted))
+	r.ContentLength = int64(len(converted))
+	r.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// requestType returns the documented request body type of the matched
+// route, or nil when there is none.
+func requestType(r *http.Request) reflect.Type {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	if doc := lookupOperationDoc(r.Method, path); doc.request != nil {
+		return reflect.TypeOf(doc.request)
+	}
+	return nil
+}
+
+const (
+	docElement = iota
+	docMapping
+	docSequence
+	docScalar
+)
+
+// docNode is a parsed XML or YAML document before it is mapped onto JSON.
+// An XML element does not say whether it holds an object, a list or a
+// value, so that is decided by the Go type the body is decoded into.
+type docNode struct {
+	kind     int
+	name     string
+	children []*docNode
+	text     string
+	// tag is the resolved YAML tag of a scalar, such as !!int.
+	tag string
+}
+
+func parseXMLDocument(body []byte) (*docNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var root *docNode
+	var stack []*docNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &docNode{kind: docElement, name: t.Name.Local}
+			for _, a := range t.Attr {
+				if a.Name.Space == "" && a.Name.Local == "key" {
+					n.name = a.Value
+				}
+			}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			case root != nil:
+				return nil, errors.New("more than one root element")
+			default:
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("no root element")
+	}
+	return root, nil
+}
+
+func parseYAMLDocument(body []byte) (*docNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, errors.New("empty document")
+	}
+	return fromYAML(doc.Content[0])
+}
+
+// fromYAML converts a YAML node. Aliases are refused, since expanding
+// them can turn a small body into a huge one.
+func fromYAML(y *yaml.Node) (*docNode, error) {
+	switch y.Kind {
+	case yaml.AliasNode:
+		return nil, errors.New("aliases are not supported")
+	case yaml.MappingNode:
+		n := &docNode{kind: docMapping}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			child, err := fromYAML(y.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			child.name = y.Content[i].Value
+			n.children = append(n.children, child)
+		}
+		return n, nil
+	case yaml.SequenceNode:
+		n := &docNode{kind: docSequence}
+		for _, item := range y.Content {
+			child, err := fromYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+		return n, nil
+	}
+	return &docNode{kind: docScalar, text: y.Value, tag: y.ShortTag()}, nil
+}
+
+// toJSON maps the node onto the JSON that encoding/json would decode into
+// a value of type t. Without a type, it is inferred from the node.
+func (n *docNode) toJSON(t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.kind == docScalar && n.tag == "!!null" {
+		return nil
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		return n.infer()
+	}
+	if t == timeType {
+		return n.text
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj := map[string]any{}
+		for _, c := range n.children {
+			obj[c.name] = c.toJSON(jsonFieldType(t, c.name))
+		}
+		return obj
+	case reflect.Map:
+		obj := map[string]any{}
+		for _, c := range n.children {
+			obj[c.name] = c.toJSON(t.Elem())
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		items := []any{}
+		for _, c := range n.children {
+			items = append(items, c.toJSON(t.Elem()))
+		}
+		return items
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(strings.TrimSpace(n.text)); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s := strings.TrimSpace(n.text); isJSONNumber(s) {
+			return json.Number(s)
+		}
+	}
+	// Strings, and values of the wrong shape, which the handler rejects.
+	return n.text
+}
+
+// infer maps a node with no target type. XML lists are written as
+// repeated item elements, as formatWriter produces them.
+func (n *docNode) infer() any {
+	switch n.kind {
+	case docScalar:
+		switch n.tag {
+		case "!!int", "!!float":
+			if isJSONNumber(n.text) {
+				return json.Number(n.text)
+			}
+		case "!!bool":
+			if b, err := strconv.ParseBool(n.text); err == nil {
+				return b
+			}
+		}
+		return n.text
+	case docSequence:
+		items := []any{}
+		for _, c := range n.children {
+			items = append(items, c.infer())
+		}
+		return items
+	case docMapping:
+		obj := map[string]any{}
+		for _, c := range n.children {
+			obj[c.name] = c.infer()
+		}
+		return obj
+	}
+
+	if len(n.children) == 0 {
+		s := strings.TrimSpace(n.text)
+		switch {
+		case s == "true" || s == "false":
+			return s == "true"
+		case isJSONNumber(s):
+			return json.Number(s)
+		}
+		return n.text
+	}
+	items := []any{}
+	obj := map[string]any{}
+	for _, c := range n.children {
+		items = append(items, c.infer())
+		obj[c.name] = c.infer()
+	}
+	if len(obj) == 1 && (len(items) > 1 || n.children[0].name == "item") {
+		return items
+	}
+	return obj
+}
+
+func isJSONNumber(s string) bool {
+	return s != "" && (s[0] == '-' || s[0] >= '0' && s[0] <= '9') && json.Valid([]byte(s))
+}
+
+// jsonFieldType returns the type of the struct field encoding/json would
+// decode the key name into, or nil when there is none.
+func jsonFieldType(t reflect.Type, name string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if typ := jsonFieldType(ft, name); typ != nil {
+					return typ
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if strings.EqualFold(tag, name) {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// formatWriter holds back JSON responses and converts them to the
+// negotiated format once the handler is done. Anything else is passed
+// straight through.
+type formatWriter struct {
+	http.ResponseWriter
+	format      string
+	request     *http.Request
+	status      int
+	decided     bool
+	transcoding bool
+	buf         bytes.Buffer
+}
+
+func (fw *formatWriter) WriteHeader(status int) {
+	if fw.decided {
+		return
+	}
+	fw.status = status
+	fw.decide()
+}
+
+func (fw *formatWriter) Write(b []byte) (int, error) {
+	if !fw.decided {
+		fw.decide()
+	}
+	if fw.transcoding {
+		return fw.buf.Write(b)
+	}
+	return fw.ResponseWriter.Write(b)
+}
+
+func (fw *formatWriter) decide() {
+	fw.decided = true
+	h := fw.Header()
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	fw.transcoding = (mediaType == "application/json" || mediaType == "application/problem+json") &&
+		fw.status != http.StatusNoContent && fw.status != http.StatusNotModified
+	if fw.transcoding {
+		return
+	}
+	if fw.status == http.StatusNotModified {
+		fw.tagETag()
+	}
+	if fw.status != 0 {
+		fw.ResponseWriter.WriteHeader(fw.status)
+	}
+}
+
+// tagETag gives the converted representation its own entity tag.
+func (fw *formatWriter) tagETag() {
+	if etag := fw.Header().Get("ETag"); strings.HasSuffix(etag, `"`) {
+		fw.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+fw.format+`"`)
+	}
+}
+
+func (fw *formatWriter) Flush() {
+	if !fw.decided {
+		fw.decide()
+	}
+	if !fw.transcoding {
+		http.NewResponseController(fw.ResponseWriter).Flush()
+	}
+}
+
+func (fw *formatWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
+
+// finish converts and sends a held-back response. A body that is not
+// valid JSON after all is sent unchanged.
+func (fw *formatWriter) finish() {
+	if !fw.decided {
+		fw.decide()
+	}
+	if !fw.transcoding {
+		return
+	}
+	h := fw.Header()
+	problem := strings.HasPrefix(h.Get("Content-Type"), "application/problem+json")
+	body := fw.buf.Bytes()
+	if v, err := decodeOrdered(json.NewDecoder(bytes.NewReader(body))); err == nil {
+		var converted []byte
+		var contentType string
+		switch fw.format {
+		case formatXML:
+			converted, err = encodeXML(v, problem)
+			contentType = "application/xml"
+			if problem {
+				contentType = "application/problem+xml"
+			}
+		case formatYAML:
+			converted, err = encodeYAML(v)
+			contentType = "application/yaml"
+		case formatMsgpack:
+			converted, err = encodeMsgpack(v)
+			contentType = msgpackMediaType
+		case formatProtobuf:
+			converted, contentType, err = encodeProtobuf(fw.request, fw.status, h, body, problem)
+		}
+		if err == nil {
+			body = converted
+			h.Set("Content-Type", contentType)
+			fw.tagETag()
+		}
+	}
+	h.Del("Content-Length")
+	status := fw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	fw.ResponseWriter.WriteHeader(status)
+	fw.ResponseWriter.Write(body)
+}
+
+// jsonMember is one member of a JSON object, kept in document order so the
+// converted response lists fields in the same order as the JSON one.
+type jsonMember struct {
+	key   string
+	value any
+}
+
+// decodeOrdered decodes a JSON value into []jsonMember for objects, []any
+// for arrays, and string, json.Number, bool or nil for the rest.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		members := []jsonMember{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, jsonMember{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return members, err
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			item, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+	return tok, nil
+}
+
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// encodeXML writes v under a response root element, or under an RFC 7807
+// problem element. Array items become item elements, keys that are not
+// valid element names become entry elements with a key attribute, and
+// null members are left out.
+func encodeXML(v any, problem bool) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if problem {
+		root.Name = xml.Name{Space: problemNamespace, Local: "problem"}
+	}
+	if err := writeXMLValue(enc, root, v); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeXMLValue(enc *xml.Encoder, start xml.StartElement, v any) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case []jsonMember:
+		for _, m := range v {
+			if m.value == nil {
+				continue
+			}
+			el := xml.StartElement{Name: xml.Name{Local: m.key}}
+			if !xmlNamePattern.MatchString(m.key) || strings.HasPrefix(strings.ToLower(m.key), "xml") {
+				el = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: m.key}}}
+			}
+			if err := writeXMLValue(enc, el, m.value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "item"}}, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(v)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case []jsonMember:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		for _, m := range v {
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.key}, yamlNode(m.value))
+		}
+		return n
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range v {
+			n.Content = append(n.Content, yamlNode(item))
+		}
+		return n
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case json.Number:
+		tag := "!!float"
+		if _, err := v.Int64(); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"studengo/ollama"
+)
+
+// featureNLQuery gates natural-language student queries, which are
+// experimental while the filters models write are checked in practice.
+const featureNLQuery = "nl_query"
+
+// StudentQuery is a question about students in plain language, such as
+// "students over 20 in program 3".
+type StudentQuery struct {
+	Question string `json:"question" validate:"required"`
+}
+
+// StudentQueryResult is the $filter the model made of a question and the
+// students it matches, at most one page of them. The filter is returned so
+// clients can show it and run it on GET /students themselves.
+type StudentQueryResult struct {
+	Filter   string    `json:"filter"`
+	Students []Student `json:"students"`
+}
+
+func queryStudents(w http.ResponseWriter, r *http.Request) {
+	var query StudentQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query", decodeErrors(err)...)
+		return
+	}
+	if errs := checkFields(query); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query", errs...)
+		return
+	}
+
+	filter, err := questionToFilter(r.Context(), query.Question)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "query translation failed", "error", err)
+		writeProblem(w, r, http.StatusBadGateway, "The question could not be translated")
+		return
+	}
+	expr, err := parseODataFilter(filter, odataFields(reflect.TypeFor[Student]()))
+	if err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The question did not translate to a valid filter: "+err.Error())
+		return
+	}
+
+	result := StudentQueryResult{Filter: filter, Students: []Student{}}
+	for s := range repoScan(r.Context(), studentStore, 0) {
+		if expr.match(reflect.ValueOf(s)) {
+			result.Students = append(result.Students, s)
+			if len(result.Students) == cfg.MaxPageSize {
+				break
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// questionToFilter asks the model for the OData $filter expressing a
+// question about students.
+func questionToFilter(ctx context.Context, question string) (filter string, err error) {
+	start := time.Now()
+	llm := stateOf(ctx).llm
+	ctx, span := tracer.Start(ctx, "ollama.generate", trace.WithAttributes(attribute.String("llm.model", llm.Model())))
+	defer span.End()
+	defer trackTiming(ctx, "ollama", start)
+	defer func() { observeOllamaCall(start, err) }()
+
+	prompt := fmt.Sprintf("Translate the question into an OData $filter over students with the fields %s. "+
+		"Use eq, ne, gt, ge, lt, le, and, or, not, contains, startswith and endswith, and quote strings with single quotes. "+
+		"Answer with the filter alone.\nQuestion: %s",
+		strings.Join(jsonFieldNames(reflect.TypeFor[Student]()), ", "), question)
+	header := http.Header{}
+	if id := requestIDFromContext(ctx); id != "" {
+		header.Set(requestIDHeader, id)
+	}
+	gen, err := llm.Generate(ctx, ollama.GenerateRequest{Prompt: prompt, MaxTokens: 100, Header: header})
+	if err != nil {
+		return "", err
+	}
+	filter = strings.Trim(strings.TrimSpace(gen.Response), "`")
+	filter = strings.TrimSpace(strings.TrimPrefix(filter, "$filter="))
+	if filter == "" {
+		return "", errors.New("empty filter")
+	}
+	return filter, nil
+}
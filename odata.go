@@ -0,0 +1,668 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// odataQuery is the subset of OData system query options a list accepts:
+// $filter, $orderby, $top, $skip and $select. Fields are named as in the
+// JSON representation.
+//
+// $filter supports eq, ne, gt, ge, lt, le, and, or, not, parentheses,
+// contains, startswith and endswith, and tolower and toupper on fields.
+// Strings are quoted with ', doubled to escape it; dates and times are
+// unquoted, such as 2026-09-01 or 2026-09-01T08:00:00Z.
+type odataQuery struct {
+	filter  odataExpr
+	orderBy []odataOrder
+	top     int
+	skip    int
+	// offset is set by $orderby, $top or $skip, which page by position
+	// instead of after and limit.
+	offset bool
+	// fields are the $select fields, or nil for all of them.
+	fields []string
+}
+
+type odataOrder struct {
+	field odataField
+	desc  bool
+}
+
+// odataField is a field of the listed type that can be filtered or
+// sorted on.
+type odataField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	// fold is "tolower" or "toupper" when the field is wrapped in one.
+	fold string
+}
+
+func (f odataField) value(item reflect.Value) any {
+	v := item.FieldByIndex(f.index)
+	switch {
+	case v.Type() == reflect.TypeFor[time.Time]():
+		return v.Interface().(time.Time)
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	case v.CanFloat():
+		return v.Float()
+	case v.Kind() == reflect.Bool:
+		return v.Bool()
+	}
+	s := v.String()
+	switch f.fold {
+	case "tolower":
+		s = strings.ToLower(s)
+	case "toupper":
+		s = strings.ToUpper(s)
+	}
+	return s
+}
+
+// odataFields maps the JSON names of the fields of t that OData can
+// compare to the fields.
+func odataFields(t reflect.Type) map[string]odataField {
+	fields := map[string]odataField{}
+	for _, f := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || f.Anonymous || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		switch {
+		case f.Type == reflect.TypeFor[time.Time](),
+			f.Type.Kind() == reflect.String, f.Type.Kind() == reflect.Bool,
+			f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Float64:
+			fields[name] = odataField{name: name, index: f.Index, typ: f.Type}
+		}
+	}
+	return fields
+}
+
+// jsonFieldNames lists the JSON names of the fields of t, in order.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for _, f := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || f.Anonymous || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseODataQuery reads the OData options of a request listing t.
+func parseODataQuery(r *http.Request, t reflect.Type) (odataQuery, []FieldError) {
+	return parseODataOptions(r.URL.Query(), t)
+}
+
+// parseODataOptions reads the OData options in query for a list of t.
+func parseODataOptions(query url.Values, t reflect.Type) (odataQuery, []FieldError) {
+	q := odataQuery{top: cfg.DefaultPageSize}
+	fields := odataFields(t)
+	var errs []FieldError
+
+	if v := query.Get("$filter"); v != "" {
+		expr, err := parseODataFilter(v, fields)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "$filter", Message: err.Error()})
+		}
+		q.filter = expr
+	}
+	if v := query.Get("$orderby"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			name, dir, _ := strings.Cut(strings.TrimSpace(part), " ")
+			dir = strings.ToLower(strings.TrimSpace(dir))
+			field, ok := fields[name]
+			if !ok || (dir != "" && dir != "asc" && dir != "desc") {
+				errs = append(errs, FieldError{Field: "$orderby", Message: fmt.Sprintf("%q is not a field optionally followed by asc or desc", strings.TrimSpace(part))})
+				continue
+			}
+			q.orderBy = append(q.orderBy, odataOrder{field: field, desc: dir == "desc"})
+		}
+		q.offset = true
+	}
+	if v := query.Get("$top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > cfg.MaxPageSize {
+			errs = append(errs, FieldError{Field: "$top", Message: "must be between 1 and " + strconv.Itoa(cfg.MaxPageSize)})
+		}
+		q.top = n
+		q.offset = true
+	}
+	if v := query.Get("$skip"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, FieldError{Field: "$skip", Message: "must be a non-negative integer"})
+		}
+		q.skip = n
+		q.offset = true
+	}
+	if v := query.Get("$select"); v != "" && strings.TrimSpace(v) != "*" {
+		names := jsonFieldNames(t)
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(names, name) {
+				errs = append(errs, FieldError{Field: "$select", Message: fmt.Sprintf("unknown field %q", name)})
+				continue
+			}
+			if !slices.Contains(q.fields, name) {
+				q.fields = append(q.fields, name)
+			}
+		}
+	}
+	if q.offset {
+		for _, name := range []string{"after", "limit"} {
+			if query.Has(name) {
+				errs = append(errs, FieldError{Field: name, Message: "cannot be combined with $orderby, $top or $skip"})
+			}
+		}
+	}
+	return q, errs
+}
+
+// selects reports whether $select narrowed the fields of the items.
+func (q odataQuery) selects() bool {
+	return q.fields != nil
+}
+
+// applyODataFilter keeps the items matching $filter.
+func applyODataFilter[T any](q odataQuery, items []T) []T {
+	if q.filter == nil {
+		return items
+	}
+	var kept []T
+	for _, item := range items {
+		if q.filter.match(reflect.ValueOf(item)) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// offsetPage sorts the filtered items by $orderby and cuts out the page
+// selected by $skip and $top. It returns the Link header pointing at the
+// next page, or "" on the last page. Items that compare equal keep their
+// order by ID.
+func offsetPage[T any](r *http.Request, q odataQuery, items []T) ([]T, string) {
+	items = sortOData(q, items)
+	if q.skip >= len(items) {
+		return []T{}, ""
+	}
+	items = items[q.skip:]
+	if len(items) <= q.top {
+		return items, ""
+	}
+
+	query := r.URL.Query()
+	query.Set("$skip", strconv.Itoa(q.skip+q.top))
+	query.Set("$top", strconv.Itoa(q.top))
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return items[:q.top], "<" + next.String() + `>; rel="next"`
+}
+
+// sortOData returns the items sorted by $orderby. Items that compare
+// equal keep their order.
+func sortOData[T any](q odataQuery, items []T) []T {
+	if len(q.orderBy) == 0 {
+		return items
+	}
+	items = slices.Clone(items)
+	slices.SortStableFunc(items, func(a, b T) int {
+		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		for _, o := range q.orderBy {
+			c := compareODataValues(o.field.value(va), o.field.value(vb))
+			if o.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return items
+}
+
+// jsonMembers encodes v, a struct, and returns its JSON members by name.
+func jsonMembers(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(data, &members)
+	return members, err
+}
+
+// selectFields encodes items with only the $select fields, in the order
+// they were named.
+func selectFields[T any](q odataQuery, items []T) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		members, err := jsonMembers(item)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		b.WriteByte('{')
+		for _, name := range q.fields {
+			value, ok := members[name]
+			if !ok {
+				// Fields left out as empty are sent as null, so every
+				// item has the selected fields.
+				value = json.RawMessage("null")
+			}
+			if b.Len() > 1 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(name)
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteByte('}')
+		out = append(out, json.RawMessage(b.String()))
+	}
+	return out, nil
+}
+
+// compareODataValues orders two values of the same field.
+func compareODataValues(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		if a {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// odataExpr is a parsed $filter expression.
+type odataExpr interface {
+	match(item reflect.Value) bool
+}
+
+type odataAnd struct{ left, right odataExpr }
+
+func (e odataAnd) match(item reflect.Value) bool { return e.left.match(item) && e.right.match(item) }
+
+type odataOr struct{ left, right odataExpr }
+
+func (e odataOr) match(item reflect.Value) bool { return e.left.match(item) || e.right.match(item) }
+
+type odataNot struct{ expr odataExpr }
+
+func (e odataNot) match(item reflect.Value) bool { return !e.expr.match(item) }
+
+// odataCompare compares a field with a literal of the same kind.
+type odataCompare struct {
+	field odataField
+	op    string
+	value any
+}
+
+func (e odataCompare) match(item reflect.Value) bool {
+	c := compareODataValues(e.field.value(item), e.value)
+	switch e.op {
+	case "eq":
+		return c == 0
+	case "ne":
+		return c != 0
+	case "gt":
+		return c > 0
+	case "ge":
+		return c >= 0
+	case "lt":
+		return c < 0
+	}
+	return c <= 0
+}
+
+// odataStringFunc is a call of contains, startswith or endswith.
+type odataStringFunc struct {
+	name  string
+	field odataField
+	arg   string
+}
+
+func (e odataStringFunc) match(item reflect.Value) bool {
+	s, _ := e.field.value(item).(string)
+	switch e.name {
+	case "contains":
+		return strings.Contains(s, e.arg)
+	case "startswith":
+		return strings.HasPrefix(s, e.arg)
+	}
+	return strings.HasSuffix(s, e.arg)
+}
+
+// odataToken is a lexical token of $filter. kind is one of "(", ")", ",",
+// "string", "literal" (numbers, dates and keywords such as true) or
+// "name".
+type odataToken struct {
+	kind string
+	text string
+}
+
+func lexODataFilter(s string) ([]odataToken, error) {
+	var tokens []odataToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, odataToken{kind: string(c)})
+			i++
+		case c == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			tokens = append(tokens, odataToken{kind: "string", text: b.String()})
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			for i++; i < len(s) && strings.IndexByte("0123456789.-:+TZ", s[i]) >= 0; i++ {
+			}
+			tokens = append(tokens, odataToken{kind: "literal", text: s[start:i]})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i++; i < len(s) && (s[i] == '_' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))); i++ {
+			}
+			tokens = append(tokens, odataToken{kind: "name", text: s[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// odataParser is a recursive descent parser of $filter:
+//
+//	expr    = and { "or" and }
+//	and     = unary { "and" unary }
+//	unary   = "not" unary | primary
+//	primary = "(" expr ")" | func "(" field "," string ")" | operand op operand
+type odataParser struct {
+	tokens []odataToken
+	pos    int
+	fields map[string]odataField
+}
+
+func parseODataFilter(s string, fields map[string]odataField) (odataExpr, error) {
+	tokens, err := lexODataFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &odataParser{tokens: tokens, fields: fields}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.describe())
+	}
+	return expr, nil
+}
+
+func (p *odataParser) peek() odataToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return odataToken{kind: "end"}
+}
+
+// keyword reports whether the next token is the keyword word, consuming
+// it if so.
+func (p *odataParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == "name" && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *odataParser) expect(kind string) error {
+	if p.peek().kind != kind {
+		return fmt.Errorf("expected %q, found %s", kind, p.describe())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *odataParser) describe() string {
+	switch t := p.peek(); t.kind {
+	case "end":
+		return "end of filter"
+	case "string":
+		return "'" + t.text + "'"
+	case "name", "literal":
+		return strconv.Quote(t.text)
+	default:
+		return strconv.Quote(t.kind)
+	}
+}
+
+func (p *odataParser) or() (odataExpr, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("or") {
+		var right odataExpr
+		if right, err = p.and(); err == nil {
+			left = odataOr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *odataParser) and() (odataExpr, error) {
+	left, err := p.unary()
+	for err == nil && p.keyword("and") {
+		var right odataExpr
+		if right, err = p.unary(); err == nil {
+			left = odataAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *odataParser) unary() (odataExpr, error) {
+	if p.keyword("not") {
+		expr, err := p.unary()
+		return odataNot{expr}, err
+	}
+	return p.primary()
+}
+
+func (p *odataParser) primary() (odataExpr, error) {
+	if p.peek().kind == "(" {
+		p.pos++
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+	if t := p.peek(); t.kind == "name" {
+		switch name := strings.ToLower(t.text); name {
+		case "contains", "startswith", "endswith":
+			p.pos++
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			field, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			if field.typ.Kind() != reflect.String {
+				return nil, fmt.Errorf("%s is not text, so %s cannot be applied to it", field.name, name)
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.peek().kind != "string" {
+				return nil, fmt.Errorf("%s expects a string, found %s", name, p.describe())
+			}
+			arg := p.tokens[p.pos].text
+			p.pos++
+			return odataStringFunc{name: name, field: field, arg: arg}, p.expect(")")
+		}
+	}
+
+	// The literal may come first, as in 18 le age.
+	flipped := p.peek().kind != "name" || isODataKeywordLiteral(p.peek().text)
+	var field odataField
+	var literal odataToken
+	var err error
+	if flipped {
+		literal, err = p.literal()
+	} else {
+		field, err = p.field()
+	}
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(p.peek().text)
+	if p.peek().kind != "name" || !slices.Contains([]string{"eq", "ne", "gt", "ge", "lt", "le"}, op) {
+		return nil, fmt.Errorf("expected a comparison operator, found %s", p.describe())
+	}
+	p.pos++
+	if flipped {
+		field, err = p.field()
+		op = cmp.Or(map[string]string{"gt": "lt", "ge": "le", "lt": "gt", "le": "ge"}[op], op)
+	} else {
+		literal, err = p.literal()
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, err := odataLiteralValue(field, literal)
+	if err != nil {
+		return nil, err
+	}
+	return odataCompare{field: field, op: op, value: value}, nil
+}
+
+// field reads a field name, optionally wrapped in tolower or toupper.
+func (p *odataParser) field() (odataField, error) {
+	t := p.peek()
+	if t.kind != "name" {
+		return odataField{}, fmt.Errorf("expected a field, found %s", p.describe())
+	}
+	p.pos++
+	if fold := strings.ToLower(t.text); fold == "tolower" || fold == "toupper" {
+		if err := p.expect("("); err != nil {
+			return odataField{}, err
+		}
+		field, err := p.field()
+		if err != nil {
+			return odataField{}, err
+		}
+		if field.typ.Kind() != reflect.String {
+			return odataField{}, fmt.Errorf("%s is not text, so %s cannot be applied to it", field.name, fold)
+		}
+		field.fold = fold
+		return field, p.expect(")")
+	}
+	field, ok := p.fields[t.text]
+	if !ok {
+		return odataField{}, fmt.Errorf("unknown field %q", t.text)
+	}
+	return field, nil
+}
+
+func (p *odataParser) literal() (odataToken, error) {
+	t := p.peek()
+	if t.kind != "string" && t.kind != "literal" && !(t.kind == "name" && isODataKeywordLiteral(t.text)) {
+		return t, fmt.Errorf("expected a value, found %s", p.describe())
+	}
+	p.pos++
+	return t, nil
+}
+
+func isODataKeywordLiteral(name string) bool {
+	return name == "true" || name == "false"
+}
+
+// odataLiteralValue converts a literal to the kind of value
+// odataField.value returns for field.
+func odataLiteralValue(field odataField, t odataToken) (any, error) {
+	mismatch := fmt.Errorf("%s cannot be compared with %s", field.name, t.text)
+	if t.kind == "string" {
+		mismatch = fmt.Errorf("%s cannot be compared with '%s'", field.name, t.text)
+	}
+	switch {
+	case field.typ == reflect.TypeFor[time.Time]():
+		if t.kind != "literal" {
+			return nil, mismatch
+		}
+		for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+			if at, err := time.Parse(layout, t.text); err == nil {
+				return at, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not a date or an RFC 3339 time", t.text)
+	case field.typ.Kind() == reflect.String:
+		if t.kind != "string" {
+			return nil, mismatch
+		}
+		return t.text, nil
+	case field.typ.Kind() == reflect.Bool:
+		if t.kind != "name" {
+			return nil, mismatch
+		}
+		return t.text == "true", nil
+	}
+	if t.kind != "literal" {
+		return nil, mismatch
+	}
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a number", t.text)
+	}
+	return n, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var odataStudents = []Student{
+	{ID: 1, Name: "Ada Lovelace", Age: 20, Email: "ada@example.com", ProgramID: 1, UpdatedAt: time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)},
+	{ID: 2, Name: "Alan Turing", Age: 41, Email: "alan@example.com", UpdatedAt: time.Date(2026, 9, 2, 8, 0, 0, 0, time.UTC)},
+	{ID: 3, Name: "Grace O'Hopper", Age: 85, Email: "grace@example.org", ProgramID: 2, UpdatedAt: time.Date(2026, 9, 3, 8, 0, 0, 0, time.UTC)},
+}
+
+// filterIDs lists the IDs of the students matching expr, comma-separated.
+func filterIDs(expr odataExpr) string {
+	var ids []string
+	for _, s := range odataStudents {
+		if expr.match(reflect.ValueOf(s)) {
+			ids = append(ids, strconv.Itoa(s.ID))
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestODataFilter(t *testing.T) {
+	fields := odataFields(reflect.TypeFor[Student]())
+	for filter, want := range map[string]string{
+		"age eq 20":                            "1",
+		"age ne 20":                            "2,3",
+		"age gt 41":                            "3",
+		"age ge 41":                            "2,3",
+		"age lt 41":                            "1",
+		"age le 41":                            "1,2",
+		"age EQ 20 AND name eq 'Ada Lovelace'": "1",
+		"18 le age and 50 gt age":              "1,2",
+		"41 eq age":                            "2",
+		"name eq 'Grace O''Hopper'":            "3",
+		"program_id eq 0":                      "2",
+		"contains(name,'an')":                  "2",
+		"startswith(email, 'a')":               "1,2",
+		"endswith(email,'.org')":               "3",
+		"CONTAINS(name,'Ada')":                 "1",
+		"tolower(name) eq 'alan turing'":       "2",
+		"startswith(toupper(name),'GRACE')":    "3",
+		"contains(tolower(email),'EXAMPLE')":   "",
+		"updated_at gt 2026-09-01":             "1,2,3",
+		"updated_at lt 2026-09-02T08:00:00Z":   "1",
+		"updated_at ge 2026-09-02T08:00:00Z":   "2,3",
+		"not (age eq 20)":                      "2,3",
+		"not not age eq 20":                    "1",
+		"(age eq 20 or age eq 85) and contains(email,'.com')": "1",
+		"((age eq 20))": "1",
+	} {
+		expr, err := parseODataFilter(filter, fields)
+		if err != nil {
+			t.Errorf("%s: %v", filter, err)
+			continue
+		}
+		if got := filterIDs(expr); got != want {
+			t.Errorf("%s matches %q, want %q", filter, got, want)
+		}
+	}
+}
+
+func TestODataFilterPrecedence(t *testing.T) {
+	fields := odataFields(reflect.TypeFor[Student]())
+	// Each filter matches the same students as its fully parenthesized
+	// reading: not binds tighter than and, and and tighter than or.
+	for filter, grouped := range map[string]string{
+		"age eq 20 or age eq 41 and program_id eq 1":          "age eq 20 or (age eq 41 and program_id eq 1)",
+		"age eq 41 and program_id eq 1 or age eq 20":          "(age eq 41 and program_id eq 1) or age eq 20",
+		"not age eq 20 and age lt 50":                         "(not age eq 20) and age lt 50",
+		"not age eq 20 or age eq 20":                          "(not age eq 20) or age eq 20",
+		"age eq 20 or age eq 41 or age eq 85 and age eq 0":    "age eq 20 or age eq 41 or (age eq 85 and age eq 0)",
+		"age gt 18 and not contains(name,'Ada') or age eq 20": "(age gt 18 and (not contains(name,'Ada'))) or age eq 20",
+	} {
+		expr, err := parseODataFilter(filter, fields)
+		if err != nil {
+			t.Fatalf("%s: %v", filter, err)
+		}
+		want, err := parseODataFilter(grouped, fields)
+		if err != nil {
+			t.Fatalf("%s: %v", grouped, err)
+		}
+		if got, want := filterIDs(expr), filterIDs(want); got != want {
+			t.Errorf("%s matches %q, but %s matches %q", filter, got, grouped, want)
+		}
+	}
+
+	// The left-hand operand of or is not swallowed by a following and.
+	expr, _ := parseODataFilter("age eq 20 or age eq 41 and program_id eq 1", fields)
+	if or, ok := expr.(odataOr); !ok {
+		t.Errorf("parsed as %T, want odataOr", expr)
+	} else if _, ok := or.right.(odataAnd); !ok {
+		t.Errorf("right of or is %T, want odataAnd", or.right)
+	}
+}
+
+func TestODataFilterErrors(t *testing.T) {
+	fields := odataFields(reflect.TypeFor[Student]())
+	for filter, want := range map[string]string{
+		// Type errors.
+		"name eq 5":                  "name cannot be compared with 5",
+		"age eq '20'":                "age cannot be compared with '20'",
+		"age eq true":                "age cannot be compared with true",
+		"updated_at gt '2026-09-01'": "updated_at cannot be compared with '2026-09-01'",
+		"updated_at gt 2026-13-01":   "2026-13-01 is not a date or an RFC 3339 time",
+		"age gt 1.2.3":               "1.2.3 is not a number",
+		"contains(age,'1')":          "age is not text, so contains cannot be applied to it",
+		"tolower(age) eq 'x'":        "age is not text, so tolower cannot be applied to it",
+		"startswith(name, 5)":        "startswith expects a string, found \"5\"",
+		"nickname eq 'Ada'":          `unknown field "nickname"`,
+		"gpa gt 3":                   `unknown field "gpa"`,
+		// Field names are case-sensitive, unlike keywords.
+		"AGE eq 20": `unknown field "AGE"`,
+		// Syntax errors.
+		"name eq 'Ada":         "unterminated string",
+		"age = 20":             `unexpected '=' at position 5`,
+		"age eq":               "expected a value, found end of filter",
+		"age 20":               `expected a comparison operator, found "20"`,
+		"age eq 20 and":        "expected a value, found end of filter",
+		"(age eq 20":           `expected ")", found end of filter`,
+		"age eq 20)":           `unexpected ")"`,
+		"age eq 20 age eq 41":  `unexpected "age"`,
+		"contains name":        `expected "(", found "name"`,
+		"contains(name 'Ada')": `expected ",", found 'Ada'`,
+		"not":                  "expected a value, found end of filter",
+		"20 eq 20":             `expected a field, found "20"`,
+	} {
+		_, err := parseODataFilter(filter, fields)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: error %v, want %q", filter, err, want)
+		}
+	}
+}
+
+func TestODataListOptions(t *testing.T) {
+	api := newTestAPI(t, nil)
+	for _, s := range odataStudents {
+		s.ID, s.ProgramID, s.UpdatedAt = 0, 0, time.Time{}
+		api.decode(t, "POST", "/v1/students", s, http.StatusCreated, nil)
+	}
+	api.decode(t, "POST", "/v1/students", Student{Name: "Edsger Dijkstra", Age: 41, Email: "edsger@example.com"}, http.StatusCreated, nil)
+
+	// Filtering comes before sorting, and sorting before $skip and $top;
+	// $select keeps the named fields, in the order named.
+	for query, want := range map[string]string{
+		"$orderby=age desc,name&$top=2":                           `[{"id":3},{"id":2}]`,
+		"$orderby=age desc,name&$top=2&$skip=1":                   `[{"id":2},{"id":4}]`,
+		"$orderby=age,name desc&$skip=1&$top=2":                   `[{"id":4},{"id":2}]`,
+		"$orderby=name&$skip=10":                                  `[]`,
+		"$filter=age ge 41&$orderby=name desc&$top=1":             `[{"id":3}]`,
+		"$filter=contains(email,'.com')&$orderby=age desc":        `[{"id":2},{"id":4},{"id":1}]`,
+		"$orderby=age&$top=1&$select=name,age":                    `[{"name":"Ada Lovelace","age":20}]`,
+		"$orderby=age desc&$top=1&$select=age,id,age":             `[{"age":85,"id":3}]`,
+		"$filter=age eq 41&$select=program_id,name&$orderby=name": `[{"program_id":null,"name":"Alan Turing"},{"program_id":null,"name":"Edsger Dijkstra"}]`,
+	} {
+		resp, data := api.do(t, "GET", "/v1/students?"+odataEscape(query), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d: %s", query, resp.StatusCode, data)
+			continue
+		}
+		got := strings.TrimSpace(string(data))
+		if !strings.Contains(query, "$select") {
+			// Reduce each student to its ID.
+			var list []Student
+			if err := json.Unmarshal(data, &list); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+			var ids []string
+			for _, s := range list {
+				ids = append(ids, `{"id":`+strconv.Itoa(s.ID)+`}`)
+			}
+			got = "[" + strings.Join(ids, ",") + "]"
+		}
+		if got != want {
+			t.Errorf("%s: %s, want %s", query, got, want)
+		}
+	}
+
+	// The Link header pages on with $skip and $top, keeping the rest.
+	resp, _ := api.do(t, "GET", "/v1/students?"+odataEscape("$orderby=age&$top=3&$select=id"), nil)
+	m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link"))
+	if m == nil {
+		t.Fatalf("Link = %q, want a next page", resp.Header.Get("Link"))
+	}
+	next, err := url.Parse(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := next.Query(); q.Get("$skip") != "3" || q.Get("$top") != "3" || q.Get("$orderby") != "age" || q.Get("$select") != "id" {
+		t.Errorf("next page = %s", m[1])
+	}
+	resp, data := api.do(t, "GET", m[1], nil)
+	if got := strings.TrimSpace(string(data)); got != `[{"id":3}]` || resp.Header.Get("Link") != "" {
+		t.Errorf("last page = %s, Link %q", got, resp.Header.Get("Link"))
+	}
+}
+
+func TestODataListOptionErrors(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	for query, want := range map[string]string{
+		"$filter=age eq 'x'":                      "$filter: age cannot be compared with 'x'",
+		"$filter=contains(age,'1')":               "$filter: age is not text, so contains cannot be applied to it",
+		"$orderby=age sideways":                   `$orderby: "age sideways" is not a field optionally followed by asc or desc`,
+		"$orderby=nickname":                       `$orderby: "nickname" is not a field optionally followed by asc or desc`,
+		"$top=0":                                  "$top: must be between 1 and " + strconv.Itoa(cfg.MaxPageSize),
+		"$top=" + strconv.Itoa(cfg.MaxPageSize+1): "$top: must be between 1 and " + strconv.Itoa(cfg.MaxPageSize),
+		"$skip=-1":                                "$skip: must be a non-negative integer",
+		"$select=name,nickname":                   `$select: unknown field "nickname"`,
+		"$top=2&limit=5":                          "limit: cannot be combined with $orderby, $top or $skip",
+		"$orderby=age&after=1":                    "after: cannot be combined with $orderby, $top or $skip",
+		// $select would drop the fields links are built from.
+		"$select=name&hateoas=true": "$select: cannot be combined with HAL links",
+	} {
+		var problem Problem
+		api.decode(t, "GET", "/v1/students?"+odataEscape(query), nil, http.StatusBadRequest, &problem)
+		var got []string
+		for _, e := range problem.Errors {
+			got = append(got, e.Field+": "+e.Message)
+		}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("%s: errors %q, want %q", query, got, want)
+		}
+	}
+
+	// $select is fine with HAL links as CSV, which has no links.
+	resp, data := api.do(t, "GET", "/v1/students?"+odataEscape("$select=name&hateoas=true&format=csv"), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("$select as CSV with hateoas: status %d: %s", resp.StatusCode, data)
+	}
+}
+
+// odataEscape escapes the values of a raw query such as "$top=2&$skip=1".
+func odataEscape(query string) string {
+	var parts []string
+	for _, part := range strings.Split(query, "&") {
+		name, value, _ := strings.Cut(part, "=")
+		parts = append(parts, name+"="+url.QueryEscape(value))
+	}
+	return strings.Join(parts, "&")
+}
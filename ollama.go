@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"studengo/ollama"
+	"studengo/ollama/ollamareplay"
+)
+
+// LLM generates the text of summaries. *ollama.Client is the one used in
+// production; NewServer takes any, so tests can stand in for the model.
+type LLM interface {
+	Model() string
+	Generate(ctx context.Context, req ollama.GenerateRequest) (ollama.Generation, error)
+	// Models lists the models available to Generate.
+	Models(ctx context.Context) ([]string, error)
+}
+
+// newOllamaClient builds the client of the configured Ollama server. With
+// ollama_replay set, its calls are recorded to or replayed from
+// ollama_replay_dir.
+func newOllamaClient(c Config) *ollama.Client {
+	o := ollama.Options{
+		URL:          c.OllamaURL,
+		Model:        c.OllamaModel,
+		Timeout:      c.OllamaTimeout,
+		MaxIdleConns: c.OllamaMaxIdleConns,
+		MaxLineBytes: c.OllamaMaxLineBytes,
+	}
+	switch c.OllamaReplay {
+	case "record":
+		o.Transport = ollamareplay.NewRecorder(c.OllamaReplayDir, nil)
+	case "replay":
+		o.Transport = ollamareplay.NewReplayer(c.OllamaReplayDir)
+	}
+	return ollama.New(o)
+}
+
+// summarizeStudent asks Ollama for a short profile summary of the student.
+func summarizeStudent(ctx context.Context, student Student) (summary string, err error) {
+	start := time.Now()
+	llm := stateOf(ctx).llm
+	var headersAt, firstTokenAt time.Time
+	var promptTokens, outputTokens int
+	ctx, span := tracer.Start(ctx, "ollama.generate", trace.WithAttributes(attribute.String("llm.model", llm.Model())))
+	defer trackTiming(ctx, "ollama", start)
+	defer func() {
+		observeOllamaCall(start, err)
+		if total := time.Since(start); total > cfg.SlowLLMThreshold {
+			slog.WarnContext(ctx, "slow llm call",
+				"model", llm.Model(),
+				"student_id", student.ID,
+				"total_ms", total.Milliseconds(),
+				"threshold_ms", cfg.SlowLLMThreshold.Milliseconds(),
+				"headers_ms", sinceStartMs(start, headersAt),
+				"first_token_ms", sinceStartMs(start, firstTokenAt),
+				"prompt_tokens", promptTokens,
+				"output_tokens", outputTokens,
+				"failed", err != nil,
+			)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	prompt := fmt.Sprintf("Summarize this student profile: Name: %s, Age: %d, Email: %s", student.Name, student.Age, student.Email)
+	prompt += localeFromContext(ctx).summaryInstruction()
+
+	header := http.Header{}
+	if id := requestIDFromContext(ctx); id != "" {
+		header.Set(requestIDHeader, id)
+	}
+	gen, err := llm.Generate(ctx, ollama.GenerateRequest{
+		Prompt:      prompt,
+		Temperature: 0.3,
+		TopP:        0.9,
+		MaxTokens:   50,
+		Header:      header,
+	})
+	headersAt, firstTokenAt = gen.HeadersAt, gen.FirstTokenAt
+	promptTokens, outputTokens = gen.PromptTokens, gen.OutputTokens
+	if errors.Is(err, ollama.ErrLineTooLong) {
+		return "", errors.New("Ollama response chunk exceeds ollama_max_line_bytes")
+	}
+	if err != nil {
+		return "", err
+	}
+	return gen.Response, nil
+}
+
+// sinceStartMs is the offset of t from start, or -1 if t never happened.
+func sinceStartMs(start, t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return t.Sub(start).Milliseconds()
+}
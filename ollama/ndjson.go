@@ -0,0 +1,69 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrLineTooLong is returned when a streamed line exceeds the limit.
+var ErrLineTooLong = errors.New("ndjson line exceeds maximum size")
+
+// ndjsonReader splits a newline-delimited JSON stream into lines of any
+// length up to max. Unlike bufio.Scanner it is not bound to a fixed token
+// buffer, so verbose model output does not fail the stream.
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line []byte
+	max  int
+}
+
+var ndjsonReaderPool = sync.Pool{New: func() any {
+	return &ndjsonReader{r: bufio.NewReaderSize(nil, 4096)}
+}}
+
+// newNDJSONReader takes a reader from the pool; release it when done.
+func newNDJSONReader(src io.Reader, max int) *ndjsonReader {
+	d := ndjsonReaderPool.Get().(*ndjsonReader)
+	d.r.Reset(src)
+	d.max = max
+	return d
+}
+
+func (d *ndjsonReader) release() {
+	d.r.Reset(nil)
+	// Very long lines are not kept around in the pool.
+	if cap(d.line) > 64<<10 {
+		d.line = nil
+	}
+	ndjsonReaderPool.Put(d)
+}
+
+// next returns the next non-empty line without its line ending. The slice
+// is only valid until the following call. It returns io.EOF at the end of
+// the stream.
+func (d *ndjsonReader) next() ([]byte, error) {
+	for {
+		d.line = d.line[:0]
+		for {
+			chunk, err := d.r.ReadSlice('\n')
+			if len(d.line)+len(chunk) > d.max+1 {
+				return nil, fmt.Errorf("%w (%d bytes)", ErrLineTooLong, d.max)
+			}
+			d.line = append(d.line, chunk...)
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil && (err != io.EOF || len(d.line) == 0) {
+				return nil, err
+			}
+			break
+		}
+		if line := bytes.TrimSpace(d.line); len(line) > 0 {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONReaderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 100<<10)
+	d := newNDJSONReader(strings.NewReader("\n"+long+"\r\nshort\nlast"), 1<<20)
+	defer d.release()
+	for _, want := range []string{long, "short", "last"} {
+		line, err := d.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != want {
+			t.Fatalf("line of %d bytes, want %d", len(line), len(want))
+		}
+	}
+	if _, err := d.next(); err != io.EOF {
+		t.Fatalf("after the last line: %v, want io.EOF", err)
+	}
+}
+
+func TestNDJSONReaderLineTooLong(t *testing.T) {
+	d := newNDJSONReader(strings.NewReader(strings.Repeat("x", 10<<10)+"\n"), 8<<10)
+	defer d.release()
+	if _, err := d.next(); !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("err = %v, want ErrLineTooLong", err)
+	}
+}
+
+func streamServer(t *testing.T, body string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGenerateLongLine(t *testing.T) {
+	text := strings.Repeat("word ", 20<<10)
+	srv := streamServer(t, `{"response":"`+text+`","done":false}`+"\n"+`{"response":"","done":true}`+"\n")
+	c := New(Options{URL: srv.URL, Model: "llama3", MaxLineBytes: 1 << 20})
+	g, err := c.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Response != text {
+		t.Errorf("response of %d bytes, want %d", len(g.Response), len(text))
+	}
+}
+
+func TestGenerateRejectsLineOverLimit(t *testing.T) {
+	srv := streamServer(t, `{"response":"`+strings.Repeat("x", 4<<10)+`","done":true}`+"\n")
+	c := New(Options{URL: srv.URL, Model: "llama3", MaxLineBytes: 1 << 10})
+	if _, err := c.Generate(context.Background(), GenerateRequest{Prompt: "hi"}); !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("err = %v, want ErrLineTooLong", err)
+	}
+}
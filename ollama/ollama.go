@@ -0,0 +1,249 @@
+// Package ollama is a client for the parts of the Ollama API the student
+// API uses: streamed generation and the list of pulled models.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Options configures a Client.
+type Options struct {
+	// URL is the base URL of the Ollama server.
+	URL   string
+	Model string
+	// Timeout bounds a whole call, reading the stream included.
+	Timeout time.Duration
+	// MaxIdleConns is the number of pooled connections kept to the server.
+	MaxIdleConns int
+	// MaxLineBytes is the longest line accepted in a streamed response.
+	MaxLineBytes int
+	// Transport, if set, replaces the pooled transport New builds, for
+	// instance to record or replay calls with ollamareplay.
+	Transport http.RoundTripper
+}
+
+// Client calls one Ollama server. It is shared by every call so
+// connections to the model server are pooled and reused, and is safe for
+// concurrent use.
+type Client struct {
+	url          string
+	model        string
+	maxLineBytes int
+	http         *http.Client
+}
+
+// New builds a client tuned for many concurrent, long-lived requests to a
+// single host.
+func New(o Options) *Client {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          o.MaxIdleConns,
+		MaxIdleConnsPerHost:   o.MaxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	if o.Transport != nil {
+		transport = o.Transport
+	}
+	return &Client{
+		url:          strings.TrimSuffix(o.URL, "/"),
+		model:        o.Model,
+		maxLineBytes: o.MaxLineBytes,
+		http: &http.Client{
+			Timeout:   o.Timeout,
+			Transport: otelhttp.NewTransport(transport),
+		},
+	}
+}
+
+// Model is the model generations are asked of.
+func (c *Client) Model() string { return c.model }
+
+// GenerateRequest is one call to /api/generate.
+type GenerateRequest struct {
+	Prompt      string
+	Temperature float64
+	TopP        float64
+	MaxTokens   int
+	// Header is added to the HTTP request, e.g. to carry a request ID.
+	Header http.Header
+}
+
+// Generation is the outcome of a generate call.
+type Generation struct {
+	Response     string
+	PromptTokens int
+	OutputTokens int
+	// HeadersAt and FirstTokenAt are when the response headers and the
+	// first non-empty token arrived; zero if they never did.
+	HeadersAt    time.Time
+	FirstTokenAt time.Time
+}
+
+// requestBufPool reuses request body buffers across calls so the hot
+// summary path does not allocate a fresh one every time. Stream readers
+// are pooled in ndjson.go.
+var requestBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// pooledBody returns its buffer to the pool once the transport has
+// finished sending the request, which may be after Do returns.
+type pooledBody struct {
+	*bytes.Reader
+	buf  *bytes.Buffer
+	once sync.Once
+}
+
+func (b *pooledBody) Close() error {
+	b.once.Do(func() { requestBufPool.Put(b.buf) })
+	return nil
+}
+
+// Generate streams a completion and returns it once the model is done.
+// The returned Generation carries the timings reached even on error.
+func (c *Client) Generate(ctx context.Context, g GenerateRequest) (Generation, error) {
+	var out Generation
+	requestBody := map[string]interface{}{
+		"model":       c.model,
+		"prompt":      g.Prompt,
+		"temperature": g.Temperature,
+		"top_p":       g.TopP,
+		"max_tokens":  g.MaxTokens,
+	}
+
+	buf := requestBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(requestBody); err != nil {
+		requestBufPool.Put(buf)
+		return out, errors.New("Failed to encode request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"/api/generate", &pooledBody{Reader: bytes.NewReader(buf.Bytes()), buf: buf})
+	if err != nil {
+		requestBufPool.Put(buf)
+		return out, errors.New("Failed to create request")
+	}
+	req.ContentLength = int64(buf.Len())
+	for name, values := range g.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return out, errors.New("Failed to call Ollama API: " + err.Error())
+	}
+	defer resp.Body.Close()
+	out.HeadersAt = time.Now()
+
+	if resp.StatusCode != http.StatusOK {
+		return out, fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+	}
+
+	stream := newNDJSONReader(resp.Body, c.maxLineBytes)
+	defer stream.release()
+	var fullResponse strings.Builder
+
+	for {
+		line, err := stream.next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrLineTooLong) {
+			return out, err
+		}
+		if err != nil {
+			return out, errors.New("Error reading Ollama response stream")
+		}
+
+		var chunk struct {
+			Response        string `json:"response"`
+			Done            bool   `json:"done"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+			Error           string `json:"error"`
+		}
+
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return out, errors.New("Failed to parse Ollama response chunk")
+		}
+		if chunk.Error != "" {
+			return out, errors.New("Ollama failed mid-stream: " + chunk.Error)
+		}
+
+		if out.FirstTokenAt.IsZero() && chunk.Response != "" {
+			out.FirstTokenAt = time.Now()
+		}
+		fullResponse.WriteString(chunk.Response)
+
+		if chunk.Done {
+			out.PromptTokens, out.OutputTokens = chunk.PromptEvalCount, chunk.EvalCount
+			break
+		}
+	}
+
+	out.Response = fullResponse.String()
+	return out, nil
+}
+
+// StatusError is returned by Models when the server answers with an
+// error status.
+type StatusError struct {
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s returned %d", e.Path, e.StatusCode)
+}
+
+// ErrBadModelList is returned by Models when the server answered but the
+// list could not be parsed.
+var ErrBadModelList = errors.New("model list could not be parsed")
+
+// Models lists the names of the models pulled on the server.
+func (c *Client) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Path: "/api/tags", StatusCode: resp.StatusCode}
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, ErrBadModelList
+	}
+	names := make([]string, len(tags.Models))
+	for i, m := range tags.Models {
+		names[i] = m.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,36 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// BenchmarkGenerate measures one call against a server replying with a
+// fixed stream, so allocations are mostly the client's own: those the
+// request buffer and stream reader pools save show up here.
+func BenchmarkGenerate(b *testing.B) {
+	var stream strings.Builder
+	for range 50 {
+		stream.WriteString(`{"response":"word ","done":false}` + "\n")
+	}
+	stream.WriteString(`{"response":"","done":true,"prompt_eval_count":12,"eval_count":50}` + "\n")
+	body := stream.String()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := New(Options{URL: srv.URL, Model: "llama3", MaxIdleConns: 4, MaxLineBytes: 1 << 20})
+	req := GenerateRequest{Prompt: strings.Repeat("Summarize this student. ", 20), Temperature: 0.7, TopP: 0.9, MaxTokens: 200}
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.Generate(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package ollamareplay records the calls an Ollama client makes and plays
+// them back later, so integration tests and demos can run on real model
+// output without a model server.
+//
+// Recordings are files in a directory, one per distinct request, named
+// after a hash of its method, path and body. Streamed responses are kept
+// whole and replayed in one go.
+//
+//	client := ollama.New(ollama.Options{URL: url, Model: "llama3", Transport: ollamareplay.NewReplayer("testdata/ollama")})
+package ollamareplay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrNotRecorded is returned by a Replayer for a request it has no
+// recording of.
+var ErrNotRecorded = errors.New("no recording of request")
+
+// Interaction is one recorded request and the response to it, the
+// contents of a recording file.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is what a recording is looked up by. Headers are left
+// out; they carry request IDs and trace context that differ every call.
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response played back for a request.
+type RecordedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Recorder passes requests on to a real server and saves every exchange
+// in its directory, replacing an earlier recording of the same request.
+type Recorder struct {
+	dir  string
+	base http.RoundTripper
+}
+
+// NewRecorder records to dir, creating it if needed, the calls it sends
+// through base. A nil base is http.DefaultTransport.
+func NewRecorder(dir string, base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, base: base}
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rec.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	in := Interaction{
+		Request:  RecordedRequest{Method: req.Method, Path: req.URL.Path, Body: canonical(body)},
+		Response: RecordedResponse{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: string(data)},
+	}
+	if err := rec.save(in); err != nil {
+		return nil, fmt.Errorf("recording %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// save writes the recording through a temporary file, so a replayer
+// reading the directory never sees half of one.
+func (rec *Recorder) save(in Interaction) error {
+	if err := os.MkdirAll(rec.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(rec.dir, ".recording-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(rec.dir, fileName(in.Request)))
+}
+
+// Replayer answers requests from the recordings in its directory and
+// never reaches a server.
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer replays the recordings in dir.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := RecordedRequest{Method: req.Method, Path: req.URL.Path, Body: canonical(body)}
+	data, err := os.ReadFile(filepath.Join(rp.dir, fileName(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w %s %s in %s", ErrNotRecorded, req.Method, req.URL.Path, rp.dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var in Interaction
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("recording of %s %s: %w", req.Method, req.URL.Path, err)
+	}
+
+	header := http.Header{}
+	if in.Response.ContentType != "" {
+		header.Set("Content-Type", in.Response.ContentType)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(in.Response.Body)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(in.Response.Body))),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads the request body and puts it back for the transport.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// canonical re-encodes a JSON body with sorted keys, so requests that
+// differ only in key order or spacing share a recording. Other bodies are
+// kept as JSON strings.
+func canonical(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if out, err := json.Marshal(v); err == nil {
+			return out
+		}
+	}
+	out, _ := json.Marshal(string(body))
+	return out
+}
+
+// fileName names the recording of a request.
+func fileName(r RecordedRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.Path)
+	h.Write(r.Body)
+	return hex.EncodeToString(h.Sum(nil))[:32] + ".json"
+}
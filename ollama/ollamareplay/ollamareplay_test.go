@@ -0,0 +1,63 @@
+package ollamareplay_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"studengo/ollama"
+	"studengo/ollama/ollamareplay"
+	"studengo/ollama/ollamatest"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	req := ollama.GenerateRequest{Prompt: "Summarize Ada.", Temperature: 0.7, Header: http.Header{"X-Request-Id": {"one"}}}
+
+	srv := ollamatest.NewServer(ollamatest.WithResponse("Ada is a diligent student."), ollamatest.WithModels("llama3", "mistral"))
+	recorder := ollama.New(ollama.Options{URL: srv.URL, Model: "llama3", MaxLineBytes: 1 << 20, Transport: ollamareplay.NewRecorder(dir, nil)})
+	recorded, err := recorder.Generate(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Models(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Fatalf("recorded %d files, want 2", len(files))
+	}
+
+	// The server is gone; the replayer answers from the recordings, for
+	// a request with other headers too.
+	replayer := ollama.New(ollama.Options{URL: srv.URL, Model: "llama3", MaxLineBytes: 1 << 20, Transport: ollamareplay.NewReplayer(dir)})
+	req.Header = http.Header{"X-Request-Id": {"two"}}
+	replayed, err := replayer.Generate(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Response != recorded.Response || replayed.OutputTokens != recorded.OutputTokens {
+		t.Errorf("replayed %+v, want %+v", replayed, recorded)
+	}
+	models, err := replayer.Models(ctx)
+	if err != nil || !slices.Equal(models, []string{"llama3", "mistral"}) {
+		t.Errorf("replayed models %v, %v", models, err)
+	}
+
+	_, err = replayer.Generate(ctx, ollama.GenerateRequest{Prompt: "Summarize Alan."})
+	if err == nil || !strings.Contains(err.Error(), ollamareplay.ErrNotRecorded.Error()) {
+		t.Errorf("unrecorded prompt: %v, want %v", err, ollamareplay.ErrNotRecorded)
+	}
+}
+
+func TestReplayerNotRecorded(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://ollama.invalid/api/generate", strings.NewReader(`{"prompt":"hi"}`))
+	if _, err := ollamareplay.NewReplayer(t.TempDir()).RoundTrip(req); !errors.Is(err, ollamareplay.ErrNotRecorded) {
+		t.Fatalf("err = %v, want ErrNotRecorded", err)
+	}
+}
@@ -0,0 +1,281 @@
+// Package ollamatest provides a fake Ollama server for tests. It speaks
+// /api/generate, /api/chat and /api/tags, streams its replies as NDJSON
+// the way Ollama does, and can be made to fail or to answer slowly.
+//
+//	srv := ollamatest.NewServer(ollamatest.WithResponse("A diligent student."))
+//	defer srv.Close()
+//	client := ollama.New(ollama.Options{URL: srv.URL, Model: "llama3", MaxLineBytes: 1 << 20})
+package ollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a generate or chat call the server received.
+type Request struct {
+	Path   string
+	Header http.Header
+	Model  string
+	// Prompt is the prompt of a generate call, or the content of the last
+	// message of a chat call.
+	Prompt   string
+	Messages []Message
+	Body     map[string]any
+}
+
+// Message is one message of a chat call.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Responder computes the reply to a call.
+type Responder func(Request) string
+
+// Server is a fake Ollama server listening on a local port.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	opts     options
+	requests []Request
+}
+
+type options struct {
+	respond     Responder
+	models      []string
+	status      int
+	message     string
+	failAfter   int
+	headerDelay time.Duration
+	chunkDelay  time.Duration
+}
+
+// Option changes how the server answers.
+type Option func(*options)
+
+// WithResponse makes every call answer text.
+func WithResponse(text string) Option {
+	return func(o *options) { o.respond = func(Request) string { return text } }
+}
+
+// WithResponder makes calls answer whatever f returns for them.
+func WithResponder(f Responder) Option {
+	return func(o *options) { o.respond = f }
+}
+
+// WithModels sets the models listed by /api/tags and accepted by generate
+// and chat calls. Calls naming another model get a 404, as from Ollama.
+// By default every model is accepted and /api/tags lists "llama3".
+func WithModels(names ...string) Option {
+	return func(o *options) { o.models = names }
+}
+
+// WithError makes generate and chat calls fail with status and an Ollama
+// error body carrying message. A status of 0 turns it off.
+func WithError(status int, message string) Option {
+	return func(o *options) { o.status, o.message = status, message }
+}
+
+// WithStreamError makes streamed replies break off with an error line
+// after n chunks, as Ollama does when the model fails mid-generation. A
+// negative n turns it off.
+func WithStreamError(n int, message string) Option {
+	return func(o *options) { o.failAfter, o.message = n, message }
+}
+
+// WithLatency delays the response headers by header and every streamed
+// chunk by chunk, to exercise timeouts and slow-call handling. A delay
+// ends early when the client goes away.
+func WithLatency(header, chunk time.Duration) Option {
+	return func(o *options) { o.headerDelay, o.chunkDelay = header, chunk }
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{opts: options{respond: func(Request) string { return "A short summary." }, failAfter: -1}}
+	s.Set(opts...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/generate", s.generate)
+	mux.HandleFunc("POST /api/chat", s.chat)
+	mux.HandleFunc("GET /api/tags", s.tags)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Set changes how the server answers from now on, so one server can be
+// taken through several modes in a test.
+func (s *Server) Set(opts ...Option) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range opts {
+		o(&s.opts)
+	}
+}
+
+// Requests returns the generate and chat calls received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// record decodes a call and stores it. It returns false, having answered,
+// when the call is to be refused.
+func (s *Server) record(w http.ResponseWriter, r *http.Request) (Request, options, bool) {
+	req := Request{Path: r.URL.Path, Header: r.Header.Clone()}
+	if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return req, options{}, false
+	}
+	req.Model, _ = req.Body["model"].(string)
+	req.Prompt, _ = req.Body["prompt"].(string)
+	if raw, ok := req.Body["messages"]; ok {
+		data, _ := json.Marshal(raw)
+		json.Unmarshal(data, &req.Messages)
+		if len(req.Messages) > 0 {
+			req.Prompt = req.Messages[len(req.Messages)-1].Content
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	o := s.opts
+	s.mu.Unlock()
+
+	if !sleep(r, o.headerDelay) {
+		return req, o, false
+	}
+	if o.status != 0 {
+		writeError(w, o.status, o.message)
+		return req, o, false
+	}
+	if o.models != nil && !slices.Contains(o.models, req.Model) && !slices.Contains(o.models, strings.TrimSuffix(req.Model, ":latest")) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", req.Model))
+		return req, o, false
+	}
+	return req, o, true
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	req, o, ok := s.record(w, r)
+	if !ok {
+		return
+	}
+	s.reply(w, r, req, o, func(text string, done bool) map[string]any {
+		return map[string]any{"model": req.Model, "created_at": time.Now().UTC().Format(time.RFC3339Nano), "response": text, "done": done}
+	})
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	req, o, ok := s.record(w, r)
+	if !ok {
+		return
+	}
+	s.reply(w, r, req, o, func(text string, done bool) map[string]any {
+		return map[string]any{"model": req.Model, "created_at": time.Now().UTC().Format(time.RFC3339Nano), "message": Message{Role: "assistant", Content: text}, "done": done}
+	})
+}
+
+// reply answers a call, streamed word by word unless it asked for
+// "stream": false. The last object carries the token counts.
+func (s *Server) reply(w http.ResponseWriter, r *http.Request, req Request, o options, chunk func(text string, done bool) map[string]any) {
+	text := o.respond(req)
+	final := chunk("", true)
+	final["done_reason"] = "stop"
+	final["prompt_eval_count"] = len(strings.Fields(req.Prompt))
+	final["eval_count"] = len(strings.Fields(text))
+
+	if stream, ok := req.Body["stream"].(bool); ok && !stream {
+		whole := chunk(text, true)
+		for k, v := range final {
+			if k != "response" && k != "message" {
+				whole[k] = v
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(whole)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for i, word := range splitWords(text) {
+		if i == o.failAfter {
+			enc.Encode(map[string]string{"error": o.message})
+			return
+		}
+		if !sleep(r, o.chunkDelay) {
+			return
+		}
+		enc.Encode(chunk(word, false))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if o.failAfter >= 0 {
+		enc.Encode(map[string]string{"error": o.message})
+		return
+	}
+	enc.Encode(final)
+}
+
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	names := s.opts.models
+	s.mu.Unlock()
+	if names == nil {
+		names = []string{"llama3"}
+	}
+	models := make([]map[string]any, len(names))
+	for i, name := range names {
+		models[i] = map[string]any{"name": name, "model": name}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"models": models})
+}
+
+// splitWords cuts text into chunks that join back into it, each word with
+// the space before it.
+func splitWords(text string) []string {
+	var chunks []string
+	for len(text) > 0 {
+		end := strings.IndexByte(strings.TrimLeft(text, " "), ' ')
+		if end < 0 {
+			chunks = append(chunks, text)
+			break
+		}
+		end += len(text) - len(strings.TrimLeft(text, " "))
+		chunks = append(chunks, text[:end])
+		text = text[end:]
+	}
+	return chunks
+}
+
+// sleep waits d, or less if the client goes away, and reports whether
+// the client is still there.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
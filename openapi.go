@@ -0,0 +1,511 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// operationDoc describes one route for the OpenAPI document. The paths and
+// methods come from the router itself, so only what the router cannot
+// know is written down here.
+type operationDoc struct {
+	summary string
+	// request and response are zero values of the JSON bodies, or nil
+	// when there is none.
+	request  any
+	response any
+	// status is the success status; 0 means 200.
+	status int
+	// contentType overrides application/json for the success body.
+	contentType string
+	query       []string
+}
+
+// queryDocs describes the query parameters operations refer to by name.
+var queryDocs = map[string]string{
+	"after":         "Return items with an ID greater than this",
+	"limit":         "Maximum number of items to return",
+	"expand":        "Comma-separated related records to include: gpa, guardians, awards",
+	"hateoas":       "Set to true to include HAL _links, as does Accept: application/hal+json",
+	"term":          "Only include records of this term, such as 2026-FALL",
+	"format":        "Response format: json, csv or pdf where supported",
+	"student_id":    "Only include records of this student",
+	"course_id":     "Only include records of this course",
+	"from":          "First date to include, such as 2026-09-01",
+	"to":            "Last date to include, such as 2026-12-18",
+	"program_id":    "Only include students of this program",
+	"width":         "Width in years of each age bucket",
+	"since":         "Cursor of the last change already seen",
+	"status":        "Only include deliveries with this status",
+	"overdue":       "Only include balances overdue by at least this many days",
+	"type":          "Only include documents of this type",
+	"query":         "GraphQL document",
+	"operationName": "Operation of the document to run",
+	"variables":     "JSON object of variable values",
+	"$filter":       "OData filter, such as age ge 18 and startswith(name,'A')",
+	"$orderby":      "Comma-separated fields to sort by, each optionally followed by asc or desc",
+	"$top":          "Maximum number of items to return, paging by position with $skip",
+	"$skip":         "Number of items to skip",
+	"$select":       "Comma-separated fields to include",
+	"dry_run":       "Validate and answer as usual without changing anything; also accepted as the X-Dry-Run header",
+	"count":         "Number of records to create",
+	"seed":          "Random seed, to create the same records again",
+	"envelope":      "Set to true to wrap the JSON response in data, error and meta, or to false to leave it bare when the server envelopes by default",
+}
+
+var operationDocs = map[string]operationDoc{
+	"GET /": {summary: "Check that the API is up", contentType: "text/plain"},
+
+	"POST /students":                    {summary: "Create a student", request: Student{}, response: studentDetail{}, status: http.StatusCreated, query: []string{"hateoas"}},
+	"GET /students":                     {summary: "List students", response: []Student{}, query: []string{"after", "limit", "hateoas", "format", "$filter", "$orderby", "$top", "$skip", "$select"}},
+	"GET /students/events":              {summary: "Stream student changes as Server-Sent Events", contentType: "text/event-stream"},
+	"GET /ws":                           {summary: "Subscribe to changes over a WebSocket"},
+	"POST /students/query":              {summary: "List the students matching a question in plain language (experimental, behind the nl_query flag)", request: StudentQuery{}, response: StudentQueryResult{}},
+	"GET /students/{id}":                {summary: "Get a student", response: studentDetail{}, query: []string{"expand", "hateoas"}},
+	"PUT /students/{id}":                {summary: "Replace a student", request: Student{}, response: studentDetail{}, query: []string{"hateoas"}},
+	"DELETE /students/{id}":             {summary: "Delete a student and their records", status: http.StatusNoContent},
+	"GET /students/{id}/summary":        {summary: "Generate an AI summary of a student", response: map[string]string{}},
+	"POST /students/{id}/summary/share": {summary: "Create a share link for a student summary", response: map[string]any{}, status: http.StatusCreated},
+	"GET /shared/students/{id}/summary": {summary: "Read a shared student summary", response: map[string]string{}},
+
+	"POST /students/{id}/guardians": {summary: "Add a guardian to a student", request: Guardian{}, response: Guardian{}, status: http.StatusCreated},
+	"GET /students/{id}/guardians":  {summary: "List the guardians of a student", response: []Guardian{}},
+	"GET /guardians/{id}":           {summary: "Get a guardian", response: Guardian{}},
+	"PUT /guardians/{id}":           {summary: "Replace a guardian", request: Guardian{}, response: Guardian{}},
+	"DELETE /guardians/{id}":        {summary: "Delete a guardian", status: http.StatusNoContent},
+
+	"POST /departments":               {summary: "Create a department", request: Department{}, response: Department{}, status: http.StatusCreated},
+	"GET /departments":                {summary: "List departments", response: []Department{}},
+	"GET /departments/{id}":           {summary: "Get a department", response: Department{}},
+	"PUT /departments/{id}":           {summary: "Replace a department", request: Department{}, response: Department{}},
+	"DELETE /departments/{id}":        {summary: "Delete a department", status: http.StatusNoContent},
+	"POST /departments/{id}/programs": {summary: "Create a program in a department", request: Program{}, response: Program{}, status: http.StatusCreated},
+	"GET /departments/{id}/programs":  {summary: "List the programs of a department", response: []Program{}},
+	"GET /programs/{id}":              {summary: "Get a program", response: Program{}},
+	"PUT /programs/{id}":              {summary: "Replace a program", request: Program{}, response: Program{}},
+	"DELETE /programs/{id}":           {summary: "Delete a program", status: http.StatusNoContent},
+	"GET /programs/{id}/students":     {summary: "List the students of a program", response: []Student{}},
+	"GET /reports/programs":           {summary: "Report students per program", response: []ProgramReport{}, query: []string{"format"}},
+	"GET /reports/departments":        {summary: "Report students per department", response: []DepartmentReport{}, query: []string{"format"}},
+	"GET /reports/enrollments":        {summary: "Report enrollments per course and term", response: []EnrollmentReport{}, query: []string{"term", "format"}},
+	"GET /reports/ages":               {summary: "Report the age distribution of students", response: []AgeBucket{}, query: []string{"width", "format"}},
+	"GET /reports/attendance":         {summary: "Report attendance per course", response: []AttendanceReport{}, query: []string{"term", "from", "to", "format"}},
+	"GET /reports/gpa":                {summary: "Report the GPA distribution of students", response: []GPABucket{}, query: []string{"program_id", "format"}},
+
+	"POST /teachers":             {summary: "Create a teacher", request: Teacher{}, response: Teacher{}, status: http.StatusCreated},
+	"GET /teachers":              {summary: "List teachers", response: []Teacher{}},
+	"GET /teachers/{id}":         {summary: "Get a teacher", response: Teacher{}},
+	"PUT /teachers/{id}":         {summary: "Replace a teacher", request: Teacher{}, response: Teacher{}},
+	"DELETE /teachers/{id}":      {summary: "Delete a teacher", status: http.StatusNoContent},
+	"GET /teachers/{id}/courses": {summary: "List the courses a teacher teaches", response: []Course{}},
+
+	"POST /terms":        {summary: "Create a term", request: Term{}, response: Term{}, status: http.StatusCreated},
+	"GET /terms":         {summary: "List terms", response: []Term{}},
+	"GET /terms/current": {summary: "Get the current term", response: Term{}},
+	"GET /terms/{id}":    {summary: "Get a term", response: Term{}},
+	"PUT /terms/{id}":    {summary: "Replace a term", request: Term{}, response: Term{}},
+	"DELETE /terms/{id}": {summary: "Delete a term", status: http.StatusNoContent},
+
+	"POST /courses":               {summary: "Create a course", request: Course{}, response: Course{}, status: http.StatusCreated},
+	"GET /courses":                {summary: "List courses", response: []Course{}},
+	"GET /courses/{id}":           {summary: "Get a course", response: Course{}},
+	"PUT /courses/{id}":           {summary: "Replace a course", request: Course{}, response: Course{}},
+	"DELETE /courses/{id}":        {summary: "Delete a course", status: http.StatusNoContent},
+	"POST /courses/{id}/sections": {summary: "Create a section of a course", request: Section{}, response: Section{}, status: http.StatusCreated},
+	"GET /courses/{id}/sections":  {summary: "List the sections of a course", response: []Section{}},
+	"GET /sections/{id}":          {summary: "Get a section", response: Section{}},
+	"PUT /sections/{id}":          {summary: "Replace a section", request: Section{}, response: Section{}},
+	"DELETE /sections/{id}":       {summary: "Delete a section", status: http.StatusNoContent},
+	"GET /courses/{id}/waitlist":  {summary: "List the waitlist of a course", response: []WaitlistEntry{}},
+	"GET /courses/{id}/students":  {summary: "List the students enrolled in a course", response: []Student{}, query: []string{"term"}},
+
+	"POST /students/{id}/enrollments":                        {summary: "Enroll a student in a course, or waitlist them when it is full", request: Enrollment{}, response: Enrollment{}, status: http.StatusCreated},
+	"GET /students/{id}/enrollments":                         {summary: "List the enrollments of a student", response: []Enrollment{}, query: []string{"term"}},
+	"GET /students/{id}/schedule.ics":                        {summary: "Export the class schedule of a student as iCalendar", contentType: "text/calendar", query: []string{"term"}},
+	"DELETE /students/{id}/enrollments/{enrollment_id}":      {summary: "Drop an enrollment", status: http.StatusNoContent},
+	"GET /students/{id}/waitlist":                            {summary: "List the waitlist places of a student", response: []WaitlistEntry{}},
+	"DELETE /students/{id}/waitlist/{entry_id}":              {summary: "Leave a waitlist", status: http.StatusNoContent},
+	"GET /students/{id}/gpa":                                 {summary: "Compute the GPA of a student", response: GPAReport{}},
+	"POST /students/{id}/enrollments/{enrollment_id}/grades": {summary: "Record a grade for an enrollment", request: Grade{}, response: Grade{}, status: http.StatusCreated},
+	"GET /students/{id}/grades":                              {summary: "List the grades of a student", response: []Grade{}, query: []string{"term"}},
+
+	"POST /courses/{id}/assignments":     {summary: "Create an assignment in a course", request: Assignment{}, response: Assignment{}, status: http.StatusCreated},
+	"GET /courses/{id}/assignments":      {summary: "List the assignments of a course", response: []Assignment{}},
+	"GET /assignments/{id}":              {summary: "Get an assignment", response: Assignment{}},
+	"PUT /assignments/{id}":              {summary: "Replace an assignment", request: Assignment{}, response: Assignment{}},
+	"DELETE /assignments/{id}":           {summary: "Delete an assignment", status: http.StatusNoContent},
+	"POST /assignments/{id}/submissions": {summary: "Submit work for an assignment", request: Submission{}, response: Submission{}, status: http.StatusCreated},
+	"GET /assignments/{id}/submissions":  {summary: "List the submissions of an assignment", response: []Submission{}},
+	"PUT /submissions/{id}/score":        {summary: "Score a submission", request: map[string]any{}, response: Submission{}},
+	"GET /students/{id}/progress":        {summary: "Report the coursework progress of a student", response: []CourseProgress{}},
+
+	"POST /cohorts":                             {summary: "Create a cohort", request: Cohort{}, response: Cohort{}, status: http.StatusCreated},
+	"GET /cohorts":                              {summary: "List cohorts", response: []Cohort{}},
+	"GET /cohorts/{id}":                         {summary: "Get a cohort", response: Cohort{}},
+	"PUT /cohorts/{id}":                         {summary: "Replace a cohort", request: Cohort{}, response: Cohort{}},
+	"DELETE /cohorts/{id}":                      {summary: "Delete a cohort", status: http.StatusNoContent},
+	"POST /cohorts/{id}/members":                {summary: "Add students to a cohort", request: map[string][]int{}, response: Cohort{}},
+	"GET /cohorts/{id}/members":                 {summary: "List the members of a cohort", response: []Student{}},
+	"DELETE /cohorts/{id}/members/{student_id}": {summary: "Remove a student from a cohort", status: http.StatusNoContent},
+	"GET /cohorts/{id}/summaries":               {summary: "Generate AI summaries of the members of a cohort", response: []CohortSummary{}},
+	"GET /cohorts/{id}/export":                  {summary: "Export the members of a cohort", query: []string{"format"}},
+
+	"GET /graphql":        {summary: "Run a GraphQL query", response: map[string]any{}, query: []string{"query", "operationName", "variables"}},
+	"POST /graphql":       {summary: "Run a GraphQL query or mutation", request: graphQLRequest{}, response: map[string]any{}},
+	"GET /graphql/schema": {summary: "The GraphQL schema as SDL", contentType: "text/plain"},
+	"GET /changes":        {summary: "Read the change feed", response: ChangePage{}, query: []string{"since", "limit"}},
+
+	"POST /webhooks":                        {summary: "Register a webhook", request: Webhook{}, response: Webhook{}, status: http.StatusCreated},
+	"GET /webhooks":                         {summary: "List webhooks", response: []Webhook{}},
+	"GET /webhooks/{id}":                    {summary: "Get a webhook", response: Webhook{}},
+	"PUT /webhooks/{id}":                    {summary: "Replace a webhook", request: Webhook{}, response: Webhook{}},
+	"DELETE /webhooks/{id}":                 {summary: "Delete a webhook", status: http.StatusNoContent},
+	"GET /webhooks/{id}/deliveries":         {summary: "List the deliveries of a webhook", response: []WebhookDelivery{}, query: []string{"status"}},
+	"POST /webhooks/{id}/replay":            {summary: "Replay every dead-lettered delivery of a webhook", response: map[string][]int{}, status: http.StatusAccepted},
+	"GET /webhooks/dead-letters":            {summary: "List the deliveries that ran out of attempts", response: []WebhookDelivery{}},
+	"POST /webhooks/deliveries/{id}/replay": {summary: "Replay a dead-lettered delivery", response: WebhookDelivery{}, status: http.StatusAccepted},
+	"DELETE /webhooks/deliveries/{id}":      {summary: "Discard a dead-lettered delivery", status: http.StatusNoContent},
+
+	"POST /students/{id}/documents": {summary: "Upload a document for a student as multipart/form-data", response: Document{}, status: http.StatusCreated},
+	"GET /students/{id}/documents":  {summary: "List the documents of a student", response: []Document{}, query: []string{"type"}},
+	"GET /documents/{id}":           {summary: "Get the metadata of a document", response: Document{}},
+	"GET /documents/{id}/content":   {summary: "Download a document", contentType: "application/octet-stream"},
+	"DELETE /documents/{id}":        {summary: "Delete a document", status: http.StatusNoContent},
+	"PUT /students/{id}/photo":      {summary: "Replace the photo of a student with a GIF, JPEG, PNG or WebP image", status: http.StatusNoContent},
+	"GET /students/{id}/photo":      {summary: "Get the photo of a student", contentType: "image/*"},
+	"DELETE /students/{id}/photo":   {summary: "Delete the photo of a student", status: http.StatusNoContent},
+
+	"POST /exports":             {summary: "Export the students matching a filter as CSV", request: Export{}, response: Export{}, status: http.StatusCreated},
+	"GET /exports":              {summary: "List exports", response: []Export{}},
+	"GET /exports/{id}":         {summary: "Get an export", response: Export{}},
+	"GET /exports/{id}/content": {summary: "Download an export", contentType: "text/csv"},
+	"DELETE /exports/{id}":      {summary: "Delete an export", status: http.StatusNoContent},
+
+	"POST /students/{id}/awards": {summary: "Give a student an award", request: Award{}, response: Award{}, status: http.StatusCreated},
+	"GET /students/{id}/awards":  {summary: "List the awards of a student", response: []Award{}},
+	"GET /awards/{id}":           {summary: "Get an award", response: Award{}},
+	"PUT /awards/{id}":           {summary: "Replace an award", request: Award{}, response: Award{}},
+	"DELETE /awards/{id}":        {summary: "Delete an award", status: http.StatusNoContent},
+
+	"POST /students/{id}/transactions": {summary: "Post a charge or payment to a student account", request: Transaction{}, response: Transaction{}, status: http.StatusCreated},
+	"GET /students/{id}/statement":     {summary: "Get the account statement of a student", response: Statement{}},
+	"GET /balances":                    {summary: "List student balances", response: []Balance{}, query: []string{"overdue"}},
+	"GET /students/{id}/transcript":    {summary: "Get the transcript of a student", response: Transcript{}, query: []string{"format"}},
+
+	"POST /attendance":        {summary: "Record attendance", request: AttendanceRecord{}, response: AttendanceRecord{}, status: http.StatusCreated},
+	"GET /attendance":         {summary: "List attendance records", response: []AttendanceRecord{}, query: []string{"student_id", "course_id", "from", "to", "term"}},
+	"GET /attendance/summary": {summary: "Summarize attendance", response: AttendanceSummary{}, query: []string{"student_id", "course_id", "from", "to", "term"}},
+
+	"POST /batch": {summary: "Run several operations in one request", request: []BatchOperation{}, response: []BatchResult{}},
+
+	"GET /healthz":      {summary: "Liveness probe", response: map[string]string{}},
+	"GET /readyz":       {summary: "Readiness probe", response: map[string]any{}},
+	"GET /version":      {summary: "Get build information", response: map[string]string{}},
+	"GET /features":     {summary: "List the feature flags enabled for the caller", response: map[string]bool{}},
+	"GET /metrics":      {summary: "Prometheus metrics", contentType: "text/plain"},
+	"GET /openapi.json": {summary: "This document", response: map[string]any{}},
+	"GET /docs":         {summary: "Interactive API documentation", contentType: "text/html"},
+
+	"GET /admin/ui":                   {summary: "Admin dashboard", contentType: "text/html"},
+	"GET /admin/diagnostics":          {summary: "Run diagnostics", response: map[string]any{}},
+	"GET /admin/audit":                {summary: "Read the audit log", response: []AuditEntry{}},
+	"GET /admin/emails":               {summary: "Read the email log", response: []EmailLogEntry{}},
+	"GET /admin/jobs":                 {summary: "List scheduled jobs", response: []JobStatus{}},
+	"POST /admin/jobs/{name}/run":     {summary: "Run a job now", status: http.StatusAccepted},
+	"GET /admin/ldap-sync":            {summary: "Report the last LDAP directory sync", response: LDAPSyncReport{}},
+	"GET /admin/log-level":            {summary: "Get the log level", response: map[string]string{}},
+	"PUT /admin/log-level":            {summary: "Set the log level", request: map[string]string{}, response: map[string]string{}},
+	"GET /admin/maintenance":          {summary: "Get maintenance mode", response: map[string]any{}},
+	"PUT /admin/maintenance":          {summary: "Turn maintenance mode on or off", request: map[string]any{}, response: map[string]any{}},
+	"GET /admin/features":             {summary: "List feature flag definitions", response: map[string]FeatureFlag{}},
+	"POST /admin/anonymize":           {summary: "Anonymize student records", response: map[string]int{}},
+	"POST /admin/backups":             {summary: "Back up every student", response: Backup{}, status: http.StatusCreated},
+	"GET /admin/backups":              {summary: "List backups", response: []Backup{}},
+	"GET /admin/backups/{id}/content": {summary: "Download a backup as JSON lines", contentType: "application/jsonl"},
+	"DELETE /admin/backups/{id}":      {summary: "Delete a backup", status: http.StatusNoContent},
+	"POST /admin/reset":               {summary: "Remove students and courses and reload the seed file (development only)", response: map[string]any{}},
+	"POST /admin/generate":            {summary: "Create fake students (development only)", response: map[string]int{}, status: http.StatusCreated, query: []string{"count", "seed"}},
+}
+
+//go:embed ui/docs.html
+var docsPage []byte
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// adminRoute reports whether a path is only open to admins.
+func adminRoute(path string) bool {
+	return strings.HasPrefix(path, "/admin/") || path == "/webhooks" || strings.HasPrefix(path, "/webhooks/")
+}
+
+// buildOpenAPI describes every route registered on r. Routes are read from
+// the router, so a new endpoint shows up even before it is documented.
+func buildOpenAPI(r *mux.Router) map[string]any {
+	schemas := schemaSet{defs: map[string]any{}}
+	paths := map[string]map[string]any{}
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || strings.HasPrefix(path, "/debug/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if paths[path] == nil {
+				paths[path] = map[string]any{}
+			}
+			paths[path][strings.ToLower(method)] = describeOperation(method, path, &schemas)
+		}
+		return nil
+	})
+
+	schemas.defs["Problem"] = schemas.structSchema(reflect.TypeFor[Problem]())
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   serviceName,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.defs,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// lookupOperationDoc finds the docs of a route. Docs are written against
+// the resource path, so they carry over to the next API version.
+func lookupOperationDoc(method, path string) operationDoc {
+	if doc, ok := operationDocs[method+" "+path]; ok {
+		return doc
+	}
+	return operationDocs[method+" "+resourcePath(path)]
+}
+
+func describeOperation(method, path string, schemas *schemaSet) map[string]any {
+	doc := lookupOperationDoc(method, path)
+	resource := resourcePath(path)
+	tag := strings.SplitN(strings.TrimPrefix(resource, "/"), "/", 2)[0]
+	if tag == "" {
+		tag = "service"
+	}
+	op := map[string]any{
+		"operationId": operationID(method, path),
+		"tags":        []string{tag},
+	}
+	if doc.summary != "" {
+		op["summary"] = doc.summary
+	}
+
+	var params []map[string]any
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		schema := map[string]any{"type": "string"}
+		if m[1] == "id" || strings.HasSuffix(m[1], "_id") {
+			schema = map[string]any{"type": "integer"}
+		}
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": schema})
+	}
+	for _, name := range doc.query {
+		params = append(params, map[string]any{"name": name, "in": "query", "description": queryDocs[name], "schema": map[string]any{"type": "string"}})
+	}
+	if isMutating(method) && dryRunSupported(path) {
+		params = append(params, map[string]any{"name": "dry_run", "in": "query", "description": queryDocs["dry_run"], "schema": map[string]any{"type": "boolean"}})
+	}
+	if strings.HasPrefix(path, apiV1+"/") {
+		params = append(params, map[string]any{"name": "envelope", "in": "query", "description": queryDocs["envelope"], "schema": map[string]any{"type": "boolean"}})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if doc.request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  negotiatedContent(schemas.of(reflect.TypeOf(doc.request))),
+		}
+	}
+
+	status := doc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case doc.response != nil:
+		content := responseContent(schemas.of(reflect.TypeOf(doc.response)))
+		if slices.Contains(doc.query, "format") {
+			// Lists taking ?format=csv also answer Accept: text/csv.
+			content["text/csv"] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+		success["content"] = content
+	case doc.contentType != "":
+		success["content"] = map[string]any{doc.contentType: map[string]any{}}
+	}
+	op["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default": map[string]any{
+			"description": "Problem details",
+			"content": map[string]any{
+				"application/problem+json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+				"application/problem+xml":  map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+			},
+		},
+	}
+
+	if adminRoute(resource) {
+		op["security"] = []map[string][]string{{"apiKey": {}}}
+	}
+	return op
+}
+
+// negotiatedContent lists the formats negotiateFormat accepts for a JSON
+// request body.
+func negotiatedContent(schema any) map[string]any {
+	content := map[string]any{}
+	for _, mediaType := range []string{"application/json", "application/xml", "application/yaml"} {
+		content[mediaType] = map[string]any{"schema": schema}
+	}
+	return content
+}
+
+// responseContent adds the binary formats offered for responses. The
+// protobuf message is named by the messageType parameter of the response
+// Content-Type.
+func responseContent(schema any) map[string]any {
+	content := negotiatedContent(schema)
+	content[msgpackMediaType] = map[string]any{"schema": schema}
+	content[protobufMediaType] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+	return content
+}
+
+// operationID turns GET /students/{id}/gpa into get_students_id_gpa.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(c rune) bool { return c == '/' || c == '.' || c == '-' }) {
+		part = strings.Trim(part, "{}")
+		id += "_" + part
+	}
+	return id
+}
+
+// schemaSet derives JSON schemas from Go types, collecting named structs
+// under components/schemas.
+type schemaSet struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (s *schemaSet) of(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeFor[json.RawMessage]():
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		if t == reflect.TypeFor[time.Duration]() {
+			return map[string]any{"type": "integer", "description": "Duration in nanoseconds"}
+		}
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		// Unexported types such as studentDetail are published capitalized.
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, done := s.defs[name]; !done {
+			s.defs[name] = nil // placeholder for recursive types
+			s.defs[name] = s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+func (s *schemaSet) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	s.addFields(t, props, &required)
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		slices.Sort(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields follows encoding/json: unexported fields and "-" are skipped,
+// embedded structs without a tag are flattened and omitempty fields are
+// optional.
+func (s *schemaSet) addFields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.addFields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema := s.of(f.Type)
+		describeRules(schema, f.Tag.Get("validate"))
+		props[name] = schema
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSONWithETag(w, r, stateOf(r.Context()).openAPIDocument)
+}
+
+func getAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// pageParams are the keyset pagination parameters of a list request:
+// items with an ID greater than after, at most limit of them.
+type pageParams struct {
+	after int
+	limit int
+}
+
+// parsePage reads ?after= and ?limit=. Without a limit the default page
+// size applies, so unpaginated requests never return the whole dataset.
+func parsePage(r *http.Request) (pageParams, []FieldError) {
+	p := pageParams{limit: cfg.DefaultPageSize}
+	var errs []FieldError
+	query := r.URL.Query()
+	if v := query.Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, FieldError{Field: "after", Message: "must be a non-negative integer"})
+		}
+		p.after = n
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > cfg.MaxPageSize {
+			errs = append(errs, FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(cfg.MaxPageSize)})
+		}
+		p.limit = n
+	}
+	return p, errs
+}
+
+// paginate cuts one page out of items sorted by ID and returns the Link
+// header pointing at the next page, or "" on the last page.
+func paginate[T entity[T]](r *http.Request, items []T, p pageParams) ([]T, string) {
+	start := 0
+	for start < len(items) && items[start].entityID() <= p.after {
+		start++
+	}
+	items = items[start:]
+	if len(items) <= p.limit {
+		return items, ""
+	}
+	page := items[:p.limit]
+
+	query := r.URL.Query()
+	query.Set("after", strconv.Itoa(page[len(page)-1].entityID()))
+	query.Set("limit", strconv.Itoa(p.limit))
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return page, "<" + next.String() + `>; rel="next"`
+}
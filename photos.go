@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// photoTypes are the image formats a student photo may be in. The format
+// is sniffed from the contents, not taken from the client.
+var photoTypes = []string{"image/gif", "image/jpeg", "image/png", "image/webp"}
+
+func photoKey(studentID int) string {
+	return "photos/" + strconv.Itoa(studentID)
+}
+
+// findPhotoStudent reads the student ID of a photo route and checks the
+// student exists.
+func findPhotoStudent(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return 0, false
+	}
+	if _, exists := findStudent(r.Context(), id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// putStudentPhoto replaces a student's photo with the request body.
+func putStudentPhoto(w http.ResponseWriter, r *http.Request) {
+	id, ok := findPhotoStudent(w, r)
+	if !ok {
+		return
+	}
+
+	body := bufio.NewReaderSize(http.MaxBytesReader(w, r.Body, cfg.PhotoMaxBytes), 512)
+	head, _ := body.Peek(512)
+	if contentType := http.DetectContentType(head); !slices.Contains(photoTypes, contentType) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Photo must be one of "+strings.Join(photoTypes, ", ")+", not "+contentType)
+		return
+	}
+	_, _, err := storeBlob(r.Context(), photoKey(id), func(out io.Writer) error {
+		_, err := io.Copy(out, body)
+		return err
+	})
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, "Photo exceeds "+strconv.FormatInt(tooBig.Limit, 10)+" bytes")
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to store photo", "student_id", id, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to store photo")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getStudentPhoto streams a student's photo.
+func getStudentPhoto(w http.ResponseWriter, r *http.Request) {
+	id, ok := findPhotoStudent(w, r)
+	if !ok {
+		return
+	}
+	photo, err := blobs.Open(r.Context(), photoKey(id))
+	if errors.Is(err, errBlobNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "Photo not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to open photo", "student_id", id, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to read photo")
+		return
+	}
+	defer photo.Close()
+
+	body := bufio.NewReaderSize(photo, 512)
+	head, _ := body.Peek(512)
+	w.Header().Set("Content-Type", http.DetectContentType(head))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, body); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream photo", "student_id", id, "error", err)
+	}
+}
+
+func deleteStudentPhoto(w http.ResponseWriter, r *http.Request) {
+	id, ok := findPhotoStudent(w, r)
+	if !ok {
+		return
+	}
+	removeStudentPhoto(r.Context(), id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeStudentPhoto deletes a student's photo, if there is one.
+func removeStudentPhoto(ctx context.Context, studentID int) {
+	if inDryRun(ctx) {
+		return
+	}
+	if err := blobs.Delete(ctx, photoKey(studentID)); err != nil {
+		slog.WarnContext(ctx, "failed to delete photo", "student_id", studentID, "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Prerequisite is a course that must be completed before enrolling in
+// another, optionally with at least a minimum letter grade.
+type Prerequisite struct {
+	CourseID int    `json:"course_id" validate:"required"`
+	MinGrade string `json:"min_grade,omitempty"`
+}
+
+// UnmetPrerequisite explains one prerequisite a student has not satisfied.
+type UnmetPrerequisite struct {
+	CourseID  int    `json:"course_id"`
+	Code      string `json:"code"`
+	MinGrade  string `json:"min_grade,omitempty"`
+	BestGrade string `json:"best_grade,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// passingPoints is the lowest grade that completes a course.
+var passingPoints = letterPoints["D"]
+
+// validatePrerequisites lists problems with the prerequisites submitted
+// with a course that can be found without looking at other courses.
+func validatePrerequisites(c Course) []FieldError {
+	var errs []FieldError
+	seen := map[int]bool{}
+	for i, p := range c.Prerequisites {
+		field := fmt.Sprintf("prerequisites[%d]", i)
+		if p.CourseID > 0 && seen[p.CourseID] {
+			errs = append(errs, FieldError{Field: field + ".course_id", Message: "is listed twice"})
+		}
+		seen[p.CourseID] = true
+		if _, ok := letterPoints[p.MinGrade]; p.MinGrade != "" && !ok {
+			errs = append(errs, FieldError{Field: field + ".min_grade", Message: "must be a letter grade from A to F"})
+		}
+	}
+	return errs
+}
+
+// prerequisiteProblems reports prerequisites naming missing courses or
+// forming a cycle, which would make the course impossible to take. id is
+// the course being saved, or 0 for a new one.
+func prerequisiteProblems(ctx context.Context, id int, c Course) []FieldError {
+	requires := map[int][]int{}
+	for _, other := range courseStore.List(ctx) {
+		for _, p := range other.Prerequisites {
+			requires[other.ID] = append(requires[other.ID], p.CourseID)
+		}
+	}
+
+	var errs []FieldError
+	for i, p := range c.Prerequisites {
+		field := fmt.Sprintf("prerequisites[%d].course_id", i)
+		if _, ok := courseStore.Find(ctx, p.CourseID); !ok {
+			errs = append(errs, FieldError{Field: field, Message: "does not exist"})
+			continue
+		}
+		if id != 0 && (p.CourseID == id || reaches(requires, p.CourseID, id)) {
+			errs = append(errs, FieldError{Field: field, Message: "would make the course a prerequisite of itself"})
+		}
+	}
+	return errs
+}
+
+// reaches reports whether course from transitively requires course to.
+func reaches(requires map[int][]int, from, to int) bool {
+	seen := map[int]bool{}
+	stack := []int{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, requires[id]...)
+	}
+	return false
+}
+
+// unmetPrerequisites compares a course's prerequisites with the
+// student's best grade in each. Pass/fail passes satisfy prerequisites
+// without a minimum grade.
+func unmetPrerequisites(ctx context.Context, studentID int, course Course) []UnmetPrerequisite {
+	if len(course.Prerequisites) == 0 {
+		return nil
+	}
+	grades := studentGrades(ctx, studentID, "")
+
+	var unmet []UnmetPrerequisite
+	for _, p := range course.Prerequisites {
+		required := passingPoints
+		if p.MinGrade != "" {
+			required = letterPoints[p.MinGrade]
+		}
+
+		best, bestPoints, met := "", -1.0, false
+		for _, g := range grades {
+			if g.CourseID != p.CourseID {
+				continue
+			}
+			if g.Scale == scalePassFail {
+				met = met || (g.Value == "P" && p.MinGrade == "")
+				if best == "" {
+					best = g.Value
+				}
+				continue
+			}
+			if points, ok := gradePoints(g); ok && points > bestPoints {
+				best, bestPoints = g.Value, points
+			}
+		}
+		if met || bestPoints >= required {
+			continue
+		}
+
+		u := UnmetPrerequisite{CourseID: p.CourseID, MinGrade: p.MinGrade, BestGrade: best}
+		if c, ok := courseStore.Find(ctx, p.CourseID); ok {
+			u.Code = c.Code
+		}
+		switch {
+		case best == "":
+			u.Reason = "not completed"
+		case p.MinGrade != "":
+			u.Reason = "grade " + best + " is below the required " + p.MinGrade
+		default:
+			u.Reason = "not passed"
+		}
+		unmet = append(unmet, u)
+	}
+	return unmet
+}
+
+// writeUnmetPrerequisites answers 422 with the unmet prerequisites as an
+// extension member of the problem document.
+func writeUnmetPrerequisites(w http.ResponseWriter, r *http.Request, course Course, unmet []UnmetPrerequisite) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(struct {
+		Problem
+		Unmet []UnmetPrerequisite `json:"unmet_prerequisites"`
+	}{newProblem(r, http.StatusUnprocessableEntity, "Prerequisites for "+course.Code+" are not met"), unmet})
+}
+
+// requiredBy lists the courses that name id as a prerequisite.
+func requiredBy(ctx context.Context, id int) []string {
+	var codes []string
+	for _, c := range repoList(ctx, courseStore) {
+		if slices.ContainsFunc(c.Prerequisites, func(p Prerequisite) bool { return p.CourseID == id }) {
+			codes = append(codes, c.Code)
+		}
+	}
+	return codes
+}
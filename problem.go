@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why one field of a request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// newProblem builds the problem document for a failed request. The type is
+// about:blank, so the title is the standard text of the status code. Text
+// is translated to the language of Accept-Language where the catalog has
+// it; field names are not.
+func newProblem(r *http.Request, status int, detail string, fieldErrors ...FieldError) Problem {
+	l := requestLocale(r)
+	if l != english && len(fieldErrors) > 0 {
+		translated := make([]FieldError, len(fieldErrors))
+		for i, fe := range fieldErrors {
+			translated[i] = FieldError{Field: fe.Field, Message: l.translate(fe.Message)}
+		}
+		fieldErrors = translated
+	}
+	return Problem{
+		Type:      "about:blank",
+		Title:     l.translate(http.StatusText(status)),
+		Status:    status,
+		Detail:    l.translate(detail),
+		Instance:  r.URL.Path,
+		RequestID: requestIDFromContext(r.Context()),
+		Errors:    fieldErrors,
+	}
+}
+
+// writeProblem answers the request with a problem+json body.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fieldErrors ...FieldError) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	setContentLanguage(w, requestLocale(r))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newProblem(r, status, detail, fieldErrors...))
+}
+
+// notFoundHandler answers requests that match no route.
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, "No route matches "+r.URL.Path)
+}
+
+// allowMethods are the methods probed when building an Allow header.
+var allowMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// allowedMethods lists the methods router serves at the path of r. OPTIONS
+// is always included, since methodNotAllowed answers it for every route.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range allowMethods {
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// methodNotAllowed answers requests to a known path with a method it does
+// not serve. OPTIONS gets 204 and anything else a 405 problem, both with
+// an Allow header listing what the path does serve.
+func methodNotAllowed(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowedMethods(router, r), ", "))
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeProblem(w, r, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
+}
+
+// validateStudent lists every problem with a student submitted for create or update.
+func validateStudent(s Student) []FieldError {
+	return checkFields(s)
+}
@@ -0,0 +1,239 @@
+package studentv1
+
+// The service stubs below follow the layout protoc-gen-go-grpc produces,
+// written by hand because that plugin is not part of the build. Keep them
+// in step with the service in student.proto.
+
+import (
+	"context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	StudentService_GetStudent_FullMethodName    = "/studengo.student.v1.StudentService/GetStudent"
+	StudentService_ListStudents_FullMethodName  = "/studengo.student.v1.StudentService/ListStudents"
+	StudentService_CreateStudent_FullMethodName = "/studengo.student.v1.StudentService/CreateStudent"
+	StudentService_UpdateStudent_FullMethodName = "/studengo.student.v1.StudentService/UpdateStudent"
+	StudentService_DeleteStudent_FullMethodName = "/studengo.student.v1.StudentService/DeleteStudent"
+	StudentService_WatchStudents_FullMethodName = "/studengo.student.v1.StudentService/WatchStudents"
+)
+
+// StudentServiceClient is the client API for StudentService.
+type StudentServiceClient interface {
+	GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	ListStudents(ctx context.Context, in *ListStudentsRequest, opts ...grpc.CallOption) (*ListStudentsResponse, error)
+	CreateStudent(ctx context.Context, in *CreateStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	UpdateStudent(ctx context.Context, in *UpdateStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	DeleteStudent(ctx context.Context, in *DeleteStudentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	WatchStudents(ctx context.Context, in *WatchStudentsRequest, opts ...grpc.CallOption) (StudentService_WatchStudentsClient, error)
+}
+
+type studentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStudentServiceClient(cc grpc.ClientConnInterface) StudentServiceClient {
+	return &studentServiceClient{cc}
+}
+
+func (c *studentServiceClient) GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	if err := c.cc.Invoke(ctx, StudentService_GetStudent_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) ListStudents(ctx context.Context, in *ListStudentsRequest, opts ...grpc.CallOption) (*ListStudentsResponse, error) {
+	out := new(ListStudentsResponse)
+	if err := c.cc.Invoke(ctx, StudentService_ListStudents_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) CreateStudent(ctx context.Context, in *CreateStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	if err := c.cc.Invoke(ctx, StudentService_CreateStudent_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) UpdateStudent(ctx context.Context, in *UpdateStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	if err := c.cc.Invoke(ctx, StudentService_UpdateStudent_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) DeleteStudent(ctx context.Context, in *DeleteStudentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	if err := c.cc.Invoke(ctx, StudentService_DeleteStudent_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) WatchStudents(ctx context.Context, in *WatchStudentsRequest, opts ...grpc.CallOption) (StudentService_WatchStudentsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &StudentService_ServiceDesc.Streams[0], StudentService_WatchStudents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStudentsRequest, StudentChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StudentService_WatchStudentsClient = grpc.ServerStreamingClient[StudentChange]
+
+// StudentServiceServer is the server API for StudentService. Embed
+// UnimplementedStudentServiceServer for forward compatibility.
+type StudentServiceServer interface {
+	GetStudent(context.Context, *GetStudentRequest) (*Student, error)
+	ListStudents(context.Context, *ListStudentsRequest) (*ListStudentsResponse, error)
+	CreateStudent(context.Context, *CreateStudentRequest) (*Student, error)
+	UpdateStudent(context.Context, *UpdateStudentRequest) (*Student, error)
+	DeleteStudent(context.Context, *DeleteStudentRequest) (*emptypb.Empty, error)
+	WatchStudents(*WatchStudentsRequest, StudentService_WatchStudentsServer) error
+	mustEmbedUnimplementedStudentServiceServer()
+}
+
+type StudentService_WatchStudentsServer = grpc.ServerStreamingServer[StudentChange]
+
+// UnimplementedStudentServiceServer answers every method with UNIMPLEMENTED.
+type UnimplementedStudentServiceServer struct{}
+
+func (UnimplementedStudentServiceServer) GetStudent(context.Context, *GetStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) ListStudents(context.Context, *ListStudentsRequest) (*ListStudentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStudents not implemented")
+}
+func (UnimplementedStudentServiceServer) CreateStudent(context.Context, *CreateStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) UpdateStudent(context.Context, *UpdateStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) DeleteStudent(context.Context, *DeleteStudentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) WatchStudents(*WatchStudentsRequest, StudentService_WatchStudentsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStudents not implemented")
+}
+func (UnimplementedStudentServiceServer) mustEmbedUnimplementedStudentServiceServer() {}
+
+func RegisterStudentServiceServer(s grpc.ServiceRegistrar, srv StudentServiceServer) {
+	s.RegisterService(&StudentService_ServiceDesc, srv)
+}
+
+func _StudentService_GetStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).GetStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: StudentService_GetStudent_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).GetStudent(ctx, req.(*GetStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_ListStudents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStudentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).ListStudents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: StudentService_ListStudents_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).ListStudents(ctx, req.(*ListStudentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_CreateStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).CreateStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: StudentService_CreateStudent_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).CreateStudent(ctx, req.(*CreateStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_UpdateStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).UpdateStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: StudentService_UpdateStudent_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).UpdateStudent(ctx, req.(*UpdateStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_DeleteStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).DeleteStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: StudentService_DeleteStudent_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).DeleteStudent(ctx, req.(*DeleteStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_WatchStudents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStudentsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StudentServiceServer).WatchStudents(m, &grpc.GenericServerStream[WatchStudentsRequest, StudentChange]{ServerStream: stream})
+}
+
+// StudentService_ServiceDesc is the grpc.ServiceDesc for StudentService.
+var StudentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "studengo.student.v1.StudentService",
+	HandlerType: (*StudentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetStudent", Handler: _StudentService_GetStudent_Handler},
+		{MethodName: "ListStudents", Handler: _StudentService_ListStudents_Handler},
+		{MethodName: "CreateStudent", Handler: _StudentService_CreateStudent_Handler},
+		{MethodName: "UpdateStudent", Handler: _StudentService_UpdateStudent_Handler},
+		{MethodName: "DeleteStudent", Handler: _StudentService_DeleteStudent_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "WatchStudents", Handler: _StudentService_WatchStudents_Handler, ServerStreams: true},
+	},
+	Metadata: "proto/student/v1/student.proto",
+}
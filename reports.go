@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// reportRow is a report line that can also be written as CSV.
+type reportRow interface {
+	csvRecord() []string
+}
+
+// wantsCSV reports whether the client asked for CSV with ?format=csv or
+// an Accept header ranking text/csv at least as high as JSON and the
+// other formats negotiateFormat offers.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	csvQ, otherQ := 0.0, 0.0
+	for _, ar := range acceptRanges(r.Header.Get("Accept")) {
+		if ar.mediaType == "text/csv" {
+			csvQ = max(csvQ, ar.q)
+		} else if _, ok := mediaFormats[ar.mediaType]; ok || ar.mediaType == halMediaType {
+			otherQ = max(otherQ, ar.q)
+		}
+	}
+	return csvQ > 0 && csvQ >= otherQ
+}
+
+// writeReport writes rows as a JSON array, or as CSV with header when the
+// client asked for it. name becomes the CSV file name.
+func writeReport[T reportRow](w http.ResponseWriter, r *http.Request, name string, header []string, rows []T) {
+	if !wantsCSV(r) {
+		writeJSONArray(w, rows)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	out := csv.NewWriter(w)
+	out.Write(header)
+	for _, row := range rows {
+		out.Write(row.csvRecord())
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		slog.WarnContext(r.Context(), "failed to write report", "report", name, "error", err)
+	}
+}
+
+// writeCSVFields writes items as CSV with a column per JSON field named
+// in fields. Strings are written without their quotes and missing or
+// null fields as empty cells; other values as their JSON.
+func writeCSVFields[T any](w http.ResponseWriter, r *http.Request, name string, fields []string, items []T) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	if err := encodeCSVFields(w, fields, items); err != nil {
+		slog.WarnContext(r.Context(), "failed to write report", "report", name, "error", err)
+	}
+}
+
+// encodeCSVFields writes the CSV of writeCSVFields to w.
+func encodeCSVFields[T any](w io.Writer, fields []string, items []T) error {
+	out := csv.NewWriter(w)
+	out.Write(fields)
+	record := make([]string, len(fields))
+	for _, item := range items {
+		members, err := jsonMembers(item)
+		if err != nil {
+			return err
+		}
+		for i, field := range fields {
+			value := members[field]
+			var s string
+			switch {
+			case len(value) == 0 || string(value) == "null":
+				record[i] = ""
+			case json.Unmarshal(value, &s) == nil:
+				record[i] = s
+			default:
+				record[i] = string(value)
+			}
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// EnrollmentReport counts the students enrolled in and waiting for one
+// course. ?term= limits both to one term.
+type EnrollmentReport struct {
+	CourseID   int     `json:"course_id"`
+	Code       string  `json:"code"`
+	Title      string  `json:"title"`
+	Capacity   int     `json:"capacity"`
+	Enrolled   int     `json:"enrolled"`
+	Waitlisted int     `json:"waitlisted"`
+	FillRate   float64 `json:"fill_rate"`
+}
+
+func (e EnrollmentReport) csvRecord() []string {
+	return []string{strconv.Itoa(e.CourseID), e.Code, e.Title, strconv.Itoa(e.Capacity), strconv.Itoa(e.Enrolled), strconv.Itoa(e.Waitlisted), formatFloat(e.FillRate)}
+}
+
+// getEnrollmentReport serves GET /reports/enrollments.
+func getEnrollmentReport(w http.ResponseWriter, r *http.Request) {
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	enrolled := map[int]int{}
+	for _, e := range enrollmentStore.List(r.Context()) {
+		if term == "" || e.Term == term {
+			enrolled[e.CourseID]++
+		}
+	}
+	waiting := map[int]int{}
+	for _, e := range waitlistStore.List(r.Context()) {
+		if term == "" || e.Term == term {
+			waiting[e.CourseID]++
+		}
+	}
+
+	report := []EnrollmentReport{}
+	for _, c := range repoList(r.Context(), courseStore) {
+		row := EnrollmentReport{
+			CourseID:   c.ID,
+			Code:       c.Code,
+			Title:      c.Title,
+			Capacity:   c.Capacity,
+			Enrolled:   enrolled[c.ID],
+			Waitlisted: waiting[c.ID],
+		}
+		if c.Capacity > 0 {
+			row.FillRate = math.Round(float64(row.Enrolled)/float64(c.Capacity)*10000) / 10000
+		}
+		report = append(report, row)
+	}
+	writeReport(w, r, "enrollments", []string{"course_id", "code", "title", "capacity", "enrolled", "waitlisted", "fill_rate"}, report)
+}
+
+// AgeBucket counts the students whose age is between MinAge and MaxAge,
+// inclusive.
+type AgeBucket struct {
+	MinAge   int `json:"min_age"`
+	MaxAge   int `json:"max_age"`
+	Students int `json:"students"`
+}
+
+func (a AgeBucket) csvRecord() []string {
+	return []string{strconv.Itoa(a.MinAge), strconv.Itoa(a.MaxAge), strconv.Itoa(a.Students)}
+}
+
+// getAgeReport serves GET /reports/ages. ?width= groups ages into
+// buckets of that many years, starting at a multiple of the width; the
+// default of 1 counts each age separately. Empty buckets are left out.
+func getAgeReport(w http.ResponseWriter, r *http.Request) {
+	width := 1
+	if v := r.URL.Query().Get("width"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "width", Message: "must be an integer from 1 to 100"})
+			return
+		}
+		width = n
+	}
+
+	counts := map[int]int{}
+	for _, s := range allStudents(r.Context()) {
+		counts[s.Age/width*width]++
+	}
+	report := []AgeBucket{}
+	for from, n := range counts {
+		report = append(report, AgeBucket{MinAge: from, MaxAge: from + width - 1, Students: n})
+	}
+	slices.SortFunc(report, func(a, b AgeBucket) int { return a.MinAge - b.MinAge })
+	writeReport(w, r, "ages", []string{"min_age", "max_age", "students"}, report)
+}
+
+// AttendanceReport is the attendance summary of one course. Excused
+// absences count towards neither rate.
+type AttendanceReport struct {
+	CourseID int    `json:"course_id"`
+	Code     string `json:"code"`
+	AttendanceSummary
+	AttendanceRate float64 `json:"attendance_rate"`
+}
+
+func (a AttendanceReport) csvRecord() []string {
+	return []string{
+		strconv.Itoa(a.CourseID), a.Code, strconv.Itoa(a.Records), strconv.Itoa(a.Present), strconv.Itoa(a.Absent),
+		strconv.Itoa(a.Late), strconv.Itoa(a.Excused), formatFloat(a.AbsenceRate), formatFloat(a.AttendanceRate),
+	}
+}
+
+// getAttendanceReport serves GET /reports/attendance, with the same
+// filters as GET /attendance. Courses without records are left out.
+func getAttendanceReport(w http.ResponseWriter, r *http.Request) {
+	list, ok := filteredAttendance(w, r)
+	if !ok {
+		return
+	}
+
+	byCourse := map[int]*AttendanceReport{}
+	for _, a := range list {
+		row, ok := byCourse[a.CourseID]
+		if !ok {
+			row = &AttendanceReport{CourseID: a.CourseID}
+			if c, found := courseStore.Find(r.Context(), a.CourseID); found {
+				row.Code = c.Code
+			}
+			byCourse[a.CourseID] = row
+		}
+		row.Records++
+		switch a.Status {
+		case "present":
+			row.Present++
+		case "absent":
+			row.Absent++
+		case "late":
+			row.Late++
+		case "excused":
+			row.Excused++
+		}
+	}
+
+	report := []AttendanceReport{}
+	for _, row := range byCourse {
+		if counted := row.Records - row.Excused; counted > 0 {
+			row.AbsenceRate = math.Round(float64(row.Absent)/float64(counted)*10000) / 10000
+			row.AttendanceRate = math.Round(float64(row.Present+row.Late)/float64(counted)*10000) / 10000
+		}
+		report = append(report, *row)
+	}
+	slices.SortFunc(report, func(a, b AttendanceReport) int { return a.CourseID - b.CourseID })
+	writeReport(w, r, "attendance", []string{"course_id", "code", "records", "present", "absent", "late", "excused", "absence_rate", "attendance_rate"}, report)
+}
+
+// GPABucket counts the students whose GPA is at least MinGPA and below
+// MaxGPA; the top bucket includes 4.0.
+type GPABucket struct {
+	MinGPA   float64 `json:"min_gpa"`
+	MaxGPA   float64 `json:"max_gpa"`
+	Students int     `json:"students"`
+}
+
+func (g GPABucket) csvRecord() []string {
+	return []string{formatFloat(g.MinGPA), formatFloat(g.MaxGPA), strconv.Itoa(g.Students)}
+}
+
+// gpaBucketWidth splits the 4.0 scale into eight buckets.
+const gpaBucketWidth = 0.5
+
+// getGPAReport serves GET /reports/gpa. Students without graded credits
+// are not counted. ?program_id= limits the report to one program.
+func getGPAReport(w http.ResponseWriter, r *http.Request) {
+	programID := 0
+	if v := r.URL.Query().Get("program_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query", FieldError{Field: "program_id", Message: "must be a positive integer"})
+			return
+		}
+		programID = n
+	}
+
+	buckets := int(4 / gpaBucketWidth)
+	report := make([]GPABucket, buckets)
+	for i := range report {
+		report[i] = GPABucket{MinGPA: float64(i) * gpaBucketWidth, MaxGPA: float64(i+1) * gpaBucketWidth}
+	}
+	for _, s := range allStudents(r.Context()) {
+		if programID != 0 && s.ProgramID != programID {
+			continue
+		}
+		gpa := computeGPA(r.Context(), s.ID).GPA
+		if gpa == nil {
+			continue
+		}
+		report[min(int(*gpa/gpaBucketWidth), buckets-1)].Students++
+	}
+	writeReport(w, r, "gpa", []string{"min_gpa", "max_gpa", "students"}, report)
+}
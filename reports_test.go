@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWantsCSV(t *testing.T) {
+	for request, want := range map[string]bool{
+		"/v1/students":                                             false,
+		"/v1/students?format=csv":                                  true,
+		"/v1/students?format=json":                                 false,
+		"/v1/students?format=json | text/csv":                      false,
+		"/v1/students?format=csv | application/json":               true,
+		"/v1/students | text/csv":                                  true,
+		"/v1/students | TEXT/CSV; charset=utf-8":                   true,
+		"/v1/students | text/csv, application/json":                true,
+		"/v1/students | application/json, text/csv;q=0.9":          false,
+		"/v1/students | application/json;q=0.5, text/csv":          true,
+		"/v1/students | text/csv;q=0.5, application/msgpack":       false,
+		"/v1/students | text/csv;q=0.5, application/hal+json":      false,
+		"/v1/students | text/csv;q=0.5, image/png, */*":            true,
+		"/v1/students | text/csv;q=0":                              false,
+		"/v1/students | text/html,application/xhtml+xml,*/*;q=0.8": false,
+	} {
+		target, accept, _ := strings.Cut(request, " | ")
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("Accept", accept)
+		if got := wantsCSV(r); got != want {
+			t.Errorf("GET %s with Accept %q: wantsCSV = %v, want %v", target, accept, got, want)
+		}
+	}
+}
+
+func TestEncodeCSVFields(t *testing.T) {
+	type row struct {
+		Name  string   `json:"name"`
+		Score float64  `json:"score"`
+		Tags  []string `json:"tags,omitempty"`
+		Note  *string  `json:"note"`
+		OK    bool     `json:"ok"`
+	}
+	note := "line one\nline two"
+	var b strings.Builder
+	err := encodeCSVFields(&b, []string{"name", "score", "tags", "note", "ok", "missing"}, []row{
+		{Name: "Lovelace, Ada", Score: 3.75, Tags: []string{"a", "b"}, OK: true},
+		{Name: `Grace "Amazing" Hopper`, Score: 4, Note: &note},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Strings lose their JSON quotes, other values keep their JSON, and
+	// absent or null fields are empty; CSV quoting is left to encoding/csv.
+	want := "name,score,tags,note,ok,missing\n" +
+		`"Lovelace, Ada",3.75,"[""a"",""b""]",,true,` + "\n" +
+		`"Grace ""Amazing"" Hopper",4,,"line one` + "\n" + `line two",false,` + "\n"
+	if b.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestStudentListCSV(t *testing.T) {
+	api := newTestAPI(t, nil)
+	for _, s := range []Student{
+		{Name: "Lovelace, Ada", Age: 20, Email: "ada@example.com"},
+		{Name: "Alan Turing", Age: 41, Email: "alan@example.com"},
+		{Name: "Grace Hopper", Age: 85, Email: "grace@example.com"},
+	} {
+		api.decode(t, "POST", "/v1/students", s, http.StatusCreated, nil)
+	}
+	csvAccept := http.Header{"Accept": {"text/csv"}}
+
+	resp, data := api.get(t, "/v1/students", csvAccept)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv; charset=utf-8" ||
+		resp.Header.Get("Content-Disposition") != `attachment; filename="students.csv"` {
+		t.Fatalf("status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "id,name,age,email,program_id,updated_at" || !strings.HasPrefix(lines[1], `1,"Lovelace, Ada",20,ada@example.com,,20`) {
+		t.Errorf("CSV =\n%s", data)
+	}
+
+	// $select picks and orders the columns, and the rest of the query
+	// applies as it does to JSON, paging included.
+	for query, want := range map[string]string{
+		"$select=email,name": "email,name\nada@example.com,\"Lovelace, Ada\"\nalan@example.com,Alan Turing\ngrace@example.com,Grace Hopper\n",
+		"$select=name&$filter=age gt 30&$orderby=age desc": "name\nGrace Hopper\nAlan Turing\n",
+		"$select=id&limit=2":            "id\n1\n2\n",
+		"$select=id&$filter=age gt 100": "id\n",
+	} {
+		resp, data := api.get(t, "/v1/students?"+odataEscape(query), csvAccept)
+		if string(data) != want {
+			t.Errorf("%s: CSV =\n%s\nwant\n%s", query, data, want)
+		}
+		if query == "$select=id&limit=2" {
+			if m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); m == nil || !strings.Contains(m[1], "after=2") {
+				t.Errorf("%s: Link = %q, want the page after 2", query, resp.Header.Get("Link"))
+			}
+		}
+	}
+
+	// ?format=csv does the same as Accept, and $select with HAL links is
+	// allowed, since CSV has no links.
+	_, data = api.get(t, "/v1/students?format=csv&hateoas=true&"+odataEscape("$select=id"), nil)
+	if string(data) != "id\n1\n2\n3\n" {
+		t.Errorf("?format=csv: CSV =\n%s", data)
+	}
+
+	// The CSV has an entity tag of its own, which changes with the list.
+	resp, _ = api.get(t, "/v1/students", csvAccept)
+	etag := resp.Header.Get("ETag")
+	if !strings.HasSuffix(etag, `-csv"`) {
+		t.Fatalf("ETag = %q, want a -csv tag", etag)
+	}
+	resp, _ = api.get(t, "/v1/students", http.Header{"Accept": {"text/csv"}, "If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("revalidation: status %d, want 304", resp.StatusCode)
+	}
+	resp, _ = api.get(t, "/v1/students", http.Header{"If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusOK || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Errorf("JSON with the CSV tag: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	api.do(t, "DELETE", "/v1/students/2", nil)
+	resp, data = api.get(t, "/v1/students", http.Header{"Accept": {"text/csv"}, "If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusOK || strings.Count(string(data), "\n") != 3 || resp.Header.Get("ETag") == etag {
+		t.Errorf("after a delete: status %d, ETag %q, CSV =\n%s", resp.StatusCode, resp.Header.Get("ETag"), data)
+	}
+
+	// Reports are offered as CSV too.
+	_, data = api.get(t, "/v1/reports/ages?width=50&format=csv", nil)
+	if string(data) != "min_age,max_age,students\n0,49,1\n50,99,1\n" {
+		t.Errorf("age report CSV =\n%s", data)
+	}
+	_, data = api.get(t, "/v1/reports/ages?width=50", csvAccept)
+	if string(data) != "min_age,max_age,students\n0,49,1\n50,99,1\n" {
+		t.Errorf("age report CSV by Accept =\n%s", data)
+	}
+}
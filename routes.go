@@ -0,0 +1,220 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registerRoutes adds every route of the API to r.
+func registerRoutes(r *mux.Router) {
+	// Resource routes are versioned; see versioning.go.
+	v1 := apiVersion{router: r, prefix: apiV1}
+
+	// Root route
+	r.Handle("/", withTimeout(cfg.RequestTimeout, homeHandler)).Methods("GET")
+
+	// Student CRUD
+	v1.Handle("/students", withTimeout(cfg.RequestTimeout, createStudent)).Methods("POST")
+	// The list is written as it is encoded, which http.TimeoutHandler
+	// would buffer whole.
+	v1.HandleFunc("/students", getStudents).Methods("GET")
+	v1.HandleFunc("/students/events", streamStudentEvents).Methods("GET")
+	v1.HandleFunc("/ws", serveWebSocket).Methods("GET")
+	v1.Handle("/students/query", withTimeout(cfg.LLMRequestTimeout, requireFeature(featureNLQuery, limitLLM(queryStudents)))).Methods("POST")
+	v1.Handle("/students/{id}", withTimeout(cfg.RequestTimeout, getStudent)).Methods("GET")
+	v1.Handle("/students/{id}", withTimeout(cfg.RequestTimeout, updateStudent)).Methods("PUT")
+	v1.Handle("/students/{id}", withTimeout(cfg.RequestTimeout, deleteStudent)).Methods("DELETE")
+	v1.Handle("/students/{id}/summary", withTimeout(cfg.LLMRequestTimeout, limitLLM(getStudentSummary))).Methods("GET")
+	v1.Handle("/students/{id}/summary/share", withTimeout(cfg.RequestTimeout, createSummaryShareLink)).Methods("POST")
+
+	// Guardians
+	v1.Handle("/students/{id}/guardians", withTimeout(cfg.RequestTimeout, createGuardian)).Methods("POST")
+	v1.Handle("/students/{id}/guardians", withTimeout(cfg.RequestTimeout, getStudentGuardians)).Methods("GET")
+	v1.Handle("/guardians/{id}", withTimeout(cfg.RequestTimeout, getGuardian)).Methods("GET")
+	v1.Handle("/guardians/{id}", withTimeout(cfg.RequestTimeout, updateGuardian)).Methods("PUT")
+	v1.Handle("/guardians/{id}", withTimeout(cfg.RequestTimeout, deleteGuardian)).Methods("DELETE")
+
+	// Departments and programs
+	v1.Handle("/departments", withTimeout(cfg.RequestTimeout, createDepartment)).Methods("POST")
+	v1.Handle("/departments", withTimeout(cfg.RequestTimeout, getDepartments)).Methods("GET")
+	v1.Handle("/departments/{id}", withTimeout(cfg.RequestTimeout, getDepartment)).Methods("GET")
+	v1.Handle("/departments/{id}", withTimeout(cfg.RequestTimeout, updateDepartment)).Methods("PUT")
+	v1.Handle("/departments/{id}", withTimeout(cfg.RequestTimeout, deleteDepartment)).Methods("DELETE")
+	v1.Handle("/departments/{id}/programs", withTimeout(cfg.RequestTimeout, createProgram)).Methods("POST")
+	v1.Handle("/departments/{id}/programs", withTimeout(cfg.RequestTimeout, getDepartmentPrograms)).Methods("GET")
+	v1.Handle("/programs/{id}", withTimeout(cfg.RequestTimeout, getProgram)).Methods("GET")
+	v1.Handle("/programs/{id}", withTimeout(cfg.RequestTimeout, updateProgram)).Methods("PUT")
+	v1.Handle("/programs/{id}", withTimeout(cfg.RequestTimeout, deleteProgram)).Methods("DELETE")
+	v1.Handle("/programs/{id}/students", withTimeout(cfg.RequestTimeout, getProgramStudents)).Methods("GET")
+	v1.Handle("/reports/programs", withTimeout(cfg.RequestTimeout, getProgramReport)).Methods("GET")
+	v1.Handle("/reports/departments", withTimeout(cfg.RequestTimeout, getDepartmentReport)).Methods("GET")
+	v1.Handle("/reports/enrollments", withTimeout(cfg.RequestTimeout, getEnrollmentReport)).Methods("GET")
+	v1.Handle("/reports/ages", withTimeout(cfg.RequestTimeout, getAgeReport)).Methods("GET")
+	v1.Handle("/reports/attendance", withTimeout(cfg.RequestTimeout, getAttendanceReport)).Methods("GET")
+	v1.Handle("/reports/gpa", withTimeout(cfg.RequestTimeout, getGPAReport)).Methods("GET")
+
+	// Teachers
+	v1.Handle("/teachers", withTimeout(cfg.RequestTimeout, createTeacher)).Methods("POST")
+	v1.Handle("/teachers", withTimeout(cfg.RequestTimeout, getTeachers)).Methods("GET")
+	v1.Handle("/teachers/{id}", withTimeout(cfg.RequestTimeout, getTeacher)).Methods("GET")
+	v1.Handle("/teachers/{id}", withTimeout(cfg.RequestTimeout, updateTeacher)).Methods("PUT")
+	v1.Handle("/teachers/{id}", withTimeout(cfg.RequestTimeout, deleteTeacher)).Methods("DELETE")
+	v1.Handle("/teachers/{id}/courses", withTimeout(cfg.RequestTimeout, getTeacherCourses)).Methods("GET")
+
+	// Terms
+	v1.Handle("/terms", withTimeout(cfg.RequestTimeout, createTerm)).Methods("POST")
+	v1.Handle("/terms", withTimeout(cfg.RequestTimeout, getTerms)).Methods("GET")
+	v1.Handle("/terms/current", withTimeout(cfg.RequestTimeout, getCurrentTerm)).Methods("GET")
+	v1.Handle("/terms/{id}", withTimeout(cfg.RequestTimeout, getTerm)).Methods("GET")
+	v1.Handle("/terms/{id}", withTimeout(cfg.RequestTimeout, updateTerm)).Methods("PUT")
+	v1.Handle("/terms/{id}", withTimeout(cfg.RequestTimeout, deleteTerm)).Methods("DELETE")
+
+	// Courses
+	v1.Handle("/courses", withTimeout(cfg.RequestTimeout, createCourse)).Methods("POST")
+	v1.Handle("/courses", withTimeout(cfg.RequestTimeout, getCourses)).Methods("GET")
+	v1.Handle("/courses/{id}", withTimeout(cfg.RequestTimeout, getCourse)).Methods("GET")
+	v1.Handle("/courses/{id}", withTimeout(cfg.RequestTimeout, updateCourse)).Methods("PUT")
+	v1.Handle("/courses/{id}", withTimeout(cfg.RequestTimeout, deleteCourse)).Methods("DELETE")
+
+	// Sections
+	v1.Handle("/courses/{id}/sections", withTimeout(cfg.RequestTimeout, createSection)).Methods("POST")
+	v1.Handle("/courses/{id}/sections", withTimeout(cfg.RequestTimeout, getCourseSections)).Methods("GET")
+	v1.Handle("/sections/{id}", withTimeout(cfg.RequestTimeout, getSection)).Methods("GET")
+	v1.Handle("/sections/{id}", withTimeout(cfg.RequestTimeout, updateSection)).Methods("PUT")
+	v1.Handle("/sections/{id}", withTimeout(cfg.RequestTimeout, deleteSection)).Methods("DELETE")
+
+	// Enrollments
+	v1.Handle("/students/{id}/enrollments", withTimeout(cfg.RequestTimeout, createEnrollment)).Methods("POST")
+	v1.Handle("/students/{id}/enrollments", withTimeout(cfg.RequestTimeout, getStudentEnrollments)).Methods("GET")
+	v1.Handle("/students/{id}/schedule.ics", withTimeout(cfg.RequestTimeout, getStudentSchedule)).Methods("GET")
+	v1.Handle("/students/{id}/enrollments/{enrollment_id}", withTimeout(cfg.RequestTimeout, deleteEnrollment)).Methods("DELETE")
+	v1.Handle("/students/{id}/waitlist", withTimeout(cfg.RequestTimeout, getStudentWaitlist)).Methods("GET")
+	v1.Handle("/students/{id}/waitlist/{entry_id}", withTimeout(cfg.RequestTimeout, leaveWaitlist)).Methods("DELETE")
+	v1.Handle("/courses/{id}/waitlist", withTimeout(cfg.RequestTimeout, getCourseWaitlist)).Methods("GET")
+	v1.Handle("/courses/{id}/students", withTimeout(cfg.RequestTimeout, getCourseStudents)).Methods("GET")
+
+	// Grades
+	v1.Handle("/students/{id}/gpa", withTimeout(cfg.RequestTimeout, getStudentGPA)).Methods("GET")
+	v1.Handle("/students/{id}/enrollments/{enrollment_id}/grades", withTimeout(cfg.RequestTimeout, recordGrade)).Methods("POST")
+	v1.Handle("/students/{id}/grades", withTimeout(cfg.RequestTimeout, getStudentGrades)).Methods("GET")
+
+	// Assignments
+	v1.Handle("/courses/{id}/assignments", withTimeout(cfg.RequestTimeout, createAssignment)).Methods("POST")
+	v1.Handle("/courses/{id}/assignments", withTimeout(cfg.RequestTimeout, getCourseAssignments)).Methods("GET")
+	v1.Handle("/assignments/{id}", withTimeout(cfg.RequestTimeout, getAssignment)).Methods("GET")
+	v1.Handle("/assignments/{id}", withTimeout(cfg.RequestTimeout, updateAssignment)).Methods("PUT")
+	v1.Handle("/assignments/{id}", withTimeout(cfg.RequestTimeout, deleteAssignment)).Methods("DELETE")
+	v1.Handle("/assignments/{id}/submissions", withTimeout(cfg.RequestTimeout, createSubmission)).Methods("POST")
+	v1.Handle("/assignments/{id}/submissions", withTimeout(cfg.RequestTimeout, getAssignmentSubmissions)).Methods("GET")
+	v1.Handle("/submissions/{id}/score", withTimeout(cfg.RequestTimeout, scoreSubmission)).Methods("PUT")
+	v1.Handle("/students/{id}/progress", withTimeout(cfg.RequestTimeout, getStudentProgress)).Methods("GET")
+
+	// Cohorts
+	v1.Handle("/cohorts", withTimeout(cfg.RequestTimeout, createCohort)).Methods("POST")
+	v1.Handle("/cohorts", withTimeout(cfg.RequestTimeout, getCohorts)).Methods("GET")
+	v1.Handle("/cohorts/{id}", withTimeout(cfg.RequestTimeout, getCohort)).Methods("GET")
+	v1.Handle("/cohorts/{id}", withTimeout(cfg.RequestTimeout, updateCohort)).Methods("PUT")
+	v1.Handle("/cohorts/{id}", withTimeout(cfg.RequestTimeout, deleteCohort)).Methods("DELETE")
+	v1.Handle("/cohorts/{id}/members", withTimeout(cfg.RequestTimeout, addCohortMembers)).Methods("POST")
+	v1.Handle("/cohorts/{id}/members", withTimeout(cfg.RequestTimeout, getCohortMembers)).Methods("GET")
+	v1.Handle("/cohorts/{id}/members/{student_id}", withTimeout(cfg.RequestTimeout, removeCohortMember)).Methods("DELETE")
+	v1.Handle("/cohorts/{id}/summaries", withTimeout(cfg.LLMRequestTimeout, limitLLM(getCohortSummaries))).Methods("GET")
+	v1.Handle("/cohorts/{id}/export", withTimeout(cfg.RequestTimeout, exportCohort)).Methods("GET")
+	r.Handle("/graphql", withTimeout(cfg.RequestTimeout, serveGraphQL)).Methods("GET", "POST")
+	r.Handle("/graphql/schema", withTimeout(cfg.RequestTimeout, getGraphQLSchema)).Methods("GET")
+	// Batches run each operation under its own route timeout.
+	v1.HandleFunc("/batch", runBatch).Methods("POST")
+	v1.Handle("/changes", withTimeout(cfg.RequestTimeout, getChanges)).Methods("GET")
+	v1.Handle("/webhooks", withTimeout(cfg.RequestTimeout, requireAdmin(createWebhook))).Methods("POST")
+	v1.Handle("/webhooks", withTimeout(cfg.RequestTimeout, requireAdmin(getWebhooks))).Methods("GET")
+	v1.Handle("/webhooks/dead-letters", withTimeout(cfg.RequestTimeout, requireAdmin(getDeadLetters))).Methods("GET")
+	v1.Handle("/webhooks/deliveries/{id}", withTimeout(cfg.RequestTimeout, requireAdmin(discardDelivery))).Methods("DELETE")
+	v1.Handle("/webhooks/deliveries/{id}/replay", withTimeout(cfg.RequestTimeout, requireAdmin(replayDelivery))).Methods("POST")
+	v1.Handle("/webhooks/{id}", withTimeout(cfg.RequestTimeout, requireAdmin(getWebhook))).Methods("GET")
+	v1.Handle("/webhooks/{id}", withTimeout(cfg.RequestTimeout, requireAdmin(updateWebhook))).Methods("PUT")
+	v1.Handle("/webhooks/{id}", withTimeout(cfg.RequestTimeout, requireAdmin(deleteWebhook))).Methods("DELETE")
+	v1.Handle("/webhooks/{id}/deliveries", withTimeout(cfg.RequestTimeout, requireAdmin(getWebhookDeliveries))).Methods("GET")
+	v1.Handle("/webhooks/{id}/replay", withTimeout(cfg.RequestTimeout, requireAdmin(replayWebhookDeliveries))).Methods("POST")
+
+	// Documents. Uploads and downloads are streamed, so they are bounded by
+	// the server's read and write timeouts instead of withTimeout, which
+	// buffers the whole response.
+	v1.HandleFunc("/students/{id}/documents", uploadDocument).Methods("POST")
+	v1.Handle("/students/{id}/documents", withTimeout(cfg.RequestTimeout, getStudentDocuments)).Methods("GET")
+	v1.Handle("/documents/{id}", withTimeout(cfg.RequestTimeout, getDocument)).Methods("GET")
+	v1.HandleFunc("/documents/{id}/content", downloadDocument).Methods("GET")
+	v1.Handle("/documents/{id}", withTimeout(cfg.RequestTimeout, deleteDocument)).Methods("DELETE")
+	v1.HandleFunc("/students/{id}/photo", putStudentPhoto).Methods("PUT")
+	v1.HandleFunc("/students/{id}/photo", getStudentPhoto).Methods("GET")
+	v1.Handle("/students/{id}/photo", withTimeout(cfg.RequestTimeout, deleteStudentPhoto)).Methods("DELETE")
+
+	// Exports and backups are built into the blob store as they are
+	// written, and downloaded from it.
+	v1.HandleFunc("/exports", createExport).Methods("POST")
+	v1.Handle("/exports", withTimeout(cfg.RequestTimeout, getExports)).Methods("GET")
+	v1.Handle("/exports/{id}", withTimeout(cfg.RequestTimeout, getExport)).Methods("GET")
+	v1.HandleFunc("/exports/{id}/content", downloadExport).Methods("GET")
+	v1.Handle("/exports/{id}", withTimeout(cfg.RequestTimeout, deleteExport)).Methods("DELETE")
+	v1.Handle("/admin/backups", requireAdmin(createBackup)).Methods("POST")
+	v1.Handle("/admin/backups", withTimeout(cfg.RequestTimeout, requireAdmin(getBackups))).Methods("GET")
+	v1.Handle("/admin/backups/{id}/content", requireAdmin(downloadBackup)).Methods("GET")
+	v1.Handle("/admin/backups/{id}", withTimeout(cfg.RequestTimeout, requireAdmin(deleteBackup))).Methods("DELETE")
+
+	// Scholarships and awards
+	v1.Handle("/students/{id}/awards", withTimeout(cfg.RequestTimeout, createAward)).Methods("POST")
+	v1.Handle("/students/{id}/awards", withTimeout(cfg.RequestTimeout, getStudentAwards)).Methods("GET")
+	v1.Handle("/awards/{id}", withTimeout(cfg.RequestTimeout, getAward)).Methods("GET")
+	v1.Handle("/awards/{id}", withTimeout(cfg.RequestTimeout, updateAward)).Methods("PUT")
+	v1.Handle("/awards/{id}", withTimeout(cfg.RequestTimeout, deleteAward)).Methods("DELETE")
+
+	// Fees
+	v1.Handle("/students/{id}/transactions", withTimeout(cfg.RequestTimeout, postTransaction)).Methods("POST")
+	v1.Handle("/students/{id}/statement", withTimeout(cfg.RequestTimeout, getStatement)).Methods("GET")
+	v1.Handle("/balances", withTimeout(cfg.RequestTimeout, getBalances)).Methods("GET")
+
+	// Transcripts
+	v1.Handle("/students/{id}/transcript", withTimeout(cfg.RequestTimeout, getTranscript)).Methods("GET")
+
+	// Attendance
+	v1.Handle("/attendance", withTimeout(cfg.RequestTimeout, createAttendance)).Methods("POST")
+	v1.Handle("/attendance", withTimeout(cfg.RequestTimeout, getAttendance)).Methods("GET")
+	v1.Handle("/attendance/summary", withTimeout(cfg.RequestTimeout, getAttendanceSummary)).Methods("GET")
+
+	// Share links
+	r.Handle("/shared/students/{id}/summary", withTimeout(cfg.LLMRequestTimeout, limitLLM(getSharedSummary))).Methods("GET")
+
+	// Health
+	r.HandleFunc("/healthz", healthHandler).Methods("GET")
+	r.HandleFunc("/readyz", readyHandler).Methods("GET")
+	r.HandleFunc("/version", versionHandler).Methods("GET")
+
+	// Feature flags
+	v1.Handle("/features", withTimeout(cfg.RequestTimeout, getFeatures)).Methods("GET")
+
+	// Metrics
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// API description
+	r.Handle("/openapi.json", withTimeout(cfg.RequestTimeout, getOpenAPI)).Methods("GET")
+	r.Handle("/docs", withTimeout(cfg.RequestTimeout, getAPIDocs)).Methods("GET")
+
+	// Admin
+	r.Handle("/admin/ui", withTimeout(cfg.RequestTimeout, requireAdmin(adminDashboard))).Methods("GET")
+	v1.Handle("/admin/diagnostics", withTimeout(cfg.RequestTimeout, requireAdmin(getDiagnostics))).Methods("GET")
+	v1.Handle("/admin/audit", withTimeout(cfg.RequestTimeout, requireAdmin(getAuditLog))).Methods("GET")
+	v1.Handle("/admin/emails", withTimeout(cfg.RequestTimeout, requireAdmin(getEmailLog))).Methods("GET")
+	v1.Handle("/admin/jobs", withTimeout(cfg.RequestTimeout, requireAdmin(getJobs))).Methods("GET")
+	v1.Handle("/admin/jobs/{name}/run", withTimeout(cfg.RequestTimeout, requireAdmin(runJob))).Methods("POST")
+	v1.Handle("/admin/ldap-sync", withTimeout(cfg.RequestTimeout, requireAdmin(getLDAPSync))).Methods("GET")
+	v1.Handle("/admin/log-level", withTimeout(cfg.RequestTimeout, requireAdmin(getLogLevel))).Methods("GET")
+	v1.Handle("/admin/log-level", withTimeout(cfg.RequestTimeout, requireAdmin(setLogLevel))).Methods("PUT")
+	v1.Handle("/admin/maintenance", withTimeout(cfg.RequestTimeout, requireAdmin(getMaintenance))).Methods("GET")
+	v1.Handle("/admin/maintenance", withTimeout(cfg.RequestTimeout, requireAdmin(setMaintenance))).Methods("PUT")
+	v1.Handle("/admin/features", withTimeout(cfg.RequestTimeout, requireAdmin(getFeatureDefinitions))).Methods("GET")
+	v1.Handle("/admin/anonymize", withTimeout(cfg.RequestTimeout, requireAdmin(anonymizeStudents))).Methods("POST")
+	v1.Handle("/admin/reset", withTimeout(cfg.RequestTimeout, requireAdmin(resetData))).Methods("POST")
+	v1.Handle("/admin/generate", withTimeout(cfg.RequestTimeout, requireAdmin(generateStudents))).Methods("POST")
+
+	// Debug
+	mountDebug(r)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// s3Blobs keeps blobs as objects in an S3-compatible bucket, such as
+// MinIO or AWS S3. Requests are signed with Signature Version 4 and use
+// path-style addressing (endpoint/bucket/key), which every implementation
+// accepts.
+type s3Blobs struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	// prefix is prepended to every key, so one bucket can hold the blobs
+	// of several deployments.
+	prefix string
+	client *http.Client
+}
+
+func newS3Blobs(c Config) (*s3Blobs, error) {
+	u, err := url.Parse(c.S3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", c.S3Endpoint)
+	}
+	return &s3Blobs{
+		endpoint:  u,
+		bucket:    c.S3Bucket,
+		region:    c.S3Region,
+		accessKey: c.S3AccessKeyID,
+		secretKey: c.S3SecretAccessKey,
+		prefix:    c.S3Prefix,
+		client:    &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}, nil
+}
+
+func (s *s3Blobs) objectPath(key string) string {
+	return "/" + s.bucket + "/" + s.prefix + key
+}
+
+// Put spools the blob to a temporary file first: S3 needs the length and
+// the digest of the payload before the first byte is sent.
+func (s *s3Blobs) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return n, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return n, err
+	}
+	var body io.ReadCloser = io.NopCloser(tmp)
+	if n == 0 {
+		body = http.NoBody
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectPath(key), body, n, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return n, err
+	}
+	resp.Body.Close()
+	return n, nil
+}
+
+func (s *s3Blobs) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectPath(key), nil, 0, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete succeeds for keys that do not exist, as S3 itself does.
+func (s *s3Blobs) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectPath(key), nil, 0, emptySHA256)
+	if errors.Is(err, errBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Ping checks that the bucket exists and the credentials may use it.
+func (s *s3Blobs) Ping(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "/"+s.bucket, nil, 0, emptySHA256)
+	if errors.Is(err, errBlobNotFound) {
+		return fmt.Errorf("bucket %s does not exist", s.bucket)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// emptySHA256 is the hex SHA-256 of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Error is the XML body of an S3 error response.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request. A 404 becomes errBlobNotFound and other
+// error statuses an error naming the S3 error code.
+func (s *s3Blobs) do(ctx context.Context, method, path string, body io.ReadCloser, length int64, payloadHash string) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawPath = awsEscapePath(u.Path)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errBlobNotFound
+	}
+	var e s3Error
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+	if e.Code == "" {
+		return nil, fmt.Errorf("s3 %s %s: status %d", method, path, resp.StatusCode)
+	}
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", method, path, e.Code, e.Message)
+}
+
+// sign adds the Signature Version 4 Authorization header to req.
+func (s *s3Blobs) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		awsEscapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{day, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscapePath percent-encodes everything but unreserved characters and
+// slashes, as the canonical request requires. The request itself is sent
+// with the same encoding, so the two always agree.
+func awsEscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
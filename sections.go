@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Meeting is one weekly slot of a section, such as MON 09:00-10:15.
+type Meeting struct {
+	Day   string `json:"day" validate:"enum=weekday"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Section is a scheduled offering of a course.
+type Section struct {
+	ID       int       `json:"id"`
+	CourseID int       `json:"course_id"`
+	Name     string    `json:"name" validate:"required"`
+	Room     string    `json:"room"`
+	Meetings []Meeting `json:"meetings"`
+	// Capacity limits the seats in this section; 0 leaves only the
+	// course capacity.
+	Capacity int `json:"capacity,omitempty" validate:"nonnegative"`
+}
+
+func (s Section) entityID() int { return s.ID }
+
+func (s Section) withID(id int) Section {
+	s.ID = id
+	return s
+}
+
+var sectionStore Repository[Section] = newCollection[Section]("sections")
+
+var weekdays = []string{"MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"}
+
+const clockLayout = "15:04"
+
+// overlaps reports whether two meetings share any time on the same day.
+// Back-to-back meetings do not overlap.
+func (m Meeting) overlaps(o Meeting) bool {
+	return m.Day == o.Day && m.Start < o.End && o.Start < m.End
+}
+
+func (m Meeting) String() string {
+	return m.Day + " " + m.Start + "-" + m.End
+}
+
+// validateSection lists every problem with a section submitted for create or update.
+func validateSection(s Section) []FieldError {
+	errs := checkFields(s)
+	for i, m := range s.Meetings {
+		field := fmt.Sprintf("meetings[%d]", i)
+		start, err1 := time.Parse(clockLayout, m.Start)
+		end, err2 := time.Parse(clockLayout, m.End)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, FieldError{Field: field, Message: "start and end must be times such as 09:00"})
+		} else if !end.After(start) {
+			errs = append(errs, FieldError{Field: field, Message: "must end after it starts"})
+		}
+	}
+	return errs
+}
+
+// normalizeSection upper-cases days and zero-pads times, so meetings
+// can be compared as strings.
+func normalizeSection(s *Section) {
+	for i := range s.Meetings {
+		m := &s.Meetings[i]
+		m.Day = strings.ToUpper(strings.TrimSpace(m.Day))
+		if t, err := time.Parse(clockLayout, m.Start); err == nil {
+			m.Start = t.Format(clockLayout)
+		}
+		if t, err := time.Parse(clockLayout, m.End); err == nil {
+			m.End = t.Format(clockLayout)
+		}
+	}
+	if s.Meetings == nil {
+		s.Meetings = []Meeting{}
+	}
+}
+
+func courseSections(ctx context.Context, courseID int) []Section {
+	list := []Section{}
+	for _, s := range repoList(ctx, sectionStore) {
+		if s.CourseID == courseID {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// scheduleConflicts returns a description of every meeting of section
+// that overlaps a section the student is enrolled in during the same term.
+func scheduleConflicts(ctx context.Context, studentID int, term string, section Section) []string {
+	var conflicts []string
+	for _, e := range studentEnrollments(ctx, studentID) {
+		if e.SectionID == 0 || e.SectionID == section.ID || e.Term != term {
+			continue
+		}
+		other, ok := sectionStore.Find(ctx, e.SectionID)
+		if !ok {
+			continue
+		}
+		for _, m := range section.Meetings {
+			for _, o := range other.Meetings {
+				if m.overlaps(o) {
+					conflicts = append(conflicts, fmt.Sprintf("%s overlaps section %d (%s)", m, other.ID, o))
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+func createSection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	var section Section
+	if err := json.NewDecoder(r.Body).Decode(&section); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section data", decodeErrors(err)...)
+		return
+	}
+	normalizeSection(&section)
+	if errs := validateSection(section); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section data", errs...)
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+
+	section.CourseID = courseID
+	section = repoInsert(r.Context(), sectionStore, section)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(section)
+}
+
+func getCourseSections(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+
+	writeJSONArray(w, courseSections(r.Context(), courseID))
+}
+
+func getSection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section ID")
+		return
+	}
+
+	section, exists := repoFind(r.Context(), sectionStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Section not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, section)
+}
+
+func updateSection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section ID")
+		return
+	}
+
+	var updated Section
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section data", decodeErrors(err)...)
+		return
+	}
+	normalizeSection(&updated)
+	if errs := validateSection(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section data", errs...)
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	before, exists := repoFind(r.Context(), sectionStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Section not found")
+		return
+	}
+	// The course of a section cannot change; enrollments refer to both.
+	updated.ID, updated.CourseID = id, before.CourseID
+	perTerm := map[string]int{}
+	for _, e := range enrollmentsWhere(r.Context(), func(e Enrollment) bool { return e.SectionID == id }) {
+		perTerm[e.Term]++
+	}
+	if n := maxValue(perTerm); updated.Capacity != 0 && updated.Capacity < n {
+		writeProblem(w, r, http.StatusConflict, "Capacity is below the "+strconv.Itoa(n)+" students already enrolled")
+		return
+	}
+	for _, e := range enrollmentsWhere(r.Context(), func(e Enrollment) bool { return e.SectionID == id }) {
+		if conflicts := scheduleConflicts(r.Context(), e.StudentID, e.Term, updated); len(conflicts) > 0 {
+			writeProblem(w, r, http.StatusConflict, "New schedule conflicts for student "+strconv.Itoa(e.StudentID)+": "+strings.Join(conflicts, "; "))
+			return
+		}
+	}
+	repoReplace(r.Context(), sectionStore, id, updated)
+	promoteWaitlist(r.Context(), updated.CourseID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteSection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid section ID")
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	if n := len(enrollmentsWhere(r.Context(), func(e Enrollment) bool { return e.SectionID == id })); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Section still has "+strconv.Itoa(n)+" enrolled students")
+		return
+	}
+	if _, exists := repoRemove(r.Context(), sectionStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Section not found")
+		return
+	}
+	for _, e := range waitlistWhere(r.Context(), func(e WaitlistEntry) bool { return e.SectionID == id }) {
+		repoRemove(r.Context(), waitlistStore, e.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
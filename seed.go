@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// seedData is the fixtures file named by the seed setting. IDs in the
+// file are ignored; records get new ones as they are inserted.
+type seedData struct {
+	Students []Student `json:"students"`
+	Courses  []Course  `json:"courses"`
+}
+
+// loadSeedFile reads and checks a fixtures file, so a typo fails startup
+// instead of leaving the demo half loaded.
+func loadSeedFile(path string) (seedData, error) {
+	var d seedData
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return d, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, s := range d.Students {
+		if errs := validateStudent(s); len(errs) > 0 {
+			return d, fmt.Errorf("%s: students[%d]: %s", path, i, describeFieldErrors(errs))
+		}
+	}
+	for i, c := range d.Courses {
+		if errs := validateCourse(c); len(errs) > 0 {
+			return d, fmt.Errorf("%s: courses[%d]: %s", path, i, describeFieldErrors(errs))
+		}
+	}
+	return d, nil
+}
+
+// seed inserts the fixtures into the collections that are empty, so
+// restarting on the file store does not load them twice. Courses go
+// first so the checks students and courses get on creation can see
+// them. No events are published: fixtures are not new students to
+// welcome or to announce to webhooks.
+func seed(ctx context.Context, d seedData) (students, courses int, err error) {
+	if courseStore.Count(ctx) == 0 {
+		courseCodeMutex.Lock()
+		for i, c := range d.Courses {
+			c.ID = 0
+			errs := departmentProblem(ctx, c)
+			errs = append(errs, instructorProblem(ctx, c)...)
+			errs = append(errs, prerequisiteProblems(ctx, 0, c)...)
+			if courseCodeTaken(ctx, c.Code, 0) {
+				errs = append(errs, FieldError{Field: "code", Message: c.Code + " is used by an earlier course"})
+			}
+			if len(errs) > 0 {
+				courseCodeMutex.Unlock()
+				return students, courses, fmt.Errorf("courses[%d]: %s", i, describeFieldErrors(errs))
+			}
+			repoInsert(ctx, courseStore, c)
+			courses++
+		}
+		courseCodeMutex.Unlock()
+	}
+
+	if studentStore.Count(ctx) == 0 {
+		for i, s := range d.Students {
+			s.ID = 0
+			if errs := programProblem(ctx, s); len(errs) > 0 {
+				return students, courses, fmt.Errorf("students[%d]: %s", i, describeFieldErrors(errs))
+			}
+			insertStudent(ctx, s)
+			students++
+		}
+	}
+	return students, courses, nil
+}
+
+// seedFromConfig loads the seed file, if one is set.
+func seedFromConfig(ctx context.Context) (students, courses int, err error) {
+	if cfg.Seed == "" {
+		return 0, 0, nil
+	}
+	d, err := loadSeedFile(cfg.Seed)
+	if err != nil {
+		return 0, 0, err
+	}
+	return seed(ctx, d)
+}
+
+// clearRepository removes every record of repo.
+func clearRepository[T entity[T]](ctx context.Context, repo Repository[T]) int {
+	list := repo.List(ctx)
+	for _, v := range list {
+		repo.Remove(ctx, v.entityID())
+	}
+	return len(list)
+}
+
+// resetData is the admin operation behind POST /admin/reset. It removes
+// every student and course, with the records kept about them, and loads
+// the seed file again. Teachers, terms, departments and programs are left
+// alone, as are webhooks and logs. It only exists in development.
+func resetData(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isDevelopment() {
+		writeProblem(w, r, http.StatusForbidden, "Reset is only available in development")
+		return
+	}
+	ctx := r.Context()
+
+	for _, d := range documentStore.List(ctx) {
+		if err := blobs.Delete(ctx, documentKey(d.ID)); err != nil {
+			slog.WarnContext(ctx, "failed to delete document contents", "document_id", d.ID, "error", err)
+		}
+	}
+	for _, s := range studentStore.List(ctx) {
+		forgetSummary(ctx, s.ID)
+	}
+	removed := map[string]int{
+		"students":    clearRepository(ctx, studentStore),
+		"courses":     clearRepository(ctx, courseStore),
+		"sections":    clearRepository(ctx, sectionStore),
+		"enrollments": clearRepository(ctx, enrollmentStore),
+		"waitlist":    clearRepository(ctx, waitlistStore),
+		"grades":      clearRepository(ctx, gradeStore),
+		"attendance":  clearRepository(ctx, attendanceStore),
+		"assignments": clearRepository(ctx, assignmentStore),
+		"submissions": clearRepository(ctx, submissionStore),
+		"guardians":   clearRepository(ctx, guardianStore),
+		"documents":   clearRepository(ctx, documentStore),
+		"awards":      clearRepository(ctx, awardStore),
+		"cohorts":     clearRepository(ctx, cohortStore),
+		"ledger":      clearRepository(ctx, transactionStore),
+	}
+
+	students, courses, err := seedFromConfig(ctx)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Reset, but the seed file could not be loaded: "+err.Error())
+		return
+	}
+	slog.InfoContext(ctx, "reset data", "removed", removed, "seeded_students", students, "seeded_courses", courses)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"removed": removed,
+		"seeded":  map[string]int{"students": students, "courses": courses},
+	})
+}
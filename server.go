@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"golang.org/x/net/netutil"
+)
+
+// Server is the whole API as an http.Handler: every route behind the
+// middleware chain, ready for an http.Server or httptest.
+//
+// A Server keeps its collections, blobs, event subscribers and whatever
+// its handlers remember between requests in a state of its own, which it
+// puts in the context of every request it serves, so servers built one
+// after another, or serving side by side, do not see each other's data. The configuration, and the
+// clock, IDs and randomness, are still set for the whole process by
+// NewServer: tests that build servers must not run in parallel.
+type Server struct {
+	router  *mux.Router
+	handler http.Handler
+	state   *state
+}
+
+// state is what one Server keeps. Package code finds it with stateOf in
+// the context of the request or background work it is doing.
+type state struct {
+	// repos holds a Repository[T] for every collection, by name.
+	repos  map[string]any
+	blobs  blobStore
+	llm    LLM
+	events eventBus
+
+	audit        auditTrail
+	authFailures lockouts
+	summaries    summaryCache
+	hub          *liveHub
+	maintenance  maintenanceMode
+	features     featureFlagFile
+	ldapSync     ldapSyncStatus
+	changes      changeFeed
+	jobs         scheduler
+	dispatcher   *webhookDispatcher
+	// emailQueue holds emails waiting for the sender. When it is full,
+	// new emails are logged as failed instead of blocking the request.
+	emailQueue chan outgoingEmail
+	// brokerQueue decouples request handlers from the broker. When it is
+	// full, events are dropped rather than slowing requests down.
+	brokerQueue chan brokerMessage
+	// shuttingDown is set once the server starts draining. /readyz then
+	// fails for shutdown_drain_delay before connections are closed, so
+	// load balancers stop routing new traffic first.
+	shuttingDown atomic.Bool
+
+	studentListCache       *listCache
+	requestSlots, llmSlots chan struct{}
+	openAPIDocument        map[string]any
+	batchHandler           http.Handler
+}
+
+type stateKey struct{}
+
+// stateOf returns the state of the Server serving ctx.
+func stateOf(ctx context.Context) *state {
+	s, ok := ctx.Value(stateKey{}).(*state)
+	if !ok {
+		panic("no server state in context")
+	}
+	return s
+}
+
+func (s *state) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stateKey{}, s)
+}
+
+// NewServer configures the API from c, keeping students in store and
+// generating summaries with model. The other collections are opened on
+// the configured store, and the blob store, feature flags and audit trail
+// are set up as configured. opts replace the clock, IDs and randomness,
+// which are otherwise the system's, and can keep every collection other
+// than students in memory.
+func NewServer(c Config, store Store, model LLM, opts ...ServerOption) (*Server, error) {
+	options := serverOptions{clock: systemClock{}, ids: randomIDs{}, random: systemRandom{}}
+	for _, o := range opts {
+		o(&options)
+	}
+	clock, idGenerator, random = options.clock, options.ids, options.random
+
+	cfg = c
+	apiKeys = loadAPIKeys(c.APIKeys)
+	trustedProxies = parseCIDRs(c.TrustedProxies)
+	shareSecret = loadShareSecret(c.ShareLinkSecret)
+	webhookClient = newWebhookClient(c)
+
+	st, err := newState(c, store, model, options.memoryRepositories)
+	if err != nil {
+		return nil, err
+	}
+	ctx := st.context(context.Background())
+	if err := loadFeatureFlags(ctx); err != nil {
+		closeCollections(st.repos)
+		return nil, fmt.Errorf("feature flags: %w", err)
+	}
+	if err := loadAuditLog(ctx); err != nil {
+		slog.Error("failed to load audit log", "error", err)
+	}
+
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = methodNotAllowed(r)
+	r.Use(otelmux.Middleware(serviceName), requestIDMiddleware, localize, requestLogger, metricsMiddleware, negotiateFormat, envelope, chaos, recoverer, limitInFlight, dryRun, maintenanceGuard)
+	registerRoutes(r)
+	st.openAPIDocument = buildOpenAPI(r)
+	st.batchHandler = legacyPaths(r)
+	metricsState.Store(st)
+
+	return &Server{router: r, handler: accessLogger(compressResponses(legacyPaths(r))), state: st}, nil
+}
+
+// newState opens the collections and the blob store of a new Server and
+// subscribes the built-in event handlers.
+func newState(c Config, store Store, model LLM, memory bool) (*state, error) {
+	blobs, err := newBlobStore(c)
+	if err != nil {
+		return nil, fmt.Errorf("blob store: %w", err)
+	}
+	repos, err := openCollections(c, store, memory)
+	if err != nil {
+		return nil, err
+	}
+	st := &state{
+		repos:            repos,
+		blobs:            blobs,
+		llm:              model,
+		hub:              newLiveHub(),
+		maintenance:      maintenanceMode{message: defaultMaintenanceMessage},
+		features:         featureFlagFile{flags: map[string]FeatureFlag{}},
+		dispatcher:       newWebhookDispatcher(),
+		emailQueue:       make(chan outgoingEmail, 256),
+		brokerQueue:      make(chan brokerMessage, 1024),
+		studentListCache: newListCache(c.ListCacheEntries, c.ListCacheMaxBytes),
+		requestSlots:     newSlots(c.MaxInFlight),
+		llmSlots:         newSlots(c.MaxInFlightLLM),
+	}
+	st.jobs.ctx = st.context(context.Background())
+	subscribeCoreHandlers(&st.events)
+	return st, nil
+}
+
+// WithMemoryRepositories keeps every collection other than students in
+// an empty repository in memory, whatever the store setting, so a test
+// starts from nothing and leaves no files behind.
+func WithMemoryRepositories() ServerOption {
+	return func(o *serverOptions) { o.memoryRepositories = true }
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r.WithContext(s.state.context(r.Context())))
+}
+
+// Start runs the server's background work, such as webhook deliveries,
+// outgoing emails and scheduled jobs, until ctx is cancelled.
+func (s *Server) Start(ctx context.Context) {
+	ctx = s.state.context(ctx)
+	startWebhookDispatcher(ctx)
+	startEventForwarder(ctx)
+	startEmailSender(ctx)
+	startScheduler(ctx)
+}
+
+// Close disconnects live clients and flushes and closes the server's
+// repositories, the student store among them.
+func (s *Server) Close() error {
+	s.state.hub.close()
+	return closeCollections(s.state.repos)
+}
+
+// Mount serves every path below prefix with h, outside the versioned
+// routes, as main does for the gRPC gateway.
+func (s *Server) Mount(prefix string, h http.Handler) {
+	s.router.PathPrefix(prefix).Handler(h)
+}
+
+// newHTTPServer builds the server from the connection settings. HTTP/2
+// is negotiated over TLS when http2 is on; plain-text connections always
+// speak HTTP/1.1.
+func newHTTPServer(handler http.Handler) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2 && cfg.TLSCertFile != "")
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Protocols:         protocols,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: cfg.HTTP2MaxStreams,
+		},
+		TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	srv.SetKeepAlivesEnabled(cfg.KeepAlives)
+	return srv
+}
+
+// listenAddress describes where the server listens, for logs.
+func listenAddress(srv *http.Server) string {
+	if cfg.UnixSocket != "" {
+		return "unix:" + cfg.UnixSocket
+	}
+	return srv.Addr
+}
+
+// listenAndServe opens the listener, caps it at max_connections and
+// serves plain HTTP or TLS depending on whether a certificate is set.
+func listenAndServe(srv *http.Server) error {
+	listen := net.Listen
+	network, addr := "tcp", srv.Addr
+	if cfg.UnixSocket != "" {
+		listen = listenUnix
+		network, addr = "unix", cfg.UnixSocket
+	}
+	ln, err := listen(network, addr)
+	if err != nil {
+		return err
+	}
+	if cfg.MaxConnections > 0 {
+		ln = netutil.LimitListener(ln, cfg.MaxConnections)
+	}
+	if cfg.TLSCertFile != "" {
+		return srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return srv.Serve(ln)
+}
+
+// listenUnix listens on the Unix socket at path, for a reverse proxy on the
+// same host, and gives it unix_socket_mode so the proxy's user can connect.
+// A socket left behind by a process that was killed is removed first; one
+// another server still answers on is an error. The socket file is removed
+// again when the listener closes.
+func listenUnix(network, path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix_socket: %s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial(network, path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix_socket: %s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	mode, err := socketMode(cfg.UnixSocketMode)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen(network, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// socketMode parses unix_socket_mode, an octal permission such as 0660.
+func socketMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if mode > 0o777 {
+		return 0, fmt.Errorf("%s has bits beyond the permissions", s)
+	}
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"studengo/ollama/ollamatest"
+)
+
+// testAPIKey is an admin key of every testAPI, which do sends.
+const testAPIKey = "test-key"
+
+// testAPI is a Server on a local port, with empty repositories, documents
+// in a temporary directory and a fake Ollama. Tests building one must not
+// run in parallel, as NewServer sets the configuration for the whole
+// process.
+type testAPI struct {
+	*httptest.Server
+	srv    *Server
+	ollama *ollamatest.Server
+}
+
+// newTestAPI starts a Server with the default configuration, adjusted by
+// configure when it is not nil.
+func newTestAPI(t *testing.T, configure func(*Config), opts ...ServerOption) *testAPI {
+	t.Helper()
+	fake := ollamatest.NewServer(ollamatest.WithResponse("A diligent student."))
+	t.Cleanup(fake.Close)
+	c := defaultConfig()
+	c.OllamaURL = fake.URL
+	c.APIKeys = []string{testAPIKey + ":tester:admin"}
+	c.DocumentsDir = t.TempDir()
+	if configure != nil {
+		configure(&c)
+	}
+	opts = append([]ServerOption{WithMemoryRepositories()}, opts...)
+	srv, err := NewServer(c, newMemoryStore[Student](), newOllamaClient(c), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &testAPI{Server: httptest.NewServer(srv), srv: srv, ollama: fake}
+	t.Cleanup(func() {
+		api.Close()
+		srv.Close()
+	})
+	return api
+}
+
+// do sends a request with body, when it is not nil, encoded as JSON
+// unless it is an io.Reader, and returns the response with its body read.
+func (api *testAPI) do(t *testing.T, method, path string, body any) (*http.Response, []byte) {
+	t.Helper()
+	r, raw := body.(io.Reader)
+	if body != nil && !raw {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, api.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil && !raw {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return api.send(t, req)
+}
+
+// get sends a GET with the given request headers.
+func (api *testAPI) get(t *testing.T, path string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest("GET", api.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return api.send(t, req)
+}
+
+// send sends req with the test API key and reads the response.
+func (api *testAPI) send(t *testing.T, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+	req.Header.Set("X-API-Key", testAPIKey)
+	resp, err := api.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+// decode sends a request like do, checks the status and decodes the
+// response into v.
+func (api *testAPI) decode(t *testing.T, method, path string, body any, status int, v any) {
+	t.Helper()
+	resp, data := api.do(t, method, path, body)
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, data)
+		}
+	}
+}
+
+func TestServerIsolation(t *testing.T) {
+	first := newTestAPI(t, nil)
+	var student Student
+	first.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &student)
+	first.decode(t, "POST", "/v1/teachers", map[string]any{"name": "Grace", "email": "grace@example.com"}, http.StatusCreated, nil)
+	first.decode(t, "PUT", "/v1/admin/maintenance", map[string]any{"enabled": true}, http.StatusOK, nil)
+
+	// The first server keeps serving while the second is built and used.
+	second := newTestAPI(t, nil)
+	var students []Student
+	second.decode(t, "GET", "/v1/students", nil, http.StatusOK, &students)
+	if len(students) != 0 {
+		t.Errorf("second server lists %d students, want none", len(students))
+	}
+	var teachers []Teacher
+	second.decode(t, "GET", "/v1/teachers", nil, http.StatusOK, &teachers)
+	if len(teachers) != 0 {
+		t.Errorf("second server lists %d teachers, want none", len(teachers))
+	}
+	if resp, data := second.do(t, "GET", "/v1/students/"+strconv.Itoa(student.ID), nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("student of the first server: status %d, want 404: %s", resp.StatusCode, data)
+	}
+
+	var created Student
+	second.decode(t, "POST", "/v1/students", Student{Name: "Alan", Age: 21, Email: "alan@example.com"}, http.StatusCreated, &created)
+	var got Student
+	second.decode(t, "GET", "/v1/students/"+strconv.Itoa(created.ID), nil, http.StatusOK, &got)
+	if got.Name != "Alan" || got.Email != "alan@example.com" {
+		t.Errorf("got %+v, want the student just created", got)
+	}
+
+	var firstStudents []Student
+	first.decode(t, "GET", "/v1/students", nil, http.StatusOK, &firstStudents)
+	if len(firstStudents) != 1 || firstStudents[0].Name != "Ada" {
+		t.Errorf("first server lists %+v after the second was used, want Ada alone", firstStudents)
+	}
+	var maintenance struct{ Enabled bool }
+	first.decode(t, "GET", "/v1/admin/maintenance", nil, http.StatusOK, &maintenance)
+	if !maintenance.Enabled {
+		t.Error("first server left maintenance mode when the second was built")
+	}
+}
+
+func TestServerRecordsChanges(t *testing.T) {
+	// Built twice, so subscribing the feed must not double or drop it.
+	newTestAPI(t, nil)
+	api := newTestAPI(t, nil)
+	var student Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &student)
+
+	var page ChangePage
+	api.decode(t, "GET", "/v1/changes", nil, http.StatusOK, &page)
+	if len(page.Changes) != 1 {
+		t.Fatalf("change feed has %d changes after one create, want 1: %+v", len(page.Changes), page.Changes)
+	}
+	if c := page.Changes[0]; c.Entity != "student" || c.EntityID != student.ID || c.Action != changeCreated {
+		t.Errorf("change = %+v, want student %d created", c, student.ID)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// shareSecret signs summary share links. When no secret is configured a
+// random one is used, so links stop working on restart.
+var shareSecret []byte
+
+// maxShareLinkTTL caps how long a single share link may stay valid.
+const maxShareLinkTTL = 30 * 24 * time.Hour
+
+func loadShareSecret(configured string) []byte {
+	if configured != "" {
+		return []byte(configured)
+	}
+	return []byte(random.Text() + random.Text())
+}
+
+func signSummaryLink(id int, expires int64) string {
+	mac := hmac.New(sha256.New, shareSecret)
+	fmt.Fprintf(mac, "summary:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// createSummaryShareLink issues a signed, time-limited URL for a student's
+// summary that can be opened without an API key. The URL is built on
+// public_url, never on the Host header, which the caller controls.
+func createSummaryShareLink(w http.ResponseWriter, r *http.Request) {
+	if cfg.PublicURL == "" {
+		writeProblem(w, r, http.StatusServiceUnavailable, "Share links need public_url to be configured")
+		return
+	}
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	ttl := cfg.ShareLinkTTL
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil || ttl <= 0 || ttl > maxShareLinkTTL {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid ttl")
+			return
+		}
+	}
+
+	_, exists := findStudent(r.Context(), id)
+
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	expiresAt := clock.Now().Add(ttl).UTC()
+	expires := expiresAt.Unix()
+	path := fmt.Sprintf("/shared/students/%d/summary?expires=%d&sig=%s", id, expires, signSummaryLink(id, expires))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":        strings.TrimSuffix(cfg.PublicURL, "/") + path,
+		"expires_at": expiresAt,
+	})
+}
+
+// getSharedSummary serves a summary to holders of a valid share link.
+func getSharedSummary(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(query.Get("sig")), []byte(signSummaryLink(id, expires))) {
+		writeProblem(w, r, http.StatusForbidden, "Invalid share link")
+		return
+	}
+	if clock.Now().Unix() > expires {
+		writeProblem(w, r, http.StatusGone, "Share link has expired")
+		return
+	}
+
+	student, exists := findStudent(r.Context(), id)
+
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	summary, err := cachedSummarizeStudent(r.Context(), student)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "summary generation failed", "student_id", id, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setContentLanguage(w, localeFromContext(r.Context()))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"summary": summary})
+}
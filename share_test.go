@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestShareLinkUsesPublicURL(t *testing.T) {
+	unset := newTestAPI(t, nil)
+	var s Student
+	unset.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &s)
+	share := "/v1/students/" + strconv.Itoa(s.ID) + "/summary/share"
+	if resp, data := unset.do(t, "POST", share, nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("without public_url: status %d, want 503: %s", resp.StatusCode, data)
+	}
+
+	api := newTestAPI(t, func(c *Config) { c.PublicURL = "https://api.example.com/" })
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &s)
+	req, err := http.NewRequest("POST", api.URL+share, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "evil.example.net"
+	req.Header.Set("X-API-Key", testAPIKey)
+	resp, err := api.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var link struct{ URL string }
+	if err := json.NewDecoder(resp.Body).Decode(&link); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("share: status %d, %v", resp.StatusCode, err)
+	}
+	path, ok := strings.CutPrefix(link.URL, "https://api.example.com/shared/")
+	if !ok {
+		t.Fatalf("share URL %q is not on public_url", link.URL)
+	}
+
+	var got struct{ Summary string }
+	api.decode(t, "GET", "/shared/"+path, nil, http.StatusOK, &got)
+	if got.Summary == "" {
+		t.Error("share link served no summary")
+	}
+}
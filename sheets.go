@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// sheetsScope is the OAuth scope of reading and writing spreadsheets.
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetsTimeout bounds one export, token exchange included.
+const sheetsTimeout = time.Minute
+
+var sheetsClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// serviceAccount is the part of a Google service-account key file the
+// export uses.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+	key         *rsa.PrivateKey
+}
+
+// loadServiceAccount reads and checks a service-account key file.
+func loadServiceAccount(path string) (*serviceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("%s: not a service-account key file", path)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("%s: private_key is not PEM", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: private_key: %v", path, err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: private_key is not an RSA key", path)
+	}
+	sa.key = rsaKey
+	return &sa, nil
+}
+
+// assertion is the signed JWT exchanged for an access token (RFC 7523).
+func (sa *serviceAccount) assertion(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iss":   sa.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(nil, sa.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// sheetsToken caches the access token between exports.
+var sheetsToken struct {
+	sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// accessToken returns a token for sa, exchanging a new assertion when the
+// cached one is about to expire.
+func accessToken(ctx context.Context, sa *serviceAccount) (string, error) {
+	sheetsToken.Lock()
+	defer sheetsToken.Unlock()
+	if sheetsToken.value != "" && time.Until(sheetsToken.expires) > time.Minute {
+		return sheetsToken.value, nil
+	}
+
+	jwt, err := sa.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {jwt}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := doSheetsRequest(req, &token); err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token exchange: no access_token in response")
+	}
+	sheetsToken.value = token.AccessToken
+	sheetsToken.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return token.AccessToken, nil
+}
+
+// doSheetsRequest sends req and decodes a JSON response into out, when
+// out is not nil. Error responses become errors carrying their body.
+func doSheetsRequest(req *http.Request, out any) error {
+	resp, err := sheetsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// sheetsExportQuery reads google_sheets_filter and google_sheets_fields as
+// $filter and $select.
+func sheetsExportQuery(c Config) (odataQuery, error) {
+	q, errs := parseODataOptions(url.Values{"$filter": {c.GoogleSheetsFilter}, "$select": {c.GoogleSheetsFields}}, reflect.TypeFor[Student]())
+	if len(errs) > 0 {
+		return q, errors.New(describeFieldErrors(errs))
+	}
+	return q, nil
+}
+
+// sheetRows lays students out as rows: a header of field names, then a
+// row per student. Numbers and booleans stay typed so Sheets can sort and
+// sum them.
+func sheetRows(fields []string, students []Student) ([][]any, error) {
+	header := make([]any, len(fields))
+	for i, f := range fields {
+		header[i] = f
+	}
+	rows := [][]any{header}
+	for _, s := range students {
+		members, err := jsonMembers(s)
+		if err != nil {
+			return nil, err
+		}
+		row := make([]any, len(fields))
+		for i, f := range fields {
+			var v any
+			if raw, ok := members[f]; ok {
+				json.Unmarshal(raw, &v)
+			}
+			if v == nil {
+				v = ""
+			}
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// sheetsExportJob replaces the contents of the configured sheet with the
+// students matching google_sheets_filter.
+func sheetsExportJob(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, sheetsTimeout)
+	defer cancel()
+
+	q, err := sheetsExportQuery(cfg)
+	if err != nil {
+		return "", err
+	}
+	sa, err := loadServiceAccount(cfg.GoogleCredentialsFile)
+	if err != nil {
+		return "", err
+	}
+	token, err := accessToken(ctx, sa)
+	if err != nil {
+		return "", err
+	}
+
+	students := applyODataFilter(q, allStudents(ctx))
+	fields := q.fields
+	if !q.selects() {
+		fields = jsonFieldNames(reflect.TypeFor[Student]())
+	}
+	rows, err := sheetRows(fields, students)
+	if err != nil {
+		return "", err
+	}
+
+	// The sheet is cleared first so rows of students no longer matching
+	// do not linger below the new ones.
+	sheet := "'" + strings.ReplaceAll(cfg.GoogleSheetsSheet, "'", "''") + "'"
+	values := strings.TrimSuffix(cfg.GoogleSheetsURL, "/") + "/v4/spreadsheets/" + url.PathEscape(cfg.GoogleSheetsID) + "/values/"
+	send := func(method, target string, body any) error {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		return doSheetsRequest(req, nil)
+	}
+	if err := send(http.MethodPost, values+url.PathEscape(sheet)+":clear", map[string]any{}); err != nil {
+		return "", fmt.Errorf("clearing %s: %w", cfg.GoogleSheetsSheet, err)
+	}
+	target := sheet + "!A1"
+	err = send(http.MethodPut, values+url.PathEscape(target)+"?valueInputOption=RAW", map[string]any{
+		"range":          target,
+		"majorDimension": "ROWS",
+		"values":         rows,
+	})
+	if err != nil {
+		return "", fmt.Errorf("writing %s: %w", cfg.GoogleSheetsSheet, err)
+	}
+	return fmt.Sprintf("exported %d students to %s", len(students), cfg.GoogleSheetsSheet), nil
+}
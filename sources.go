@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+)
+
+// The API reads the time, makes up IDs and draws random numbers through
+// the sources below rather than calling time.Now, crypto/rand and
+// math/rand directly. NewServer installs the real ones; tests pass fixed
+// ones with WithClock, WithIDs and WithRandom so that responses, and the
+// golden files made from them, are the same on every run.
+//
+// Wall-clock time the outside world checks stays on time.Now: network
+// deadlines, request signatures for S3 and Google, the scheduler's timers
+// and latency metrics.
+// Entity IDs need no source of their own; every repository numbers its
+// records from 1.
+
+// Clock tells the time recorded in and returned with resources.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator makes up the IDs of requests, events and webhook
+// deliveries.
+type IDGenerator interface {
+	NewID() string
+}
+
+// Random is the randomness behind jitter, fault injection, fake data and
+// generated secrets.
+type Random interface {
+	IntN(n int) int
+	Int64N(n int64) int64
+	// Text returns a string suitable for a secret, as crypto/rand.Text does.
+	Text() string
+}
+
+var (
+	clock       Clock       = systemClock{}
+	idGenerator IDGenerator = randomIDs{}
+	random      Random      = systemRandom{}
+)
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// randomIDs makes 128-bit random IDs in hex.
+type randomIDs struct{}
+
+func (randomIDs) NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+type systemRandom struct{}
+
+func (systemRandom) IntN(n int) int       { return mrand.IntN(n) }
+func (systemRandom) Int64N(n int64) int64 { return mrand.Int64N(n) }
+func (systemRandom) Text() string         { return rand.Text() }
+
+// FakeClock is a Clock that only moves when told to.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a clock standing at t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SequentialIDs hands out 00000000000000000000000000000001, then ...02,
+// and so on: IDs of the same shape as the random ones.
+type SequentialIDs struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func (s *SequentialIDs) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	return fmt.Sprintf("%032x", s.next)
+}
+
+// SeededRandom is a Random that draws the same numbers for the same seed.
+// Its secrets are predictable and only fit for tests.
+type SeededRandom struct {
+	mu sync.Mutex
+	r  *mrand.Rand
+}
+
+// NewSeededRandom returns a Random seeded with seed.
+func NewSeededRandom(seed uint64) *SeededRandom {
+	return &SeededRandom{r: mrand.New(mrand.NewPCG(seed, seed))}
+}
+
+func (s *SeededRandom) IntN(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.IntN(n)
+}
+
+func (s *SeededRandom) Int64N(n int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Int64N(n)
+}
+
+func (s *SeededRandom) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := make([]byte, 16)
+	for i := range b {
+		b[i] = byte(s.r.Uint32())
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
+
+// ServerOption replaces one of the sources NewServer installs, or the
+// repositories it serves.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	clock              Clock
+	ids                IDGenerator
+	random             Random
+	memoryRepositories bool
+}
+
+// WithClock makes the server tell the time by c.
+func WithClock(c Clock) ServerOption {
+	return func(s *serverOptions) { s.clock = c }
+}
+
+// WithIDs makes the server take request, event and delivery IDs from g.
+func WithIDs(g IDGenerator) ServerOption {
+	return func(s *serverOptions) { s.ids = g }
+}
+
+// WithRandom makes the server draw random numbers and secrets from r.
+func WithRandom(r Random) ServerOption {
+	return func(s *serverOptions) { s.random = r }
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// entity is a stored record identified by an integer ID. withID returns a
+// copy carrying the given ID.
+type entity[T any] interface {
+	entityID() int
+	withID(id int) T
+}
+
+// Repository persists one kind of entity. Implementations must be safe
+// for concurrent use.
+type Repository[T any] interface {
+	Find(ctx context.Context, id int) (T, bool)
+	// List returns every entity in no particular order.
+	List(ctx context.Context) []T
+	// Scan yields the entities with an ID greater than after in ID order,
+	// reading each as it is reached, so a caller that stops early or
+	// writes them out one by one never holds the collection.
+	Scan(ctx context.Context, after int) iter.Seq[T]
+	// Insert assigns the entity a new ID and stores it.
+	Insert(ctx context.Context, v T) T
+	// Replace overwrites an existing entity and returns the previous version.
+	Replace(ctx context.Context, id int, v T) (T, bool)
+	// Remove deletes an entity and returns the removed version.
+	Remove(ctx context.Context, id int) (T, bool)
+	Count(ctx context.Context) int
+	// Version changes whenever the stored data does.
+	Version(ctx context.Context) uint64
+}
+
+// Store persists students.
+type Store = Repository[Student]
+
+func (s Student) entityID() int { return s.ID }
+
+func (s Student) withID(id int) Student {
+	s.ID = id
+	return s
+}
+
+// studentStore is the store NewServer is given, chosen by the store setting.
+var studentStore Store = newCollection[Student]("students")
+
+// newStore builds the student store named in the configuration.
+func newStore(c Config) (Store, error) {
+	return newRepository[Student](c, "students")
+}
+
+// collection names one of the collections a Server keeps. It is used as a
+// repository: each call goes to the collection of that name in the state
+// of the Server serving ctx.
+type collection[T entity[T]] string
+
+// collectionOpeners open every collection declared with newCollection
+// other than students, which NewServer is given.
+var collectionOpeners []func(c Config, memory bool) (string, any, error)
+
+// newCollection declares a collection, which NewServer opens on the
+// configured store, or in memory when memory is set.
+func newCollection[T entity[T]](name string) collection[T] {
+	if name != "students" {
+		collectionOpeners = append(collectionOpeners, func(c Config, memory bool) (string, any, error) {
+			if memory {
+				c.Store = "memory"
+			}
+			repo, err := newRepository[T](c, name)
+			return name, repo, err
+		})
+	}
+	return collection[T](name)
+}
+
+// openCollections opens the collections of a new Server.
+func openCollections(c Config, students Store, memory bool) (map[string]any, error) {
+	repos := map[string]any{"students": students}
+	for _, open := range collectionOpeners {
+		name, repo, err := open(c, memory)
+		if err != nil {
+			closeCollections(repos)
+			return nil, err
+		}
+		repos[name] = repo
+	}
+	return repos, nil
+}
+
+// closeCollections flushes and closes the repositories that hold
+// resources, such as the file store's log.
+func closeCollections(repos map[string]any) error {
+	var errs []error
+	for _, repo := range repos {
+		if c, ok := repo.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c collection[T]) repo(ctx context.Context) Repository[T] {
+	return stateOf(ctx).repos[string(c)].(Repository[T])
+}
+
+func (c collection[T]) Find(ctx context.Context, id int) (T, bool) {
+	return c.repo(ctx).Find(ctx, id)
+}
+
+func (c collection[T]) List(ctx context.Context) []T {
+	return c.repo(ctx).List(ctx)
+}
+
+func (c collection[T]) Scan(ctx context.Context, after int) iter.Seq[T] {
+	return c.repo(ctx).Scan(ctx, after)
+}
+
+func (c collection[T]) Insert(ctx context.Context, v T) T {
+	return c.repo(ctx).Insert(ctx, v)
+}
+
+func (c collection[T]) Replace(ctx context.Context, id int, v T) (T, bool) {
+	return c.repo(ctx).Replace(ctx, id, v)
+}
+
+func (c collection[T]) Remove(ctx context.Context, id int) (T, bool) {
+	return c.repo(ctx).Remove(ctx, id)
+}
+
+func (c collection[T]) Count(ctx context.Context) int {
+	return c.repo(ctx).Count(ctx)
+}
+
+func (c collection[T]) Version(ctx context.Context) uint64 {
+	return c.repo(ctx).Version(ctx)
+}
+
+// newRepository builds a repository for one collection on the configured
+// backend. The file backend keeps students in store_file and every other
+// collection next to it as <name>.jsonl.
+func newRepository[T entity[T]](c Config, name string) (Repository[T], error) {
+	switch c.Store {
+	case "memory":
+		return newMemoryStore[T](), nil
+	case "sharded":
+		return newShardedStore[T](c.StoreShards), nil
+	case "file":
+		path := c.StoreFile
+		if name != "students" {
+			path = filepath.Join(filepath.Dir(c.StoreFile), name+".jsonl")
+		}
+		s, err := newFileStore[T](path, c.StoreFlushInterval, c.StoreBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown store %q", c.Store)
+}
+
+// The helpers below are how handlers reach a repository. They add a trace
+// span and request timing around every call.
+
+func repoFind[T any](ctx context.Context, repo Repository[T], id int) (T, bool) {
+	ctx, span := tracer.Start(ctx, "store.find")
+	defer span.End()
+	defer trackTiming(ctx, "store", time.Now())
+	return repo.Find(ctx, id)
+}
+
+// repoList returns every entity ordered by ID, so list responses and
+// their ETags are stable.
+func repoList[T entity[T]](ctx context.Context, repo Repository[T]) []T {
+	ctx, span := tracer.Start(ctx, "store.list")
+	defer span.End()
+	defer trackTiming(ctx, "store", time.Now())
+
+	list := repo.List(ctx)
+	slices.SortFunc(list, func(a, b T) int { return a.entityID() - b.entityID() })
+	return list
+}
+
+// repoScan yields entities in ID order from after on; see Repository.Scan.
+func repoScan[T any](ctx context.Context, repo Repository[T], after int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ctx, span := tracer.Start(ctx, "store.scan")
+		defer span.End()
+		defer trackTiming(ctx, "store", time.Now())
+		repo.Scan(ctx, after)(yield)
+	}
+}
+
+// The write helpers only report what they would do during a dry run: an
+// insert returns the entity with ID 0, a replace or remove the entity it
+// would have changed.
+
+func repoInsert[T entity[T]](ctx context.Context, repo Repository[T], v T) T {
+	ctx, span := tracer.Start(ctx, "store.insert")
+	defer span.End()
+	defer trackTiming(ctx, "store", time.Now())
+	if inDryRun(ctx) {
+		return v.withID(0)
+	}
+	return repo.Insert(ctx, v)
+}
+
+func repoReplace[T any](ctx context.Context, repo Repository[T], id int, v T) (T, bool) {
+	ctx, span := tracer.Start(ctx, "store.replace")
+	defer span.End()
+	defer trackTiming(ctx, "store", time.Now())
+	if inDryRun(ctx) {
+		return repo.Find(ctx, id)
+	}
+	return repo.Replace(ctx, id, v)
+}
+
+func repoRemove[T any](ctx context.Context, repo Repository[T], id int) (T, bool) {
+	ctx, span := tracer.Start(ctx, "store.remove")
+	defer span.End()
+	defer trackTiming(ctx, "store", time.Now())
+	if inDryRun(ctx) {
+		return repo.Find(ctx, id)
+	}
+	return repo.Remove(ctx, id)
+}
+
+func findStudent(ctx context.Context, id int) (Student, bool) {
+	return repoFind(ctx, studentStore, id)
+}
+
+func allStudents(ctx context.Context) []Student {
+	return repoList(ctx, studentStore)
+}
+
+func insertStudent(ctx context.Context, s Student) Student {
+	s.UpdatedAt = clock.Now().UTC()
+	return repoInsert(ctx, studentStore, s)
+}
+
+func replaceStudent(ctx context.Context, id int, s Student) (Student, bool) {
+	return repoReplace(ctx, studentStore, id, s)
+}
+
+func storeVersion(ctx context.Context) uint64 {
+	return studentStore.Version(ctx)
+}
+
+func removeStudent(ctx context.Context, id int) (Student, bool) {
+	return repoRemove(ctx, studentStore, id)
+}
+
+// memoryStore keeps entities in one map. Reads take the read lock so
+// concurrent list and get requests do not serialize behind each other.
+type memoryStore[T entity[T]] struct {
+	mu      sync.RWMutex
+	items   map[int]T
+	nextID  atomic.Int64
+	version atomic.Uint64
+}
+
+func newMemoryStore[T entity[T]]() *memoryStore[T] {
+	return &memoryStore[T]{items: make(map[int]T)}
+}
+
+func (m *memoryStore[T]) Find(_ context.Context, id int) (T, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.items[id]
+	return v, ok
+}
+
+func (m *memoryStore[T]) List(_ context.Context) []T {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []T
+	for _, v := range m.items {
+		list = append(list, v)
+	}
+	return list
+}
+
+// Scan copies the IDs, not the entities, and looks each entity up as it
+// is reached, so writes are not held up while the caller consumes them.
+// An entity removed during the scan is skipped.
+func (m *memoryStore[T]) Scan(_ context.Context, after int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		m.mu.RLock()
+		ids := m.idsAfter(after, nil)
+		m.mu.RUnlock()
+		slices.Sort(ids)
+		for _, id := range ids {
+			m.mu.RLock()
+			v, ok := m.items[id]
+			m.mu.RUnlock()
+			if ok && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// idsAfter appends the IDs greater than after to ids. The caller holds
+// the read lock.
+func (m *memoryStore[T]) idsAfter(after int, ids []int) []int {
+	for id := range m.items {
+		if id > after {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (m *memoryStore[T]) Insert(_ context.Context, v T) T {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v = v.withID(int(m.nextID.Add(1)))
+	m.items[v.entityID()] = v
+	m.version.Add(1)
+	return v
+}
+
+func (m *memoryStore[T]) Replace(_ context.Context, id int, v T) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.items[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	m.items[id] = v.withID(id)
+	m.version.Add(1)
+	return before, true
+}
+
+func (m *memoryStore[T]) Remove(_ context.Context, id int) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.items[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	delete(m.items, id)
+	m.version.Add(1)
+	return before, true
+}
+
+func (m *memoryStore[T]) Count(_ context.Context) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.items)
+}
+
+func (m *memoryStore[T]) Version(_ context.Context) uint64 {
+	return m.version.Load()
+}
+
+// shardedStore partitions entities across independently locked shards by
+// ID, so concurrent writes to different entities rarely contend.
+type shardedStore[T entity[T]] struct {
+	shards []*memoryStore[T]
+	nextID atomic.Int64
+}
+
+func newShardedStore[T entity[T]](n int) *shardedStore[T] {
+	s := &shardedStore[T]{shards: make([]*memoryStore[T], n)}
+	for i := range s.shards {
+		s.shards[i] = newMemoryStore[T]()
+	}
+	return s
+}
+
+func (s *shardedStore[T]) shard(id int) *memoryStore[T] {
+	// Multiplicative hashing spreads sequential IDs evenly across shards.
+	h := uint64(id) * 0x9E3779B97F4A7C15
+	return s.shards[h%uint64(len(s.shards))]
+}
+
+func (s *shardedStore[T]) Find(ctx context.Context, id int) (T, bool) {
+	return s.shard(id).Find(ctx, id)
+}
+
+func (s *shardedStore[T]) List(ctx context.Context) []T {
+	var list []T
+	for _, shard := range s.shards {
+		list = append(list, shard.List(ctx)...)
+	}
+	return list
+}
+
+func (s *shardedStore[T]) Scan(ctx context.Context, after int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var ids []int
+		for _, shard := range s.shards {
+			shard.mu.RLock()
+			ids = shard.idsAfter(after, ids)
+			shard.mu.RUnlock()
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			if v, ok := s.Find(ctx, id); ok && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func (s *shardedStore[T]) Insert(_ context.Context, v T) T {
+	v = v.withID(int(s.nextID.Add(1)))
+	shard := s.shard(v.entityID())
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.items[v.entityID()] = v
+	shard.version.Add(1)
+	return v
+}
+
+func (s *shardedStore[T]) Replace(ctx context.Context, id int, v T) (T, bool) {
+	return s.shard(id).Replace(ctx, id, v)
+}
+
+func (s *shardedStore[T]) Remove(ctx context.Context, id int) (T, bool) {
+	return s.shard(id).Remove(ctx, id)
+}
+
+func (s *shardedStore[T]) Count(ctx context.Context) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Count(ctx)
+	}
+	return n
+}
+
+// Version sums the shard versions. Every write bumps exactly one shard, so
+// the sum changes on every write.
+func (s *shardedStore[T]) Version(ctx context.Context) uint64 {
+	var v uint64
+	for _, shard := range s.shards {
+		v += shard.Version(ctx)
+	}
+	return v
+}
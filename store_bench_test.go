@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// The benchmarks run parallel Find calls alongside a share of writes, so
+// the memory store's one lock can be compared with the sharded store's:
+//
+//	go test -run '^$' -bench Store -cpu 1,4,16
+
+const benchStoreSize = 10_000
+
+func benchmarkStore(b *testing.B, repo Repository[Student], writePercent int) {
+	ctx := context.Background()
+	for i := range benchStoreSize {
+		repo.Insert(ctx, studentRecords.Make(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewPCG(rand.Uint64(), 0))
+		for pb.Next() {
+			id := 1 + r.IntN(benchStoreSize)
+			if r.IntN(100) < writePercent {
+				repo.Replace(ctx, id, studentRecords.Make(id))
+			} else {
+				repo.Find(ctx, id)
+			}
+		}
+	})
+}
+
+func BenchmarkStore(b *testing.B) {
+	stores := []struct {
+		name string
+		new  func() Repository[Student]
+	}{
+		{"memory", func() Repository[Student] { return newMemoryStore[Student]() }},
+		{"sharded", func() Repository[Student] { return newShardedStore[Student](16) }},
+	}
+	for _, s := range stores {
+		for _, writes := range []int{0, 10, 50} {
+			b.Run(fmt.Sprintf("%s/writes=%d%%", s.name, writes), func(b *testing.B) {
+				benchmarkStore(b, s.new(), writes)
+			})
+		}
+	}
+}
+
+// BenchmarkStoreList mixes an occasional full List into parallel Finds,
+// which share the read lock with it instead of queuing behind it.
+func BenchmarkStoreList(b *testing.B) {
+	for name, newRepo := range map[string]func() Repository[Student]{
+		"memory":  func() Repository[Student] { return newMemoryStore[Student]() },
+		"sharded": func() Repository[Student] { return newShardedStore[Student](16) },
+	} {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			repo := newRepo()
+			for i := range benchStoreSize {
+				repo.Insert(ctx, studentRecords.Make(i))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%100 == 0 {
+						repo.List(ctx)
+					} else {
+						repo.Find(ctx, 1+i%benchStoreSize)
+					}
+				}
+			})
+		})
+	}
+}
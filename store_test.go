@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"studengo/storetest"
+)
+
+var studentRecords = storetest.Records[Student]{
+	Make: func(n int) Student {
+		return Student{Name: fmt.Sprint("Student ", n), Age: 18 + n%10, Email: fmt.Sprintf("student%d@example.com", n)}
+	},
+	ID: func(s Student) int { return s.ID },
+}
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Repository[Student] {
+		return newMemoryStore[Student]()
+	}, studentRecords)
+}
+
+func TestShardedStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Repository[Student] {
+		return newShardedStore[Student](4)
+	}, studentRecords)
+}
+
+func TestFileStore(t *testing.T) {
+	open := func(t *testing.T, path string) *fileStore[Student] {
+		s, err := newFileStore[Student](path, time.Millisecond, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	// Stores closed to be reopened are not closed again on cleanup.
+	closed := make(map[*fileStore[Student]]bool)
+	records := studentRecords
+	records.Reopen = func(t *testing.T, repo storetest.Repository[Student]) storetest.Repository[Student] {
+		s := repo.(*fileStore[Student])
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		closed[s] = true
+		reopened := open(t, s.file.Name())
+		t.Cleanup(func() { reopened.Close() })
+		return reopened
+	}
+	storetest.Run(t, func(t *testing.T) storetest.Repository[Student] {
+		s := open(t, filepath.Join(t.TempDir(), "students.jsonl"))
+		t.Cleanup(func() {
+			if !closed[s] {
+				s.Close()
+			}
+		})
+		return s
+	}, records)
+}
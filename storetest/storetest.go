@@ -0,0 +1,398 @@
+// Package storetest is a conformance suite for repository backends. Every
+// backend runs the same checks, so the memory, sharded and file stores
+// (and any added later) agree on IDs, not-found results, versions and
+// behaviour under concurrent use.
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storetest.Repository[Student] {
+//			return newMemoryStore[Student]()
+//		}, storetest.Records[Student]{
+//			Make: func(n int) Student { return Student{Name: fmt.Sprint("Student ", n), Age: 20} },
+//			ID:   func(s Student) int { return s.ID },
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Repository is the method set a backend under test must have. It mirrors
+// the API's Repository, which a backend's store type satisfies as is.
+type Repository[T any] interface {
+	Find(ctx context.Context, id int) (T, bool)
+	List(ctx context.Context) []T
+	Scan(ctx context.Context, after int) iter.Seq[T]
+	Insert(ctx context.Context, v T) T
+	Replace(ctx context.Context, id int, v T) (T, bool)
+	Remove(ctx context.Context, id int) (T, bool)
+	Count(ctx context.Context) int
+	Version(ctx context.Context) uint64
+}
+
+// Records tells the suite how to make and inspect the records it stores.
+type Records[T any] struct {
+	// Make builds a record, without an ID, that differs for every n.
+	Make func(n int) T
+	// ID returns the ID of a record.
+	ID func(T) int
+	// Equal reports whether a record read back matches the one written.
+	// It defaults to reflect.DeepEqual; backends that round-trip records
+	// through an encoding may need something looser.
+	Equal func(a, b T) bool
+	// Reopen, when set, closes a store and opens it again on the same
+	// data, so persistent backends are checked to keep what they stored.
+	Reopen func(t *testing.T, repo Repository[T]) Repository[T]
+}
+
+// Run runs the suite as subtests of t. newStore is called once per
+// subtest and must return an empty store; cleanup goes through t.Cleanup.
+func Run[T any](t *testing.T, newStore func(t *testing.T) Repository[T], r Records[T]) {
+	if r.Equal == nil {
+		r.Equal = func(a, b T) bool { return reflect.DeepEqual(a, b) }
+	}
+	s := suite[T]{newStore: newStore, r: r}
+	t.Run("Empty", s.empty)
+	t.Run("InsertFind", s.insertFind)
+	t.Run("UniqueIDs", s.uniqueIDs)
+	t.Run("Replace", s.replace)
+	t.Run("Remove", s.remove)
+	t.Run("NotFound", s.notFound)
+	t.Run("Scan", s.scan)
+	t.Run("Version", s.version)
+	t.Run("Concurrent", s.concurrent)
+	if r.Reopen != nil {
+		t.Run("Reopen", s.reopen)
+	}
+}
+
+type suite[T any] struct {
+	newStore func(t *testing.T) Repository[T]
+	r        Records[T]
+}
+
+// insert stores n new records and returns them as the store gave them back.
+func (s suite[T]) insert(t *testing.T, repo Repository[T], n int) []T {
+	t.Helper()
+	out := make([]T, n)
+	for i := range out {
+		out[i] = repo.Insert(context.Background(), s.r.Make(i+1))
+	}
+	return out
+}
+
+// mustFind fails t unless repo holds want under its ID.
+func (s suite[T]) mustFind(t *testing.T, repo Repository[T], want T) {
+	t.Helper()
+	got, ok := repo.Find(context.Background(), s.r.ID(want))
+	if !ok {
+		t.Fatalf("Find(%d): not found", s.r.ID(want))
+	}
+	if !s.r.Equal(got, want) {
+		t.Fatalf("Find(%d) = %+v, want %+v", s.r.ID(want), got, want)
+	}
+}
+
+func (s suite[T]) empty(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	if n := repo.Count(ctx); n != 0 {
+		t.Errorf("Count = %d, want 0", n)
+	}
+	if list := repo.List(ctx); len(list) != 0 {
+		t.Errorf("List returned %d records, want none", len(list))
+	}
+	if _, ok := repo.Find(ctx, 1); ok {
+		t.Error("Find(1) found a record in an empty store")
+	}
+}
+
+func (s suite[T]) insertFind(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	v := repo.Insert(ctx, s.r.Make(1))
+	if s.r.ID(v) <= 0 {
+		t.Fatalf("Insert assigned ID %d, want a positive one", s.r.ID(v))
+	}
+	s.mustFind(t, repo, v)
+	if n := repo.Count(ctx); n != 1 {
+		t.Errorf("Count = %d, want 1", n)
+	}
+	list := repo.List(ctx)
+	if len(list) != 1 || !s.r.Equal(list[0], v) {
+		t.Errorf("List = %+v, want [%+v]", list, v)
+	}
+}
+
+func (s suite[T]) uniqueIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	seen := make(map[int]bool)
+	for _, v := range s.insert(t, repo, 20) {
+		id := s.r.ID(v)
+		if seen[id] {
+			t.Fatalf("ID %d assigned twice", id)
+		}
+		seen[id] = true
+	}
+
+	// IDs of removed records are not handed out again, so links to a
+	// deleted record never lead to a new one.
+	for id := range seen {
+		repo.Remove(ctx, id)
+	}
+	for _, v := range s.insert(t, repo, 5) {
+		if seen[s.r.ID(v)] {
+			t.Fatalf("ID %d reused after its record was removed", s.r.ID(v))
+		}
+	}
+
+	// An ID passed in with the record is ignored.
+	stored := s.insert(t, repo, 1)[0]
+	if v := repo.Insert(ctx, stored); s.r.ID(v) == s.r.ID(stored) {
+		t.Fatalf("Insert kept ID %d from the record", s.r.ID(v))
+	}
+}
+
+func (s suite[T]) scan(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	vs := s.insert(t, repo, 10)
+	repo.Remove(ctx, s.r.ID(vs[4]))
+	vs = append(vs[:4], vs[5:]...)
+
+	var got []T
+	for v := range repo.Scan(ctx, s.r.ID(vs[1])) {
+		got = append(got, v)
+	}
+	want := vs[2:]
+	if len(got) != len(want) {
+		t.Fatalf("Scan after ID %d yielded %d records, want %d", s.r.ID(vs[1]), len(got), len(want))
+	}
+	for i := range want {
+		if !s.r.Equal(got[i], want[i]) {
+			t.Fatalf("Scan yielded %+v at %d, want %+v", got[i], i, want[i])
+		}
+	}
+
+	n := 0
+	for range repo.Scan(ctx, 0) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("Scan stopped after %d records, want 3", n)
+	}
+}
+
+func (s suite[T]) replace(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	vs := s.insert(t, repo, 2)
+
+	before, ok := repo.Replace(ctx, s.r.ID(vs[0]), s.r.Make(3))
+	if !ok {
+		t.Fatalf("Replace(%d): not found", s.r.ID(vs[0]))
+	}
+	if !s.r.Equal(before, vs[0]) {
+		t.Errorf("Replace returned %+v, want the previous version %+v", before, vs[0])
+	}
+	got, _ := repo.Find(ctx, s.r.ID(vs[0]))
+	if s.r.ID(got) != s.r.ID(vs[0]) {
+		t.Errorf("replaced record has ID %d, want %d", s.r.ID(got), s.r.ID(vs[0]))
+	}
+	if s.r.Equal(got, vs[0]) {
+		t.Error("Find returned the record from before Replace")
+	}
+	s.mustFind(t, repo, vs[1])
+	if n := repo.Count(ctx); n != 2 {
+		t.Errorf("Count = %d after Replace, want 2", n)
+	}
+}
+
+func (s suite[T]) remove(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	vs := s.insert(t, repo, 3)
+
+	removed, ok := repo.Remove(ctx, s.r.ID(vs[1]))
+	if !ok {
+		t.Fatalf("Remove(%d): not found", s.r.ID(vs[1]))
+	}
+	if !s.r.Equal(removed, vs[1]) {
+		t.Errorf("Remove returned %+v, want %+v", removed, vs[1])
+	}
+	if _, ok := repo.Find(ctx, s.r.ID(vs[1])); ok {
+		t.Error("Find found a removed record")
+	}
+	if n := repo.Count(ctx); n != 2 {
+		t.Errorf("Count = %d after Remove, want 2", n)
+	}
+	s.mustFind(t, repo, vs[0])
+	s.mustFind(t, repo, vs[2])
+}
+
+func (s suite[T]) notFound(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	v := s.insert(t, repo, 1)[0]
+	gone := s.insert(t, repo, 1)[0]
+	repo.Remove(ctx, s.r.ID(gone))
+	version := repo.Version(ctx)
+
+	var zero T
+	for _, id := range []int{0, -1, s.r.ID(gone), s.r.ID(v) + 1000} {
+		if got, ok := repo.Find(ctx, id); ok || !reflect.DeepEqual(got, zero) {
+			t.Errorf("Find(%d) = %+v, %v; want the zero value, false", id, got, ok)
+		}
+		if got, ok := repo.Replace(ctx, id, s.r.Make(2)); ok || !reflect.DeepEqual(got, zero) {
+			t.Errorf("Replace(%d) = %+v, %v; want the zero value, false", id, got, ok)
+		}
+		if got, ok := repo.Remove(ctx, id); ok || !reflect.DeepEqual(got, zero) {
+			t.Errorf("Remove(%d) = %+v, %v; want the zero value, false", id, got, ok)
+		}
+	}
+	if n := repo.Count(ctx); n != 1 {
+		t.Errorf("Count = %d, want 1: a failed Replace must not create a record", n)
+	}
+	if got := repo.Version(ctx); got != version {
+		t.Errorf("Version changed from %d to %d on writes that found nothing", version, got)
+	}
+	s.mustFind(t, repo, v)
+}
+
+func (s suite[T]) version(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	last := repo.Version(ctx)
+	changed := func(op string) {
+		t.Helper()
+		now := repo.Version(ctx)
+		if now == last {
+			t.Errorf("Version still %d after %s", now, op)
+		}
+		last = now
+	}
+
+	v := repo.Insert(ctx, s.r.Make(1))
+	changed("Insert")
+	repo.Replace(ctx, s.r.ID(v), s.r.Make(2))
+	changed("Replace")
+	repo.Remove(ctx, s.r.ID(v))
+	changed("Remove")
+
+	repo.Find(ctx, s.r.ID(v))
+	repo.List(ctx)
+	repo.Count(ctx)
+	if now := repo.Version(ctx); now != last {
+		t.Errorf("Version changed from %d to %d on reads", last, now)
+	}
+}
+
+// concurrent runs writers and readers side by side. Run it with -race;
+// without it the suite can only catch lost or duplicated writes.
+func (s suite[T]) concurrent(t *testing.T) {
+	const workers, perWorker = 8, 50
+	ctx := context.Background()
+	repo := s.newStore(t)
+
+	var wg sync.WaitGroup
+	ids := make([][]int, workers)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				v := repo.Insert(ctx, s.r.Make(w*perWorker+i+1))
+				ids[w] = append(ids[w], s.r.ID(v))
+				repo.Find(ctx, s.r.ID(v))
+				if i%5 == 0 {
+					repo.Replace(ctx, s.r.ID(v), s.r.Make(w*perWorker+i+1))
+				}
+				if i%10 == 0 {
+					repo.List(ctx)
+					repo.Version(ctx)
+				}
+			}
+		}()
+	}
+	// Readers race the writers, removing every third record they see.
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				for _, v := range repo.List(ctx) {
+					if s.r.ID(v)%3 == 0 {
+						repo.Remove(ctx, s.r.ID(v))
+					}
+				}
+				repo.Count(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for _, list := range ids {
+		for _, id := range list {
+			if seen[id] {
+				t.Fatalf("ID %d assigned to two concurrent inserts", id)
+			}
+			seen[id] = true
+		}
+	}
+	// Finish the removals the readers may have missed, then check that
+	// everything else is there.
+	for id := range seen {
+		if id%3 == 0 {
+			repo.Remove(ctx, id)
+			delete(seen, id)
+		}
+	}
+	if n := repo.Count(ctx); n != len(seen) {
+		t.Errorf("Count = %d, want %d", n, len(seen))
+	}
+	for _, v := range repo.List(ctx) {
+		if !seen[s.r.ID(v)] {
+			t.Errorf("List returned unexpected ID %d", s.r.ID(v))
+		}
+	}
+	for id := range seen {
+		if _, ok := repo.Find(ctx, id); !ok {
+			t.Errorf("Find(%d): lost a concurrent write", id)
+		}
+	}
+}
+
+func (s suite[T]) reopen(t *testing.T) {
+	ctx := context.Background()
+	repo := s.newStore(t)
+	vs := s.insert(t, repo, 4)
+	repo.Replace(ctx, s.r.ID(vs[0]), s.r.Make(10))
+	vs[0], _ = repo.Find(ctx, s.r.ID(vs[0]))
+	repo.Remove(ctx, s.r.ID(vs[3]))
+	vs = vs[:3]
+
+	repo = s.r.Reopen(t, repo)
+	if n := repo.Count(ctx); n != len(vs) {
+		t.Fatalf("Count = %d after reopening, want %d", n, len(vs))
+	}
+	for _, v := range vs {
+		s.mustFind(t, repo, v)
+	}
+	v := repo.Insert(ctx, s.r.Make(11))
+	for _, old := range vs {
+		if s.r.ID(v) == s.r.ID(old) {
+			t.Fatalf("Insert after reopening reused ID %d", s.r.ID(v))
+		}
+	}
+	if _, ok := repo.Find(ctx, s.r.ID(v)); !ok {
+		t.Fatalf("Find(%d): not found after reopening", s.r.ID(v))
+	}
+}
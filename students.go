@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Student struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name" validate:"required"`
+	Age   int    `json:"age" validate:"positive"`
+	Email string `json:"email" validate:"required"`
+	// ProgramID is the program the student follows, if any.
+	ProgramID int `json:"program_id,omitempty" validate:"omitempty,positive"`
+	// UpdatedAt is when the student was last written. It is zero for
+	// records stored before it was tracked.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+func createStudent(w http.ResponseWriter, r *http.Request) {
+	var student Student
+	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateStudent(student); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student data", errs...)
+		return
+	}
+
+	departmentMutex.Lock()
+	if errs := programProblem(r.Context(), student); len(errs) > 0 {
+		departmentMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Program not found", errs...)
+		return
+	}
+	student = insertStudent(r.Context(), student)
+	departmentMutex.Unlock()
+	publishEvent(r.Context(), StudentCreated{Student: student})
+
+	setLastModified(w, student.UpdatedAt)
+	setLinkedContentType(w, r)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(studentRepresentation(r, student))
+}
+
+func getStudents(w http.ResponseWriter, r *http.Request) {
+	page, errs := parsePage(r)
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid pagination parameters", errs...)
+		return
+	}
+	query, errs := parseODataQuery(r, reflect.TypeFor[Student]())
+	if len(errs) == 0 && query.selects() && wantsLinks(r) && !wantsCSV(r) {
+		errs = append(errs, FieldError{Field: "$select", Message: "cannot be combined with HAL links"})
+	}
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query options", errs...)
+		return
+	}
+
+	// CSV is written as the list is, with the $select fields as columns,
+	// but skips the list cache, which holds JSON.
+	if wantsCSV(r) {
+		etag := strings.TrimSuffix(listETag(storeVersion(r.Context()), r), `"`) + `-csv"`
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		list, link := studentListPage(r, page, query)
+		if link != "" {
+			w.Header().Set("Link", link)
+		}
+		fields := query.fields
+		if !query.selects() {
+			fields = jsonFieldNames(reflect.TypeFor[Student]())
+		}
+		writeCSVFields(w, r, "students", fields, list)
+		return
+	}
+
+	// Linked pages are rare and vary by Accept, so they skip the list
+	// cache and take their ETag from the body.
+	if wantsLinks(r) {
+		list, link := studentListPage(r, page, query)
+		if link != "" {
+			w.Header().Set("Link", link)
+		}
+		setLinkedContentType(w, r)
+		writeJSONWithETag(w, r, linkedStudentPage(r, list, link))
+		return
+	}
+
+	// The version is read before the list so a concurrent write can only
+	// make the ETag and cache entry older than the data, never newer.
+	version := storeVersion(r.Context())
+	etag := listETag(version, r)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	key := cacheKey(r)
+	studentListCache := stateOf(r.Context()).studentListCache
+	if cached, ok := studentListCache.get(version, key); ok {
+		if cached.link != "" {
+			w.Header().Set("Link", cached.link)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(cached.body)
+		return
+	}
+
+	list, link := studentListPage(r, page, query)
+	if link != "" {
+		w.Header().Set("Link", link)
+	}
+	cw := &captureWriter{ResponseWriter: w, limit: cfg.ListCacheMaxBytes}
+	var err error
+	if query.selects() {
+		var selected []json.RawMessage
+		if selected, err = selectFields(query, list); err == nil {
+			err = writeJSONArray(cw, selected)
+		}
+	} else {
+		err = writeJSONArray(cw, list)
+	}
+	if err != nil {
+		slog.WarnContext(r.Context(), "failed to stream student list", "error", err)
+		return
+	}
+	if !cw.overflow {
+		studentListCache.put(version, key, cachedList{body: cw.buf.Bytes(), link: link})
+	}
+}
+
+// studentListPage returns the students of one page of a list request
+// and the Link header of the next page. A keyset page is scanned from the
+// store and the scan stops one student past the page, so the request
+// holds a page however many students there are; $orderby, $top and $skip
+// need every match at hand to sort and count.
+func studentListPage(r *http.Request, page pageParams, query odataQuery) ([]Student, string) {
+	if query.offset {
+		return offsetPage(r, query, applyODataFilter(query, allStudents(r.Context())))
+	}
+	var students []Student
+	for s := range repoScan(r.Context(), studentStore, page.after) {
+		if query.filter != nil && !query.filter.match(reflect.ValueOf(s)) {
+			continue
+		}
+		students = append(students, s)
+		if len(students) > page.limit {
+			break
+		}
+	}
+	return paginate(r, students, page)
+}
+
+func getStudent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	student, exists := findStudent(r.Context(), id)
+
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	setLastModified(w, student.UpdatedAt)
+	setLinkedContentType(w, r)
+	writeJSONWithETag(w, r, studentRepresentation(r, student))
+}
+
+// studentDetail is a student with the related records named in ?expand=.
+type studentDetail struct {
+	Student
+	GPA       *GPAReport  `json:"gpa,omitempty"`
+	Guardians *[]Guardian `json:"guardians,omitempty"`
+	Awards    *[]Award    `json:"awards,omitempty"`
+	// Links are set with ?hateoas=true or a HAL Accept header.
+	Links *studentLinks `json:"_links,omitempty"`
+}
+
+func expandStudent(r *http.Request, student Student) studentDetail {
+	detail := studentDetail{Student: student}
+	if expands(r, "gpa") {
+		gpa := computeGPA(r.Context(), student.ID)
+		detail.GPA = &gpa
+	}
+	if expands(r, "guardians") {
+		guardians := studentGuardians(r, student.ID)
+		detail.Guardians = &guardians
+	}
+	if expands(r, "awards") {
+		awards := studentAwards(r, student.ID)
+		detail.Awards = &awards
+	}
+	return detail
+}
+
+func updateStudent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var updated Student
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateStudent(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student data", errs...)
+		return
+	}
+
+	departmentMutex.Lock()
+	if errs := programProblem(r.Context(), updated); len(errs) > 0 {
+		departmentMutex.Unlock()
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Program not found", errs...)
+		return
+	}
+	if current, exists := findStudent(r.Context(), id); exists && !unmodifiedSince(r, current.UpdatedAt) {
+		departmentMutex.Unlock()
+		writeProblem(w, r, http.StatusPreconditionFailed, "Student was modified after "+r.Header.Get("If-Unmodified-Since"))
+		return
+	}
+	updated.ID = id
+	updated.UpdatedAt = clock.Now().UTC()
+	before, exists := replaceStudent(r.Context(), id, updated)
+	departmentMutex.Unlock()
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	recordAudit(r, "update", id, &before, &updated)
+	publishEvent(r.Context(), StudentUpdated{Before: before, After: updated})
+
+	setLastModified(w, updated.UpdatedAt)
+	setLinkedContentType(w, r)
+	json.NewEncoder(w).Encode(studentRepresentation(r, updated))
+}
+
+func deleteStudent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	departmentMutex.Lock()
+	if current, exists := findStudent(r.Context(), id); exists && !unmodifiedSince(r, current.UpdatedAt) {
+		departmentMutex.Unlock()
+		writeProblem(w, r, http.StatusPreconditionFailed, "Student was modified after "+r.Header.Get("If-Unmodified-Since"))
+		return
+	}
+	before, exists := removeStudent(r.Context(), id)
+	departmentMutex.Unlock()
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	removeStudentEnrollments(r.Context(), id)
+	removeStudentDocuments(r.Context(), id)
+	removeStudentPhoto(r.Context(), id)
+	recordAudit(r, "delete", id, &before, nil)
+	publishEvent(r.Context(), StudentDeleted{Student: before})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getStudentSummary(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	student, exists := findStudent(r.Context(), id)
+
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	summary, err := cachedSummarizeStudent(r.Context(), student)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "summary generation failed", "student_id", id, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setContentLanguage(w, localeFromContext(r.Context()))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"summary": summary})
+}
+
+func homeHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "✅ Student API is working! Visit /students or /students/{id}")
+}
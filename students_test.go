@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestListStudentsPages(t *testing.T) {
+	api := newTestAPI(t, nil)
+	var created []Student
+	for i := range 7 {
+		var s Student
+		api.decode(t, "POST", "/v1/students", Student{Name: "Student " + strconv.Itoa(i), Age: 18 + i, Email: "s" + strconv.Itoa(i) + "@example.com"}, http.StatusCreated, &s)
+		created = append(created, s)
+	}
+	api.do(t, "DELETE", "/v1/students/"+strconv.Itoa(created[3].ID), nil)
+
+	// Students aged 20 and over, two at a time: 2, 4, 5 and 6, as 3 is gone.
+	path := "/v1/students?limit=2&$filter=" + url.QueryEscape("age ge 20")
+	var pages [][]int
+	for path != "" {
+		resp, data := api.do(t, "GET", path, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, resp.StatusCode, data)
+		}
+		var page []Student
+		if err := json.Unmarshal(data, &page); err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, s := range page {
+			ids = append(ids, s.ID)
+		}
+		pages = append(pages, ids)
+		path = ""
+		if m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			path = m[1]
+		}
+		if len(pages) > 3 {
+			t.Fatalf("more pages than expected: %v", pages)
+		}
+	}
+	want := [][]int{{created[2].ID, created[4].ID}, {created[5].ID, created[6].ID}}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	for i := range want {
+		if len(pages[i]) != 2 || pages[i][0] != want[i][0] || pages[i][1] != want[i][1] {
+			t.Fatalf("pages = %v, want %v", pages, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"studengo/ollama/ollamatest"
+)
+
+func TestStudentSummary(t *testing.T) {
+	api := newTestAPI(t, nil)
+	var ada, alan Student
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, &ada)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Alan Turing", Age: 21, Email: "alan@example.com"}, http.StatusCreated, &alan)
+
+	var got struct{ Summary string }
+	api.decode(t, "GET", "/v1/students/"+strconv.Itoa(ada.ID)+"/summary", nil, http.StatusOK, &got)
+	if got.Summary != "A diligent student." {
+		t.Errorf("summary = %q, want the model's reply", got.Summary)
+	}
+	requests := api.ollama.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0].Prompt, "Ada Lovelace") {
+		t.Fatalf("Ollama got %+v, want one prompt about Ada Lovelace", requests)
+	}
+
+	// A second request is answered from the summary cache.
+	api.decode(t, "GET", "/v1/students/"+strconv.Itoa(ada.ID)+"/summary", nil, http.StatusOK, &got)
+	if n := len(api.ollama.Requests()); n != 1 {
+		t.Errorf("Ollama called %d times, want the cached summary", n)
+	}
+
+	api.ollama.Set(ollamatest.WithStreamError(2, "model crashed"))
+	resp, data := api.do(t, "GET", "/v1/students/"+strconv.Itoa(alan.ID)+"/summary", nil)
+	if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(data), "model crashed") {
+		t.Errorf("summary after a stream error: status %d: %s", resp.StatusCode, data)
+	}
+}
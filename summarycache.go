@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// cachedSummaryEntry is a generated summary together with the student
+// data it was generated from.
+type cachedSummaryEntry struct {
+	fingerprint string
+	summary     string
+	createdAt   time.Time
+}
+
+// summaryKey is a student and the language of their summary.
+type summaryKey struct {
+	studentID int
+	language  string
+}
+
+// summaryCache holds a server's generated summaries.
+type summaryCache struct {
+	mu      sync.RWMutex
+	entries map[summaryKey]cachedSummaryEntry
+}
+
+// studentFingerprint identifies the inputs of a summary, so editing a
+// student or switching models makes its cached summary stale.
+func studentFingerprint(ctx context.Context, s Student) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d\x00%s", stateOf(ctx).llm.Model(), s.Name, s.Age, s.Email))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupSummary returns the cached summary of s in the language of ctx if
+// it is current.
+func lookupSummary(ctx context.Context, s Student) (string, bool) {
+	if cfg.SummaryCacheTTL <= 0 {
+		return "", false
+	}
+	cache := &stateOf(ctx).summaries
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	e, ok := cache.entries[summaryKey{s.ID, localeFromContext(ctx).Language}]
+	if !ok || e.fingerprint != studentFingerprint(ctx, s) || clock.Now().Sub(e.createdAt) > cfg.SummaryCacheTTL {
+		return "", false
+	}
+	return e.summary, true
+}
+
+// forgetSummary drops the cached summaries of a deleted student.
+func forgetSummary(ctx context.Context, id int) {
+	cache := &stateOf(ctx).summaries
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for key := range cache.entries {
+		if key.studentID == id {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+func storeSummary(ctx context.Context, s Student, summary string) {
+	if cfg.SummaryCacheTTL <= 0 {
+		return
+	}
+	cache := &stateOf(ctx).summaries
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.entries == nil {
+		cache.entries = make(map[summaryKey]cachedSummaryEntry)
+	}
+	cache.entries[summaryKey{s.ID, localeFromContext(ctx).Language}] = cachedSummaryEntry{fingerprint: studentFingerprint(ctx, s), summary: summary, createdAt: clock.Now()}
+}
+
+// cachedSummarizeStudent serves a summary from the cache, generating and
+// caching it on a miss. Summaries are cached per language.
+func cachedSummarizeStudent(ctx context.Context, s Student) (string, error) {
+	if summary, ok := lookupSummary(ctx, s); ok {
+		return summary, nil
+	}
+	summary, err := summarizeStudent(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	storeSummary(ctx, s, summary)
+	publishEvent(ctx, SummaryGenerated{StudentID: s.ID, Model: stateOf(ctx).llm.Model(), Summary: summary})
+	return summary, nil
+}
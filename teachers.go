@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Teacher struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required"`
+}
+
+func (t Teacher) entityID() int { return t.ID }
+
+func (t Teacher) withID(id int) Teacher {
+	t.ID = id
+	return t
+}
+
+var teacherStore Repository[Teacher] = newCollection[Teacher]("teachers")
+
+// validateTeacher lists every problem with a teacher submitted for create or update.
+func validateTeacher(t Teacher) []FieldError {
+	return checkFields(t)
+}
+
+// coursesTaughtBy returns the courses whose instructor is the teacher.
+func coursesTaughtBy(ctx context.Context, teacherID int) []Course {
+	list := []Course{}
+	for _, c := range repoList(ctx, courseStore) {
+		if c.InstructorID == teacherID {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func createTeacher(w http.ResponseWriter, r *http.Request) {
+	var teacher Teacher
+	if err := json.NewDecoder(r.Body).Decode(&teacher); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateTeacher(teacher); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher data", errs...)
+		return
+	}
+
+	teacher = repoInsert(r.Context(), teacherStore, teacher)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(teacher)
+}
+
+func getTeachers(w http.ResponseWriter, r *http.Request) {
+	page, errs := parsePage(r)
+	if len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid pagination parameters", errs...)
+		return
+	}
+
+	etag := listETag(teacherStore.Version(r.Context()), r)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	list, link := paginate(r, repoList(r.Context(), teacherStore), page)
+	if link != "" {
+		w.Header().Set("Link", link)
+	}
+	if err := writeJSONArray(w, list); err != nil {
+		slog.WarnContext(r.Context(), "failed to stream teacher list", "error", err)
+	}
+}
+
+func getTeacher(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher ID")
+		return
+	}
+
+	teacher, exists := repoFind(r.Context(), teacherStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Teacher not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, teacher)
+}
+
+func updateTeacher(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher ID")
+		return
+	}
+
+	var updated Teacher
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateTeacher(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher data", errs...)
+		return
+	}
+
+	if _, exists := repoReplace(r.Context(), teacherStore, id, updated); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Teacher not found")
+		return
+	}
+
+	updated.ID = id
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func deleteTeacher(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher ID")
+		return
+	}
+
+	courseCodeMutex.Lock()
+	defer courseCodeMutex.Unlock()
+
+	if n := len(coursesTaughtBy(r.Context(), id)); n > 0 {
+		writeProblem(w, r, http.StatusConflict, "Teacher is still the instructor of "+strconv.Itoa(n)+" courses")
+		return
+	}
+	if _, exists := repoRemove(r.Context(), teacherStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Teacher not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getTeacherCourses lists the courses a teacher is the instructor of.
+func getTeacherCourses(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid teacher ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), teacherStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Teacher not found")
+		return
+	}
+
+	writeJSONArray(w, coursesTaughtBy(r.Context(), id))
+}
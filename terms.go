@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Term is an academic term such as 2026-FALL. Enrollments and grades
+// refer to terms by name.
+type Term struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name" validate:"term"`
+	StartDate string `json:"start_date" validate:"date"`
+	EndDate   string `json:"end_date" validate:"date"`
+	Current   bool   `json:"current"`
+}
+
+func (t Term) entityID() int { return t.ID }
+
+func (t Term) withID(id int) Term {
+	t.ID = id
+	return t
+}
+
+var termStore Repository[Term] = newCollection[Term]("terms")
+
+// termMutex serializes term writes so names stay unique and at most one
+// term is current.
+var termMutex = &sync.Mutex{}
+
+// validateTerm lists every problem with a term submitted for create or update.
+func validateTerm(t Term) []FieldError {
+	errs := checkFields(t)
+	start, err1 := time.Parse(dateLayout, t.StartDate)
+	end, err2 := time.Parse(dateLayout, t.EndDate)
+	if err1 == nil && err2 == nil && !end.After(start) {
+		errs = append(errs, FieldError{Field: "end_date", Message: "must be after start_date"})
+	}
+	return errs
+}
+
+// findTerm looks a term up by name.
+func findTerm(ctx context.Context, name string) (Term, bool) {
+	for _, t := range termStore.List(ctx) {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Term{}, false
+}
+
+// currentTerm returns the term flagged as current, if any.
+func currentTerm(ctx context.Context) (Term, bool) {
+	for _, t := range termStore.List(ctx) {
+		if t.Current {
+			return t, true
+		}
+	}
+	return Term{}, false
+}
+
+// termFilter reads ?term= and checks that it names a known term.
+func termFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("term")))
+	if name == "" {
+		return "", true
+	}
+	if _, ok := findTerm(r.Context(), name); !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Unknown term", FieldError{Field: "term", Message: "does not exist"})
+		return "", false
+	}
+	return name, true
+}
+
+// saveTerm checks uniqueness and then stores the term via write, clearing
+// the current flag of every other term when this one becomes current.
+func saveTerm(ctx context.Context, t Term, write func(Term) (Term, bool)) (Term, bool, string) {
+	termMutex.Lock()
+	defer termMutex.Unlock()
+
+	if other, ok := findTerm(ctx, t.Name); ok && other.ID != t.ID {
+		return Term{}, true, "A term named " + t.Name + " already exists"
+	}
+	saved, ok := write(t)
+	if !ok {
+		return Term{}, false, ""
+	}
+	if saved.Current {
+		for _, other := range termStore.List(ctx) {
+			if other.Current && other.ID != saved.ID {
+				other.Current = false
+				repoReplace(ctx, termStore, other.ID, other)
+			}
+		}
+	}
+	return saved, true, ""
+}
+
+func decodeTerm(w http.ResponseWriter, r *http.Request) (Term, bool) {
+	var t Term
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term data", decodeErrors(err)...)
+		return t, false
+	}
+	t.Name = strings.ToUpper(strings.TrimSpace(t.Name))
+	if errs := validateTerm(t); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term data", errs...)
+		return t, false
+	}
+	return t, true
+}
+
+func createTerm(w http.ResponseWriter, r *http.Request) {
+	term, ok := decodeTerm(w, r)
+	if !ok {
+		return
+	}
+	term.ID = 0
+
+	term, _, conflict := saveTerm(r.Context(), term, func(t Term) (Term, bool) {
+		return repoInsert(r.Context(), termStore, t), true
+	})
+	if conflict != "" {
+		writeProblem(w, r, http.StatusConflict, conflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(term)
+}
+
+func getTerms(w http.ResponseWriter, r *http.Request) {
+	writeJSONArray(w, repoList(r.Context(), termStore))
+}
+
+func getTerm(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID")
+		return
+	}
+
+	term, exists := repoFind(r.Context(), termStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Term not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, term)
+}
+
+func getCurrentTerm(w http.ResponseWriter, r *http.Request) {
+	term, exists := currentTerm(r.Context())
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "No term is marked current")
+		return
+	}
+
+	writeJSONWithETag(w, r, term)
+}
+
+func updateTerm(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID")
+		return
+	}
+
+	updated, ok := decodeTerm(w, r)
+	if !ok {
+		return
+	}
+	updated.ID = id
+
+	// Renaming would orphan the records that refer to the term by name.
+	if before, exists := repoFind(r.Context(), termStore, id); exists && before.Name != updated.Name && termInUse(r.Context(), before.Name) {
+		writeProblem(w, r, http.StatusConflict, "Term "+before.Name+" is in use and cannot be renamed")
+		return
+	}
+
+	updated, exists, conflict := saveTerm(r.Context(), updated, func(t Term) (Term, bool) {
+		_, ok := repoReplace(r.Context(), termStore, id, t)
+		return t, ok
+	})
+	if conflict != "" {
+		writeProblem(w, r, http.StatusConflict, conflict)
+		return
+	}
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Term not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// termInUse reports whether any enrollment, grade or award refers to the term.
+func termInUse(ctx context.Context, name string) bool {
+	for _, e := range enrollmentStore.List(ctx) {
+		if e.Term == name {
+			return true
+		}
+	}
+	for _, g := range gradeStore.List(ctx) {
+		if g.Term == name {
+			return true
+		}
+	}
+	for _, a := range awardStore.List(ctx) {
+		if a.Term == name {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteTerm(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID")
+		return
+	}
+
+	termMutex.Lock()
+	defer termMutex.Unlock()
+
+	term, exists := repoFind(r.Context(), termStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Term not found")
+		return
+	}
+	if termInUse(r.Context(), term.Name) {
+		writeProblem(w, r, http.StatusConflict, "Term "+term.Name+" still has enrollments, grades or awards")
+		return
+	}
+	repoRemove(r.Context(), termStore, id)
+	w.WriteHeader(http.StatusNoContent)
+}
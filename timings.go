@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// requestTimings accumulates how long a request spent in each phase, such
+// as store access or Ollama calls, for the slow-request log.
+type requestTimings struct {
+	mu     sync.Mutex
+	phases map[string]time.Duration
+}
+
+func withTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timingsKey, &requestTimings{phases: map[string]time.Duration{}})
+}
+
+// trackTiming adds the time elapsed since start to a phase of the request.
+// It is meant to be deferred.
+func trackTiming(ctx context.Context, phase string, start time.Time) {
+	t, ok := ctx.Value(timingsKey).(*requestTimings)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.phases[phase] += time.Since(start)
+	t.mu.Unlock()
+}
+
+// timingAttrs returns the breakdown as log attributes in milliseconds.
+func timingAttrs(ctx context.Context) []any {
+	t, ok := ctx.Value(timingsKey).(*requestTimings)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var attrs []any
+	for phase, d := range t.phases {
+		attrs = append(attrs, phase+"_ms", d.Milliseconds())
+	}
+	return attrs
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TranscriptCourse is one course line of a transcript. Grade is empty
+// while the course is still in progress.
+type TranscriptCourse struct {
+	Code    string `json:"code"`
+	Title   string `json:"title"`
+	Credits int    `json:"credits"`
+	Grade   string `json:"grade,omitempty"`
+	Scale   string `json:"scale,omitempty"`
+}
+
+// TranscriptTerm groups the courses taken in one term.
+type TranscriptTerm struct {
+	Term    string             `json:"term"`
+	Courses []TranscriptCourse `json:"courses"`
+	GPA     *float64           `json:"gpa"`
+}
+
+// Transcript is a student's academic record assembled from enrollments
+// and grades.
+type Transcript struct {
+	Student     Student          `json:"student"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Terms       []TranscriptTerm `json:"terms"`
+	GPA         *float64         `json:"gpa"`
+	Credits     int              `json:"credits"`
+}
+
+func buildTranscript(ctx context.Context, student Student) Transcript {
+	grades := map[int]Grade{}
+	for _, g := range studentGrades(ctx, student.ID, "") {
+		grades[g.EnrollmentID] = g
+	}
+	gpa := computeGPA(ctx, student.ID)
+	termGPA := map[string]float64{}
+	for _, t := range gpa.Terms {
+		termGPA[t.Term] = t.GPA
+	}
+
+	byTerm := map[string]*TranscriptTerm{}
+	for _, e := range studentEnrollments(ctx, student.ID) {
+		course, ok := courseStore.Find(ctx, e.CourseID)
+		if !ok {
+			continue
+		}
+		line := TranscriptCourse{Code: course.Code, Title: course.Title, Credits: course.Credits}
+		term := e.Term
+		if g, ok := grades[e.ID]; ok {
+			line.Grade, line.Scale = g.Value, g.Scale
+			term = g.Term
+		}
+		t, ok := byTerm[term]
+		if !ok {
+			t = &TranscriptTerm{Term: term, Courses: []TranscriptCourse{}}
+			if v, ok := termGPA[term]; ok {
+				t.GPA = &v
+			}
+			byTerm[term] = t
+		}
+		t.Courses = append(t.Courses, line)
+	}
+
+	transcript := Transcript{
+		Student:     student,
+		GeneratedAt: clock.Now().UTC(),
+		Terms:       []TranscriptTerm{},
+		GPA:         gpa.GPA,
+		Credits:     gpa.Credits,
+	}
+	for _, t := range byTerm {
+		transcript.Terms = append(transcript.Terms, *t)
+	}
+	slices.SortFunc(transcript.Terms, func(a, b TranscriptTerm) int { return termOrder(a.Term) - termOrder(b.Term) })
+	return transcript
+}
+
+// wantsPDF reports whether the client asked for a PDF with ?format=pdf or
+// an Accept header preferring application/pdf.
+func wantsPDF(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "pdf"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/pdf")
+}
+
+// getTranscript returns the transcript as JSON, or as a PDF document.
+func getTranscript(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	student, exists := findStudent(r.Context(), id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	transcript := buildTranscript(r.Context(), student)
+	if !wantsPDF(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transcript)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="transcript-`+strconv.Itoa(id)+`.pdf"`)
+	w.Write(renderTranscriptPDF(transcript))
+}
+
+// transcriptLines lays the transcript out as plain text lines for the PDF.
+func transcriptLines(t Transcript) []string {
+	lines := []string{
+		"Academic Transcript",
+		"",
+		"Student: " + t.Student.Name + " (ID " + strconv.Itoa(t.Student.ID) + ")",
+		"Email: " + t.Student.Email,
+		"Generated: " + t.GeneratedAt.Format(time.RFC1123),
+		"",
+	}
+	for _, term := range t.Terms {
+		name := term.Term
+		if name == "" {
+			name = "No term"
+		}
+		lines = append(lines, name)
+		for _, c := range term.Courses {
+			grade := c.Grade
+			if grade == "" {
+				grade = "In progress"
+			}
+			lines = append(lines, "    "+padRight(c.Code, 10)+padRight(c.Title, 36)+padRight(strconv.Itoa(c.Credits)+" cr", 8)+grade)
+		}
+		if term.GPA != nil {
+			lines = append(lines, "    Term GPA: "+strconv.FormatFloat(*term.GPA, 'f', 2, 64))
+		}
+		lines = append(lines, "")
+	}
+	gpa := "n/a"
+	if t.GPA != nil {
+		gpa = strconv.FormatFloat(*t.GPA, 'f', 2, 64)
+	}
+	return append(lines, "Cumulative GPA: "+gpa+"    Credits: "+strconv.Itoa(t.Credits))
+}
+
+func padRight(s string, n int) string {
+	if len(s) >= n {
+		return s[:n-1] + " "
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
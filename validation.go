@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request bodies are checked against `validate` struct tags, so the rules
+// of a resource sit next to its fields. A tag is a comma-separated list of
+// rules:
+//
+//	required     not empty: a non-blank string, a positive ID, a set time
+//	positive     greater than zero
+//	nonnegative  zero or more
+//	omitempty    skip the other rules when the field is empty
+//	enum=NAME    one of the values listed in enums
+//	date         a date such as 2026-09-01
+//	term         a term such as 2026-FALL
+//	url          an absolute http or https URL
+//	each         apply the rules after it to every element of a slice
+//
+// Struct fields and slices of structs are checked through, their errors
+// naming the path as in meetings[1].day. Every violation is reported, not
+// just the first. Rules spanning several fields stay in the validate
+// functions of each resource, which start from checkFields.
+
+// enums are the value sets enum= rules refer to.
+var enums = map[string][]string{
+	"attendance_status": attendanceStatuses,
+	"award_kind":        awardKinds,
+	"contact_method":    contactMethods,
+	"event_type":        eventTypes,
+	"grade_scale":       {scaleLetter, scalePercent, scalePassFail},
+	"relationship":      guardianRelationships,
+	"transaction_kind":  {kindCharge, kindPayment},
+	"weekday":           weekdays,
+}
+
+// checkFields applies the validate tags of v, a struct, and returns the
+// violations in field order.
+func checkFields(v any) []FieldError {
+	return checkStruct(reflect.ValueOf(v), "")
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	// rules apply to the field, each to every element of a slice.
+	rules, each []string
+	omitempty   bool
+}
+
+var rulesCache sync.Map // reflect.Type -> []fieldRules
+
+// rulesOf parses the tags of t once. An unknown rule is a programming
+// error and panics the first time the type is checked.
+func rulesOf(t reflect.Type) []fieldRules {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+	var list []fieldRules
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fr := fieldRules{index: i, name: name}
+		target := &fr.rules
+		for _, r := range strings.Split(f.Tag.Get("validate"), ",") {
+			switch r {
+			case "":
+			case "omitempty":
+				fr.omitempty = true
+			case "each":
+				target = &fr.each
+			default:
+				rule, arg, _ := strings.Cut(r, "=")
+				if !knownRules[rule] || (rule == "enum") != (arg != "") || (rule == "enum" && enums[arg] == nil) {
+					panic(fmt.Sprintf("%s.%s: unknown validate rule %q", t.Name(), f.Name, r))
+				}
+				*target = append(*target, r)
+			}
+		}
+		list = append(list, fr)
+	}
+	rulesCache.Store(t, list)
+	return list
+}
+
+var knownRules = map[string]bool{
+	"required": true, "positive": true, "nonnegative": true, "enum": true, "date": true, "term": true, "url": true,
+}
+
+func checkStruct(v reflect.Value, prefix string) []FieldError {
+	var errs []FieldError
+	for _, fr := range rulesOf(v.Type()) {
+		fv := v.Field(fr.index)
+		path := prefix + fr.name
+		if fr.omitempty && fv.IsZero() {
+			continue
+		}
+		if msg := checkValue(fv, fr.rules); msg != "" {
+			errs = append(errs, FieldError{Field: path, Message: msg})
+			continue
+		}
+		switch {
+		case fv.Kind() == reflect.Slice:
+			for i := range fv.Len() {
+				elem := fv.Index(i)
+				elemPath := path + "[" + strconv.Itoa(i) + "]"
+				if msg := checkValue(elem, fr.each); msg != "" {
+					errs = append(errs, FieldError{Field: elemPath, Message: msg})
+				} else if elem.Kind() == reflect.Struct && elem.Type() != reflect.TypeFor[time.Time]() {
+					errs = append(errs, checkStruct(elem, elemPath+".")...)
+				}
+			}
+		case fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeFor[time.Time]():
+			errs = append(errs, checkStruct(fv, path+".")...)
+		}
+	}
+	return errs
+}
+
+// checkValue applies rules to v and returns the message of the first one
+// it breaks, or "".
+func checkValue(v reflect.Value, rules []string) string {
+	for _, r := range rules {
+		rule, arg, _ := strings.Cut(r, "=")
+		if msg := checkRule(v, rule, arg); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func checkRule(v reflect.Value, rule, arg string) string {
+	switch rule {
+	case "required":
+		switch v.Kind() {
+		case reflect.String:
+			if strings.TrimSpace(v.String()) == "" {
+				return "is required"
+			}
+		case reflect.Int, reflect.Int64:
+			if v.Int() <= 0 {
+				return "is required"
+			}
+		default:
+			if v.IsZero() {
+				return "is required"
+			}
+		}
+	case "positive":
+		switch v.Kind() {
+		case reflect.Float64:
+			if v.Float() <= 0 {
+				return "must be positive"
+			}
+		default:
+			if v.Int() <= 0 {
+				return "must be a positive integer"
+			}
+		}
+	case "nonnegative":
+		if (v.Kind() == reflect.Float64 && v.Float() < 0) || (v.Kind() != reflect.Float64 && v.Int() < 0) {
+			return "must not be negative"
+		}
+	case "enum":
+		if !slices.Contains(enums[arg], v.String()) {
+			return "must be one of " + strings.Join(enums[arg], ", ")
+		}
+	case "date":
+		if _, err := time.Parse(dateLayout, v.String()); err != nil {
+			return "must be a date such as 2026-09-01"
+		}
+	case "term":
+		if !termPattern.MatchString(v.String()) {
+			return "must look like 2026-FALL (SPRING, SUMMER, FALL or WINTER)"
+		}
+	case "url":
+		if u, err := url.Parse(v.String()); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an absolute http or https URL"
+		}
+	}
+	return ""
+}
+
+// describeRules adds what a validate tag enforces to the OpenAPI schema of
+// its field, so the document lists the same values the API accepts.
+func describeRules(schema map[string]any, tag string) {
+	for _, r := range strings.Split(tag, ",") {
+		rule, arg, _ := strings.Cut(r, "=")
+		switch rule {
+		case "each":
+			if items, ok := schema["items"].(map[string]any); ok {
+				schema = items
+			}
+		case "enum":
+			schema["enum"] = enums[arg]
+		case "date":
+			schema["format"] = "date"
+		case "url":
+			schema["format"] = "uri"
+		case "term":
+			schema["pattern"] = termPattern.String()
+		}
+	}
+}
+
+// decodeErrors turns the error of decoding a JSON request body into field
+// errors, so a value of the wrong type is reported against its field like
+// any other violation.
+func decodeErrors(err error) []FieldError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return []FieldError{{Field: field, Message: "must be " + jsonKind(typeErr.Type)}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Field: "body", Message: "is required"}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Field: "body", Message: "must be valid JSON: " + err.Error()}}
+	case errors.As(err, &timeErr):
+		return []FieldError{{Field: "body", Message: "has a time that is not RFC 3339, such as 2026-09-01T09:00:00Z"}}
+	}
+	return []FieldError{{Field: "body", Message: err.Error()}}
+}
+
+// jsonKind names the JSON type that decodes into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		if t == reflect.TypeFor[time.Time]() {
+			return "an RFC 3339 time"
+		}
+		return "an object"
+	}
+	return "a " + t.String()
+}
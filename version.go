@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Build metadata, injected at build time with
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.gitCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	gitCommit = ""
+	buildTime = ""
+)
+
+// startTime is when the process started, for uptime reporting.
+var startTime = time.Now()
+
+// buildInfo describes the running binary. Commit and build time fall back
+// to the VCS stamp the Go toolchain embeds when ldflags were not given.
+func buildInfo() map[string]string {
+	commit, built := gitCommit, buildTime
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && built == "":
+				built = s.Value
+			}
+		}
+	}
+	return map[string]string{
+		"version":    version,
+		"git_commit": commit,
+		"build_time": built,
+		"go_version": runtime.Version(),
+	}
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildInfo())
+}
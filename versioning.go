@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// API versioning
+//
+// The resource API is served under /v1. Health checks, metrics, the API
+// description, the admin dashboard, share links, GraphQL and the gRPC
+// gateway stay unversioned: they are either not client contracts or
+// versioned by their own schema.
+//
+// Within a version only backwards-compatible changes are made: new
+// endpoints, new optional query parameters, new response fields, and
+// looser validation. Anything an existing client could notice is a
+// breaking change and goes to the next version. That includes removing or
+// renaming a field, changing its type or meaning, making validation
+// stricter, and changing a status code.
+//
+// /v2 is introduced as a second subrouter next to v1. Handlers that change
+// get a v2 variant, and unchanged ones are registered under both prefixes.
+// Once v2 ships, v1 keeps working for at least six months. Its responses
+// then carry Deprecation and Sunset headers, and the removal date is
+// announced in the changelog.
+//
+// Requests to the unversioned paths used before /v1 are served as v1 while
+// legacy_paths is on. Such responses carry a Deprecation header and a Link
+// to the /v1 path, and legacy_requests_total counts them so the aliases
+// can be turned off once clients have moved.
+
+// apiV1 is the prefix of the current API version.
+const apiV1 = "/v1"
+
+var legacyRequests = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "legacy_requests_total",
+	Help: "Requests made to unversioned API paths.",
+})
+
+// apiVersion registers routes under a version prefix. It adds the prefix to
+// each route instead of using a subrouter, so that a wrong method still
+// gets 405 rather than falling through to the 404 handler.
+type apiVersion struct {
+	router *mux.Router
+	prefix string
+}
+
+func (v apiVersion) Handle(path string, h http.Handler) *mux.Route {
+	return v.router.Handle(v.prefix+path, h)
+}
+
+func (v apiVersion) HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) *mux.Route {
+	return v.router.HandleFunc(v.prefix+path, f)
+}
+
+// resourcePath strips the version prefix, turning /v1/students into
+// /students.
+func resourcePath(path string) string {
+	if rest, ok := strings.CutPrefix(path, apiV1); ok && strings.HasPrefix(rest, "/") {
+		return rest
+	}
+	return path
+}
+
+// routes reports whether router has a route for req, whatever its method.
+func routes(router *mux.Router, req *http.Request) bool {
+	var match mux.RouteMatch
+	router.Match(req, &match)
+	return match.MatchErr == nil || match.MatchErr == mux.ErrMethodMismatch
+}
+
+// legacyPaths serves the unversioned paths that predate /v1 as their v1
+// equivalents. Paths the router knows as they are, such as /healthz, are
+// left alone.
+func legacyPaths(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.LegacyPaths || strings.HasPrefix(r.URL.Path, apiV1+"/") || routes(router, r) {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		u := *r.URL
+		u.Path = apiV1 + r.URL.Path
+		u.RawPath = ""
+		versioned := r.Clone(r.Context())
+		versioned.URL = &u
+		if !routes(router, versioned) {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		legacyRequests.Inc()
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", "<"+u.EscapedPath()+`>; rel="successor-version"`)
+		router.ServeHTTP(w, versioned)
+	})
+}
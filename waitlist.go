@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// WaitlistEntry holds a student's place in line for a full course or
+// section. Position is computed when the entry is read: 1 is next in line.
+type WaitlistEntry struct {
+	ID        int       `json:"id"`
+	StudentID int       `json:"student_id"`
+	CourseID  int       `json:"course_id"`
+	SectionID int       `json:"section_id,omitempty"`
+	Term      string    `json:"term,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+	Position  int       `json:"position"`
+}
+
+func (e WaitlistEntry) entityID() int { return e.ID }
+
+func (e WaitlistEntry) withID(id int) WaitlistEntry {
+	e.ID = id
+	return e
+}
+
+// waitlistStore is guarded by enrollmentMutex like enrollments, since
+// every change to one can change the other.
+var waitlistStore Repository[WaitlistEntry] = newCollection[WaitlistEntry]("waitlist")
+
+// sameQueue reports whether two entries wait for the same seats.
+func sameQueue(a, b WaitlistEntry) bool {
+	return a.CourseID == b.CourseID && a.SectionID == b.SectionID && a.Term == b.Term
+}
+
+// waitlistWhere returns the entries matching keep in line order, with
+// their positions filled in.
+func waitlistWhere(ctx context.Context, keep func(WaitlistEntry) bool) []WaitlistEntry {
+	all := repoList(ctx, waitlistStore)
+	list := []WaitlistEntry{}
+	for _, e := range all {
+		if !keep(e) {
+			continue
+		}
+		for _, ahead := range all {
+			if ahead.ID > e.ID {
+				break
+			}
+			if sameQueue(ahead, e) {
+				e.Position++
+			}
+		}
+		list = append(list, e)
+	}
+	return list
+}
+
+// seatAvailable reports whether the course, and the section if one is
+// named, have room for another student in the term.
+func seatAvailable(ctx context.Context, course Course, section Section, term string) bool {
+	courseCount, sectionCount := 0, 0
+	for _, e := range courseEnrollments(ctx, course.ID) {
+		if e.Term != term {
+			continue
+		}
+		courseCount++
+		if section.ID != 0 && e.SectionID == section.ID {
+			sectionCount++
+		}
+	}
+	if courseCount >= course.Capacity {
+		return false
+	}
+	return section.ID == 0 || section.Capacity == 0 || sectionCount < section.Capacity
+}
+
+// promoteWaitlist enrolls waiting students of a course, first come first
+// served, for as long as seats are free. Students whose schedule now
+// conflicts keep their place. The caller must hold enrollmentMutex.
+func promoteWaitlist(ctx context.Context, courseID int) {
+	course, ok := courseStore.Find(ctx, courseID)
+	if !ok {
+		return
+	}
+	for _, w := range waitlistWhere(ctx, func(w WaitlistEntry) bool { return w.CourseID == courseID }) {
+		var section Section
+		if w.SectionID != 0 {
+			if section, ok = sectionStore.Find(ctx, w.SectionID); !ok {
+				continue
+			}
+		}
+		if !seatAvailable(ctx, course, section, w.Term) {
+			continue
+		}
+		if w.SectionID != 0 && len(scheduleConflicts(ctx, w.StudentID, w.Term, section)) > 0 {
+			continue
+		}
+
+		repoRemove(ctx, waitlistStore, w.ID)
+		enrollment := repoInsert(ctx, enrollmentStore, Enrollment{
+			StudentID:  w.StudentID,
+			CourseID:   w.CourseID,
+			SectionID:  w.SectionID,
+			Term:       w.Term,
+			EnrolledAt: clock.Now().UTC(),
+		})
+		publishEvent(ctx, EnrollmentAdded{Enrollment: enrollment, Promoted: true})
+	}
+}
+
+// getCourseWaitlist lists who is waiting for a course, in line order.
+func getCourseWaitlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), courseStore, courseID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Course not found")
+		return
+	}
+	term, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSONArray(w, waitlistWhere(r.Context(), func(e WaitlistEntry) bool {
+		return e.CourseID == courseID && (term == "" || e.Term == term)
+	}))
+}
+
+func getStudentWaitlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	if _, exists := findStudent(r.Context(), studentID); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	writeJSONArray(w, waitlistWhere(r.Context(), func(e WaitlistEntry) bool { return e.StudentID == studentID }))
+}
+
+// leaveWaitlist takes a student off a waitlist.
+func leaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	studentID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+	entryID, err := strconv.Atoi(params["entry_id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid waitlist entry ID")
+		return
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+
+	e, exists := repoFind(r.Context(), waitlistStore, entryID)
+	if !exists || e.StudentID != studentID {
+		writeProblem(w, r, http.StatusNotFound, "Waitlist entry not found")
+		return
+	}
+	repoRemove(r.Context(), waitlistStore, entryID)
+	w.WriteHeader(http.StatusNoContent)
+}
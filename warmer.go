@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// warmWindow is a daily time range such as 01:00-05:00, which may wrap
+// past midnight.
+type warmWindow struct {
+	from, to time.Duration
+}
+
+func parseWarmWindow(raw string) (warmWindow, error) {
+	var fh, fm, th, tm int
+	if _, err := fmt.Sscanf(raw, "%d:%d-%d:%d", &fh, &fm, &th, &tm); err != nil {
+		return warmWindow{}, fmt.Errorf("%q is not of the form HH:MM-HH:MM", raw)
+	}
+	if fh > 23 || th > 23 || fm > 59 || tm > 59 || fh < 0 || th < 0 || fm < 0 || tm < 0 {
+		return warmWindow{}, fmt.Errorf("%q is not a valid time range", raw)
+	}
+	return warmWindow{
+		from: time.Duration(fh)*time.Hour + time.Duration(fm)*time.Minute,
+		to:   time.Duration(th)*time.Hour + time.Duration(tm)*time.Minute,
+	}, nil
+}
+
+func (w warmWindow) contains(t time.Time) bool {
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.from <= w.to {
+		return d >= w.from && d < w.to
+	}
+	return d >= w.from || d < w.to
+}
+
+// warmSummaries is run by the summary_warm job during the configured
+// off-peak window, so interactive requests are served from the cache. It
+// generates summaries for students whose cached summary is
+// missing or stale, most recently created first, up to one batch per run.
+// Updating a student changes its fingerprint, so recently edited students
+// are picked up here. Summaries are warmed in English, the language of
+// requests without Accept-Language.
+func warmSummaries(ctx context.Context) {
+	var stale []Student
+	for _, s := range studentStore.List(ctx) {
+		if _, ok := lookupSummary(ctx, s); !ok {
+			stale = append(stale, s)
+		}
+	}
+	slices.SortFunc(stale, func(a, b Student) int { return b.ID - a.ID })
+	if len(stale) > cfg.SummaryWarmBatch {
+		stale = stale[:cfg.SummaryWarmBatch]
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	start := time.Now()
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	sem := make(chan struct{}, cfg.SummaryWarmConcurrency)
+	for _, s := range stale {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if _, err := cachedSummarizeStudent(ctx, s); err != nil {
+				slog.WarnContext(ctx, "summary warming failed", "student_id", s.ID, "error", err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	slog.Info("summary warming finished", "students", len(stale), "failed", failed, "duration_ms", time.Since(start).Milliseconds())
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Webhook is an integrator's subscription to domain events. Events lists
+// the event types to deliver; empty means all of them. Deliveries are
+// signed with Secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url" validate:"url"`
+	Events    []string  `json:"events" validate:"each,enum=event_type"`
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h Webhook) entityID() int { return h.ID }
+
+func (h Webhook) withID(id int) Webhook {
+	h.ID = id
+	return h
+}
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook.
+// Payload is kept so every attempt sends identical, identically signed bytes.
+// A delivery that runs out of attempts is failed and dead-lettered: it is
+// kept, whatever the retention, until it is replayed or discarded.
+type WebhookDelivery struct {
+	ID             int       `json:"id"`
+	WebhookID      int       `json:"webhook_id"`
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time `json:"next_attempt_at,omitzero"`
+	CreatedAt      time.Time `json:"created_at"`
+	DeliveredAt    time.Time `json:"delivered_at,omitzero"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at,omitzero"`
+	// Replays counts the times the delivery was taken out of the
+	// dead-letter list for another round of attempts.
+	Replays int             `json:"replays,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (d WebhookDelivery) entityID() int { return d.ID }
+
+func (d WebhookDelivery) withID(id int) WebhookDelivery {
+	d.ID = id
+	return d
+}
+
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryFailed    = "failed"
+)
+
+var (
+	webhookStore  Repository[Webhook]         = newCollection[Webhook]("webhooks")
+	deliveryStore Repository[WebhookDelivery] = newCollection[WebhookDelivery]("webhook_deliveries")
+)
+
+// webhookClient sends deliveries. NewServer replaces it once the configuration
+// is loaded.
+var webhookClient = newWebhookClient(cfg)
+
+func newWebhookClient(c Config) *http.Client {
+	return &http.Client{
+		Timeout:   c.WebhookTimeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		// A redirect would resend the payload somewhere the integrator
+		// did not register.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// validateWebhook lists every problem with a submitted webhook.
+func validateWebhook(h Webhook) []FieldError {
+	return checkFields(h)
+}
+
+// webhookView hides the signing secret.
+func webhookView(h Webhook) Webhook {
+	h.Secret = ""
+	return h
+}
+
+func (h Webhook) wants(eventType string) bool {
+	return h.Active && (len(h.Events) == 0 || slices.Contains(h.Events, eventType))
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDispatcher queues deliveries for a pool of senders. queued
+// tracks deliveries waiting in or taken from the queue, so the retry
+// job does not send one twice.
+type webhookDispatcher struct {
+	queue  chan int
+	mu     sync.Mutex
+	queued map[int]bool
+}
+
+func newWebhookDispatcher() *webhookDispatcher {
+	return &webhookDispatcher{queue: make(chan int, 1024), queued: map[int]bool{}}
+}
+
+// enqueue hands a delivery to the senders unless it is already queued. A
+// full queue leaves it pending for the retry job.
+func (d *webhookDispatcher) enqueue(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queued[id] {
+		return
+	}
+	select {
+	case d.queue <- id:
+		d.queued[id] = true
+	default:
+	}
+}
+
+func (d *webhookDispatcher) done(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.queued, id)
+}
+
+// recordDeliveries is the event bus subscriber that creates a delivery
+// for every webhook interested in the event.
+func recordDeliveries(ctx context.Context, e Event) {
+	var payload []byte
+	for _, h := range repoList(ctx, webhookStore) {
+		if !h.wants(e.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(e); err != nil {
+				slog.ErrorContext(ctx, "failed to encode webhook payload", "event", e.Type, "error", err)
+				return
+			}
+		}
+		now := clock.Now().UTC()
+		d := repoInsert(ctx, deliveryStore, WebhookDelivery{
+			WebhookID:     h.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Status:        deliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			Payload:       payload,
+		})
+		stateOf(ctx).dispatcher.enqueue(d.ID)
+	}
+}
+
+// startWebhookDispatcher starts the senders, which stop when ctx is
+// cancelled. Retries are requeued by the webhook_retry job.
+func startWebhookDispatcher(ctx context.Context) {
+	dispatcher := stateOf(ctx).dispatcher
+	for range cfg.WebhookWorkers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-dispatcher.queue:
+					attemptDelivery(ctx, id)
+					dispatcher.done(id)
+				}
+			}
+		}()
+	}
+}
+
+// sweepDeliveries queues pending deliveries whose next attempt is due and
+// returns how many there were.
+func sweepDeliveries(ctx context.Context) int {
+	now, due := clock.Now(), 0
+	for _, d := range repoList(ctx, deliveryStore) {
+		if d.Status == deliveryPending && !d.NextAttemptAt.After(now) {
+			stateOf(ctx).dispatcher.enqueue(d.ID)
+			due++
+		}
+	}
+	return due
+}
+
+// retryDelay is the exponential backoff before attempt n+1, with up to
+// 20% jitter so failing receivers are not hit in lockstep.
+func retryDelay(attempts int) time.Duration {
+	d := cfg.WebhookRetryBase << (attempts - 1)
+	if d <= 0 || d > time.Hour {
+		d = time.Hour
+	}
+	return d + time.Duration(random.Int64N(int64(d)/5+1))
+}
+
+// attemptDelivery makes one attempt at a delivery and records the outcome.
+func attemptDelivery(ctx context.Context, id int) {
+	d, ok := deliveryStore.Find(ctx, id)
+	if !ok || d.Status != deliveryPending {
+		return
+	}
+	hook, ok := webhookStore.Find(ctx, d.WebhookID)
+	if !ok {
+		d.Status, d.LastError = deliveryFailed, "webhook deleted"
+		deliveryStore.Replace(ctx, id, d)
+		return
+	}
+
+	d.Attempts++
+	status, err := sendDelivery(ctx, hook, d)
+	d.LastStatusCode = status
+	if err == nil {
+		d.Status, d.LastError = deliverySucceeded, ""
+		d.DeliveredAt, d.NextAttemptAt = clock.Now().UTC(), time.Time{}
+	} else {
+		d.LastError = err.Error()
+		if d.Attempts >= cfg.WebhookMaxAttempts {
+			d.Status, d.NextAttemptAt = deliveryFailed, time.Time{}
+			d.DeadLetteredAt = clock.Now().UTC()
+			slog.WarnContext(ctx, "webhook delivery dead-lettered", "webhook_id", hook.ID, "delivery_id", id, "attempts", d.Attempts, "error", err)
+		} else {
+			d.NextAttemptAt = clock.Now().UTC().Add(retryDelay(d.Attempts))
+		}
+	}
+	deliveryStore.Replace(ctx, id, d)
+}
+
+func sendDelivery(ctx context.Context, hook Webhook, d WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "studengo-webhooks/"+version)
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Signature", signPayload(hook.Secret, d.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, &deliveryError{status: resp.StatusCode}
+	}
+	return resp.StatusCode, nil
+}
+
+type deliveryError struct {
+	status int
+}
+
+func (e *deliveryError) Error() string {
+	return "receiver answered " + strconv.Itoa(e.status)
+}
+
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateWebhook(hook); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook data", errs...)
+		return
+	}
+
+	if hook.Secret == "" {
+		hook.Secret = random.Text()
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	hook.Active = true
+	hook.CreatedAt = clock.Now().UTC()
+	hook = repoInsert(r.Context(), webhookStore, hook)
+
+	// The only response that includes the secret.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	list := []Webhook{}
+	for _, h := range repoList(r.Context(), webhookStore) {
+		list = append(list, webhookView(h))
+	}
+	writeJSONArray(w, list)
+}
+
+func getWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	hook, exists := repoFind(r.Context(), webhookStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	writeJSONWithETag(w, r, webhookView(hook))
+}
+
+// updateWebhook replaces the URL, events and active flag. The secret is
+// kept unless a new one is given.
+func updateWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	var updated Webhook
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook data", decodeErrors(err)...)
+		return
+	}
+	if errs := validateWebhook(updated); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook data", errs...)
+		return
+	}
+
+	before, exists := repoFind(r.Context(), webhookStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if updated.Secret == "" {
+		updated.Secret = before.Secret
+	}
+	if updated.Events == nil {
+		updated.Events = []string{}
+	}
+	updated.ID, updated.CreatedAt = id, before.CreatedAt
+	repoReplace(r.Context(), webhookStore, id, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookView(updated))
+}
+
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	if _, exists := repoRemove(r.Context(), webhookStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	for _, d := range deliveryStore.List(r.Context()) {
+		if d.WebhookID == id {
+			repoRemove(r.Context(), deliveryStore, d.ID)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookDeliveries is the delivery log of a webhook, newest first,
+// optionally filtered by ?status=.
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	if _, exists := repoFind(r.Context(), webhookStore, id); !exists {
+		writeProblem(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	list := []WebhookDelivery{}
+	for _, d := range repoList(r.Context(), deliveryStore) {
+		if d.WebhookID == id && (status == "" || d.Status == status) {
+			list = append(list, d)
+		}
+	}
+	slices.Reverse(list)
+	writeJSONArray(w, list)
+}
+
+// deadLetters lists the failed deliveries, of one webhook when webhookID
+// is not 0, most recently dead-lettered first.
+func deadLetters(ctx context.Context, webhookID int) []WebhookDelivery {
+	list := []WebhookDelivery{}
+	for _, d := range repoList(ctx, deliveryStore) {
+		if d.Status == deliveryFailed && (webhookID == 0 || d.WebhookID == webhookID) {
+			list = append(list, d)
+		}
+	}
+	slices.SortStableFunc(list, func(a, b WebhookDelivery) int { return b.DeadLetteredAt.Compare(a.DeadLetteredAt) })
+	return list
+}
+
+// replay gives a dead-lettered delivery a fresh round of attempts. It
+// keeps its ID and payload, so receivers can tell it from a new event by
+// X-Webhook-Delivery, and returns the delivery as queued.
+func replay(ctx context.Context, d WebhookDelivery) WebhookDelivery {
+	d.Status, d.Attempts, d.Replays = deliveryPending, 0, d.Replays+1
+	d.NextAttemptAt, d.DeadLetteredAt = clock.Now().UTC(), time.Time{}
+	repoReplace(ctx, deliveryStore, d.ID, d)
+	if !inDryRun(ctx) {
+		stateOf(ctx).dispatcher.enqueue(d.ID)
+	}
+	return d
+}
+
+// findDeadLetterVar loads the failed delivery named by {id}, writing the
+// problem and returning false when there is none.
+func findDeadLetterVar(w http.ResponseWriter, r *http.Request) (WebhookDelivery, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid delivery ID")
+		return WebhookDelivery{}, false
+	}
+	d, exists := repoFind(r.Context(), deliveryStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Delivery not found")
+		return WebhookDelivery{}, false
+	}
+	if d.Status != deliveryFailed {
+		writeProblem(w, r, http.StatusConflict, "Delivery has status "+d.Status+" and is not dead-lettered")
+		return WebhookDelivery{}, false
+	}
+	return d, true
+}
+
+// getDeadLetters is the dead-letter list of every webhook.
+func getDeadLetters(w http.ResponseWriter, r *http.Request) {
+	writeJSONArray(w, deadLetters(r.Context(), 0))
+}
+
+// replayDelivery serves POST /webhooks/deliveries/{id}/replay.
+func replayDelivery(w http.ResponseWriter, r *http.Request) {
+	d, ok := findDeadLetterVar(w, r)
+	if !ok {
+		return
+	}
+	hook, exists := repoFind(r.Context(), webhookStore, d.WebhookID)
+	if !exists || !hook.Active {
+		writeProblem(w, r, http.StatusConflict, "Webhook is inactive; activate it before replaying its deliveries")
+		return
+	}
+
+	d = replay(r.Context(), d)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(d)
+}
+
+// replayWebhookDeliveries replays every dead letter of a webhook, as when
+// its receiver is back after an outage.
+func replayWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	hook, exists := repoFind(r.Context(), webhookStore, id)
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if !hook.Active {
+		writeProblem(w, r, http.StatusConflict, "Webhook is inactive; activate it before replaying its deliveries")
+		return
+	}
+
+	replayed := []int{}
+	for _, d := range deadLetters(r.Context(), id) {
+		replayed = append(replayed, replay(r.Context(), d).ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string][]int{"replayed": replayed})
+}
+
+// discardDelivery removes a dead letter that is not worth replaying.
+func discardDelivery(w http.ResponseWriter, r *http.Request) {
+	d, ok := findDeadLetterVar(w, r)
+	if !ok {
+		return
+	}
+	repoRemove(r.Context(), deliveryStore, d.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver answers deliveries with a status that can be changed,
+// and records what it was sent.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	status     int
+	deliveries []receivedDelivery
+}
+
+type receivedDelivery struct {
+	id, signature string
+	body          []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	rcv := &webhookReceiver{status: http.StatusInternalServerError}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.deliveries = append(rcv.deliveries, receivedDelivery{id: r.Header.Get("X-Webhook-Delivery"), signature: r.Header.Get("X-Webhook-Signature"), body: body})
+		w.WriteHeader(rcv.status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (rcv *webhookReceiver) answer(status int) {
+	rcv.mu.Lock()
+	rcv.status = status
+	rcv.mu.Unlock()
+}
+
+func (rcv *webhookReceiver) received() []receivedDelivery {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return slices.Clone(rcv.deliveries)
+}
+
+// waitForDelivery polls a delivery until it has status.
+func (api *testAPI) waitForDelivery(t *testing.T, hookID, id int, status string) WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var list []WebhookDelivery
+		api.decode(t, "GET", "/v1/webhooks/"+strconv.Itoa(hookID)+"/deliveries", nil, http.StatusOK, &list)
+		for _, d := range list {
+			if d.ID == id && d.Status == status {
+				return d
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery %d did not become %s: %+v", id, status, list)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	rcv := newWebhookReceiver(t)
+	api := newTestAPI(t, func(c *Config) {
+		// Every failure dead-letters the delivery at once.
+		c.WebhookMaxAttempts = 1
+		c.Retention = time.Nanosecond
+	})
+	// The senders alone, without the scheduler, so only the test retries.
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	startWebhookDispatcher(api.srv.state.context(ctx))
+	var hook Webhook
+	api.decode(t, "POST", "/v1/webhooks", Webhook{URL: rcv.URL, Events: []string{eventStudentCreated}}, http.StatusCreated, &hook)
+	hookPath := "/v1/webhooks/" + strconv.Itoa(hook.ID)
+
+	api.decode(t, "POST", "/v1/students", Student{Name: "Ada Lovelace", Age: 20, Email: "ada@example.com"}, http.StatusCreated, nil)
+	dead := api.waitForDelivery(t, hook.ID, 1, deliveryFailed)
+	if dead.Attempts != 1 || dead.LastStatusCode != http.StatusInternalServerError || dead.LastError != "receiver answered 500" || dead.DeadLetteredAt.IsZero() || dead.NextAttemptAt != (time.Time{}) {
+		t.Errorf("dead letter = %+v", dead)
+	}
+	var letters []WebhookDelivery
+	api.decode(t, "GET", "/v1/webhooks/dead-letters", nil, http.StatusOK, &letters)
+	if len(letters) != 1 || letters[0].ID != dead.ID {
+		t.Fatalf("dead letters = %+v", letters)
+	}
+
+	// Dead letters are kept whatever the retention.
+	if _, err := retentionPurgeJob(api.srv.state.context(context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	api.decode(t, "GET", "/v1/webhooks/dead-letters", nil, http.StatusOK, &letters)
+	if len(letters) != 1 {
+		t.Fatalf("dead letters after the retention purge = %+v", letters)
+	}
+
+	// Replaying needs an active webhook and a dead letter.
+	api.decode(t, "PUT", hookPath, Webhook{URL: rcv.URL, Events: hook.Events, Active: false}, http.StatusOK, nil)
+	for path, status := range map[string]int{
+		"/v1/webhooks/deliveries/1/replay":  http.StatusConflict,
+		"/v1/webhooks/deliveries/99/replay": http.StatusNotFound,
+		"/v1/webhooks/deliveries/x/replay":  http.StatusBadRequest,
+		hookPath + "/replay":                http.StatusConflict,
+		"/v1/webhooks/99/replay":            http.StatusNotFound,
+	} {
+		api.decode(t, "POST", path, nil, status, nil)
+	}
+	api.decode(t, "PUT", hookPath, Webhook{URL: rcv.URL, Events: hook.Events, Active: true}, http.StatusOK, nil)
+
+	// A replay resends the same delivery, with the same ID, payload and
+	// signature, for a fresh round of attempts.
+	rcv.answer(http.StatusNoContent)
+	var replayed WebhookDelivery
+	api.decode(t, "POST", "/v1/webhooks/deliveries/1/replay", nil, http.StatusAccepted, &replayed)
+	if replayed.Status != deliveryPending || replayed.Attempts != 0 || replayed.Replays != 1 || !replayed.DeadLetteredAt.IsZero() {
+		t.Errorf("replayed = %+v", replayed)
+	}
+	delivered := api.waitForDelivery(t, hook.ID, 1, deliverySucceeded)
+	if delivered.Attempts != 1 || delivered.Replays != 1 || delivered.LastError != "" {
+		t.Errorf("delivered = %+v", delivered)
+	}
+	sent := rcv.received()
+	if len(sent) != 2 || sent[1].id != "1" || sent[1].id != sent[0].id || string(sent[1].body) != string(sent[0].body) || sent[1].signature != sent[0].signature {
+		t.Errorf("receiver got %+v", sent)
+	}
+	if want := signPayload(hook.Secret, sent[1].body); sent[1].signature != want {
+		t.Errorf("signature = %s, want %s", sent[1].signature, want)
+	}
+	api.decode(t, "GET", "/v1/webhooks/dead-letters", nil, http.StatusOK, &letters)
+	if len(letters) != 0 {
+		t.Errorf("dead letters after the replay = %+v", letters)
+	}
+	api.decode(t, "POST", "/v1/webhooks/deliveries/1/replay", nil, http.StatusConflict, nil)
+	api.decode(t, "DELETE", "/v1/webhooks/deliveries/1", nil, http.StatusConflict, nil)
+
+	// After an outage, a webhook's dead letters are replayed together.
+	rcv.answer(http.StatusServiceUnavailable)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Alan Turing", Age: 41, Email: "alan@example.com"}, http.StatusCreated, nil)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Grace Hopper", Age: 85, Email: "grace@example.com"}, http.StatusCreated, nil)
+	api.waitForDelivery(t, hook.ID, 2, deliveryFailed)
+	api.waitForDelivery(t, hook.ID, 3, deliveryFailed)
+	rcv.answer(http.StatusOK)
+	var all struct {
+		Replayed []int `json:"replayed"`
+	}
+	api.decode(t, "POST", hookPath+"/replay", nil, http.StatusAccepted, &all)
+	slices.Sort(all.Replayed)
+	if !slices.Equal(all.Replayed, []int{2, 3}) {
+		t.Errorf("replayed = %v, want [2 3]", all.Replayed)
+	}
+	api.waitForDelivery(t, hook.ID, 2, deliverySucceeded)
+	api.waitForDelivery(t, hook.ID, 3, deliverySucceeded)
+	api.decode(t, "POST", hookPath+"/replay", nil, http.StatusAccepted, &all)
+	if len(all.Replayed) != 0 {
+		t.Errorf("replayed with no dead letters = %v", all.Replayed)
+	}
+
+	// A dead letter not worth replaying is discarded.
+	rcv.answer(http.StatusGone)
+	api.decode(t, "POST", "/v1/students", Student{Name: "Edsger Dijkstra", Age: 41, Email: "edsger@example.com"}, http.StatusCreated, nil)
+	api.waitForDelivery(t, hook.ID, 4, deliveryFailed)
+	api.decode(t, "DELETE", "/v1/webhooks/deliveries/4", nil, http.StatusNoContent, nil)
+	api.decode(t, "DELETE", "/v1/webhooks/deliveries/4", nil, http.StatusNotFound, nil)
+	api.decode(t, "GET", "/v1/webhooks/dead-letters", nil, http.StatusOK, &letters)
+	if len(letters) != 0 {
+		t.Errorf("dead letters after the discard = %+v", letters)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket keep-alive: the server pings every wsPingPeriod and drops a
+// client that has not answered within wsPongWait.
+const (
+	wsPingPeriod = 30 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsWriteWait  = 10 * time.Second
+	// wsAuthWait is how long a client connecting without credentials has
+	// to send an auth message.
+	wsAuthWait = 10 * time.Second
+)
+
+// The default origin check only accepts pages served from this host.
+var wsUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// wsCommand is a message from a client. Action is auth, subscribe or
+// unsubscribe. A subscription matches changes to any of the entity types
+// listed and any change concerning one of the student IDs.
+type wsCommand struct {
+	Action     string   `json:"action"`
+	APIKey     string   `json:"api_key,omitempty"`
+	Entities   []string `json:"entities,omitempty"`
+	StudentIDs []int    `json:"student_ids,omitempty"`
+}
+
+// wsMessage is a message to a client. Type is ready, subscribed, change
+// or error.
+type wsMessage struct {
+	Type          string          `json:"type"`
+	Subscriptions *wsSubscription `json:"subscriptions,omitempty"`
+	Change        *Change         `json:"change,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+type wsSubscription struct {
+	Entities   []string `json:"entities"`
+	StudentIDs []int    `json:"student_ids"`
+}
+
+// changeEntities are the entity types that appear in the change feed.
+var changeEntities = []string{"student", "enrollment", "attendance"}
+
+// changeStudentID returns the student a change concerns.
+func changeStudentID(c Change) int {
+	switch d := c.Data.(type) {
+	case Enrollment:
+		return d.StudentID
+	case AttendanceRecord:
+		return d.StudentID
+	}
+	if c.Entity == "student" {
+		return c.EntityID
+	}
+	return 0
+}
+
+func (s *wsSubscription) matches(c Change) bool {
+	return slices.Contains(s.Entities, c.Entity) || slices.Contains(s.StudentIDs, changeStudentID(c))
+}
+
+// apply adds or removes the entities and students of a command, and
+// returns an error message for an unknown entity type.
+func (s *wsSubscription) apply(cmd wsCommand) string {
+	for _, e := range cmd.Entities {
+		if !slices.Contains(changeEntities, e) {
+			return "unknown entity " + e + "; want one of student, enrollment, attendance"
+		}
+	}
+	if cmd.Action == "subscribe" {
+		for _, e := range cmd.Entities {
+			if !slices.Contains(s.Entities, e) {
+				s.Entities = append(s.Entities, e)
+			}
+		}
+		for _, id := range cmd.StudentIDs {
+			if !slices.Contains(s.StudentIDs, id) {
+				s.StudentIDs = append(s.StudentIDs, id)
+			}
+		}
+		return ""
+	}
+	s.Entities = slices.DeleteFunc(s.Entities, func(e string) bool { return slices.Contains(cmd.Entities, e) })
+	s.StudentIDs = slices.DeleteFunc(s.StudentIDs, func(id int) bool { return slices.Contains(cmd.StudentIDs, id) })
+	return ""
+}
+
+// serveWebSocket serves /ws. Clients authenticate with an API key, either
+// on the upgrade request like any other endpoint or, since browsers
+// cannot set headers there, in an auth message sent first. They then
+// subscribe to entity types or student IDs and receive each matching
+// change feed entry as a change message.
+func serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if wait, locked := lockedOut(r.Context(), ip); locked {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many failed attempts")
+		return
+	}
+	_, authed := authenticate(r)
+	if !authed && apiKeyFromRequest(r) != "" {
+		recordAuthFailure(r.Context(), ip)
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	hub := stateOf(r.Context()).hub
+	updates, err := hub.join()
+	if err != nil {
+		w.Header().Set("Retry-After", "5")
+		writeProblem(w, r, http.StatusServiceUnavailable, "Live updates are unavailable: "+err.Error())
+		return
+	}
+	defer hub.leave(updates)
+
+	conn, err := wsUpgrader.Upgrade(hijackable{w}, r, nil)
+	if err != nil {
+		// The upgrader has already answered the client.
+		return
+	}
+	defer conn.Close()
+
+	ctx := r.Context()
+	if !authed {
+		conn.SetReadDeadline(time.Now().Add(wsAuthWait))
+		var cmd wsCommand
+		if err := conn.ReadJSON(&cmd); err != nil || cmd.Action != "auth" {
+			wsClose(conn, websocket.ClosePolicyViolation, "authenticate first")
+			return
+		}
+		if _, ok := apiKeys[cmd.APIKey]; !ok {
+			recordAuthFailure(ctx, ip)
+			wsClose(conn, websocket.ClosePolicyViolation, "unauthorized")
+			return
+		}
+	}
+	recordAuthSuccess(ctx, ip)
+
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// Only this goroutine writes; the reader hands commands over.
+	commands := make(chan wsCommand)
+	readerDone := make(chan struct{})
+	go wsReadCommands(ctx, conn, commands, readerDone)
+
+	send := func(m wsMessage) bool {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(m) == nil
+	}
+	sub := &wsSubscription{Entities: []string{}, StudentIDs: []int{}}
+	if !send(wsMessage{Type: "ready", Subscriptions: sub}) {
+		return
+	}
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case <-readerDone:
+			return
+		case c, ok := <-updates:
+			if !ok {
+				wsClose(conn, websocket.CloseGoingAway, "server is shutting down or client fell behind")
+				return
+			}
+			if sub.matches(c) && !send(wsMessage{Type: "change", Change: &c}) {
+				return
+			}
+		case cmd := <-commands:
+			msg := wsMessage{Type: "subscribed", Subscriptions: sub}
+			switch cmd.Action {
+			case "subscribe", "unsubscribe":
+				if problem := sub.apply(cmd); problem != "" {
+					msg = wsMessage{Type: "error", Error: problem}
+				}
+			default:
+				msg = wsMessage{Type: "error", Error: "unknown action " + cmd.Action + "; want subscribe or unsubscribe"}
+			}
+			if !send(msg) {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsReadCommands reads client messages until the connection fails,
+// including by missing a pong, and then closes done.
+func wsReadCommands(ctx context.Context, conn *websocket.Conn, commands chan<- wsCommand, done chan<- struct{}) {
+	defer close(done)
+	for {
+		var cmd wsCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.DebugContext(ctx, "websocket closed", "error", err)
+			}
+			return
+		}
+		select {
+		case commands <- cmd:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// hijackable reaches the connection through the middleware's response
+// writers, which expose it to http.ResponseController but do not
+// implement http.Hijacker themselves.
+type hijackable struct {
+	http.ResponseWriter
+}
+
+func (h hijackable) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
+
+func wsClose(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+}